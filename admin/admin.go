@@ -0,0 +1,165 @@
+// Package admin provides an HTTP handler for operational debugging of a Cache.
+//
+// Endpoints, relative to the mount point:
+//
+//	GET    /info             cache name and labels, see ttlcache.WithLabels
+//	GET    /stats            cache counters
+//	GET    /key?key=42       record lookup by uint64 key
+//	GET    /key?string=name  record lookup by ttlcache.StringKey(name)
+//	DELETE /key?...          record deletion, same parameters as lookup
+//	POST   /expired          remove outdated records now
+//	GET    /snapshot         stream a snapshot of the cache
+//
+// Mount it under an internal mux with http.StripPrefix:
+//
+//	mux.Handle("/debug/cache/", http.StripPrefix("/debug/cache", admin.NewHandler(cache, ttlcache.GobCodec{})))
+package admin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	ttlcache "github.com/loicalleyne/ttlswisscache"
+)
+
+// Handler serves the admin endpoints.
+type Handler struct {
+	cache *ttlcache.Cache
+	codec ttlcache.Codec
+	mux   *http.ServeMux
+}
+
+// NewHandler creates a handler for the cache.
+// codec encodes snapshot values.
+func NewHandler(cache *ttlcache.Cache, codec ttlcache.Codec) *Handler {
+	h := &Handler{cache: cache, codec: codec, mux: http.NewServeMux()}
+	h.mux.HandleFunc("/info", h.info)
+	h.mux.HandleFunc("/stats", h.stats)
+	h.mux.HandleFunc("/key", h.key)
+	h.mux.HandleFunc("/expired", h.expired)
+	h.mux.HandleFunc("/snapshot", h.snapshot)
+	return h
+}
+
+// ServeHTTP implements http.Handler.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+// Record is the JSON representation of a cache record.
+type Record struct {
+	Key   uint64      `json:"key"`
+	TTL   string      `json:"ttl"`
+	Value interface{} `json:"value"`
+}
+
+// Info is the JSON representation of the identity of a cache.
+type Info struct {
+	Name   string            `json:"name"`
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+func (h *Handler) info(w http.ResponseWriter, r *http.Request) {
+	if !allow(w, r, http.MethodGet) {
+		return
+	}
+	writeJSON(w, http.StatusOK, Info{Name: h.cache.Name(), Labels: h.cache.Labels()})
+}
+
+func (h *Handler) stats(w http.ResponseWriter, r *http.Request) {
+	if !allow(w, r, http.MethodGet) {
+		return
+	}
+	writeJSON(w, http.StatusOK, h.cache.Stats())
+}
+
+func (h *Handler) key(w http.ResponseWriter, r *http.Request) {
+	if !allow(w, r, http.MethodGet, http.MethodDelete) {
+		return
+	}
+	key, err := parseKey(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if r.Method == http.MethodDelete {
+		if !h.cache.Remove(key) {
+			writeError(w, http.StatusNotFound, fmt.Errorf("key %d not found", key))
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"deleted": key})
+		return
+	}
+
+	// Peek doesn't count the lookup as a read of the record.
+	value, ttl, ok := h.cache.Peek(key)
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("key %d not found", key))
+		return
+	}
+	if _, err := json.Marshal(value); err != nil {
+		value = fmt.Sprintf("%#v", value)
+	}
+	writeJSON(w, http.StatusOK, Record{Key: key, TTL: ttl.String(), Value: value})
+}
+
+func (h *Handler) expired(w http.ResponseWriter, r *http.Request) {
+	if !allow(w, r, http.MethodPost) {
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]int{"removed": h.cache.DeleteExpired()})
+}
+
+func (h *Handler) snapshot(w http.ResponseWriter, r *http.Request) {
+	if !allow(w, r, http.MethodGet) {
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", `attachment; filename="cache.snapshot"`)
+	// The status is already sent once streaming starts, a failure can only abort the body.
+	if err := h.cache.Snapshot(w, h.codec); err != nil {
+		panic(http.ErrAbortHandler)
+	}
+}
+
+func parseKey(r *http.Request) (uint64, error) {
+	q := r.URL.Query()
+	if s := q.Get("string"); s != "" {
+		return ttlcache.StringKey(s), nil
+	}
+	s := q.Get("key")
+	if s == "" {
+		return 0, fmt.Errorf("key or string parameter is required")
+	}
+	key, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid key %q", s)
+	}
+	return key, nil
+}
+
+func allow(w http.ResponseWriter, r *http.Request, methods ...string) bool {
+	for _, m := range methods {
+		if r.Method == m {
+			return true
+		}
+	}
+	for _, m := range methods {
+		w.Header().Add("Allow", m)
+	}
+	writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+	return false
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,109 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	ttlcache "github.com/loicalleyne/ttlswisscache"
+)
+
+func TestHandler(t *testing.T) {
+	cache := ttlcache.New(time.Hour)
+	defer cache.Close()
+	cache.Set(ttlcache.StringKey("key"), "value", time.Hour)
+	cache.Set(ttlcache.IntKey(1), 1, -time.Second)
+
+	srv := httptest.NewServer(http.StripPrefix("/debug/cache", NewHandler(cache, ttlcache.GobCodec{})))
+	defer srv.Close()
+
+	tt := []struct {
+		method string
+		path   string
+		status int
+	}{
+		{method: http.MethodGet, path: "/info", status: http.StatusOK},
+		{method: http.MethodGet, path: "/stats", status: http.StatusOK},
+		{method: http.MethodGet, path: "/key?string=key", status: http.StatusOK},
+		{method: http.MethodGet, path: "/key?key=2", status: http.StatusNotFound},
+		{method: http.MethodGet, path: "/key?key=abc", status: http.StatusBadRequest},
+		{method: http.MethodGet, path: "/key", status: http.StatusBadRequest},
+		{method: http.MethodGet, path: "/expired", status: http.StatusMethodNotAllowed},
+		{method: http.MethodPost, path: "/expired", status: http.StatusOK},
+		{method: http.MethodGet, path: "/snapshot", status: http.StatusOK},
+		{method: http.MethodDelete, path: "/key?string=key", status: http.StatusOK},
+		{method: http.MethodDelete, path: "/key?string=key", status: http.StatusNotFound},
+	}
+
+	for _, tc := range tt {
+		req, _ := http.NewRequest(tc.method, srv.URL+"/debug/cache"+tc.path, nil)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tc.status {
+			t.Errorf("incorrect status for %s %s: got: %d expected: %d", tc.method, tc.path, resp.StatusCode, tc.status)
+		}
+	}
+
+	if st := cache.Stats(); st.Expired != 1 || st.Deletes != 1 {
+		t.Errorf("incorrect stats: got: %+v", st)
+	}
+}
+
+func TestHandler_Key(t *testing.T) {
+	cache := ttlcache.New(time.Hour)
+	defer cache.Close()
+	cache.Set(42, "value", time.Hour)
+
+	rec := httptest.NewRecorder()
+	NewHandler(cache, ttlcache.GobCodec{}).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/key?key=42", nil))
+
+	var record Record
+	if err := json.NewDecoder(rec.Body).Decode(&record); err != nil {
+		t.Fatal(err)
+	}
+	if record.Key != 42 || record.Value != "value" || record.TTL == "" {
+		t.Errorf("incorrect record: got: %+v", record)
+	}
+}
+
+func TestHandler_KeyPeek(t *testing.T) {
+	cache := ttlcache.New(time.Hour)
+	defer cache.Close()
+	cache.SetOnce(42, "token", time.Hour)
+	h := NewHandler(cache, ttlcache.GobCodec{})
+
+	for i := 0; i < 2; i++ {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/key?key=42", nil))
+		if rec.Code != http.StatusOK {
+			t.Errorf("incorrect status: got: %d expected: %d", rec.Code, http.StatusOK)
+		}
+	}
+	if st := cache.Stats(); st.Hits != 0 || st.Misses != 0 {
+		t.Errorf("lookup counted as a read: got: %+v", st)
+	}
+	if v, ok := cache.Get(42); !ok || v != "token" {
+		t.Errorf("lookup consumed the value: got: %v, %v", v, ok)
+	}
+}
+
+func TestHandler_Info(t *testing.T) {
+	cache := ttlcache.New(time.Hour, ttlcache.WithName("users"), ttlcache.WithLabels(map[string]string{"team": "search"}))
+	defer cache.Close()
+
+	rec := httptest.NewRecorder()
+	NewHandler(cache, ttlcache.GobCodec{}).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/info", nil))
+
+	var info Info
+	if err := json.NewDecoder(rec.Body).Decode(&info); err != nil {
+		t.Fatal(err)
+	}
+	if info.Name != "users" || len(info.Labels) != 1 || info.Labels["team"] != "search" {
+		t.Errorf("incorrect info: got: %+v", info)
+	}
+}
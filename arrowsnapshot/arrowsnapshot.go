@@ -0,0 +1,114 @@
+// Package arrowsnapshot writes cache snapshots as Arrow IPC streams and loads
+// them back, so cache contents can be analyzed with Arrow tooling, e.g. pyarrow
+// or DuckDB, and bulk-loaded on restart.
+//
+// A stream has three columns: key (uint64), deadline (timestamp in nanoseconds,
+// UTC) and value (binary, encoded by a ttlcache.Codec). Records are written in
+// batches of up to BatchRows rows, read from the cache with Scan, so writers are
+// blocked only for the time of a batch.
+package arrowsnapshot
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/ipc"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+
+	ttlcache "github.com/loicalleyne/ttlswisscache"
+)
+
+// BatchRows is the maximum number of rows of a record batch.
+const BatchRows = 64 * 1024
+
+// Schema is the schema of the snapshot streams.
+var Schema = arrow.NewSchema([]arrow.Field{
+	{Name: "key", Type: arrow.PrimitiveTypes.Uint64},
+	{Name: "deadline", Type: &arrow.TimestampType{Unit: arrow.Nanosecond, TimeZone: "UTC"}},
+	{Name: "value", Type: arrow.BinaryTypes.Binary},
+}, nil)
+
+// ErrSchema is returned by Read for streams not matching Schema.
+var ErrSchema = errors.New("arrowsnapshot: unexpected schema")
+
+// Write writes the live records of cache to w as an Arrow IPC stream, encoding
+// the values with codec, and returns the number of records written.
+// opts are passed to the IPC writer, e.g. ipc.WithZstd().
+func Write(w io.Writer, cache *ttlcache.Cache, codec ttlcache.Codec, opts ...ipc.Option) (int, error) {
+	mem := memory.DefaultAllocator
+	iw := ipc.NewWriter(w, append([]ipc.Option{ipc.WithSchema(Schema), ipc.WithAllocator(mem)}, opts...)...)
+	b := array.NewRecordBuilder(mem, Schema)
+	defer b.Release()
+	keys := b.Field(0).(*array.Uint64Builder)
+	deadlines := b.Field(1).(*array.TimestampBuilder)
+	values := b.Field(2).(*array.BinaryBuilder)
+
+	n := 0
+	for cur := (ttlcache.Cursor{}); !cur.Done(); {
+		var entries []ttlcache.Entry
+		entries, cur = cache.Scan(cur, BatchRows)
+		if len(entries) == 0 {
+			continue
+		}
+		for _, e := range entries {
+			data, err := codec.Marshal(e.Value)
+			if err != nil {
+				iw.Close()
+				return n, fmt.Errorf("arrowsnapshot: encode key %d: %w", e.Key, err)
+			}
+			keys.Append(e.Key)
+			deadlines.Append(arrow.Timestamp(e.Deadline.UnixNano()))
+			values.Append(data)
+		}
+		rec := b.NewRecord()
+		err := iw.Write(rec)
+		rec.Release()
+		if err != nil {
+			iw.Close()
+			return n, err
+		}
+		n += len(entries)
+	}
+	return n, iw.Close()
+}
+
+// Read loads the records of an Arrow IPC stream written by Write into cache
+// with their original deadlines, decoding the values with codec. Records that
+// have expired in the meantime are skipped. It returns the number of records
+// loaded. opts are passed to the IPC reader.
+func Read(r io.Reader, cache *ttlcache.Cache, codec ttlcache.Codec, opts ...ipc.Option) (int, error) {
+	ir, err := ipc.NewReader(r, opts...)
+	if err != nil {
+		return 0, err
+	}
+	defer ir.Release()
+	if !ir.Schema().Equal(Schema) {
+		return 0, ErrSchema
+	}
+
+	n := 0
+	for ir.Next() {
+		rec := ir.Record()
+		keys := rec.Column(0).(*array.Uint64)
+		deadlines := rec.Column(1).(*array.Timestamp)
+		values := rec.Column(2).(*array.Binary)
+		now := cache.Now().UnixNano()
+		for i := 0; i < int(rec.NumRows()); i++ {
+			deadline := int64(deadlines.Value(i))
+			if deadline < now {
+				continue
+			}
+			value, err := codec.Unmarshal(values.Value(i))
+			if err != nil {
+				return n, fmt.Errorf("arrowsnapshot: decode key %d: %w", keys.Value(i), err)
+			}
+			cache.Apply(ttlcache.Event{Op: ttlcache.OpSet, Key: keys.Value(i), Value: value, Deadline: time.Unix(0, deadline)})
+			n++
+		}
+	}
+	return n, ir.Err()
+}
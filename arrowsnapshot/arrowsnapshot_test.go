@@ -0,0 +1,67 @@
+package arrowsnapshot
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow/ipc"
+
+	ttlcache "github.com/loicalleyne/ttlswisscache"
+)
+
+func TestWriteRead(t *testing.T) {
+	src := ttlcache.New(0)
+	defer src.Close()
+	for i := 0; i < BatchRows+10; i++ {
+		src.Set(ttlcache.IntKey(i), i, time.Hour)
+	}
+	src.Set(ttlcache.IntKey(-1), -1, -time.Second)
+
+	var buf bytes.Buffer
+	n, err := Write(&buf, src, ttlcache.JSONCodec{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != BatchRows+10 {
+		t.Errorf("incorrect number of written records: got: %d expected: %d", n, BatchRows+10)
+	}
+
+	dst := ttlcache.New(0)
+	defer dst.Close()
+	n, err = Read(&buf, dst, ttlcache.JSONCodec{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != BatchRows+10 {
+		t.Errorf("incorrect number of read records: got: %d expected: %d", n, BatchRows+10)
+	}
+	if v, ok := dst.Get(ttlcache.IntKey(42)); !ok || v != float64(42) {
+		t.Errorf("incorrect value: got: %v, %v expected: %v, %v", v, ok, 42, true)
+	}
+	want, _ := src.TTL(ttlcache.IntKey(42))
+	if ttl, _ := dst.TTL(ttlcache.IntKey(42)); ttl > want || ttl < want-time.Second {
+		t.Errorf("deadline wasn't kept: got: %v expected: %v", ttl, want)
+	}
+	if _, ok := dst.Get(ttlcache.IntKey(-1)); ok {
+		t.Error("outdated record was written")
+	}
+}
+
+func TestWrite_Zstd(t *testing.T) {
+	c := ttlcache.New(0)
+	defer c.Close()
+	c.Set(1, "value", time.Hour)
+
+	var buf bytes.Buffer
+	if _, err := Write(&buf, c, ttlcache.GobCodec{}, ipc.WithZstd()); err != nil {
+		t.Fatal(err)
+	}
+	c.Clear()
+	if n, err := Read(&buf, c, ttlcache.GobCodec{}); err != nil || n != 1 {
+		t.Errorf("incorrect read: got: %d, %v expected: %d, %v", n, err, 1, nil)
+	}
+	if v, _ := c.Get(1); v != "value" {
+		t.Errorf("incorrect value: got: %v expected: %v", v, "value")
+	}
+}
@@ -0,0 +1,270 @@
+// Package bench replays access traces against a cache and reports its hit
+// ratio, throughput, allocations and garbage collection pauses, to evaluate
+// eviction policies and tunings on recorded workloads.
+//
+// A trace is a list of accesses read from CSV, one "timestamp,op,key" line per
+// access, or from the binary format of WriteBinary. Timestamps are Unix
+// nanoseconds, ops are get, set and delete:
+//
+//	trace, err := bench.ReadCSV(file)
+//	clock := ttltest.NewClock()
+//	cache := ttlcache.NewCache(ttlcache.WithClock(clock), ttlcache.WithMaxEntries(10000), ttlcache.WithEviction(ttlcache.TinyLFU))
+//	r := bench.Replay(cache, trace, bench.Config{TTL: time.Hour, SetOnMiss: true, Clock: clock, Resolution: time.Minute})
+//	fmt.Println(r)
+package bench
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	ttlcache "github.com/loicalleyne/ttlswisscache"
+	"github.com/loicalleyne/ttlswisscache/ttltest"
+)
+
+// recordLen is the length of an access in the binary format.
+const recordLen = 8 + 1 + 8
+
+// Op is the operation of an access.
+type Op uint8
+
+// Ops of an access.
+const (
+	Get Op = iota
+	Set
+	Delete
+)
+
+func (op Op) String() string {
+	switch op {
+	case Get:
+		return "get"
+	case Set:
+		return "set"
+	case Delete:
+		return "delete"
+	default:
+		return "unknown"
+	}
+}
+
+// Access is an access of a trace.
+type Access struct {
+	Time time.Time
+	Op   Op
+	Key  uint64
+}
+
+// Config configures a Replay.
+type Config struct {
+	// TTL is the ttl of the records set.
+	TTL time.Duration
+	// SetOnMiss sets the keys of missed gets like a cache-aside loader.
+	SetOnMiss bool
+	// Value is the value of the records set.
+	Value interface{}
+	// Clock, if set, is advanced to the timestamps of the accesses so ttls
+	// follow the time of the trace. It must be the clock of the cache, see
+	// ttlcache.WithClock, and the accesses must be in time order.
+	Clock *ttltest.Clock
+	// Resolution removes the outdated records every Resolution of the time
+	// of the trace with DeleteExpired, like the cleanup manager of a cache
+	// with this resolution. It requires Clock.
+	Resolution time.Duration
+}
+
+// Result is the outcome of a Replay.
+type Result struct {
+	Gets, Hits, Misses uint64
+	Sets, Deletes      uint64
+	Duration           time.Duration
+	Allocs             uint64 // Heap allocations during the replay.
+	AllocBytes         uint64
+	GCs                uint32
+	PauseTotal         time.Duration // Stop-the-world pauses of the GCs.
+	PauseMax           time.Duration // Longest pause of the last 256 GCs.
+}
+
+// Ops returns the number of accesses replayed.
+func (r Result) Ops() uint64 {
+	return r.Gets + r.Sets + r.Deletes
+}
+
+// HitRatio returns the share of gets that hit.
+func (r Result) HitRatio() float64 {
+	if r.Gets == 0 {
+		return 0
+	}
+	return float64(r.Hits) / float64(r.Gets)
+}
+
+// Throughput returns the number of accesses per second.
+func (r Result) Throughput() float64 {
+	if r.Duration <= 0 {
+		return 0
+	}
+	return float64(r.Ops()) / r.Duration.Seconds()
+}
+
+func (r Result) String() string {
+	return fmt.Sprintf("%d ops in %v (%.0f ops/s), hit ratio %.4f, %d allocs (%d B), %d GCs paused %v (max %v)",
+		r.Ops(), r.Duration, r.Throughput(), r.HitRatio(), r.Allocs, r.AllocBytes, r.GCs, r.PauseTotal, r.PauseMax)
+}
+
+// Replay runs the accesses of trace against cache in order on the calling
+// goroutine and returns the Result. Allocations and pauses are those of the
+// whole process during the replay.
+func Replay(cache *ttlcache.Cache, trace []Access, cfg Config) Result {
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	start := time.Now()
+	r := replay(cache, trace, cfg)
+	r.Duration = time.Since(start)
+	runtime.ReadMemStats(&after)
+
+	r.Allocs = after.Mallocs - before.Mallocs
+	r.AllocBytes = after.TotalAlloc - before.TotalAlloc
+	r.GCs = after.NumGC - before.NumGC
+	r.PauseTotal = time.Duration(after.PauseTotalNs - before.PauseTotalNs)
+	// PauseNs is a ring of the last 256 pauses, the one of GC n at (n+255)%256.
+	for n := max(before.NumGC+1, after.NumGC-min(after.NumGC, 255)); n <= after.NumGC; n++ {
+		r.PauseMax = max(r.PauseMax, time.Duration(after.PauseNs[(n+255)%256]))
+	}
+	return r
+}
+
+// replay runs the accesses of trace against cache and counts them.
+func replay(cache *ttlcache.Cache, trace []Access, cfg Config) Result {
+	var r Result
+	var last, cleanup time.Time
+	for i := range trace {
+		a := &trace[i]
+		if cfg.Clock != nil {
+			if i == 0 {
+				cleanup = a.Time.Add(cfg.Resolution)
+			} else if a.Time.After(last) {
+				cfg.Clock.Advance(a.Time.Sub(last))
+			}
+			last = a.Time
+			if cfg.Resolution > 0 && !a.Time.Before(cleanup) {
+				cache.DeleteExpired()
+				cleanup = a.Time.Add(cfg.Resolution)
+			}
+		}
+		switch a.Op {
+		case Get:
+			r.Gets++
+			if _, ok := cache.Get(a.Key); ok {
+				r.Hits++
+				continue
+			}
+			r.Misses++
+			if cfg.SetOnMiss {
+				cache.Set(a.Key, cfg.Value, cfg.TTL)
+			}
+		case Set:
+			r.Sets++
+			cache.Set(a.Key, cfg.Value, cfg.TTL)
+		case Delete:
+			r.Deletes++
+			cache.Delete(a.Key)
+		}
+	}
+	return r
+}
+
+// ReadCSV reads a trace of "timestamp,op,key" lines. Timestamps are Unix
+// nanoseconds, ops are get, set or delete. Keys that aren't decimal uint64 are
+// hashed with ttlcache.StringKey. Empty lines and lines starting with # are
+// skipped.
+func ReadCSV(r io.Reader) ([]Access, error) {
+	var trace []Access
+	sc := bufio.NewScanner(r)
+	for line := 1; sc.Scan(); line++ {
+		text := strings.TrimSpace(sc.Text())
+		if text == "" || text[0] == '#' {
+			continue
+		}
+		fields := strings.SplitN(text, ",", 3)
+		if len(fields) != 3 {
+			return trace, fmt.Errorf("bench: line %d: expected timestamp,op,key", line)
+		}
+		ts, err := strconv.ParseInt(strings.TrimSpace(fields[0]), 10, 64)
+		if err != nil {
+			return trace, fmt.Errorf("bench: line %d: timestamp: %w", line, err)
+		}
+		op, err := parseOp(strings.TrimSpace(fields[1]))
+		if err != nil {
+			return trace, fmt.Errorf("bench: line %d: %w", line, err)
+		}
+		field := strings.TrimSpace(fields[2])
+		key, err := strconv.ParseUint(field, 10, 64)
+		if err != nil {
+			key = ttlcache.StringKey(field)
+		}
+		trace = append(trace, Access{Time: time.Unix(0, ts), Op: op, Key: key})
+	}
+	return trace, sc.Err()
+}
+
+func parseOp(s string) (Op, error) {
+	switch strings.ToLower(s) {
+	case "get":
+		return Get, nil
+	case "set":
+		return Set, nil
+	case "delete", "del":
+		return Delete, nil
+	}
+	return 0, fmt.Errorf("unknown op %q", s)
+}
+
+// WriteBinary writes trace in the binary format: per access, the timestamp in
+// Unix nanoseconds (int64), the op (uint8) and the key (uint64), big endian.
+// It is about five times more compact than CSV and faster to read.
+func WriteBinary(w io.Writer, trace []Access) error {
+	bw := bufio.NewWriter(w)
+	var rec [recordLen]byte
+	for _, a := range trace {
+		binary.BigEndian.PutUint64(rec[0:], uint64(a.Time.UnixNano()))
+		rec[8] = byte(a.Op)
+		binary.BigEndian.PutUint64(rec[9:], a.Key)
+		if _, err := bw.Write(rec[:]); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// ReadBinary reads a trace written by WriteBinary.
+func ReadBinary(r io.Reader) ([]Access, error) {
+	var trace []Access
+	br := bufio.NewReader(r)
+	var rec [recordLen]byte
+	for {
+		if _, err := io.ReadFull(br, rec[:]); err != nil {
+			if err == io.EOF {
+				return trace, nil
+			}
+			if errors.Is(err, io.EOF) {
+				err = io.ErrUnexpectedEOF
+			}
+			return trace, err
+		}
+		if rec[8] > byte(Delete) {
+			return trace, fmt.Errorf("bench: access %d: unknown op %d", len(trace)+1, rec[8])
+		}
+		trace = append(trace, Access{
+			Time: time.Unix(0, int64(binary.BigEndian.Uint64(rec[0:]))),
+			Op:   Op(rec[8]),
+			Key:  binary.BigEndian.Uint64(rec[9:]),
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package bench
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	ttlcache "github.com/loicalleyne/ttlswisscache"
+	"github.com/loicalleyne/ttlswisscache/ttltest"
+)
+
+const csvTrace = `# timestamp,op,key
+0,get,1
+1000000000,get,1
+2000000000,set,user:2
+3000000000,get,user:2
+4000000000,delete,1
+5000000000,get,1
+125000000000,get,user:2
+`
+
+func TestReplay(t *testing.T) {
+	trace, err := ReadCSV(strings.NewReader(csvTrace))
+	if err != nil {
+		t.Fatalf("reading the trace failed: %v", err)
+	}
+	if len(trace) != 7 || trace[2].Key != ttlcache.StringKey("user:2") || trace[4].Op != Delete {
+		t.Fatalf("incorrect trace: %+v", trace)
+	}
+
+	clock := ttltest.NewClock()
+	c := ttlcache.NewCache(ttlcache.WithClock(clock))
+	defer c.Close()
+	r := Replay(c, trace, Config{TTL: time.Minute, SetOnMiss: true, Clock: clock, Resolution: time.Second})
+	// The first get of 1 misses, the gets of user:2 hit until it expires.
+	expected := Result{Gets: 5, Hits: 2, Misses: 3, Sets: 1, Deletes: 1}
+	if r.Gets != expected.Gets || r.Hits != expected.Hits || r.Misses != expected.Misses || r.Sets != expected.Sets || r.Deletes != expected.Deletes {
+		t.Errorf("incorrect result: got: %+v expected: %+v", r, expected)
+	}
+	if r.Ops() != 7 || r.HitRatio() != 0.4 {
+		t.Errorf("incorrect ops and hit ratio: got: %d, %v expected: %d, %v", r.Ops(), r.HitRatio(), 7, 0.4)
+	}
+	if r.Duration <= 0 || r.Throughput() <= 0 {
+		t.Errorf("incorrect duration: got: %v", r.Duration)
+	}
+	if !clock.Now().Equal(ttltest.Start.Add(125 * time.Second)) {
+		t.Errorf("incorrect clock after the replay: got: %v expected: %v", clock.Now(), ttltest.Start.Add(125*time.Second))
+	}
+}
+
+func TestBinary(t *testing.T) {
+	trace, _ := ReadCSV(strings.NewReader(csvTrace))
+	var buf bytes.Buffer
+	if err := WriteBinary(&buf, trace); err != nil {
+		t.Fatalf("writing the trace failed: %v", err)
+	}
+	if buf.Len() != len(trace)*recordLen {
+		t.Errorf("incorrect length: got: %d expected: %d", buf.Len(), len(trace)*recordLen)
+	}
+	read, err := ReadBinary(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("reading the trace failed: %v", err)
+	}
+	for i := range trace {
+		if !read[i].Time.Equal(trace[i].Time) || read[i].Op != trace[i].Op || read[i].Key != trace[i].Key {
+			t.Errorf("incorrect access %d: got: %+v expected: %+v", i, read[i], trace[i])
+		}
+	}
+	if _, err := ReadBinary(bytes.NewReader(buf.Bytes()[:buf.Len()-1])); err == nil {
+		t.Error("truncated trace was read")
+	}
+	if _, err := ReadCSV(strings.NewReader("0,put,1\n")); err == nil {
+		t.Error("unknown op was read")
+	}
+}
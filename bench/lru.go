@@ -0,0 +1,154 @@
+package bench
+
+import (
+	"sync"
+
+	ttlcache "github.com/loicalleyne/ttlswisscache"
+)
+
+// protectedPercent is the share of the protected segment of NewSLRU.
+const protectedPercent = 80
+
+// NewLRU returns an exact LRU ttlcache.EvictionPolicy for shards of
+// maxEntries records, see ttlcache.WithEvictionPolicy. It moves a record in a
+// linked list under a mutex on every read, so it mostly serves as a baseline
+// for the built-in policies.
+func NewLRU(maxEntries int) ttlcache.EvictionPolicy {
+	return newSegmentedLRU(0)
+}
+
+// NewSLRU returns a segmented LRU ttlcache.EvictionPolicy for shards of
+// maxEntries records: new records enter a probation segment and move to a
+// protected one, 80% of the records, when read again, so records read once
+// are evicted before those read twice.
+func NewSLRU(maxEntries int) ttlcache.EvictionPolicy {
+	return newSegmentedLRU(uint32(max(maxEntries, 1)) * protectedPercent / 100)
+}
+
+// Segments of segmentedLRU.
+const (
+	probation = iota
+	protected
+)
+
+// segmentedLRU implements NewLRU and NewSLRU over the slots of a shard. Each
+// segment is an LRU list linked through the slots, most recent first.
+type segmentedLRU struct {
+	mu       sync.Mutex // Guards OnAccess, the other methods run under the shard write lock.
+	nodes    []lruNode  // By slot.
+	segments [2]lruList
+	protCap  uint32 // 0 for a plain LRU.
+}
+
+type lruNode struct {
+	prev, next uint32
+	segment    uint8
+}
+
+type lruList struct {
+	head, tail uint32
+	len        uint32
+}
+
+func newSegmentedLRU(protCap uint32) *segmentedLRU {
+	e := &segmentedLRU{protCap: protCap}
+	e.Reset(0)
+	return e
+}
+
+func (e *segmentedLRU) OnAdd(_ uint64, i uint32) {
+	for uint32(len(e.nodes)) <= i {
+		e.nodes = append(e.nodes, lruNode{})
+	}
+	e.pushFront(probation, i)
+}
+
+func (e *segmentedLRU) OnAccess(_ uint64, i uint32) {
+	if i == ttlcache.NoSlot {
+		return
+	}
+	e.mu.Lock()
+	segment := e.nodes[i].segment
+	e.unlink(i)
+	if e.protCap == 0 {
+		e.pushFront(segment, i)
+		e.mu.Unlock()
+		return
+	}
+	e.pushFront(protected, i)
+	if p := &e.segments[protected]; p.len > e.protCap {
+		demoted := p.tail
+		e.unlink(demoted)
+		e.pushFront(probation, demoted)
+	}
+	e.mu.Unlock()
+}
+
+func (e *segmentedLRU) OnRemove(_ uint64, i, last uint32) {
+	e.unlink(i)
+	if i == last {
+		return
+	}
+	// Relink the node of the last slot at i.
+	n := e.nodes[last]
+	e.nodes[i] = n
+	l := &e.segments[n.segment]
+	if n.prev == ttlcache.NoSlot {
+		l.head = i
+	} else {
+		e.nodes[n.prev].next = i
+	}
+	if n.next == ttlcache.NoSlot {
+		l.tail = i
+	} else {
+		e.nodes[n.next].prev = i
+	}
+}
+
+// Victim returns the least recently used record of probation, or of the
+// protected segment if probation is empty.
+func (e *segmentedLRU) Victim([]uint64, []int64) uint32 {
+	if victim := e.segments[probation].tail; victim != ttlcache.NoSlot {
+		return victim
+	}
+	return e.segments[protected].tail
+}
+
+func (e *segmentedLRU) Reset(n uint32) {
+	for s := range e.segments {
+		e.segments[s] = lruList{head: ttlcache.NoSlot, tail: ttlcache.NoSlot}
+	}
+	e.nodes = e.nodes[:0]
+	for i := uint32(0); i < n; i++ {
+		e.nodes = append(e.nodes, lruNode{})
+		e.pushFront(probation, i)
+	}
+}
+
+func (e *segmentedLRU) pushFront(segment uint8, i uint32) {
+	l := &e.segments[segment]
+	e.nodes[i] = lruNode{prev: ttlcache.NoSlot, next: l.head, segment: segment}
+	if l.head == ttlcache.NoSlot {
+		l.tail = i
+	} else {
+		e.nodes[l.head].prev = i
+	}
+	l.head = i
+	l.len++
+}
+
+func (e *segmentedLRU) unlink(i uint32) {
+	n := e.nodes[i]
+	l := &e.segments[n.segment]
+	if n.prev == ttlcache.NoSlot {
+		l.head = n.next
+	} else {
+		e.nodes[n.prev].next = n.next
+	}
+	if n.next == ttlcache.NoSlot {
+		l.tail = n.prev
+	} else {
+		e.nodes[n.next].prev = n.prev
+	}
+	l.len--
+}
@@ -0,0 +1,54 @@
+package bench
+
+import (
+	"testing"
+	"time"
+
+	ttlcache "github.com/loicalleyne/ttlswisscache"
+)
+
+func TestNewLRU(t *testing.T) {
+	c := ttlcache.New(0, ttlcache.WithShardCount(1), ttlcache.WithMaxEntries(3), ttlcache.WithEvictionPolicy(NewLRU))
+	defer c.Close()
+	for i := uint64(1); i <= 3; i++ {
+		c.Set(i, i, time.Hour)
+	}
+	c.Get(1)
+	c.Set(4, 4, time.Hour)
+	// 2 is the least recently used.
+	for key, expected := range map[uint64]bool{1: true, 2: false, 3: true, 4: true} {
+		if _, ok := c.Get(key); ok != expected {
+			t.Errorf("incorrect presence of %d: got: %v expected: %v", key, ok, expected)
+		}
+	}
+	if err := c.Verify(); err != nil {
+		t.Errorf("incorrect error: got: %v expected: %v", err, nil)
+	}
+}
+
+func TestNewSLRU(t *testing.T) {
+	c := ttlcache.New(0, ttlcache.WithShardCount(1), ttlcache.WithMaxEntries(10), ttlcache.WithEvictionPolicy(NewSLRU))
+	defer c.Close()
+	for i := uint64(0); i < 5; i++ {
+		c.Set(i, i, time.Hour)
+		c.Get(i)
+	}
+	// A scan of keys read once doesn't evict the keys read twice.
+	for i := uint64(100); i < 200; i++ {
+		c.Set(i, i, time.Hour)
+	}
+	for i := uint64(0); i < 5; i++ {
+		if _, ok := c.Get(i); !ok {
+			t.Errorf("incorrect presence of %d: got: %v expected: %v", i, ok, true)
+		}
+	}
+	if n := c.Stats().Entries; n != 10 {
+		t.Errorf("incorrect length: got: %d expected: %d", n, 10)
+	}
+	for i := uint64(100); i < 200; i += 3 {
+		c.Delete(i)
+	}
+	if err := c.Verify(); err != nil {
+		t.Errorf("incorrect error: got: %v expected: %v", err, nil)
+	}
+}
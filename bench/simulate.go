@@ -0,0 +1,104 @@
+package bench
+
+import (
+	"runtime"
+	"sync"
+	"time"
+
+	ttlcache "github.com/loicalleyne/ttlswisscache"
+	"github.com/loicalleyne/ttlswisscache/ttltest"
+)
+
+// Policy is an eviction policy compared by Simulate: the options configuring
+// it on the caches of the simulation, e.g. ttlcache.WithEviction or
+// ttlcache.WithEvictionPolicy.
+type Policy struct {
+	Name    string
+	Options []ttlcache.Option
+}
+
+// DefaultPolicies returns the policies Simulate compares by default: NewLRU,
+// NewSLRU and the built-in TinyLFU, SampledLRU and CLOCK.
+func DefaultPolicies() []Policy {
+	return []Policy{
+		{Name: "lru", Options: []ttlcache.Option{ttlcache.WithEvictionPolicy(NewLRU)}},
+		{Name: "slru", Options: []ttlcache.Option{ttlcache.WithEvictionPolicy(NewSLRU)}},
+		{Name: ttlcache.TinyLFU.String(), Options: []ttlcache.Option{ttlcache.WithEviction(ttlcache.TinyLFU)}},
+		{Name: ttlcache.SampledLRU.String(), Options: []ttlcache.Option{ttlcache.WithEviction(ttlcache.SampledLRU)}},
+		{Name: ttlcache.CLOCK.String(), Options: []ttlcache.Option{ttlcache.WithEviction(ttlcache.CLOCK)}},
+	}
+}
+
+// SimConfig configures a Simulate.
+type SimConfig struct {
+	// MaxEntries bounds the caches, see ttlcache.WithMaxEntries.
+	MaxEntries int
+	// TTL is the ttl of the records set.
+	TTL time.Duration
+	// Resolution removes the outdated records every Resolution of the time
+	// of the trace, see Config.
+	Resolution time.Duration
+}
+
+// SimResult is the outcome of a policy in a Simulate. The allocation and pause
+// fields of Result are zero, the replays running concurrently.
+type SimResult struct {
+	Name string
+	Result
+	// Bytes is the heap retained by the cache of the policy once the trace is
+	// replayed, an estimate of its overhead per record as values are nil.
+	Bytes int64
+}
+
+// Simulate replays trace against a cache per policy, DefaultPolicies if none,
+// concurrently, and returns their results in the order of the policies. Each
+// cache has a single shard bounded by cfg.MaxEntries, so the policies are
+// compared on the same records, and its own clock following the timestamps of
+// the trace. Missed gets set the key with a nil value, so no real values are
+// stored and the hit ratios are those of a cache-aside cache.
+func Simulate(trace []Access, cfg SimConfig, policies ...Policy) []SimResult {
+	if len(policies) == 0 {
+		policies = DefaultPolicies()
+	}
+	results := make([]SimResult, len(policies))
+	caches := make([]*ttlcache.Cache, len(policies))
+	var wg sync.WaitGroup
+	for i, p := range policies {
+		clock := ttltest.NewClock()
+		opts := append([]ttlcache.Option{
+			ttlcache.WithClock(clock),
+			ttlcache.WithResolution(0),
+			ttlcache.WithShardCount(1),
+			ttlcache.WithMaxEntries(cfg.MaxEntries),
+		}, p.Options...)
+		caches[i] = ttlcache.NewCache(opts...)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			start := time.Now()
+			r := replay(caches[i], trace, Config{TTL: cfg.TTL, SetOnMiss: true, Clock: clock, Resolution: cfg.Resolution})
+			r.Duration = time.Since(start)
+			results[i] = SimResult{Name: p.Name, Result: r}
+		}()
+	}
+	wg.Wait()
+
+	// Measure the heap each cache retains by dropping them one at a time.
+	heap := heapAlloc()
+	for i := range caches {
+		caches[i].Close()
+		caches[i] = nil
+		after := heapAlloc()
+		results[i].Bytes = max(heap-after, 0)
+		heap = after
+	}
+	return results
+}
+
+// heapAlloc returns the bytes of live heap objects after a garbage collection.
+func heapAlloc() int64 {
+	var ms runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&ms)
+	return int64(ms.HeapAlloc)
+}
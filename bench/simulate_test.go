@@ -0,0 +1,56 @@
+package bench
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSimulate(t *testing.T) {
+	// A hot set of 10 keys read between the keys of a scan read once.
+	var trace []Access
+	at := time.Unix(0, 0)
+	for i := 0; i < 2000; i++ {
+		at = at.Add(time.Millisecond)
+		trace = append(trace, Access{Time: at, Op: Get, Key: uint64(i % 10)})
+		trace = append(trace, Access{Time: at, Op: Get, Key: uint64(1000 + i)})
+	}
+
+	results := Simulate(trace, SimConfig{MaxEntries: 20, TTL: time.Hour, Resolution: time.Second})
+	policies := DefaultPolicies()
+	if len(results) != len(policies) {
+		t.Fatalf("incorrect number of results: got: %d expected: %d", len(results), len(policies))
+	}
+	for i, r := range results {
+		if r.Name != policies[i].Name {
+			t.Errorf("incorrect name: got: %s expected: %s", r.Name, policies[i].Name)
+		}
+		if r.Gets != uint64(len(trace)) || r.Hits+r.Misses != r.Gets || r.Sets != 0 {
+			t.Errorf("incorrect result of %s: got: %+v", r.Name, r.Result)
+		}
+		// At best the hot keys hit after their first read.
+		if r.Hits > 2000-10 {
+			t.Errorf("incorrect hits of %s: got: %d expected at most: %d", r.Name, r.Hits, 2000-10)
+		}
+		if r.Bytes < 0 {
+			t.Errorf("incorrect bytes of %s: got: %d", r.Name, r.Bytes)
+		}
+	}
+	// Scan resistant policies keep the hot keys.
+	for _, r := range results[1:3] {
+		if r.HitRatio() < 0.4 {
+			t.Errorf("incorrect hit ratio of %s: got: %v expected at least: %v", r.Name, r.HitRatio(), 0.4)
+		}
+	}
+}
+
+func TestSimulate_Policies(t *testing.T) {
+	trace := []Access{{Op: Get, Key: 1}, {Op: Set, Key: 2}, {Op: Get, Key: 1}, {Op: Delete, Key: 1}}
+	results := Simulate(trace, SimConfig{MaxEntries: 10, TTL: time.Hour}, Policy{Name: "lru", Options: DefaultPolicies()[0].Options})
+	if len(results) != 1 {
+		t.Fatalf("incorrect number of results: got: %d expected: %d", len(results), 1)
+	}
+	expected := Result{Gets: 2, Hits: 1, Misses: 1, Sets: 1, Deletes: 1}
+	if r := results[0]; r.Gets != expected.Gets || r.Hits != expected.Hits || r.Misses != expected.Misses || r.Sets != expected.Sets || r.Deletes != expected.Deletes {
+		t.Errorf("incorrect result: got: %+v expected: %+v", r.Result, expected)
+	}
+}
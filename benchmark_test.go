@@ -63,3 +63,206 @@ func benchmarkCacheGet(b *testing.B, numberOfKeys int) {
 		_ = val
 	}
 }
+
+func BenchmarkCache_Set_CoarseClock_10000(b *testing.B) {
+	c := New(9999*time.Second, WithCoarseClock())
+	defer c.Close()
+
+	for i := 0; i < b.N; i++ {
+		c.Set(IntKey(i%10000), i, 0)
+	}
+}
+
+func BenchmarkCache_GetMany_10000(b *testing.B) {
+	c := New(9999 * time.Second)
+	keys := make([]uint64, 10000)
+	for i := range keys {
+		keys[i] = IntKey(i)
+		c.Set(keys[i], i, 0)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.GetMany(keys)
+	}
+}
+
+func BenchmarkBytesCache_Set_10000(b *testing.B) {
+	c := NewBytesCache(9999*time.Second, 64<<20)
+	value := make([]byte, 100)
+
+	for i := 0; i < b.N; i++ {
+		c.Set(IntKey(i%10000), value, 0)
+	}
+}
+
+func BenchmarkBytesCache_Get_10000(b *testing.B) {
+	c := NewBytesCache(9999*time.Second, 64<<20)
+	value := make([]byte, 100)
+
+	for i := 0; i < 10000; i++ {
+		c.Set(IntKey(i), value, 0)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.Get(IntKey(i % 10000))
+	}
+}
+
+func BenchmarkBytesCache_GetRef_10000(b *testing.B) {
+	c := NewBytesCache(9999*time.Second, 64<<20)
+	value := make([]byte, 4096)
+
+	for i := 0; i < 10000; i++ {
+		c.Set(IntKey(i), value, 0)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ref, _ := c.GetRef(IntKey(i % 10000))
+		ref.Release()
+	}
+}
+
+func BenchmarkInt64Cache_Set_10000(b *testing.B) {
+	c := NewInt64Cache(9999 * time.Second)
+
+	for i := 0; i < b.N; i++ {
+		c.Set(IntKey(i%10000), int64(i), 0)
+	}
+}
+
+func BenchmarkCache_Set_Reserved_100000(b *testing.B) {
+	c := New(9999*time.Second, WithCapacity(100000))
+
+	for i := 0; i < b.N; i++ {
+		c.Set(IntKey(i%100000), i, 0)
+	}
+}
+
+func BenchmarkCache_Get_SeededHasher_10000(b *testing.B) {
+	c := New(9999*time.Second, WithHasher(SeededHasher()))
+
+	for i := 0; i < 10000; i++ {
+		c.Set(IntKey(i), i, 0)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.Get(IntKey(i % 10000))
+	}
+}
+
+// Gets running while the cleanup manager scans a large cache.
+func BenchmarkCache_Get_DuringCleanup(b *testing.B) {
+	c := New(0)
+	for i := 0; i < 1000000; i++ {
+		c.Set(IntKey(i), i, time.Hour)
+	}
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				c.DeleteExpired()
+			}
+		}
+	}()
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			c.Get(IntKey(i % 1000000))
+			i++
+		}
+	})
+	b.StopTimer()
+	close(done)
+}
+
+func BenchmarkCache_SetAsync_Parallel(b *testing.B) {
+	c := New(9999*time.Second, WithWriteBuffer(4096))
+
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			c.SetAsync(IntKey(i%10000), i, 0)
+			i++
+		}
+	})
+	c.Flush()
+}
+
+func BenchmarkCache_Set_Parallel(b *testing.B) {
+	c := New(9999 * time.Second)
+
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			c.Set(IntKey(i%10000), i, 0)
+			i++
+		}
+	})
+}
+
+// A cleanup scan finding nothing to remove.
+func BenchmarkCache_DeleteExpired_1000000(b *testing.B) {
+	c := New(0)
+	for i := 0; i < 1000000; i++ {
+		c.Set(IntKey(i), i, time.Hour)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.DeleteExpired()
+	}
+}
+
+func BenchmarkCache_Get_Miss_BloomFilter_10000(b *testing.B) {
+	c := New(9999*time.Second, WithBloomFilter(10000))
+	for i := 0; i < 10000; i++ {
+		c.Set(IntKey(i), i, 0)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.Get(IntKey(10000 + i%10000))
+	}
+}
+
+func BenchmarkCache_Get_CLOCK_10000(b *testing.B) {
+	c := New(9999*time.Second, WithMaxEntries(10000))
+	for i := 0; i < 10000; i++ {
+		c.Set(IntKey(i), i, time.Hour)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.Get(IntKey(i % 10000))
+	}
+}
+
+func BenchmarkCache_Set_CLOCK_Evicting_10000(b *testing.B) {
+	c := New(9999*time.Second, WithMaxEntries(10000))
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.Set(IntKey(i), i, time.Hour)
+	}
+}
+
+func BenchmarkCache_Get_TinyLFU_10000(b *testing.B) {
+	c := New(9999*time.Second, WithMaxEntries(10000), WithEviction(TinyLFU))
+	for i := 0; i < 10000; i++ {
+		c.Set(IntKey(i), i, time.Hour)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.Get(IntKey(i % 10000))
+	}
+}
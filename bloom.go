@@ -0,0 +1,97 @@
+package ttlswisscache
+
+import (
+	"math/bits"
+	"sync/atomic"
+)
+
+const (
+	bloomHashes      = 4 // Number of counters per key.
+	bloomCountersPer = 8 // Counters per expected key, about 2% false positives.
+	bloomSaturated   = 0xff
+)
+
+// bloomFilter is a counting bloom filter of the keys of a cache, see WithBloomFilter.
+// Counters are bytes packed in words updated with CAS, so it is read without locks.
+// A saturated counter is never decremented again.
+type bloomFilter struct {
+	words []atomic.Uint32
+	mask  uint64 // Number of counters - 1.
+}
+
+func newBloomFilter(expected int) *bloomFilter {
+	n := uint64(1) << bits.Len64(uint64(max(expected, 1)*bloomCountersPer-1))
+	return &bloomFilter{words: make([]atomic.Uint32, (n+3)/4), mask: n - 1}
+}
+
+// WithBloomFilter maintains a counting bloom filter of the keys alongside the
+// cache, sized for expected keys. MightContain and Get then reject most missing
+// keys without taking a shard lock, which pays off when misses outnumber hits.
+// Every inserted and removed key updates four counters.
+// The false positive rate grows past expected keys.
+func WithBloomFilter(expected int) Option {
+	return func(o *options) {
+		o.bloomFilter = expected
+	}
+}
+
+// MightContain reports whether the cache may hold a record of key.
+// Without WithBloomFilter it always reports true. With it false means the key
+// is missing for sure, true means it may be present.
+func (c *Cache) MightContain(key uint64) bool {
+	return c.filter == nil || c.filter.contains(key)
+}
+
+// add counts key in the filter.
+func (f *bloomFilter) add(key uint64) {
+	h1, h2 := bloomHash(key)
+	for i := uint64(0); i < bloomHashes; i++ {
+		f.update(h1+i*h2, 1)
+	}
+}
+
+// remove uncounts key from the filter.
+func (f *bloomFilter) remove(key uint64) {
+	h1, h2 := bloomHash(key)
+	for i := uint64(0); i < bloomHashes; i++ {
+		f.update(h1+i*h2, -1)
+	}
+}
+
+func (f *bloomFilter) contains(key uint64) bool {
+	h1, h2 := bloomHash(key)
+	for i := uint64(0); i < bloomHashes; i++ {
+		j := (h1 + i*h2) & f.mask
+		if (f.words[j>>2].Load()>>((j&3)*8))&0xff == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// update adds delta to the counter h, unless it is saturated.
+func (f *bloomFilter) update(h uint64, delta int) {
+	j := h & f.mask
+	w := &f.words[j>>2]
+	shift := (j & 3) * 8
+	for {
+		old := w.Load()
+		n := (old >> shift) & 0xff
+		if n == bloomSaturated || (delta < 0 && n == 0) {
+			return
+		}
+		next := old + 1<<shift
+		if delta < 0 {
+			next = old - 1<<shift
+		}
+		if w.CompareAndSwap(old, next) {
+			return
+		}
+	}
+}
+
+// bloomHash returns the two hashes the counters of key are derived from.
+func bloomHash(key uint64) (uint64, uint64) {
+	h := hashKey(key ^ 0x5bd1e9955bd1e995)
+	return h, h>>32 | 1
+}
@@ -0,0 +1,83 @@
+package ttlswisscache
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestWithBloomFilter(t *testing.T) {
+	c := New(0, WithBloomFilter(10000))
+	defer c.Close()
+	for i := 0; i < 10000; i++ {
+		c.Set(IntKey(i), i, time.Hour)
+	}
+	for i := 0; i < 10000; i++ {
+		if !c.MightContain(IntKey(i)) {
+			t.Fatalf("stored key %d was rejected", i)
+		}
+	}
+	positives := 0
+	for i := 10000; i < 20000; i++ {
+		if c.MightContain(IntKey(i)) {
+			positives++
+		}
+	}
+	if positives > 500 {
+		t.Errorf("incorrect false positives: got: %d expected at most: %d", positives, 500)
+	}
+
+	for i := 0; i < 5000; i++ {
+		c.Delete(IntKey(i))
+	}
+	c.Set(IntKey(5000), "again", -time.Second)
+	c.DeleteExpired()
+	for i := 5001; i < 10000; i++ {
+		if v, ok := c.Get(IntKey(i)); !ok || v != i {
+			t.Fatalf("incorrect value after deletes: got: %v, %v expected: %d, true", v, ok, i)
+		}
+	}
+
+	c.Clear()
+	for i := 0; i < 10000; i++ {
+		if c.MightContain(IntKey(i)) {
+			t.Fatalf("key %d is contained after Clear", i)
+		}
+	}
+	d := New(0)
+	defer d.Close()
+	if !d.MightContain(IntKey(1)) {
+		t.Error("key was rejected without a bloom filter")
+	}
+}
+
+// Random writes and deletes never make the filter reject a stored key.
+func TestWithBloomFilter_Model(t *testing.T) {
+	c := New(0, WithBloomFilter(100), WithShardCount(2))
+	defer c.Close()
+	model := make(map[uint64]bool)
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < 100000; i++ {
+		key := uint64(rnd.Intn(1000))
+		if rnd.Intn(3) == 0 {
+			c.Delete(key)
+			delete(model, key)
+			continue
+		}
+		c.Set(key, i, time.Hour)
+		model[key] = true
+	}
+	for key := range model {
+		if !c.MightContain(key) {
+			t.Fatalf("stored key %d was rejected", key)
+		}
+	}
+
+	d := c.Clone()
+	defer d.Close()
+	for key := range model {
+		if !d.MightContain(key) {
+			t.Fatalf("cloned key %d was rejected", key)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+// Package breaker keeps circuit breaker state per target, e.g. an upstream host,
+// in an Int64Cache.
+//
+// A target is closed until threshold failures happen within the failure window.
+// It is then open for the cool-down, during which Allow denies every call, and
+// half-open afterwards: Allow lets a single probe through, a success closes the
+// target and a failure opens it again. Every state is a record with a ttl, so
+// cool-downs end and failures are forgotten without timers.
+package breaker
+
+import (
+	"time"
+
+	ttlcache "github.com/loicalleyne/ttlswisscache"
+)
+
+// State is the state of a target.
+type State uint8
+
+const (
+	// Closed lets calls through.
+	Closed State = iota
+	// Open denies calls until the cool-down ends.
+	Open
+	// HalfOpen lets a single probe through.
+	HalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// Breaker tracks the state of targets. It is safe for concurrent use,
+// several breakers can share a cache as long as their targets differ.
+type Breaker struct {
+	cache     *ttlcache.Int64Cache
+	threshold int64
+	window    time.Duration
+	cooldown  time.Duration
+}
+
+// New returns a breaker opening a target for cooldown after threshold failures
+// within window. Time is read from the cache clock, see ttlcache.WithClock.
+func New(cache *ttlcache.Int64Cache, threshold int, window, cooldown time.Duration) *Breaker {
+	return &Breaker{cache: cache, threshold: int64(threshold), window: window, cooldown: cooldown}
+}
+
+// Allow reports whether a call to target may happen now.
+// In the half-open state only the first caller is allowed, as the probe.
+func (b *Breaker) Allow(target string) bool {
+	switch b.State(target) {
+	case Open:
+		return false
+	case HalfOpen:
+		return b.cache.Add(key("probe", target), 1, b.cooldown) == 1
+	}
+	return true
+}
+
+// Success records a successful call to target, closing it if it isn't.
+func (b *Breaker) Success(target string) {
+	if b.State(target) == Closed {
+		return
+	}
+	b.Reset(target)
+}
+
+// Failure records a failed call to target and returns its new state.
+func (b *Breaker) Failure(target string) State {
+	switch b.State(target) {
+	case Open:
+		return Open
+	case HalfOpen:
+		b.trip(target)
+		return Open
+	}
+	if b.cache.Add(key("failures", target), 1, b.window) >= b.threshold {
+		b.trip(target)
+		return Open
+	}
+	return Closed
+}
+
+// State returns the state of target.
+func (b *Breaker) State(target string) State {
+	switch {
+	case b.live(key("open", target)):
+		return Open
+	case b.live(key("tripped", target)):
+		return HalfOpen
+	}
+	return Closed
+}
+
+// Reset closes target and forgets its failures.
+func (b *Breaker) Reset(target string) {
+	for _, name := range []string{"failures", "open", "tripped", "probe"} {
+		b.cache.Delete(key(name, target))
+	}
+}
+
+// trip opens target for the cool-down. It stays half-open for the failure
+// window after it, closing if no probe happens meanwhile.
+func (b *Breaker) trip(target string) {
+	b.cache.Set(key("open", target), 1, b.cooldown)
+	b.cache.Set(key("tripped", target), 1, b.cooldown+b.window)
+	b.cache.Delete(key("probe", target))
+	b.cache.Delete(key("failures", target))
+}
+
+// live reports whether the record of key exists and is not outdated.
+func (b *Breaker) live(key uint64) bool {
+	ttl, ok := b.cache.TTL(key)
+	return ok && ttl > 0
+}
+
+// key returns the key of the named record of target.
+func key(name, target string) uint64 {
+	return ttlcache.StringKey("breaker\x00" + name + "\x00" + target)
+}
@@ -0,0 +1,81 @@
+package breaker
+
+import (
+	"testing"
+	"time"
+
+	ttlcache "github.com/loicalleyne/ttlswisscache"
+	"github.com/loicalleyne/ttlswisscache/ttltest"
+)
+
+func newBreaker(t *testing.T) (*Breaker, *ttltest.Clock) {
+	clock := ttltest.NewClock()
+	c := ttlcache.NewInt64Cache(0, ttlcache.WithClock(clock))
+	t.Cleanup(func() { c.Close() })
+	return New(c, 3, time.Minute, 10*time.Second), clock
+}
+
+func TestBreaker(t *testing.T) {
+	b, clock := newBreaker(t)
+	const target = "db:5432"
+
+	for i := 0; i < 2; i++ {
+		if s := b.Failure(target); s != Closed {
+			t.Errorf("incorrect state after %d failures: got: %v expected: %v", i+1, s, Closed)
+		}
+	}
+	if s := b.Failure(target); s != Open {
+		t.Errorf("incorrect state after 3 failures: got: %v expected: %v", s, Open)
+	}
+	if b.Allow(target) {
+		t.Error("call to an open target was allowed")
+	}
+	if !b.Allow("other") {
+		t.Error("call to another target was denied")
+	}
+
+	clock.Advance(10*time.Second + time.Nanosecond)
+	if s := b.State(target); s != HalfOpen {
+		t.Errorf("incorrect state after the cool-down: got: %v expected: %v", s, HalfOpen)
+	}
+	if !b.Allow(target) {
+		t.Error("probe was denied")
+	}
+	if b.Allow(target) {
+		t.Error("second probe was allowed")
+	}
+	if s := b.Failure(target); s != Open {
+		t.Errorf("incorrect state after a failed probe: got: %v expected: %v", s, Open)
+	}
+
+	clock.Advance(10*time.Second + time.Nanosecond)
+	if !b.Allow(target) {
+		t.Error("probe was denied")
+	}
+	b.Success(target)
+	if s := b.State(target); s != Closed {
+		t.Errorf("incorrect state after a successful probe: got: %v expected: %v", s, Closed)
+	}
+	if s := b.Failure(target); s != Closed {
+		t.Errorf("failures before the trip were kept: got: %v expected: %v", s, Closed)
+	}
+}
+
+func TestBreaker_Window(t *testing.T) {
+	b, clock := newBreaker(t)
+	b.Failure("a")
+	b.Failure("a")
+	clock.Advance(time.Minute + time.Nanosecond)
+	b.cache.DeleteExpired()
+	if s := b.Failure("a"); s != Closed {
+		t.Errorf("failures outside the window were counted: got: %v expected: %v", s, Closed)
+	}
+
+	b.Failure("b")
+	b.Failure("b")
+	b.Failure("b")
+	clock.Advance(time.Minute + 10*time.Second + time.Nanosecond)
+	if s := b.State("b"); s != Closed {
+		t.Errorf("incorrect state of an unprobed target: got: %v expected: %v", s, Closed)
+	}
+}
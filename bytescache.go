@@ -0,0 +1,380 @@
+package ttlswisscache
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// chunkSize is the allocation unit of BytesCache slabs.
+	chunkSize = 64 << 10
+	// bytesHeaderLen is key (8 bytes), deadline (8 bytes) and value length (4 bytes).
+	bytesHeaderLen = 8 + 8 + 4
+	// MaxBytesValueSize is the largest value a BytesCache stores.
+	MaxBytesValueSize = chunkSize - bytesHeaderLen
+
+	posBits = 40
+	posMask = 1<<posBits - 1
+	genMask = 1<<(64-posBits) - 1
+)
+
+// ErrValueTooLarge is returned when a value exceeds MaxBytesValueSize, or by
+// TypedView.Set when WithMaxValueSize rejects it.
+var ErrValueTooLarge = errors.New("ttlswisscache: value too large")
+
+// BytesCache is a key-value storage for []byte values with TTL for each record.
+//
+// Values are copied into large byte slabs and the index maps keys to slab
+// positions, so neither contains pointers and the garbage collector doesn't
+// scan millions of entries. Memory is bounded by maxBytes: every shard writes
+// its slabs as a ring, and once full, new records overwrite the oldest ones.
+// Overwritten records are dropped as if evicted.
+//
+// TTL and Close semantics follow Cache: outdated records stay visible until the
+// cleanup manager removes them from the index, and Set returns ErrClosed after Close.
+type BytesCache struct {
+	done    chan struct{}
+	closed  atomic.Bool
+	list    []*bytesShard
+	shift   uint
+	hash    func(uint64) uint64
+	clock   *coarseClock
+	cleanup cleanupSwitch
+}
+
+// bytesShard is a ring of chunks with an index of record positions.
+// An index entry holds the generation of the ring (high bits) and the position
+// of the record (low bits). The generation is incremented on every wrap around,
+// so positions overwritten by the current generation are detected.
+type bytesShard struct {
+	sync.RWMutex
+	index  map[uint64]uint64
+	chunks []*bytesChunk
+	pos    uint64
+	gen    uint64
+	stats  counters
+}
+
+// bytesChunk is a slab of records.
+// Chunks pinned by a ValueRef are copied before they are written again,
+// so the referenced bytes never change.
+type bytesChunk struct {
+	data []byte
+	pins atomic.Int32
+}
+
+// NewBytesCache creates a []byte storage using up to maxBytes for the values.
+// resolution and opts configure the cache like in New.
+func NewBytesCache(resolution time.Duration, maxBytes int, opts ...Option) *BytesCache {
+	o := newOptions(append([]Option{WithResolution(resolution)}, opts...))
+	c := &BytesCache{
+		done:  make(chan struct{}),
+		list:  make([]*bytesShard, o.shardCount),
+		shift: shardShift(o.shardCount),
+		hash:  o.hasher,
+	}
+	n := (maxBytes/o.shardCount + chunkSize - 1) / chunkSize
+	if n < 1 {
+		n = 1
+	}
+	for i := range c.list {
+		c.list[i] = &bytesShard{
+			index:  make(map[uint64]uint64, perShardCapacity(o.capacity, o.shardCount)),
+			chunks: make([]*bytesChunk, n),
+			gen:    1,
+		}
+	}
+
+	c.clock = newClock(o)
+	c.cleanup.start(o, c.done, func(context.Context) int { return c.DeleteExpired() }, c.clock.coarse())
+
+	return c
+}
+
+func (c *BytesCache) shard(key uint64) *bytesShard {
+	return c.list[c.hash(key)>>c.shift]
+}
+
+// Get returns a copy of the stored value.
+// The second returned variable is an existence flag like in the map.
+func (c *BytesCache) Get(key uint64) ([]byte, bool) {
+	s := c.shard(key)
+	s.RLock()
+	rec, ok := s.lookup(key)
+	var value []byte
+	if ok {
+		value = append([]byte(nil), rec[bytesHeaderLen:]...)
+	}
+	s.RUnlock()
+	if !ok {
+		s.stats.misses.Add(1)
+		return nil, false
+	}
+	s.stats.hits.Add(1)
+	return value, true
+}
+
+// ValueRef is a zero-copy view of a BytesCache value returned by GetRef.
+type ValueRef struct {
+	value []byte
+	chunk *bytesChunk
+}
+
+// Bytes returns the value. It is valid until Release and must not be modified.
+func (r *ValueRef) Bytes() []byte {
+	return r.value
+}
+
+// Release unpins the value. Further calls are no-ops.
+func (r *ValueRef) Release() {
+	if r.chunk != nil {
+		r.chunk.pins.Add(-1)
+		r.chunk, r.value = nil, nil
+	}
+}
+
+// GetRef returns a view of the stored value without copying it.
+// The second returned variable is an existence flag like in the map.
+// The slab holding the value is pinned until Release: writes that would
+// overwrite it copy the slab first, so the view stays valid. Release every
+// ref, as pinned slabs make writes to them more expensive.
+func (c *BytesCache) GetRef(key uint64) (ValueRef, bool) {
+	s := c.shard(key)
+	s.RLock()
+	rec, chunk, ok := s.lookupChunk(key)
+	var ref ValueRef
+	if ok {
+		chunk.pins.Add(1)
+		ref = ValueRef{value: rec[bytesHeaderLen:len(rec):len(rec)], chunk: chunk}
+	}
+	s.RUnlock()
+	if !ok {
+		s.stats.misses.Add(1)
+		return ValueRef{}, false
+	}
+	s.stats.hits.Add(1)
+	return ref, true
+}
+
+// Set copies the value to the cache with given ttl.
+// It returns ErrValueTooLarge for values over MaxBytesValueSize
+// and ErrClosed after Close.
+func (c *BytesCache) Set(key uint64, value []byte, ttl time.Duration) error {
+	if len(value) > MaxBytesValueSize {
+		return ErrValueTooLarge
+	}
+	deadline := c.clock.unixNano() + int64(ttl)
+	s := c.shard(key)
+	s.Lock()
+	if c.closed.Load() {
+		s.Unlock()
+		return ErrClosed
+	}
+	s.write(key, deadline, value)
+	s.Unlock()
+	s.stats.sets.Add(1)
+	return nil
+}
+
+// TTL returns the remaining time to live of the stored record.
+// Outdated records waiting for the cleanup manager report zero.
+func (c *BytesCache) TTL(key uint64) (time.Duration, bool) {
+	s := c.shard(key)
+	s.RLock()
+	rec, ok := s.lookup(key)
+	var deadline int64
+	if ok {
+		deadline = int64(binary.LittleEndian.Uint64(rec[8:]))
+	}
+	s.RUnlock()
+	if !ok {
+		return 0, false
+	}
+	ttl := time.Duration(deadline - c.clock.unixNano())
+	if ttl < 0 {
+		ttl = 0
+	}
+	return ttl, true
+}
+
+// Delete removes record from storage.
+// The space is reclaimed when the ring wraps around.
+func (c *BytesCache) Delete(key uint64) {
+	s := c.shard(key)
+	s.Lock()
+	_, ok := s.lookup(key)
+	if ok {
+		delete(s.index, key)
+	}
+	s.Unlock()
+	if ok {
+		s.stats.deletes.Add(1)
+	}
+}
+
+// Len returns the number of indexed records, including outdated ones waiting for cleanup.
+func (c *BytesCache) Len() int {
+	n := 0
+	for _, s := range c.list {
+		s.RLock()
+		n += len(s.index)
+		s.RUnlock()
+	}
+	return n
+}
+
+// Stats returns a snapshot of the cache counters.
+// Records overwritten by newer ones count as expired once the cleanup manager drops them.
+func (c *BytesCache) Stats() Stats {
+	st := Stats{Shards: len(c.list)}
+	for _, s := range c.list {
+		s.RLock()
+		st.Entries += len(s.index)
+		s.RUnlock()
+		s.stats.addTo(&st)
+	}
+	return st
+}
+
+// Clear removes all records from storage and keeps the allocated slabs.
+func (c *BytesCache) Clear() {
+	for _, s := range c.list {
+		s.Lock()
+		for k := range s.index {
+			delete(s.index, k)
+		}
+		s.pos = 0
+		s.gen++
+		s.Unlock()
+	}
+}
+
+// Close stops cleanup manager and releases the slabs.
+// Closing a closed cache returns ErrClosed.
+func (c *BytesCache) Close() error {
+	if !c.closed.CompareAndSwap(false, true) {
+		return ErrClosed
+	}
+	close(c.done)
+	for _, s := range c.list {
+		s.Lock()
+		s.index = make(map[uint64]uint64)
+		for i := range s.chunks {
+			s.chunks[i] = nil
+		}
+		s.pos = 0
+		s.gen++
+		s.Unlock()
+	}
+	return nil
+}
+
+// DeleteExpired removes outdated and overwritten records from the index
+// and returns their number. The cleanup manager calls it every resolution tick.
+// Like in Cache, shards are scanned under their read lock.
+func (c *BytesCache) DeleteExpired() int {
+	now := c.clock.unixNano()
+	var (
+		expired []uint64
+		n       int
+	)
+	for _, s := range c.list {
+		s.RLock()
+		expired = expired[:0]
+		for key := range s.index {
+			if s.expired(key, now) {
+				expired = append(expired, key)
+			}
+		}
+		s.RUnlock()
+		if len(expired) == 0 {
+			continue
+		}
+
+		removed := 0
+		s.Lock()
+		for _, key := range expired {
+			// The record may have been set again since the scan.
+			if _, ok := s.index[key]; ok && s.expired(key, now) {
+				delete(s.index, key)
+				removed++
+			}
+		}
+		s.Unlock()
+		s.stats.expired.Add(uint64(removed))
+		n += removed
+	}
+	return n
+}
+
+// expired reports whether the indexed record is outdated at now or overwritten.
+func (s *bytesShard) expired(key uint64, now int64) bool {
+	rec, ok := s.lookup(key)
+	return !ok || int64(binary.LittleEndian.Uint64(rec[8:])) < now
+}
+
+// lookup returns the record of the key, header included.
+// It reports false for missing keys and overwritten records.
+func (s *bytesShard) lookup(key uint64) ([]byte, bool) {
+	rec, _, ok := s.lookupChunk(key)
+	return rec, ok
+}
+
+// lookupChunk is lookup that also returns the chunk holding the record.
+func (s *bytesShard) lookupChunk(key uint64) ([]byte, *bytesChunk, bool) {
+	v, ok := s.index[key]
+	if !ok {
+		return nil, nil, false
+	}
+	gen, pos := v>>posBits, v&posMask
+	cur := s.gen & genMask
+	// Records of the current generation are before the write position,
+	// records of the previous one after it, the rest has been overwritten.
+	if !(gen == cur && pos < s.pos) && !((gen+1)&genMask == cur && pos >= s.pos) {
+		return nil, nil, false
+	}
+	chunk := s.chunks[pos/chunkSize]
+	off := pos % chunkSize
+	if chunk == nil || off+bytesHeaderLen > chunkSize {
+		return nil, nil, false
+	}
+	hdr := chunk.data[off : off+bytesHeaderLen]
+	size := uint64(binary.LittleEndian.Uint32(hdr[16:]))
+	if binary.LittleEndian.Uint64(hdr) != key || off+bytesHeaderLen+size > chunkSize {
+		return nil, nil, false
+	}
+	return chunk.data[off : off+bytesHeaderLen+size], chunk, true
+}
+
+// write appends the record at the write position, moving to the next chunk
+// when it doesn't fit and wrapping around at the end of the ring.
+func (s *bytesShard) write(key uint64, deadline int64, value []byte) {
+	need := uint64(bytesHeaderLen + len(value))
+	if s.pos%chunkSize+need > chunkSize {
+		s.pos += chunkSize - s.pos%chunkSize
+	}
+	if s.pos/chunkSize >= uint64(len(s.chunks)) {
+		s.pos = 0
+		s.gen++
+	}
+	i, off := s.pos/chunkSize, s.pos%chunkSize
+	switch chunk := s.chunks[i]; {
+	case chunk == nil:
+		s.chunks[i] = &bytesChunk{data: make([]byte, chunkSize)}
+	case chunk.pins.Load() > 0:
+		// Refs keep the old slab, the live records move to a copy.
+		s.chunks[i] = &bytesChunk{data: append([]byte(nil), chunk.data...)}
+	}
+	rec := s.chunks[i].data[off : off+need]
+	binary.LittleEndian.PutUint64(rec, key)
+	binary.LittleEndian.PutUint64(rec[8:], uint64(deadline))
+	binary.LittleEndian.PutUint32(rec[16:], uint32(len(value)))
+	copy(rec[bytesHeaderLen:], value)
+
+	s.index[key] = (s.gen&genMask)<<posBits | s.pos
+	s.pos += need
+}
@@ -0,0 +1,154 @@
+package ttlswisscache
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestBytesCache_GetSet(t *testing.T) {
+	c := NewBytesCache(time.Hour, 1<<20)
+	defer c.Close()
+
+	tt := []struct {
+		key   uint64
+		value []byte
+	}{
+		{1, []byte("one")},
+		{2, []byte{}},
+		{3, bytes.Repeat([]byte("x"), MaxBytesValueSize)},
+	}
+	for _, tc := range tt {
+		if err := c.Set(tc.key, tc.value, time.Minute); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, tc := range tt {
+		v, ok := c.Get(tc.key)
+		if !ok || !bytes.Equal(v, tc.value) {
+			t.Errorf("incorrect value for key %d: got: %d bytes expected: %d bytes", tc.key, len(v), len(tc.value))
+		}
+	}
+	if ttl, ok := c.TTL(1); !ok || ttl <= 0 || ttl > time.Minute {
+		t.Errorf("incorrect ttl: got: %v", ttl)
+	}
+
+	c.Set(1, []byte("uno"), time.Minute)
+	if v, _ := c.Get(1); string(v) != "uno" {
+		t.Errorf("incorrect overwritten value: got: %s expected: %s", v, "uno")
+	}
+	c.Delete(1)
+	if _, ok := c.Get(1); ok {
+		t.Error("record was not deleted")
+	}
+	if err := c.Set(4, make([]byte, MaxBytesValueSize+1), time.Minute); err != ErrValueTooLarge {
+		t.Errorf("incorrect error: got: %v expected: %v", err, ErrValueTooLarge)
+	}
+}
+
+func TestBytesCache_Eviction(t *testing.T) {
+	// One chunk per shard.
+	c := NewBytesCache(time.Hour, 32*chunkSize, WithShardCount(32))
+	defer c.Close()
+	value := make([]byte, 1000)
+
+	const n = 100000
+	for i := 0; i < n; i++ {
+		copy(value, fmt.Sprint(i))
+		c.Set(uint64(i), value, time.Minute)
+	}
+	if _, ok := c.Get(0); ok {
+		t.Error("oldest record was not overwritten")
+	}
+	v, ok := c.Get(n - 1)
+	if !ok || !bytes.HasPrefix(v, []byte(fmt.Sprint(n-1))) {
+		t.Error("newest record is missing")
+	}
+	if c.DeleteExpired() == 0 {
+		t.Error("overwritten records were not dropped from the index")
+	}
+	if l := c.Len(); l > 32*chunkSize/len(value) {
+		t.Errorf("index larger than the slabs: got: %d", l)
+	}
+}
+
+func TestBytesCache_GetRef(t *testing.T) {
+	c := NewBytesCache(time.Hour, chunkSize, WithShardCount(1)) // A single chunk.
+	defer c.Close()
+
+	if _, ok := c.GetRef(1); ok {
+		t.Error("missing record was found")
+	}
+	value := bytes.Repeat([]byte("a"), 1000)
+	c.Set(0, value, time.Minute)
+	ref, ok := c.GetRef(0)
+	if !ok || !bytes.Equal(ref.Bytes(), value) {
+		t.Fatalf("incorrect ref: got: %q, %v expected: %q, true", ref.Bytes(), ok, value)
+	}
+
+	// Wrap around the ring, overwriting the pinned record.
+	other := bytes.Repeat([]byte("b"), 1000)
+	for i := 1; i < 1000; i++ {
+		c.Set(uint64(i), other, time.Minute)
+	}
+	if _, ok := c.Get(0); ok {
+		t.Error("oldest record was not overwritten")
+	}
+	if !bytes.Equal(ref.Bytes(), value) {
+		t.Error("pinned value was overwritten")
+	}
+	if v, ok := c.Get(999); !ok || !bytes.Equal(v, other) {
+		t.Error("newest record is missing")
+	}
+
+	ref.Release()
+	ref.Release()
+	if ref.Bytes() != nil {
+		t.Errorf("incorrect released ref: got: %q expected: nil", ref.Bytes())
+	}
+}
+
+func TestBytesCache_DeleteExpired(t *testing.T) {
+	c := NewBytesCache(time.Hour, 1<<20)
+	defer c.Close()
+	c.Set(1, []byte("1"), time.Hour)
+	c.Set(2, []byte("2"), -time.Second)
+
+	if n := c.DeleteExpired(); n != 1 {
+		t.Errorf("incorrect number of removed records: got: %d expected: %d", n, 1)
+	}
+	if _, ok := c.Get(1); !ok {
+		t.Error("live record was removed")
+	}
+	c.Clear()
+	if l := c.Len(); l != 0 {
+		t.Errorf("incorrect length after Clear: got: %d expected: %d", l, 0)
+	}
+}
+
+func TestBytesCache_SetAllocs(t *testing.T) {
+	c := NewBytesCache(time.Hour, 1<<20)
+	defer c.Close()
+	value := []byte("value")
+	c.Set(1, value, time.Minute)
+
+	if n := testing.AllocsPerRun(100, func() { c.Set(1, value, time.Minute) }); n != 0 {
+		t.Errorf("incorrect number of allocations: got: %v expected: %v", n, 0)
+	}
+}
+
+func TestBytesCache_Closed(t *testing.T) {
+	c := NewBytesCache(time.Hour, 1<<20)
+	c.Set(1, []byte("a"), time.Hour)
+	c.Close()
+	if err := c.Close(); err != ErrClosed {
+		t.Errorf("incorrect second close error: got: %v expected: %v", err, ErrClosed)
+	}
+	if err := c.Set(2, []byte("b"), time.Hour); err != ErrClosed {
+		t.Errorf("incorrect set error: got: %v expected: %v", err, ErrClosed)
+	}
+	if c.Len() != 0 {
+		t.Errorf("incorrect length after close: got: %d expected: %d", c.Len(), 0)
+	}
+}
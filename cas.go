@@ -0,0 +1,71 @@
+package ttlswisscache
+
+import "time"
+
+// SetIfAbsent adds value with given ttl unless the cache holds a record of key
+// that isn't outdated, and reports whether it did. The check and the set are
+// atomic: of concurrent calls with the same key, exactly one stores its value.
+// Outdated records waiting for the cleanup manager count as absent.
+// After Close, or if WithMaxValueSize rejects value, nothing is stored and
+// SetIfAbsent reports false.
+func (c *Cache) SetIfAbsent(key uint64, value interface{}, ttl time.Duration) bool {
+	value, ok := c.limit(key, value)
+	if !ok {
+		return false
+	}
+	now := c.clock.unixNano()
+	s := c.shards.get(key)
+	s.Lock()
+	if it, ok := s.get(key); (ok && it.deadline >= now) || c.closing.Load() {
+		s.Unlock() // Live tombstones count as present.
+		return false
+	}
+	it := item{deadline: now + int64(ttl), value: c.clone(value)}
+	s.put(key, it)
+	c.subs.publish(OpSet, key, it)
+	s.Unlock()
+	s.stats.sets.Add(1)
+	return true
+}
+
+// CompareAndSwap replaces the value of key with new and its ttl with ttl if
+// the record isn't outdated and its value equals old, and reports whether it
+// did. old must be comparable. A new value rejected by WithMaxValueSize
+// isn't stored.
+func (c *Cache) CompareAndSwap(key uint64, old, new interface{}, ttl time.Duration) bool {
+	new, ok := c.limit(key, new)
+	if !ok {
+		return false
+	}
+	now := c.clock.unixNano()
+	s := c.shards.get(key)
+	s.Lock()
+	if it, ok := s.get(key); !ok || it.deadline < now || it.value != old || c.closing.Load() {
+		s.Unlock()
+		return false
+	}
+	it := item{deadline: now + int64(ttl), value: c.clone(new)}
+	s.put(key, it)
+	c.subs.publish(OpSet, key, it)
+	s.Unlock()
+	s.stats.sets.Add(1)
+	return true
+}
+
+// CompareAndDelete removes the record of key if it isn't outdated and its
+// value equals old, and reports whether it did. old must be comparable.
+func (c *Cache) CompareAndDelete(key uint64, old interface{}) bool {
+	now := c.clock.unixNano()
+	s := c.shards.get(key)
+	s.Lock()
+	if it, ok := s.get(key); !ok || it.deadline < now || it.value != old {
+		s.Unlock()
+		return false
+	}
+	it, _ := s.delete(key)
+	s.stats.deletes.Add(1)
+	c.subs.publish(OpDelete, key, it)
+	s.Unlock()
+	c.cascade()
+	return true
+}
@@ -0,0 +1,62 @@
+package ttlswisscache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCache_SetIfAbsent(t *testing.T) {
+	clock := &fixedClock{now: time.Unix(1000, 0)}
+	c := New(0, WithClock(clock))
+	defer c.Close()
+
+	if !c.SetIfAbsent(IntKey(1), "a", time.Minute) {
+		t.Error("value of a missing key was not stored")
+	}
+	if c.SetIfAbsent(IntKey(1), "b", time.Minute) {
+		t.Error("value of a present key was stored")
+	}
+	clock.now = clock.now.Add(2 * time.Minute)
+	if !c.SetIfAbsent(IntKey(1), "c", time.Minute) {
+		t.Error("value of an outdated key was not stored")
+	}
+	if v, _ := c.Get(IntKey(1)); v != "c" {
+		t.Errorf("incorrect value: got: %v expected: %v", v, "c")
+	}
+}
+
+func TestCache_CompareAndSwap(t *testing.T) {
+	clock := &fixedClock{now: time.Unix(1000, 0)}
+	c := New(0, WithClock(clock))
+	defer c.Close()
+	c.Set(IntKey(1), 1, time.Minute)
+	c.Set(IntKey(2), []int{1}, time.Minute)
+
+	if c.CompareAndSwap(IntKey(1), 2, 3, time.Hour) || c.CompareAndSwap(IntKey(2), 1, 3, time.Hour) {
+		t.Error("value was swapped without matching")
+	}
+	if !c.CompareAndSwap(IntKey(1), 1, 2, time.Hour) {
+		t.Error("matching value was not swapped")
+	}
+	if v, _ := c.Get(IntKey(1)); v != 2 {
+		t.Errorf("incorrect value: got: %v expected: %v", v, 2)
+	}
+	if ttl, _ := c.TTL(IntKey(1)); ttl != time.Hour {
+		t.Errorf("incorrect ttl: got: %v expected: %v", ttl, time.Hour)
+	}
+
+	if c.CompareAndDelete(IntKey(1), 1) {
+		t.Error("value was deleted without matching")
+	}
+	clock.now = clock.now.Add(2 * time.Hour)
+	if c.CompareAndSwap(IntKey(1), 2, 3, time.Hour) || c.CompareAndDelete(IntKey(1), 2) {
+		t.Error("outdated value was swapped or deleted")
+	}
+	c.Set(IntKey(1), 2, time.Minute)
+	if !c.CompareAndDelete(IntKey(1), 2) {
+		t.Error("matching value was not deleted")
+	}
+	if _, ok := c.Get(IntKey(1)); ok {
+		t.Error("deleted value was found")
+	}
+}
@@ -0,0 +1,187 @@
+package ttlswisscache
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// changeHeaderLen is op (1 byte), key (8 bytes) and deadline (8 bytes).
+const changeHeaderLen = 1 + 8 + 8
+
+// maxChangeLen guards readers against corrupted length prefixes.
+const maxChangeLen = 64 << 20
+
+// ChangeStream streams cache mutations to a writer.
+type ChangeStream struct {
+	cache *Cache
+	sub   *subscriber
+	done  chan struct{}
+	err   error
+}
+
+// CDC starts streaming set, delete, expire and clear events of the cache to w.
+// Events are written in the order they were applied. Every record is a uvarint
+// length followed by op (1 byte), key (8 bytes), deadline in Unix nano (8 bytes)
+// and, for OpSet only, the value encoded with codec. Integers are big endian.
+// Keys cached as missing by SetNotFound are not streamed.
+//
+// buffer sets how many events may be pending while w is busy. Events are dropped
+// when it is full, check Dropped to detect gaps: a follower has to resynchronize
+// from a snapshot after one.
+func (c *Cache) CDC(w io.Writer, codec Codec, buffer int) *ChangeStream {
+	s := &ChangeStream{
+		cache: c,
+		sub:   c.subs.add(SubscribeOptions{Buffer: buffer}),
+		done:  make(chan struct{}),
+	}
+	go s.run(NewChangeWriter(w, codec))
+	return s
+}
+
+func (s *ChangeStream) run(cw *ChangeWriter) {
+	defer close(s.done)
+	for ev := range s.sub.ch {
+		if s.err != nil {
+			continue // Drain until the subscription is removed.
+		}
+		if s.err = cw.Write(ev); s.err != nil {
+			s.cache.subs.remove(s.sub)
+			continue
+		}
+		if len(s.sub.ch) == 0 {
+			s.err = cw.Flush()
+		}
+	}
+	if s.err == nil {
+		s.err = cw.Flush()
+	}
+}
+
+// Dropped returns the number of events lost because the buffer was full.
+func (s *ChangeStream) Dropped() uint64 {
+	return s.sub.dropped.Load()
+}
+
+// Stop ends the stream, waits for pending events to be written and returns
+// the first write error.
+func (s *ChangeStream) Stop() error {
+	s.cache.subs.remove(s.sub)
+	<-s.done
+	return s.err
+}
+
+// ChangeWriter encodes events in the CDC format.
+type ChangeWriter struct {
+	w     *bufio.Writer
+	codec Codec
+	buf   []byte
+}
+
+// NewChangeWriter creates a writer of CDC records.
+func NewChangeWriter(w io.Writer, codec Codec) *ChangeWriter {
+	return &ChangeWriter{w: bufio.NewWriter(w), codec: codec}
+}
+
+// Write encodes the event. Call Flush to send buffered records.
+// Events of keys cached as missing by SetNotFound are skipped.
+func (cw *ChangeWriter) Write(ev Event) error {
+	if ev.notFound {
+		return nil
+	}
+	var value []byte
+	if ev.Op == OpSet {
+		var err error
+		if value, err = cw.codec.Marshal(ev.Value); err != nil {
+			return fmt.Errorf("ttlswisscache: encode key %d: %w", ev.Key, err)
+		}
+	}
+	var deadline int64
+	if !ev.Deadline.IsZero() {
+		deadline = ev.Deadline.UnixNano()
+	}
+
+	cw.buf = binary.AppendUvarint(cw.buf[:0], uint64(changeHeaderLen+len(value)))
+	cw.buf = append(cw.buf, byte(ev.Op))
+	cw.buf = binary.BigEndian.AppendUint64(cw.buf, ev.Key)
+	cw.buf = binary.BigEndian.AppendUint64(cw.buf, uint64(deadline))
+	if _, err := cw.w.Write(cw.buf); err != nil {
+		return err
+	}
+	_, err := cw.w.Write(value)
+	return err
+}
+
+// Flush sends buffered records to the underlying writer.
+func (cw *ChangeWriter) Flush() error {
+	return cw.w.Flush()
+}
+
+// ChangeReader decodes records written by CDC.
+type ChangeReader struct {
+	r      *bufio.Reader
+	codec  Codec
+	header [changeHeaderLen]byte
+}
+
+// NewChangeReader creates a reader of CDC records.
+func NewChangeReader(r io.Reader, codec Codec) *ChangeReader {
+	return &ChangeReader{r: bufio.NewReader(r), codec: codec}
+}
+
+// Next returns the next event. It returns io.EOF at the end of the stream.
+func (cr *ChangeReader) Next() (Event, error) {
+	size, err := binary.ReadUvarint(cr.r)
+	if err != nil {
+		if err == io.EOF {
+			return Event{}, io.EOF
+		}
+		return Event{}, unexpectedEOF(err)
+	}
+	if size < changeHeaderLen || size > maxChangeLen {
+		return Event{}, errors.New("ttlswisscache: invalid change record length")
+	}
+	if _, err := io.ReadFull(cr.r, cr.header[:]); err != nil {
+		return Event{}, unexpectedEOF(err)
+	}
+	// The value gets its own buffer, codecs may return values aliasing it.
+	data := make([]byte, size-changeHeaderLen)
+	if _, err := io.ReadFull(cr.r, data); err != nil {
+		return Event{}, unexpectedEOF(err)
+	}
+
+	ev := Event{
+		Op:  Op(cr.header[0]),
+		Key: binary.BigEndian.Uint64(cr.header[1:]),
+	}
+	if deadline := int64(binary.BigEndian.Uint64(cr.header[9:])); deadline != 0 {
+		ev.Deadline = time.Unix(0, deadline)
+	}
+	if ev.Op == OpSet {
+		if ev.Value, err = cr.codec.Unmarshal(data); err != nil {
+			return Event{}, fmt.Errorf("ttlswisscache: decode key %d: %w", ev.Key, err)
+		}
+	}
+	return ev, nil
+}
+
+// Apply replays an event, e.g. one read from a CDC stream, on the cache.
+// OpSet keeps the original deadline. Events of keys cached as missing by
+// SetNotFound, received from Subscribe, cache them as missing again.
+func (c *Cache) Apply(ev Event) {
+	switch ev.Op {
+	case OpSet:
+		it := item{deadline: ev.Deadline.UnixNano(), value: ev.Value}
+		if ev.notFound {
+			it.value = notFoundValue{}
+		}
+		c.store(ev.Key, it)
+	case OpDelete, OpExpire, OpEvict:
+		c.Delete(ev.Key)
+	case OpClear:
+		c.Clear()
+	}
+}
@@ -0,0 +1,149 @@
+package ttlswisscache
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestCache_CDC(t *testing.T) {
+	src := New(time.Hour)
+	defer src.Close()
+
+	var buf bytes.Buffer
+	stream := src.CDC(&buf, GobCodec{}, 1024)
+	src.Set(IntKey(1), "one", time.Hour)
+	src.Set(IntKey(2), "two", time.Hour)
+	src.Delete(IntKey(1))
+	src.Set(IntKey(3), "three", -time.Second)
+	src.DeleteExpired()
+	if err := stream.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if stream.Dropped() != 0 {
+		t.Errorf("unexpected dropped events: %d", stream.Dropped())
+	}
+
+	expected := []Op{OpSet, OpSet, OpDelete, OpSet, OpExpire}
+	dst := New(time.Hour)
+	defer dst.Close()
+	r := NewChangeReader(&buf, GobCodec{})
+	for i := 0; ; i++ {
+		ev, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			if i != len(expected) {
+				t.Errorf("incorrect number of events: got: %d expected: %d", i, len(expected))
+			}
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i < len(expected) && ev.Op != expected[i] {
+			t.Errorf("incorrect event %d: got: %v expected: %v", i, ev.Op, expected[i])
+		}
+		dst.Apply(ev)
+	}
+
+	if _, ok := dst.Get(IntKey(1)); ok {
+		t.Error("deleted record was replicated")
+	}
+	if v, _ := dst.Get(IntKey(2)); v != "two" {
+		t.Errorf("incorrect value: got: %v expected: %v", v, "two")
+	}
+	if _, ok := dst.Get(IntKey(3)); ok {
+		t.Error("expired record was replicated")
+	}
+	srcTTL, _ := src.TTL(IntKey(2))
+	dstTTL, _ := dst.TTL(IntKey(2))
+	if d := srcTTL - dstTTL; d < -time.Second || d > time.Second {
+		t.Errorf("deadline was not preserved: got: %v expected: %v", dstTTL, srcTTL)
+	}
+}
+
+func TestCache_Apply_NotFound(t *testing.T) {
+	src := New(time.Hour)
+	defer src.Close()
+	events, cancel := src.Subscribe(16)
+	defer cancel()
+	var buf bytes.Buffer
+	stream := src.CDC(&buf, GobCodec{}, 16)
+	src.SetNotFound(IntKey(1), time.Minute)
+	src.Set(IntKey(2), nil, time.Minute)
+	if err := stream.Stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	dst := New(time.Hour)
+	defer dst.Close()
+	for len(events) > 0 {
+		dst.Apply(<-events)
+	}
+	if _, r := dst.Lookup(IntKey(1)); r != NotFound {
+		t.Errorf("incorrect replayed marker: got: %v expected: %v", r, NotFound)
+	}
+	if v, r := dst.Lookup(IntKey(2)); r != Hit || v != nil {
+		t.Errorf("incorrect replayed nil value: got: %v, %v expected: %v, %v", v, r, nil, Hit)
+	}
+
+	r := NewChangeReader(&buf, GobCodec{})
+	if ev, err := r.Next(); err != nil || ev.Key != IntKey(2) {
+		t.Errorf("incorrect first streamed event: got: key %d, %v expected: key %d", ev.Key, err, IntKey(2))
+	}
+	if _, err := r.Next(); err != io.EOF {
+		t.Errorf("incorrect end of the stream: got: %v expected: %v", err, io.EOF)
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("broken pipe")
+}
+
+func TestCache_CDCWriteError(t *testing.T) {
+	c := New(time.Hour)
+	defer c.Close()
+
+	stream := c.CDC(failingWriter{}, GobCodec{}, 16)
+	c.Set(IntKey(1), "one", time.Hour)
+	time.Sleep(10 * time.Millisecond)
+	c.Set(IntKey(2), "two", time.Hour)
+	if err := stream.Stop(); err == nil {
+		t.Error("expected write error")
+	}
+}
+
+func TestChangeReader_Truncated(t *testing.T) {
+	var buf bytes.Buffer
+	cw := NewChangeWriter(&buf, GobCodec{})
+	cw.Write(Event{Op: OpSet, Key: 1, Value: "value", Deadline: time.Now()})
+	cw.Flush()
+
+	r := NewChangeReader(bytes.NewReader(buf.Bytes()[:buf.Len()-1]), GobCodec{})
+	if _, err := r.Next(); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("incorrect error: got: %v expected: %v", err, io.ErrUnexpectedEOF)
+	}
+}
+
+func TestChangeReader_MsgpackBytes(t *testing.T) {
+	var buf bytes.Buffer
+	cw := NewChangeWriter(&buf, MsgpackCodec{})
+	cw.Write(Event{Op: OpSet, Key: 1, Value: []byte("bbbb")})
+	cw.Write(Event{Op: OpSet, Key: 2, Value: []byte("aaaa")})
+	cw.Flush()
+
+	r := NewChangeReader(&buf, MsgpackCodec{})
+	first, err := r.Next()
+	if err != nil {
+		t.Fatalf("next failed: %v", err)
+	}
+	if _, err := r.Next(); err != nil {
+		t.Fatalf("next failed: %v", err)
+	}
+	if b, _ := first.Value.([]byte); string(b) != "bbbb" {
+		t.Errorf("incorrect value: got: %v expected: %v", first.Value, "bbbb")
+	}
+}
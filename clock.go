@@ -0,0 +1,91 @@
+package ttlswisscache
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// clockResolution is the update interval of the coarse clock.
+const clockResolution = time.Millisecond
+
+// Clock is a source of the current time, see WithClock.
+type Clock interface {
+	Now() time.Time
+}
+
+// coarseClock is a clock read from memory instead of the system, see WithCoarseClock.
+// A nil clock reads the system clock, a clock with a source reads the source.
+type coarseClock struct {
+	now    atomic.Int64 // Unix nano
+	source Clock
+}
+
+func newCoarseClock() *coarseClock {
+	k := &coarseClock{}
+	k.update()
+	return k
+}
+
+// newClock returns the clock configured by o, nil for the system clock.
+func newClock(o options) *coarseClock {
+	switch {
+	case o.clock != nil:
+		return &coarseClock{source: o.clock}
+	case o.coarseClock:
+		return newCoarseClock()
+	}
+	return nil
+}
+
+// unixNano returns the current time in Unix nano.
+func (k *coarseClock) unixNano() int64 {
+	if k == nil {
+		return time.Now().UnixNano()
+	}
+	if k.source != nil {
+		return k.source.Now().UnixNano()
+	}
+	return k.now.Load()
+}
+
+// coarse returns the clock if the cleanup manager must update it, nil otherwise.
+func (k *coarseClock) coarse() *coarseClock {
+	if k == nil || k.source != nil {
+		return nil
+	}
+	return k
+}
+
+func (k *coarseClock) update() {
+	k.now.Store(time.Now().UnixNano())
+}
+
+// WithCoarseClock makes the cache stamp and compare deadlines with a clock
+// the cleanup manager updates every millisecond, saving a time.Now call per
+// operation on hot paths. Deadlines are then up to a millisecond early, more
+// while a cleanup is running, since the same goroutine updates the clock.
+// The cleanup manager runs for the clock even if the resolution is <= 0.
+func WithCoarseClock() Option {
+	return func(o *options) {
+		o.coarseClock = true
+	}
+}
+
+// WithClock makes the cache read the current time from clock instead of the
+// system, e.g. a fake clock in tests, see the ttltest package. The cleanup
+// manager still runs on real time tickers. It takes precedence over WithCoarseClock.
+func WithClock(clock Clock) Option {
+	return func(o *options) {
+		o.clock = clock
+	}
+}
+
+// Now returns the current time of the cache clock, see WithClock and WithCoarseClock.
+func (c *Cache) Now() time.Time {
+	return time.Unix(0, c.clock.unixNano())
+}
+
+// Now returns the current time of the cache clock, see WithClock and WithCoarseClock.
+func (c *numberCache[T]) Now() time.Time {
+	return time.Unix(0, c.clock.unixNano())
+}
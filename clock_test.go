@@ -0,0 +1,56 @@
+package ttlswisscache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWithCoarseClock(t *testing.T) {
+	c := New(0, WithCoarseClock())
+	defer c.Close()
+
+	before := time.Now().UnixNano()
+	time.Sleep(20 * time.Millisecond)
+	if now := c.clock.unixNano(); now <= before {
+		t.Errorf("coarse clock was not updated: got: %d expected after: %d", now, before)
+	}
+
+	c.Set(IntKey(1), 1, 10*time.Millisecond)
+	if ttl, ok := c.TTL(IntKey(1)); !ok || ttl > 10*time.Millisecond {
+		t.Errorf("incorrect ttl: got: %v, %v expected at most: %v", ttl, ok, 10*time.Millisecond)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if n := c.DeleteExpired(); n != 1 {
+		t.Errorf("incorrect number of expired records: got: %d expected: %d", n, 1)
+	}
+}
+
+func TestWithCoarseClock_Cleanup(t *testing.T) {
+	c := NewInt64Cache(5*time.Millisecond, WithCoarseClock())
+	defer c.Close()
+
+	c.Set(IntKey(1), 1, time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	if _, ok := c.Get(IntKey(1)); ok {
+		t.Error("outdated record was not removed")
+	}
+}
+
+type fixedClock struct{ now time.Time }
+
+func (k *fixedClock) Now() time.Time { return k.now }
+
+func TestWithClock(t *testing.T) {
+	clock := &fixedClock{now: time.Unix(1000, 0)}
+	c := New(0, WithClock(clock), WithCoarseClock())
+	defer c.Close()
+	if c.clock.coarse() != nil {
+		t.Error("clock is updated by the cleanup manager")
+	}
+
+	c.Set(IntKey(1), 1, time.Minute)
+	clock.now = clock.now.Add(time.Minute + time.Second)
+	if n := c.DeleteExpired(); n != 1 {
+		t.Errorf("incorrect number of expired records: got: %d expected: %d", n, 1)
+	}
+}
@@ -0,0 +1,78 @@
+package ttlswisscache
+
+import "github.com/mhmtszr/concurrent-swiss-map/swiss"
+
+// Clone returns an independent cache with the same configuration and records,
+// deadlines included. Every shard is copied under its read lock, so the clone
+// is consistent per shard like a snapshot. Tags, dependencies and namespace
+// generations and quotas are copied too, namespace statistics but entries and
+// cost start afresh.
+// Values are shared unless WithCloner is set, in which case they are cloned.
+// Shared values are passed to WithRetainer, so each cache releases its own
+// reference with WithDestructor.
+// Subscriptions and writes still queued by SetAsync are not carried over.
+func (c *Cache) Clone() *Cache {
+	d := newCache(c.opts)
+	c.namespaces.mu.Lock()
+	for name, state := range c.namespaces.states {
+		dst := d.namespaceState(name)
+		dst.gen.Store(state.gen.Load())
+		dst.quota.Store(state.quota.Load())
+	}
+	d.namespaces.stale.Store(c.namespaces.stale.Load())
+	c.namespaces.mu.Unlock()
+	clone := func(value interface{}) interface{} { return d.adopt(c.clone(value)) }
+	for i, s := range c.shards.list {
+		s.RLock()
+		d.shards.list[i].copyFrom(s, clone, c.opts.cloner == nil)
+		s.RUnlock()
+	}
+	d.deps.copyFrom(&c.deps)
+	return d
+}
+
+// copyFrom replaces the records of the shard with copies of the src ones,
+// retained if shared is true, see shard.retain.
+// src must be locked and use the same hash as the shard.
+func (s *shard) copyFrom(src *shard, clone func(interface{}) interface{}, shared bool) {
+	s.Lock()
+	defer s.Unlock()
+	// Copied records keep their versions, which stay below the shard's.
+	s.version = max(s.version, src.version) + 1
+	n := uint32(src.count())
+	if s.filter != nil {
+		for _, key := range s.keys {
+			s.filter.remove(key)
+		}
+		for _, key := range src.keys {
+			s.filter.add(key)
+		}
+	}
+	s.keys = append(s.keys[:0], src.keys...)
+	s.deadlines = append(s.deadlines[:0], src.deadlines...)
+	s.versions = append(s.versions[:0], src.versions...)
+	if s.evictor != nil {
+		s.evictor.Reset(n)
+	}
+	for i, v := range s.values {
+		account(s.keys[i], v, false)
+	}
+	s.values = s.values[:0]
+	for i, v := range src.values {
+		v = clone(v)
+		account(src.keys[i], v, true)
+		s.values = append(s.values, v)
+		if shared {
+			s.retain(src.keys[i], v)
+		}
+	}
+	s.capacity = max(n, s.reserved)
+	s.index = swiss.NewMap[uint64, uint32](s.capacity)
+	for i, key := range s.keys {
+		s.index.Put(key, uint32(i))
+	}
+	s.tags, s.tagged = nil, nil
+	for key, tags := range src.tags {
+		s.tag(key, tags)
+	}
+}
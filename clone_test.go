@@ -0,0 +1,153 @@
+package ttlswisscache
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCache_Clone(t *testing.T) {
+	c := New(0, WithShardCount(4))
+	defer c.Close()
+	for i := 0; i < 1000; i++ {
+		c.Set(IntKey(i), i, time.Hour)
+	}
+	c.Set(IntKey(1000), 1000, -time.Hour)
+
+	d := c.Clone()
+	defer d.Close()
+	c.Delete(IntKey(1))
+	d.Set(IntKey(2), "changed", time.Hour)
+
+	if n := len(d.shards.list); n != 4 {
+		t.Errorf("incorrect shard count: got: %d expected: %d", n, 4)
+	}
+	if v, ok := d.Get(IntKey(1)); !ok || v != 1 {
+		t.Errorf("incorrect cloned value: got: %v, %v expected: 1, true", v, ok)
+	}
+	if v, _ := c.Get(IntKey(2)); v != 2 {
+		t.Errorf("clone write changed the cache: got: %v expected: %v", v, 2)
+	}
+	ttl, ok := d.TTL(IntKey(3))
+	if !ok || ttl <= 59*time.Minute || ttl > time.Hour {
+		t.Errorf("incorrect cloned ttl: got: %v expected: about %v", ttl, time.Hour)
+	}
+	if n := d.DeleteExpired(); n != 1 {
+		t.Errorf("incorrect number of expired records: got: %d expected: %d", n, 1)
+	}
+	if n := d.Stats().Entries; n != 1000 {
+		t.Errorf("incorrect number of cloned records: got: %d expected: %d", n, 1000)
+	}
+}
+
+func TestCache_Clone_Cloner(t *testing.T) {
+	c := New(0, WithCloner(func(v interface{}) interface{} {
+		return append([]int(nil), v.([]int)...)
+	}))
+	defer c.Close()
+	c.Set(IntKey(1), []int{1}, time.Hour)
+
+	d := c.Clone()
+	defer d.Close()
+	v, _ := c.shards.get(IntKey(1)).get(IntKey(1))
+	w, _ := d.shards.get(IntKey(1)).get(IntKey(1))
+	if &v.value.([]int)[0] == &w.value.([]int)[0] {
+		t.Error("value is shared with the clone")
+	}
+}
+
+func TestCache_Clone_Namespaces(t *testing.T) {
+	c := New(0)
+	defer c.Close()
+	users := c.Namespace("users")
+	users.Set(IntKey(1), "alice", time.Hour)
+	users.InvalidateNamespace()
+
+	d := c.Clone()
+	defer d.Close()
+	if v, ok := d.Namespace("users").Get(IntKey(1)); ok {
+		t.Errorf("invalidated record is visible in the clone: got: %v", v)
+	}
+}
+
+// refs counts the references to values taken by Set, WithRetainer and released
+// by WithDestructor.
+type refs struct {
+	mu   sync.Mutex
+	refs map[interface{}]int
+}
+
+func (r *refs) options() []Option {
+	return []Option{
+		WithRetainer(func(_ uint64, value interface{}) { r.add(value, 1) }),
+		WithDestructor(func(_ uint64, value interface{}) { r.add(value, -1) }),
+	}
+}
+
+func (r *refs) add(value interface{}, n int) {
+	r.mu.Lock()
+	if r.refs == nil {
+		r.refs = map[interface{}]int{}
+	}
+	r.refs[value] += n
+	r.mu.Unlock()
+}
+
+func (r *refs) get(value interface{}) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.refs[value]
+}
+
+func TestCache_Clone_Destructor(t *testing.T) {
+	var r refs
+	c := New(0, r.options()...)
+	for _, v := range []string{"shared", "overwritten"} {
+		r.add(v, 1) // Taken by Set.
+	}
+	c.Set(IntKey(1), "shared", time.Hour)
+	c.Set(IntKey(2), "overwritten", time.Hour)
+
+	d := c.Clone()
+	if n := r.get("shared"); n != 2 {
+		t.Errorf("incorrect references of a cloned value: got: %d expected: %d", n, 2)
+	}
+	for _, v := range []string{"own", "merged"} {
+		r.add(v, 1)
+	}
+	d.Set(IntKey(2), "own", time.Hour)
+	d.Set(IntKey(3), "merged", time.Hour)
+	c.Merge(d, Overwrite)
+	c.Delete(IntKey(1))
+	d.Delete(IntKey(1))
+	c.Close()
+	d.Close()
+
+	expected := map[interface{}]int{"shared": 0, "overwritten": 0, "own": 0, "merged": 0}
+	if !reflect.DeepEqual(r.refs, expected) {
+		t.Errorf("incorrect references: got: %v expected: %v", r.refs, expected)
+	}
+}
+
+func TestCache_Merge_CloseSource(t *testing.T) {
+	var r refs
+	old := New(0, r.options()...)
+	r.add("rec", 1)
+	old.Set(IntKey(1), "rec", time.Hour)
+	d := New(0, r.options()...)
+	d.Merge(old, Overwrite)
+	old.Close()
+
+	if n := r.get("rec"); n != 1 {
+		t.Errorf("incorrect references after closing the source: got: %d expected: %d", n, 1)
+	}
+	if v, ok := d.Get(IntKey(1)); !ok || v != "rec" {
+		t.Errorf("incorrect value: got: %v, %v expected: %v", v, ok, "rec")
+	}
+	d.Delete(IntKey(1))
+	if n := r.get("rec"); n != 0 {
+		t.Errorf("incorrect references after Delete: got: %d expected: %d", n, 0)
+	}
+	d.Close()
+}
@@ -0,0 +1,218 @@
+// Package cluster is a client sharding keys across several cache servers.
+//
+// Servers are respserver instances, cmd/ttlcached or anything speaking the Redis protocol.
+// Keys are placed with a consistent hash ring, so a membership change only moves
+// the keys of the nodes that joined or left. Unreachable nodes are taken off the ring
+// by health checks and put back once they answer again; their keys are served by the
+// next node in the meantime, starting as misses.
+package cluster
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/loicalleyne/ttlswisscache/internal/resp"
+	"github.com/loicalleyne/ttlswisscache/internal/ring"
+)
+
+// DefaultHealthInterval is the default period of health checks.
+const DefaultHealthInterval = time.Second
+
+const defaultMaxIdle = 16
+
+// ErrNoNodes is returned when no healthy node can serve the key.
+var ErrNoNodes = errors.New("cluster: no healthy nodes")
+
+// Client sends commands to the node owning the key.
+// It is safe for concurrent use.
+type Client struct {
+	interval time.Duration
+
+	mu    sync.RWMutex
+	nodes map[string]*node
+	ring  *ring.Ring // healthy nodes only
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+type node struct {
+	client  *resp.Client
+	healthy bool
+}
+
+// New creates a client for the server addresses and starts health checks
+// every interval. Nodes are assumed healthy until a check or a request fails.
+func New(interval time.Duration, addrs ...string) *Client {
+	if interval <= 0 {
+		interval = DefaultHealthInterval
+	}
+	c := &Client{
+		interval: interval,
+		nodes:    make(map[string]*node),
+		ring:     ring.New(ring.DefaultReplicas),
+		done:     make(chan struct{}),
+	}
+	c.SetNodes(addrs...)
+	c.wg.Add(1)
+	go c.checker()
+	return c
+}
+
+// SetNodes replaces the cluster members. Connections to removed nodes are closed.
+func (c *Client) SetNodes(addrs ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	keep := make(map[string]struct{}, len(addrs))
+	for _, addr := range addrs {
+		keep[addr] = struct{}{}
+		if _, ok := c.nodes[addr]; !ok {
+			c.nodes[addr] = &node{client: resp.NewClient(addr, defaultMaxIdle), healthy: true}
+		}
+	}
+	for addr, n := range c.nodes {
+		if _, ok := keep[addr]; !ok {
+			n.client.Close()
+			delete(c.nodes, addr)
+		}
+	}
+	c.rebuild()
+}
+
+// Nodes returns the addresses of the members and whether they are healthy.
+func (c *Client) Nodes() map[string]bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	nodes := make(map[string]bool, len(c.nodes))
+	for addr, n := range c.nodes {
+		nodes[addr] = n.healthy
+	}
+	return nodes
+}
+
+// Owner returns the address of the healthy node owning the key.
+func (c *Client) Owner(key string) (string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	addr := c.ring.Get(key)
+	return addr, addr != ""
+}
+
+// Get returns the value of the key.
+func (c *Client) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	v, err := c.do(ctx, key, []byte("GET"), []byte(key))
+	if err != nil {
+		return nil, false, err
+	}
+	if v.Null {
+		return nil, false, nil
+	}
+	return v.Bulk, true, nil
+}
+
+// Set stores the value with the given ttl.
+// A ttl below one millisecond uses the default ttl of the server.
+func (c *Client) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	args := [][]byte{[]byte("SET"), []byte(key), value}
+	if ms := ttl.Milliseconds(); ms > 0 {
+		args = append(args, []byte("PX"), strconv.AppendInt(nil, ms, 10))
+	}
+	_, err := c.do(ctx, key, args...)
+	return err
+}
+
+// Delete removes the key.
+func (c *Client) Delete(ctx context.Context, key string) error {
+	_, err := c.do(ctx, key, []byte("DEL"), []byte(key))
+	return err
+}
+
+// Close stops health checks and closes idle connections.
+func (c *Client) Close() error {
+	close(c.done)
+	c.wg.Wait()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, n := range c.nodes {
+		n.client.Close()
+	}
+	return nil
+}
+
+func (c *Client) do(ctx context.Context, key string, args ...[]byte) (resp.Value, error) {
+	c.mu.RLock()
+	addr := c.ring.Get(key)
+	n := c.nodes[addr]
+	c.mu.RUnlock()
+	if n == nil {
+		return resp.Value{}, ErrNoNodes
+	}
+	v, err := n.client.Do(ctx, args...)
+	if err != nil && v.Type != resp.Error && ctx.Err() == nil {
+		// The node is unreachable, move its keys until a health check succeeds.
+		c.setHealthy(addr, n, false)
+	}
+	return v, err
+}
+
+// setHealthy updates the node state if it is still a member.
+func (c *Client) setHealthy(addr string, n *node, healthy bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.nodes[addr] != n || n.healthy == healthy {
+		return
+	}
+	n.healthy = healthy
+	c.rebuild()
+}
+
+// rebuild recreates the ring from the healthy nodes. c.mu must be held.
+func (c *Client) rebuild() {
+	r := ring.New(ring.DefaultReplicas)
+	for addr, n := range c.nodes {
+		if n.healthy {
+			r.Add(addr)
+		}
+	}
+	c.ring = r
+}
+
+func (c *Client) checker() {
+	defer c.wg.Done()
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.check()
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// check pings every node in parallel.
+func (c *Client) check() {
+	c.mu.RLock()
+	nodes := make(map[string]*node, len(c.nodes))
+	for addr, n := range c.nodes {
+		nodes[addr] = n
+	}
+	c.mu.RUnlock()
+
+	var wg sync.WaitGroup
+	for addr, n := range nodes {
+		wg.Add(1)
+		go func(addr string, n *node) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), c.interval)
+			defer cancel()
+			_, err := n.client.Do(ctx, []byte("PING"))
+			c.setHealthy(addr, n, err == nil)
+		}(addr, n)
+	}
+	wg.Wait()
+}
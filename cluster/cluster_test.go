@@ -0,0 +1,124 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	ttlcache "github.com/loicalleyne/ttlswisscache"
+	"github.com/loicalleyne/ttlswisscache/respserver"
+)
+
+type server struct {
+	addr  string
+	cache *ttlcache.Cache
+	srv   *respserver.Server
+}
+
+func startServer(t *testing.T) *server {
+	t.Helper()
+	cache := ttlcache.New(time.Hour)
+	srv := respserver.New(cache, time.Hour)
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go srv.Serve(l)
+	t.Cleanup(func() {
+		srv.Close()
+		cache.Close()
+	})
+	return &server{addr: l.Addr().String(), cache: cache, srv: srv}
+}
+
+func TestClient(t *testing.T) {
+	ctx := context.Background()
+	servers := []*server{startServer(t), startServer(t), startServer(t)}
+	c := New(10*time.Millisecond, servers[0].addr, servers[1].addr, servers[2].addr)
+	defer c.Close()
+
+	for i := 0; i < 300; i++ {
+		key := fmt.Sprint("key", i)
+		if err := c.Set(ctx, key, []byte(key), time.Minute); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, s := range servers {
+		if n := s.cache.Stats().Entries; n < 50 {
+			t.Errorf("unbalanced distribution: %s got %d keys", s.addr, n)
+		}
+	}
+	v, ok, err := c.Get(ctx, "key7")
+	if err != nil || !ok || string(v) != "key7" {
+		t.Errorf("incorrect value: got: %s %v %v expected: %s", v, ok, err, "key7")
+	}
+	if err := c.Delete(ctx, "key7"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, _ := c.Get(ctx, "key7"); ok {
+		t.Error("key was not deleted")
+	}
+}
+
+func TestClient_HealthCheck(t *testing.T) {
+	ctx := context.Background()
+	a, b := startServer(t), startServer(t)
+	c := New(10*time.Millisecond, a.addr, b.addr)
+	defer c.Close()
+
+	// Find a key owned by b, then take b down.
+	var key string
+	for i := 0; ; i++ {
+		key = fmt.Sprint("key", i)
+		if owner, _ := c.Owner(key); owner == b.addr {
+			break
+		}
+	}
+	b.srv.Close()
+	waitFor(t, func() bool { return !c.Nodes()[b.addr] })
+
+	if owner, _ := c.Owner(key); owner != a.addr {
+		t.Errorf("incorrect owner after failure: got: %s expected: %s", owner, a.addr)
+	}
+	if err := c.Set(ctx, key, []byte("v"), time.Minute); err != nil {
+		t.Errorf("write failed after failover: %v", err)
+	}
+}
+
+func TestClient_SetNodes(t *testing.T) {
+	ctx := context.Background()
+	a, b := startServer(t), startServer(t)
+	c := New(time.Hour, a.addr)
+	defer c.Close()
+
+	for i := 0; i < 100; i++ {
+		c.Set(ctx, fmt.Sprint("key", i), []byte("v"), time.Minute)
+	}
+	c.SetNodes(a.addr, b.addr)
+	moved := 0
+	for i := 0; i < 100; i++ {
+		if owner, _ := c.Owner(fmt.Sprint("key", i)); owner == b.addr {
+			moved++
+		}
+	}
+	if moved == 0 || moved == 100 {
+		t.Errorf("incorrect number of moved keys: got: %d", moved)
+	}
+
+	c.SetNodes()
+	if _, _, err := c.Get(ctx, "key1"); err != ErrNoNodes {
+		t.Errorf("incorrect error: got: %v expected: %v", err, ErrNoNodes)
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); !cond(); {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,77 @@
+// Command ttlcached runs a cache as a standalone service.
+//
+// The cache is served over gRPC and, optionally, over the Redis and memcached protocols:
+//
+//	ttlcached -grpc :7070 -resp :6380 -memcached :11211
+package main
+
+import (
+	"flag"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"google.golang.org/grpc"
+
+	ttlcache "github.com/loicalleyne/ttlswisscache"
+	"github.com/loicalleyne/ttlswisscache/grpccache"
+	"github.com/loicalleyne/ttlswisscache/mcserver"
+	"github.com/loicalleyne/ttlswisscache/respserver"
+)
+
+func main() {
+	var (
+		grpcAddr   = flag.String("grpc", ":7070", "gRPC listen address, empty to disable")
+		respAddr   = flag.String("resp", "", "Redis protocol listen address, empty to disable")
+		mcAddr     = flag.String("memcached", "", "memcached protocol listen address, empty to disable")
+		resolution = flag.Duration("resolution", time.Second, "cleanup interval")
+		defaultTTL = flag.Duration("default-ttl", time.Hour, "ttl of records stored without one over RESP and memcached")
+	)
+	flag.Parse()
+
+	cache := ttlcache.New(*resolution)
+	defer cache.Close()
+
+	if *grpcAddr != "" {
+		l, err := net.Listen("tcp", *grpcAddr)
+		if err != nil {
+			log.Fatal(err)
+		}
+		srv := grpc.NewServer()
+		grpccache.NewServer(cache, ttlcache.MsgpackCodec{}).Register(srv)
+		defer srv.GracefulStop()
+		go serve("grpc", l.Addr(), func() error { return srv.Serve(l) })
+	}
+	if *respAddr != "" {
+		l, err := net.Listen("tcp", *respAddr)
+		if err != nil {
+			log.Fatal(err)
+		}
+		srv := respserver.New(cache, *defaultTTL)
+		defer srv.Close()
+		go serve("resp", l.Addr(), func() error { return srv.Serve(l) })
+	}
+	if *mcAddr != "" {
+		l, err := net.Listen("tcp", *mcAddr)
+		if err != nil {
+			log.Fatal(err)
+		}
+		srv := mcserver.New(cache, *defaultTTL)
+		defer srv.Close()
+		go serve("memcached", l.Addr(), func() error { return srv.Serve(l) })
+	}
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+	<-sig
+}
+
+func serve(name string, addr net.Addr, fn func() error) {
+	log.Printf("%s: listening on %s", name, addr)
+	if err := fn(); err != nil && err != respserver.ErrServerClosed && err != grpc.ErrServerStopped {
+		log.Printf("%s: %v", name, err)
+	}
+}
@@ -0,0 +1,108 @@
+package ttlswisscache
+
+import (
+	"sync"
+	"time"
+)
+
+// WithGetManyCoalescing makes concurrent GetMany calls of small key sets wait up
+// to delay, e.g. 100µs, for each other and read their keys in a single batch
+// grouped by shard, dataloader-style. Every shard is then locked once per batch
+// instead of once per key and call, which pays off under fan-out-heavy request
+// handling at the cost of the delay. Batches are read as soon as they reach
+// 1024 keys, GetMany calls of that many keys aren't coalesced.
+func WithGetManyCoalescing(delay time.Duration) Option {
+	return func(o *options) {
+		o.coalesceDelay = delay
+	}
+}
+
+// coalescer merges the GetMany calls arriving within the delay of a batch.
+type coalescer struct {
+	cache *Cache
+	delay time.Duration
+	mu    sync.Mutex
+	batch *getBatch // Batch collecting keys, nil if none.
+}
+
+// getBatch is the key set of coalesced GetMany calls.
+type getBatch struct {
+	keys    []uint64
+	timer   *time.Timer
+	values  map[uint64]interface{} // Set before done is closed.
+	done    chan struct{}
+	mu      sync.Mutex
+	claimed map[uint64]bool // Keys of the SetOnce values handed over, guarded by mu.
+}
+
+func newCoalescer(c *Cache, delay time.Duration) *coalescer {
+	if delay <= 0 {
+		return nil
+	}
+	return &coalescer{cache: c, delay: delay}
+}
+
+// get adds keys to the current batch, waits until it is read and returns the
+// values of keys.
+func (co *coalescer) get(keys []uint64) map[uint64]interface{} {
+	co.mu.Lock()
+	b := co.batch
+	if b == nil {
+		b = &getBatch{done: make(chan struct{})}
+		b.timer = time.AfterFunc(co.delay, func() { co.flush(b) })
+		co.batch = b
+	}
+	b.keys = append(b.keys, keys...)
+	full := len(b.keys) >= minParallelGetMany
+	co.mu.Unlock()
+	if full {
+		co.flush(b)
+	}
+
+	<-b.done
+	values := make(map[uint64]interface{}, len(keys))
+	for _, key := range keys {
+		value, ok := b.values[key]
+		if !ok {
+			continue
+		}
+		if v, once := value.(*onceValue); once {
+			if b.claim(key) {
+				values[key] = v.value
+			}
+			continue
+		}
+		values[key] = co.cache.clone(value)
+	}
+	return values
+}
+
+// claim reports whether the caller is the first to take the consumed SetOnce
+// value of key, which goes to a single caller of the batch.
+func (b *getBatch) claim(key uint64) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.claimed[key] {
+		return false
+	}
+	if b.claimed == nil {
+		b.claimed = make(map[uint64]bool)
+	}
+	b.claimed[key] = true
+	return true
+}
+
+// flush reads the batch unless it was read already.
+func (co *coalescer) flush(b *getBatch) {
+	co.mu.Lock()
+	if co.batch != b {
+		co.mu.Unlock()
+		return
+	}
+	co.batch = nil
+	co.mu.Unlock()
+	b.timer.Stop()
+	// Values are cloned for every caller, consumed SetOnce values go to one.
+	b.values = co.cache.getGrouped(b.keys, false)
+	close(b.done)
+}
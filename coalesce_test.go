@@ -0,0 +1,54 @@
+package ttlswisscache
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWithGetManyCoalescing(t *testing.T) {
+	c := New(0, WithGetManyCoalescing(20*time.Millisecond))
+	defer c.Close()
+	for i := 0; i < 100; i++ {
+		c.Set(IntKey(i), i, time.Hour)
+	}
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			keys := []uint64{IntKey(g), IntKey(g + 1), IntKey(-1)}
+			values := c.GetMany(keys)
+			if len(values) != 2 || values[IntKey(g)] != g || values[IntKey(g+1)] != g+1 {
+				t.Errorf("incorrect values: got: %v expected keys: %v", values, keys[:2])
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestWithGetManyCoalescing_Full(t *testing.T) {
+	c := New(0, WithGetManyCoalescing(time.Hour))
+	defer c.Close()
+	keys := make([]uint64, minParallelGetMany/2)
+	for i := range keys {
+		keys[i] = IntKey(i)
+		c.Set(keys[i], i, time.Hour)
+	}
+
+	done := make(chan int, 2)
+	for g := 0; g < 2; g++ {
+		go func() { done <- len(c.GetMany(keys)) }()
+	}
+	for g := 0; g < 2; g++ {
+		select {
+		case n := <-done:
+			if n != len(keys) {
+				t.Errorf("incorrect number of values: got: %d expected: %d", n, len(keys))
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("full batch wasn't read")
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package ttlswisscache
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+
+	"github.com/shamaton/msgpack/v2"
+)
+
+// Codec converts stored values to bytes and back.
+// It is used wherever values leave the process, e.g. snapshots.
+type Codec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte) (interface{}, error)
+}
+
+// GobCodec encodes values with encoding/gob.
+// Concrete types stored behind interface{} must be registered with gob.Register.
+type GobCodec struct{}
+
+// Marshal encodes the value with its type information.
+func (GobCodec) Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Unmarshal decodes a value produced by Marshal.
+func (GobCodec) Unmarshal(data []byte) (interface{}, error) {
+	var v interface{}
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// JSONCodec encodes values with encoding/json.
+// Decoded values have the generic JSON types: numbers become float64,
+// objects become map[string]interface{}.
+type JSONCodec struct{}
+
+// Marshal encodes the value as JSON.
+func (JSONCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+// Unmarshal decodes a JSON document.
+func (JSONCodec) Unmarshal(data []byte) (interface{}, error) {
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// MsgpackCodec encodes values with MessagePack.
+// It is compact, fast and readable from other languages.
+// Decoded values have the generic MessagePack types: integers keep their
+// width, maps become map[interface{}]interface{}.
+type MsgpackCodec struct{}
+
+// Marshal encodes the value as MessagePack.
+func (MsgpackCodec) Marshal(v interface{}) ([]byte, error) {
+	return msgpack.Marshal(v)
+}
+
+// Unmarshal decodes a MessagePack document.
+func (MsgpackCodec) Unmarshal(data []byte) (interface{}, error) {
+	var v interface{}
+	if err := msgpack.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
@@ -0,0 +1,39 @@
+package ttlswisscache
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCodecs(t *testing.T) {
+	tt := []struct {
+		name     string
+		codec    Codec
+		value    interface{}
+		expected interface{}
+	}{
+		{name: "gob string", codec: GobCodec{}, value: "value", expected: "value"},
+		{name: "gob int", codec: GobCodec{}, value: 42, expected: 42},
+		{name: "json string", codec: JSONCodec{}, value: "value", expected: "value"},
+		{name: "json int", codec: JSONCodec{}, value: 42, expected: float64(42)},
+		{name: "msgpack string", codec: MsgpackCodec{}, value: "value", expected: "value"},
+		{name: "msgpack bytes", codec: MsgpackCodec{}, value: []byte("value"), expected: []byte("value")},
+		{name: "msgpack slice", codec: MsgpackCodec{}, value: []interface{}{"a", true}, expected: []interface{}{"a", true}},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			data, err := tc.codec.Marshal(tc.value)
+			if err != nil {
+				t.Fatalf("marshal failed: %v", err)
+			}
+			actual, err := tc.codec.Unmarshal(data)
+			if err != nil {
+				t.Fatalf("unmarshal failed: %v", err)
+			}
+			if !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("incorrect value: got: %#v expected: %#v", actual, tc.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+// Package counters tracks per-key counts over sliding windows, e.g. QPS or error
+// rates, with time-bucketed counters stored in an Int64Cache.
+//
+// Add increments the bucket of the current time, Sum adds up the buckets of a
+// window. Buckets expire with the cache ttl once they are older than the
+// retention, so keys no longer counted take no memory.
+package counters
+
+import (
+	"time"
+
+	ttlcache "github.com/loicalleyne/ttlswisscache"
+)
+
+// bucketMix spreads bucket numbers over the key bits.
+const bucketMix = 0x9e3779b97f4a7c15
+
+// Counters counts events per key in buckets. It is safe for concurrent use.
+type Counters struct {
+	cache     *ttlcache.Int64Cache
+	bucket    time.Duration
+	retention time.Duration
+}
+
+// New returns counters keeping bucket-wide buckets for retention in cache,
+// which should be dedicated to them. Time is read from the cache clock,
+// see ttlcache.WithClock. bucket must be positive.
+func New(cache *ttlcache.Int64Cache, bucket, retention time.Duration) *Counters {
+	return &Counters{cache: cache, bucket: bucket, retention: max(retention, bucket)}
+}
+
+// Add adds n to the count of key now.
+func (c *Counters) Add(key uint64, n int64) {
+	c.cache.Add(bucketKey(key, c.now()), n, c.retention+c.bucket)
+}
+
+// Sum returns the count of key over the last window, rounded up to whole
+// buckets including the current one and capped at the retention.
+func (c *Counters) Sum(key uint64, window time.Duration) int64 {
+	b := c.now()
+	buckets := int64((min(window, c.retention) + c.bucket - 1) / c.bucket)
+	var sum int64
+	for i := int64(0); i < buckets; i++ {
+		n, _ := c.cache.Get(bucketKey(key, b-i))
+		sum += n
+	}
+	return sum
+}
+
+// Rate returns the count of key per second over the last window, see Sum.
+func (c *Counters) Rate(key uint64, window time.Duration) float64 {
+	buckets := (min(window, c.retention) + c.bucket - 1) / c.bucket
+	if buckets == 0 {
+		return 0
+	}
+	return float64(c.Sum(key, window)) / (buckets * c.bucket).Seconds()
+}
+
+// now returns the number of the current bucket.
+func (c *Counters) now() int64 {
+	return c.cache.Now().UnixNano() / int64(c.bucket)
+}
+
+// bucketKey returns the key of the bucket b of key.
+func bucketKey(key uint64, b int64) uint64 {
+	return key ^ uint64(b)*bucketMix
+}
@@ -0,0 +1,48 @@
+package counters
+
+import (
+	"testing"
+	"time"
+
+	ttlcache "github.com/loicalleyne/ttlswisscache"
+	"github.com/loicalleyne/ttlswisscache/ttltest"
+)
+
+func TestCounters(t *testing.T) {
+	clock := ttltest.NewClock()
+	cache := ttlcache.NewInt64Cache(0, ttlcache.WithClock(clock))
+	defer cache.Close()
+	c := New(cache, time.Second, time.Minute)
+	key := ttlcache.StringKey("GET /users")
+
+	for i := 0; i < 10; i++ {
+		c.Add(key, 1)
+		c.Add(ttlcache.StringKey("other"), 100)
+		clock.Advance(time.Second)
+	}
+	c.Add(key, 5)
+
+	if n := c.Sum(key, time.Second); n != 5 {
+		t.Errorf("incorrect sum of the current bucket: got: %d expected: %d", n, 5)
+	}
+	if n := c.Sum(key, 3*time.Second); n != 7 {
+		t.Errorf("incorrect sum over 3s: got: %d expected: %d", n, 7)
+	}
+	if n := c.Sum(key, 2500*time.Millisecond); n != 7 {
+		t.Errorf("incorrect sum over 2.5s: got: %d expected: %d", n, 7)
+	}
+	if n := c.Sum(key, time.Hour); n != 15 {
+		t.Errorf("incorrect sum over the retention: got: %d expected: %d", n, 15)
+	}
+	if r := c.Rate(key, 5*time.Second); r != 1.8 {
+		t.Errorf("incorrect rate: got: %v expected: %v", r, 1.8)
+	}
+
+	clock.Advance(2 * time.Minute)
+	if n := cache.DeleteExpired(); n != 21 {
+		t.Errorf("incorrect number of expired buckets: got: %d expected: %d", n, 21)
+	}
+	if n := c.Sum(key, time.Hour); n != 0 {
+		t.Errorf("incorrect sum after the retention: got: %d expected: %d", n, 0)
+	}
+}
@@ -0,0 +1,29 @@
+package ttlswisscache
+
+import "time"
+
+// Dedup records key for window and reports whether it was already recorded by
+// a window still running, e.g. to process webhooks and message redeliveries
+// once. The check and the record are atomic: of concurrent calls with the same
+// key, exactly one reports false. A duplicate doesn't extend the window.
+// Outdated records waiting for the cleanup manager don't count.
+// After Close nothing is recorded and Dedup reports false.
+func (c *Cache) Dedup(key uint64, window time.Duration) bool {
+	now := c.clock.unixNano()
+	s := c.shards.get(key)
+	s.Lock()
+	if it, ok := s.get(key); ok && it.deadline >= now {
+		s.Unlock()
+		return true
+	}
+	if c.closing.Load() {
+		s.Unlock()
+		return false
+	}
+	it := item{deadline: now + int64(window), value: struct{}{}}
+	s.put(key, it)
+	c.subs.publish(OpSet, key, it)
+	s.Unlock()
+	s.stats.sets.Add(1)
+	return false
+}
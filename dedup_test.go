@@ -0,0 +1,48 @@
+package ttlswisscache
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCache_Dedup(t *testing.T) {
+	c := New(0)
+	defer c.Close()
+	key := StringKey("delivery-1")
+
+	if c.Dedup(key, time.Hour) {
+		t.Error("first delivery was reported as a duplicate")
+	}
+	if !c.Dedup(key, time.Hour) {
+		t.Error("redelivery was not reported as a duplicate")
+	}
+
+	c.Set(IntKey(1), 1, -time.Second)
+	if c.Dedup(IntKey(1), time.Hour) {
+		t.Error("outdated record was reported as a duplicate")
+	}
+}
+
+func TestCache_Dedup_Concurrent(t *testing.T) {
+	c := New(0)
+	defer c.Close()
+	var (
+		wg    sync.WaitGroup
+		first atomic.Int32
+	)
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if !c.Dedup(IntKey(1), time.Hour) {
+				first.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := first.Load(); n != 1 {
+		t.Errorf("incorrect number of first deliveries: got: %d expected: %d", n, 1)
+	}
+}
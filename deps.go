@@ -0,0 +1,188 @@
+package ttlswisscache
+
+import (
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// dependencies maps records to the records declared with SetWithDeps on them.
+// The dependencies of a record are stored as tags of it, so they are dropped
+// with the record like tags. Dependents are forgotten once removed, but the
+// map may hold dependents set again without dependencies, so a dependent is
+// removed only if it still carries the tag of the dependency.
+type dependencies struct {
+	n          atomic.Int32 // Fast path for caches without dependencies.
+	mu         sync.Mutex
+	dependents map[uint64]map[uint64]struct{}
+	deps       map[uint64][]uint64 // Dependencies of the dependents, to forget them.
+	removed    []uint64            // Removed dependencies whose dependents are not removed yet.
+}
+
+// SetWithDeps adds value to the cache with given ttl like Set and makes the record
+// depend on the records of deps: it is removed once any of them is deleted, expires
+// or is invalidated, and so are the records depending on it in turn. Overwriting a
+// dependency keeps its dependents. If a dependency is missing the record is removed
+// right away. Removals are reported like Delete.
+//
+// Dependencies belong to the record like tags: setting the key again drops them.
+// Snapshots and CDC streams don't carry them.
+func (c *Cache) SetWithDeps(key uint64, value interface{}, ttl time.Duration, deps ...uint64) {
+	tags := make([]string, len(deps))
+	for i, dep := range deps {
+		tags[i] = depTag(dep)
+	}
+	if !c.setWithTags(key, value, ttl, tags) {
+		return
+	}
+	// Dependencies removed before they are added are caught below.
+	c.deps.add(key, deps)
+
+	for _, dep := range deps {
+		if !c.alive(dep) {
+			c.dropDependent(dep, key)
+		}
+	}
+	c.cascade()
+}
+
+// alive reports whether key has a live record with a value, not a tombstone,
+// a SetNotFound marker or an outdated record waiting for the cleanup. It
+// doesn't count as an access of the eviction policy.
+func (c *Cache) alive(key uint64) bool {
+	s := c.shards.get(key)
+	s.RLock()
+	defer s.RUnlock()
+	i, ok := s.index.Get(key)
+	if !ok || s.deadlines[i] < c.clock.unixNano() {
+		return false
+	}
+	_, ok = (item{value: s.values[i]}).load()
+	return ok
+}
+
+// depTag returns the tag marking the dependents of the record of the key.
+func depTag(key uint64) string {
+	return "\x00dep\x00" + strconv.FormatUint(key, 16)
+}
+
+func (d *dependencies) add(key uint64, deps []uint64) {
+	if len(deps) == 0 {
+		return
+	}
+	d.mu.Lock()
+	if d.dependents == nil {
+		d.dependents = make(map[uint64]map[uint64]struct{})
+		d.deps = make(map[uint64][]uint64)
+	}
+	d.forget(key)
+	d.deps[key] = append([]uint64(nil), deps...)
+	for _, dep := range deps {
+		keys, ok := d.dependents[dep]
+		if !ok {
+			keys = make(map[uint64]struct{})
+			d.dependents[dep] = keys
+			d.n.Add(1)
+		}
+		keys[key] = struct{}{}
+	}
+	d.mu.Unlock()
+}
+
+// forget drops the key from the dependents of its dependencies.
+// d.mu must be held.
+func (d *dependencies) forget(key uint64) {
+	for _, dep := range d.deps[key] {
+		keys, ok := d.dependents[dep]
+		if !ok {
+			continue
+		}
+		delete(keys, key)
+		if len(keys) == 0 {
+			delete(d.dependents, dep)
+			d.n.Add(-1)
+		}
+	}
+	delete(d.deps, key)
+}
+
+// onRemoved queues the dependents of the removed record of the key for cascade
+// and forgets the record as a dependent.
+// It is called under the lock of the record's shard.
+func (d *dependencies) onRemoved(key uint64) {
+	if d.n.Load() == 0 {
+		return
+	}
+	d.mu.Lock()
+	if _, ok := d.dependents[key]; ok {
+		d.removed = append(d.removed, key)
+	}
+	d.forget(key)
+	d.mu.Unlock()
+}
+
+// pop returns a removed dependency and its dependents.
+func (d *dependencies) pop() (uint64, map[uint64]struct{}, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for len(d.removed) > 0 {
+		dep := d.removed[len(d.removed)-1]
+		d.removed = d.removed[:len(d.removed)-1]
+		if keys, ok := d.dependents[dep]; ok {
+			delete(d.dependents, dep)
+			d.n.Add(-1)
+			return dep, keys, true
+		}
+	}
+	return 0, nil, false
+}
+
+// reset forgets all dependencies, e.g. after Clear.
+func (d *dependencies) reset() {
+	d.mu.Lock()
+	d.dependents, d.deps, d.removed = nil, nil, nil
+	d.n.Store(0)
+	d.mu.Unlock()
+}
+
+// copyFrom replaces the dependencies with the src ones.
+func (d *dependencies) copyFrom(src *dependencies) {
+	src.mu.Lock()
+	defer src.mu.Unlock()
+	d.reset()
+	for key, deps := range src.deps {
+		d.add(key, deps)
+	}
+}
+
+// cascade removes the dependents of removed records, transitively.
+// No shard may be locked by the caller.
+func (c *Cache) cascade() {
+	if c.deps.n.Load() == 0 {
+		return
+	}
+	for {
+		dep, keys, ok := c.deps.pop()
+		if !ok {
+			return
+		}
+		for key := range keys {
+			c.dropDependent(dep, key)
+		}
+	}
+}
+
+// dropDependent removes the record of the key if it still depends on dep.
+func (c *Cache) dropDependent(dep, key uint64) {
+	tag := depTag(dep)
+	s := c.shards.get(key)
+	s.Lock()
+	if _, ok := s.tagged[tag][key]; ok {
+		if it, ok := s.delete(key); ok {
+			s.stats.deletes.Add(1)
+			c.subs.publish(OpDelete, key, it)
+		}
+	}
+	s.Unlock()
+}
@@ -0,0 +1,111 @@
+package ttlswisscache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCache_SetWithDeps(t *testing.T) {
+	c := New(0)
+	defer c.Close()
+	c.Set(IntKey(1), 1, time.Hour)
+	c.Set(IntKey(2), 2, time.Hour)
+	c.SetWithDeps(IntKey(3), 3, time.Hour, IntKey(1), IntKey(2))
+	c.SetWithDeps(IntKey(4), 4, time.Hour, IntKey(3))
+	c.SetWithDeps(IntKey(5), 5, time.Hour, IntKey(2))
+
+	c.Set(IntKey(1), 10, time.Hour)
+	if _, ok := c.Get(IntKey(3)); !ok {
+		t.Error("dependent was removed when its dependency was overwritten")
+	}
+
+	c.Delete(IntKey(1))
+	for _, key := range []uint64{IntKey(3), IntKey(4)} {
+		if v, ok := c.Get(key); ok {
+			t.Errorf("incorrect value of a dependent: got: %v expected: none", v)
+		}
+	}
+	for _, key := range []uint64{IntKey(2), IntKey(5)} {
+		if _, ok := c.Get(key); !ok {
+			t.Errorf("record %d was removed", key)
+		}
+	}
+	if st := c.Stats(); st.Deletes != 3 {
+		t.Errorf("incorrect deletes: got: %d expected: %d", st.Deletes, 3)
+	}
+}
+
+func TestCache_SetWithDeps_Expired(t *testing.T) {
+	c := New(0)
+	defer c.Close()
+	c.Set(IntKey(1), 1, -time.Second)
+	c.SetWithDeps(IntKey(2), 2, time.Hour, IntKey(1))
+
+	if n := c.DeleteExpired(); n != 1 {
+		t.Errorf("incorrect number of expired records: got: %d expected: %d", n, 1)
+	}
+	if v, ok := c.Get(IntKey(2)); ok {
+		t.Errorf("incorrect value of a dependent: got: %v expected: none", v)
+	}
+}
+
+func TestCache_SetWithDeps_Missing(t *testing.T) {
+	c := New(0)
+	defer c.Close()
+	c.SetWithDeps(IntKey(2), 2, time.Hour, IntKey(1))
+
+	if v, ok := c.Get(IntKey(2)); ok {
+		t.Errorf("incorrect value of a dependent on a missing record: got: %v expected: none", v)
+	}
+}
+
+func TestCache_SetWithDeps_Markers(t *testing.T) {
+	c := New(0, WithTombstones(time.Hour))
+	defer c.Close()
+	c.Set(IntKey(1), 1, time.Hour)
+	c.Delete(IntKey(1))
+	c.SetNotFound(IntKey(2), time.Hour)
+	c.Set(IntKey(3), 3, -time.Second)
+
+	for _, dep := range []uint64{IntKey(1), IntKey(2), IntKey(3)} {
+		c.SetWithDeps(IntKey(10), 10, time.Hour, dep)
+		if v, ok := c.Get(IntKey(10)); ok {
+			t.Errorf("incorrect value of a dependent on record %d: got: %v expected: none", dep, v)
+		}
+	}
+}
+
+func TestCache_SetWithDeps_SetAgain(t *testing.T) {
+	c := New(0)
+	defer c.Close()
+	c.Set(IntKey(1), 1, time.Hour)
+	c.SetWithDeps(IntKey(2), 2, time.Hour, IntKey(1))
+	c.Set(IntKey(2), 20, time.Hour)
+
+	c.Delete(IntKey(1))
+	if v, ok := c.Get(IntKey(2)); !ok || v != 20 {
+		t.Errorf("incorrect value of a former dependent: got: %v, %v expected: 20, true", v, ok)
+	}
+}
+
+func TestCache_SetWithDeps_Pruned(t *testing.T) {
+	c := New(0, WithMaxValueSize(4, RejectOversized))
+	defer c.Close()
+	c.Set(IntKey(1), "dep", time.Hour)
+	c.SetWithDeps(IntKey(2), "rejected value", time.Hour, IntKey(1))
+	if n := c.deps.n.Load(); n != 0 {
+		t.Errorf("dependency of a rejected write was recorded: got: %d dependencies expected: %d", n, 0)
+	}
+
+	for i := 2; i < 100; i++ {
+		c.SetWithDeps(IntKey(i), "v", time.Hour, IntKey(1))
+		c.Delete(IntKey(i))
+	}
+	c.SetWithDeps(IntKey(100), "v", time.Hour, IntKey(1))
+	c.deps.mu.Lock()
+	dependents, deps := len(c.deps.dependents[IntKey(1)]), len(c.deps.deps)
+	c.deps.mu.Unlock()
+	if dependents != 1 || deps != 1 {
+		t.Errorf("removed dependents were kept: got: %d dependents, %d records expected: 1, 1", dependents, deps)
+	}
+}
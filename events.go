@@ -0,0 +1,311 @@
+package ttlswisscache
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Op is a kind of cache mutation.
+type Op uint8
+
+const (
+	// OpSet reports a stored record, including a new deadline set by Expire.
+	OpSet Op = iota + 1
+	// OpDelete reports a record removed by the user.
+	OpDelete
+	// OpExpire reports an outdated record removed by the cleanup manager.
+	OpExpire
+	// OpClear reports that all records were removed. Key and Value are empty.
+	OpClear
+	// OpEvict reports a record removed by the eviction policy, see WithMaxEntries.
+	OpEvict
+)
+
+func (o Op) String() string {
+	switch o {
+	case OpSet:
+		return "set"
+	case OpDelete:
+		return "delete"
+	case OpExpire:
+		return "expire"
+	case OpClear:
+		return "clear"
+	case OpEvict:
+		return "evict"
+	default:
+		return "unknown"
+	}
+}
+
+// Event describes a mutation of the cache.
+type Event struct {
+	Op       Op
+	Key      uint64
+	Value    interface{}
+	Deadline time.Time // Zero for OpDelete, OpEvict and OpClear.
+	notFound bool      // The record is a SetNotFound marker, see Apply.
+}
+
+// Callbacks are functions a cache calls on mutations, see WithCallbacks.
+// They are called synchronously under the lock of the record's shard, in the
+// order of the mutations: they must be fast and must not call the cache.
+// WithAsyncEvictions moves OnEvicted calls out of the lock.
+type Callbacks struct {
+	// OnSet is called with every stored record, including a new deadline set by Expire.
+	OnSet func(key uint64, value interface{})
+	// OnEvicted is called with every removed record, reason is OpDelete for records
+	// removed by the user, OpExpire for outdated ones and OpEvict for the ones
+	// evicted to bound the cache. Clear doesn't call it for the records it
+	// removes, ClearWithCallbacks does.
+	OnEvicted func(key uint64, value interface{}, reason Op)
+}
+
+func (cb *Callbacks) call(op Op, key uint64, it item) {
+	switch {
+	case op == OpSet && cb.OnSet != nil:
+		value, _ := it.load()
+		cb.OnSet(key, value)
+	case (op == OpDelete || op == OpExpire || op == OpEvict) && cb.OnEvicted != nil:
+		value, _ := it.load()
+		cb.OnEvicted(key, value, op)
+	}
+}
+
+// Overflow tells a subscription what to do with an event when its buffer is full.
+type Overflow uint8
+
+const (
+	// DropNewest discards the event.
+	DropNewest Overflow = iota
+	// DropOldest discards the oldest event of the buffer to make room.
+	DropOldest
+	// BlockWithTimeout waits up to SubscribeOptions.Timeout for room in the
+	// buffer and then discards the event. The mutation waits meanwhile, under
+	// the lock of its shard.
+	BlockWithTimeout
+	// Disconnect cancels the subscription and closes its channel, the
+	// buffered events are still received.
+	Disconnect
+)
+
+func (o Overflow) String() string {
+	switch o {
+	case DropNewest:
+		return "drop-newest"
+	case DropOldest:
+		return "drop-oldest"
+	case BlockWithTimeout:
+		return "block-with-timeout"
+	case Disconnect:
+		return "disconnect"
+	default:
+		return "unknown"
+	}
+}
+
+// SubscribeOptions configure a subscription, see SubscribeWith.
+type SubscribeOptions struct {
+	Buffer   int           // Channel capacity.
+	Overflow Overflow      // What to do when the buffer is full, DropNewest by default.
+	Timeout  time.Duration // Wait of BlockWithTimeout.
+}
+
+// Subscription is a stream of cache events, see SubscribeWith.
+type Subscription struct {
+	C    <-chan Event // Closed on Cancel, on Close and on Disconnect.
+	subs *subscribers
+	sub  *subscriber
+	once sync.Once
+}
+
+// Dropped returns the number of events lost because the buffer was full.
+// A disconnected subscription counts the event it was disconnected on.
+func (s *Subscription) Dropped() uint64 {
+	return s.sub.dropped.Load()
+}
+
+// Disconnected reports whether the subscription was cancelled by Disconnect.
+func (s *Subscription) Disconnected() bool {
+	return s.sub.disconnected.Load()
+}
+
+// Cancel ends the subscription and closes its channel.
+func (s *Subscription) Cancel() {
+	s.once.Do(func() { s.subs.remove(s.sub) })
+}
+
+// subscriber is a single subscription.
+type subscriber struct {
+	ch           chan Event
+	opts         SubscribeOptions
+	dropped      atomic.Uint64
+	disconnected atomic.Bool
+}
+
+// subscribers fans events out to every subscription.
+// Events are published under the shard lock so the order of events for a key
+// matches the order of mutations.
+type subscribers struct {
+	n         atomic.Int32 // Fast path for caches without subscriptions.
+	mu        sync.RWMutex
+	list      map[*subscriber]struct{}
+	closed    bool
+	dropped   atomic.Uint64 // Events lost by any subscription.
+	callbacks Callbacks
+	evictions *evictionPool // Runs OnEvicted, nil without WithAsyncEvictions.
+	deps      *dependencies // Notified of removed records.
+}
+
+// Subscribe returns a channel receiving cache events and a function cancelling the subscription.
+// buffer sets the channel capacity. Events are dropped when the channel is full,
+// so the subscriber can never block cache operations.
+// The channel is closed on cancel and on Close, right away on a closed cache.
+// It is SubscribeWith with buffer and DropNewest.
+func (c *Cache) Subscribe(buffer int) (<-chan Event, func()) {
+	s := c.SubscribeWith(SubscribeOptions{Buffer: buffer})
+	return s.C, s.Cancel
+}
+
+// SubscribeWith returns a subscription to cache events configured by opts.
+// Events are published under the lock of the mutated record's shard, so
+// opts.Overflow sets what a slow subscriber costs: lost events with
+// DropNewest and DropOldest, writes waiting up to opts.Timeout with
+// BlockWithTimeout, the subscription itself with Disconnect.
+// Stats.EventsDropped counts the events lost by every subscription.
+func (c *Cache) SubscribeWith(opts SubscribeOptions) *Subscription {
+	sub := c.subs.add(opts)
+	return &Subscription{C: sub.ch, subs: &c.subs, sub: sub}
+}
+
+func (s *subscribers) add(opts SubscribeOptions) *subscriber {
+	sub := &subscriber{ch: make(chan Event, opts.Buffer), opts: opts}
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		close(sub.ch)
+		return sub
+	}
+	if s.list == nil {
+		s.list = make(map[*subscriber]struct{})
+	}
+	s.list[sub] = struct{}{}
+	s.n.Add(1)
+	s.mu.Unlock()
+	return sub
+}
+
+func (s *subscribers) remove(sub *subscriber) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.list[sub]; !ok {
+		return
+	}
+	delete(s.list, sub)
+	s.n.Add(-1)
+	close(sub.ch)
+}
+
+func (s *subscribers) closeAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	for sub := range s.list {
+		delete(s.list, sub)
+		s.n.Add(-1)
+		close(sub.ch)
+	}
+}
+
+// pending returns the number of events waiting in the subscription channels
+// and of OnEvicted calls not done yet.
+func (s *subscribers) pending() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	n := 0
+	if s.evictions != nil {
+		n = int(s.evictions.pending.Load())
+	}
+	for sub := range s.list {
+		n += len(sub.ch)
+	}
+	return n
+}
+
+func (s *subscribers) active() bool {
+	return s.n.Load() > 0
+}
+
+func (s *subscribers) publish(op Op, key uint64, it item) {
+	removed := op == OpDelete || op == OpExpire || op == OpEvict
+	if removed && s.deps != nil {
+		s.deps.onRemoved(key)
+	}
+	if removed && s.evictions != nil {
+		value, _ := it.load()
+		s.evictions.enqueue(key, value, op)
+	} else {
+		s.callbacks.call(op, key, it)
+	}
+	if !s.active() {
+		return
+	}
+	ev := Event{Op: op, Key: key}
+	ev.Value, _ = it.load()
+	_, ev.notFound = it.value.(notFoundValue)
+	if op == OpSet || op == OpExpire {
+		ev.Deadline = time.Unix(0, it.deadline)
+	}
+
+	s.mu.RLock()
+	for sub := range s.list {
+		if sub.disconnected.Load() {
+			continue
+		}
+		select {
+		case sub.ch <- ev:
+		default:
+			s.overflow(sub, ev)
+		}
+	}
+	s.mu.RUnlock()
+}
+
+// overflow handles an event for the full buffer of sub, see Overflow.
+// s.mu must be read locked.
+func (s *subscribers) overflow(sub *subscriber, ev Event) {
+	switch sub.opts.Overflow {
+	case DropOldest:
+		for {
+			select {
+			case sub.ch <- ev:
+				return
+			default:
+			}
+			select {
+			case <-sub.ch:
+				s.drop(sub)
+			default:
+			}
+		}
+	case BlockWithTimeout:
+		timer := time.NewTimer(sub.opts.Timeout)
+		defer timer.Stop()
+		select {
+		case sub.ch <- ev:
+			return
+		case <-timer.C:
+		}
+	case Disconnect:
+		if sub.disconnected.CompareAndSwap(false, true) {
+			go s.remove(sub) // Takes s.mu, read locked by the caller.
+		}
+	}
+	s.drop(sub)
+}
+
+func (s *subscribers) drop(sub *subscriber) {
+	sub.dropped.Add(1)
+	s.dropped.Add(1)
+}
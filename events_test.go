@@ -0,0 +1,147 @@
+package ttlswisscache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCache_Subscribe(t *testing.T) {
+	c := New(10 * time.Millisecond)
+	defer c.Close()
+
+	events, cancel := c.Subscribe(16)
+	defer cancel()
+
+	c.Set(IntKey(1), "value", time.Hour)
+	c.Delete(IntKey(1))
+	c.Set(IntKey(2), "value", -time.Second)
+	c.Clear()
+
+	expected := []Op{OpSet, OpDelete, OpSet}
+	for _, op := range expected {
+		ev := <-events
+		if ev.Op != op {
+			t.Errorf("incorrect event: got: %v expected: %v", ev.Op, op)
+		}
+	}
+
+	// The cleaner may run before Clear, so OpExpire is optional.
+	ev := <-events
+	if ev.Op == OpExpire {
+		if ev.Key != IntKey(2) {
+			t.Errorf("incorrect expired key: got: %v expected: %v", ev.Key, IntKey(2))
+		}
+		ev = <-events
+	}
+	if ev.Op != OpClear {
+		t.Errorf("incorrect event: got: %v expected: %v", ev.Op, OpClear)
+	}
+}
+
+func TestCache_SubscribeExpire(t *testing.T) {
+	c := New(10 * time.Millisecond)
+	defer c.Close()
+
+	events, cancel := c.Subscribe(16)
+	defer cancel()
+
+	c.Set(IntKey(1), "value", time.Millisecond)
+	if ev := <-events; ev.Op != OpSet || ev.Value != "value" {
+		t.Errorf("incorrect event: got: %v %v expected: %v %v", ev.Op, ev.Value, OpSet, "value")
+	}
+	if ev := <-events; ev.Op != OpExpire || ev.Key != IntKey(1) {
+		t.Errorf("incorrect event: got: %v %v expected: %v %v", ev.Op, ev.Key, OpExpire, IntKey(1))
+	}
+}
+
+func TestCache_SubscribeCancel(t *testing.T) {
+	c := New(time.Hour)
+
+	events, cancel := c.Subscribe(1)
+	cancel()
+	cancel() // Safe to call twice.
+	if _, ok := <-events; ok {
+		t.Error("channel was not closed on cancel")
+	}
+
+	events, _ = c.Subscribe(0) // Unbuffered subscriber must not block writers.
+	c.Set(IntKey(1), "value", time.Hour)
+	c.Close()
+	if _, ok := <-events; ok {
+		t.Error("channel was not closed on Close")
+	}
+}
+
+func TestCache_SubscribeWith(t *testing.T) {
+	c := New(0)
+	defer c.Close()
+	newest := c.SubscribeWith(SubscribeOptions{Buffer: 2})
+	oldest := c.SubscribeWith(SubscribeOptions{Buffer: 2, Overflow: DropOldest})
+	blocking := c.SubscribeWith(SubscribeOptions{Buffer: 2, Overflow: BlockWithTimeout, Timeout: time.Millisecond})
+	disconnect := c.SubscribeWith(SubscribeOptions{Buffer: 2, Overflow: Disconnect})
+	for i := 1; i <= 4; i++ {
+		c.Set(IntKey(i), i, time.Hour)
+	}
+
+	keys := func(s *Subscription) []uint64 {
+		var keys []uint64
+		for len(s.C) > 0 {
+			keys = append(keys, (<-s.C).Key)
+		}
+		return keys
+	}
+	for _, tc := range []struct {
+		s       *Subscription
+		keys    []uint64
+		dropped uint64
+	}{
+		{newest, []uint64{IntKey(1), IntKey(2)}, 2},
+		{oldest, []uint64{IntKey(3), IntKey(4)}, 2},
+		{blocking, []uint64{IntKey(1), IntKey(2)}, 2},
+		{disconnect, []uint64{IntKey(1), IntKey(2)}, 1},
+	} {
+		policy := tc.s.sub.opts.Overflow
+		if got := keys(tc.s); len(got) != len(tc.keys) || got[0] != tc.keys[0] || got[1] != tc.keys[1] {
+			t.Errorf("incorrect %v events: got: %v expected: %v", policy, got, tc.keys)
+		}
+		if n := tc.s.Dropped(); n != tc.dropped {
+			t.Errorf("incorrect %v drops: got: %d expected: %d", policy, n, tc.dropped)
+		}
+	}
+	if !disconnect.Disconnected() {
+		t.Error("slow subscription wasn't disconnected")
+	}
+	waitUntil(t, "the disconnection", func() bool {
+		_, ok := <-disconnect.C
+		return !ok
+	})
+	if newest.Disconnected() {
+		t.Error("dropping subscription was disconnected")
+	}
+	if n := c.Stats().EventsDropped; n != 7 {
+		t.Errorf("incorrect number of dropped events: got: %d expected: %d", n, 7)
+	}
+
+}
+
+func TestCache_SubscribeWith_Block(t *testing.T) {
+	c := New(0)
+	defer c.Close()
+	s := c.SubscribeWith(SubscribeOptions{Buffer: 1, Overflow: BlockWithTimeout, Timeout: time.Hour})
+	defer s.Cancel()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c.Set(IntKey(1), 1, time.Hour)
+		c.Set(IntKey(2), 2, time.Hour)
+	}()
+	for _, want := range []uint64{IntKey(1), IntKey(2)} {
+		if ev := <-s.C; ev.Key != want {
+			t.Errorf("incorrect event key: got: %d expected: %d", ev.Key, want)
+		}
+	}
+	<-done
+	if n := s.Dropped(); n != 0 {
+		t.Errorf("incorrect number of dropped events: got: %d expected: %d", n, 0)
+	}
+}
@@ -0,0 +1,236 @@
+package ttlswisscache
+
+import "sync/atomic"
+
+// Eviction is a policy picking the records to remove from a cache bounded by
+// WithMaxEntries.
+type Eviction uint8
+
+const (
+	// NoEviction lets the cache grow without bound.
+	NoEviction Eviction = iota
+	// CLOCK approximates LRU with a reference bit per record: reads set it and
+	// the eviction hand sweeps the records, clearing set bits and evicting the
+	// first record without one. Reads cost a bit test and rarely an atomic or,
+	// far less than moving a record in a linked list.
+	CLOCK
+	// TinyLFU is W-TinyLFU: new records enter a small LRU window, then compete
+	// for the main segmented LRU with its victim on their frequency, estimated by
+	// a count-min sketch halved periodically to age old reads out. It keeps the
+	// best hit ratio of the policies on most workloads, at the cost of a lock
+	// per read of a bounded shard and about 16 bytes per record.
+	TinyLFU
+	// SampledLRU evicts the least recently used of 5 records picked at random,
+	// like Redis. Records only carry the time of their last read, so it costs
+	// less memory than an exact LRU and evicts better than a random pick.
+	SampledLRU
+	// LRUTTL is CLOCK preferring, among the unreferenced records the hand
+	// meets, the one closest to its deadline: of equally cold records it keeps
+	// those with the most time to live, which may still be read.
+	LRUTTL
+)
+
+func (e Eviction) String() string {
+	switch e {
+	case NoEviction:
+		return "none"
+	case CLOCK:
+		return "clock"
+	case TinyLFU:
+		return "tinylfu"
+	case SampledLRU:
+		return "sampled-lru"
+	case LRUTTL:
+		return "lru-ttl"
+	default:
+		return "unknown"
+	}
+}
+
+// WithEviction sets the policy evicting records once the cache holds
+// WithMaxEntries records. Evicted records are reported with OpEvict.
+func WithEviction(policy Eviction) Option {
+	return func(o *options) {
+		o.eviction = policy
+	}
+}
+
+// WithMaxEntries bounds the number of records, outdated ones included, evicting
+// records with the WithEviction policy, CLOCK by default, to store new ones.
+// The bound is split evenly over the shards, so a shard may evict before the
+// cache holds n records.
+func WithMaxEntries(n int) Option {
+	return func(o *options) {
+		o.maxEntries = n
+	}
+}
+
+// NoSlot is the slot passed to EvictionPolicy.OnAccess for a missing key.
+const NoSlot = ^uint32(0)
+
+// EvictionPolicy tracks the records of a shard by slot to pick eviction
+// victims, see WithEvictionPolicy. The records of a shard are kept densely in
+// slots 0 to n-1: removing a record moves the last one into its slot.
+// Its methods are called under the shard write lock, except OnAccess.
+type EvictionPolicy interface {
+	// OnAdd tracks the new record of key in slot i.
+	OnAdd(key uint64, i uint32)
+	// OnAccess records a read or overwrite of slot i, NoSlot for a missing key.
+	// It is called under the shard read lock, concurrently with other accesses.
+	OnAccess(key uint64, i uint32)
+	// OnRemove forgets the record of slot i, the record of slot last then moves
+	// into it.
+	OnRemove(key uint64, i, last uint32)
+	// Victim returns the slot of the record to evict, keys and deadlines
+	// (unix nanoseconds) are those of the records by slot, never empty.
+	Victim(keys []uint64, deadlines []int64) uint32
+	// Reset tracks n records in slots 0 to n-1 afresh, e.g. after Clear.
+	Reset(n uint32)
+}
+
+// WithEvictionPolicy sets the policy evicting records once the cache holds
+// WithMaxEntries records, overriding WithEviction. newPolicy is called for
+// each shard with its bound, see WithMaxEntries, and may wrap a built-in
+// policy returned by Eviction.NewPolicy, e.g. to spare business-critical keys.
+func WithEvictionPolicy(newPolicy func(maxEntries int) EvictionPolicy) Option {
+	return func(o *options) {
+		o.evictionPolicy = newPolicy
+	}
+}
+
+// NewPolicy returns the policy e for a shard of maxEntries records, nil for
+// NoEviction. The built-in policies track the records of a single shard.
+func (e Eviction) NewPolicy(maxEntries int) EvictionPolicy {
+	switch e {
+	case CLOCK:
+		return &clockEvictor{}
+	case TinyLFU:
+		return newTinyLFU(uint32(max(maxEntries, 1)))
+	case SampledLRU:
+		return &sampledLRU{}
+	case LRUTTL:
+		return &lruTTL{}
+	}
+	return nil
+}
+
+// maxShardEntries returns the bound of a record count of each of count shards,
+// see WithMaxEntries.
+func maxShardEntries(maxEntries, count int) uint32 {
+	return uint32(max((maxEntries+count-1)/count, 1))
+}
+
+// evict removes a record chosen by the evictor to make room for a new one.
+// The shard must be locked.
+func (s *shard) evict() {
+	i := s.evictor.Victim(s.keys, s.deadlines)
+	key := s.keys[i]
+	if nv, ok := s.values[i].(*namespacedValue); ok {
+		nv.state.evicted.Add(1)
+	}
+	it, _ := s.delete(key)
+	s.stats.evicted.Add(1)
+	if s.evicted != nil {
+		s.evicted(key, it)
+	}
+}
+
+// clockEvictor implements CLOCK with reference bits packed in words.
+type clockEvictor struct {
+	refs []atomic.Uint32
+	hand uint32
+}
+
+func (e *clockEvictor) OnAdd(_ uint64, i uint32) {
+	if int(i>>5) >= len(e.refs) {
+		refs := make([]atomic.Uint32, max(2*len(e.refs), int(i>>5)+1))
+		for j := range e.refs {
+			refs[j].Store(e.refs[j].Load())
+		}
+		e.refs = refs
+	}
+	// New records start unreferenced, so records never read go first.
+	e.refs[i>>5].And(^(1 << (i & 31)))
+}
+
+func (e *clockEvictor) OnAccess(_ uint64, i uint32) {
+	if i == NoSlot {
+		return
+	}
+	w, bit := &e.refs[i>>5], uint32(1)<<(i&31)
+	// Testing first spares the cache line of records read again.
+	if w.Load()&bit == 0 {
+		w.Or(bit)
+	}
+}
+
+func (e *clockEvictor) OnRemove(_ uint64, i, last uint32) {
+	if e.referenced(last) {
+		e.refs[i>>5].Or(1 << (i & 31))
+	} else {
+		e.refs[i>>5].And(^(1 << (i & 31)))
+	}
+	e.refs[last>>5].And(^(1 << (last & 31)))
+}
+
+func (e *clockEvictor) Victim(keys []uint64, _ []int64) uint32 {
+	n := uint32(len(keys))
+	for {
+		if e.hand >= n {
+			e.hand = 0
+		}
+		if !e.referenced(e.hand) {
+			// The hand stays: the last record moves into the victim's slot.
+			return e.hand
+		}
+		e.refs[e.hand>>5].And(^(1 << (e.hand & 31)))
+		e.hand++
+	}
+}
+
+func (e *clockEvictor) Reset(n uint32) {
+	for j := range e.refs {
+		e.refs[j].Store(0)
+	}
+	if n > 0 {
+		e.OnAdd(0, n-1)
+	}
+	e.hand = 0
+}
+
+func (e *clockEvictor) referenced(i uint32) bool {
+	return e.refs[i>>5].Load()&(1<<(i&31)) != 0
+}
+
+// lruTTLCandidates is the number of unreferenced records LRUTTL compares.
+const lruTTLCandidates = 5
+
+// lruTTL implements LRUTTL on the reference bits of CLOCK.
+type lruTTL struct {
+	clockEvictor
+}
+
+// victim sweeps like CLOCK until it meets lruTTLCandidates unreferenced
+// records, or has gone round once after meeting one, and returns the one with
+// the earliest deadline.
+func (e *lruTTL) Victim(keys []uint64, deadlines []int64) uint32 {
+	n := uint32(len(keys))
+	victim, found := uint32(0), 0
+	for steps := uint32(0); found < lruTTLCandidates && (found == 0 || steps < n); steps++ {
+		if e.hand >= n {
+			e.hand = 0
+		}
+		if e.referenced(e.hand) {
+			e.refs[e.hand>>5].And(^(1 << (e.hand & 31)))
+		} else {
+			if found == 0 || deadlines[e.hand] < deadlines[victim] {
+				victim = e.hand
+			}
+			found++
+		}
+		e.hand++
+	}
+	// The last record moves into the victim's slot, so the next sweep starts with it.
+	e.hand = victim
+	return victim
+}
@@ -0,0 +1,178 @@
+package ttlswisscache
+
+import (
+	"math/rand/v2"
+	"testing"
+	"time"
+)
+
+func TestWithEviction_CLOCK(t *testing.T) {
+	var evicted []uint64
+	c := New(0, WithShardCount(1), WithMaxEntries(100), WithEviction(CLOCK), WithCallbacks(Callbacks{
+		OnEvicted: func(key uint64, _ interface{}, reason Op) {
+			if reason == OpEvict {
+				evicted = append(evicted, key)
+			}
+		},
+	}))
+	defer c.Close()
+
+	for i := 0; i < 100; i++ {
+		c.Set(IntKey(i), i, time.Hour)
+	}
+	// Records read since the last sweep get a second chance.
+	for i := 0; i < 50; i++ {
+		c.Get(IntKey(i))
+	}
+	for i := 100; i < 150; i++ {
+		c.Set(IntKey(i), i, time.Hour)
+	}
+
+	if n := c.Stats().Entries; n != 100 {
+		t.Errorf("incorrect number of records: got: %d expected: %d", n, 100)
+	}
+	if len(evicted) != 50 {
+		t.Errorf("incorrect number of evictions: got: %d expected: %d", len(evicted), 50)
+	}
+	for i := 0; i < 50; i++ {
+		if _, ok := c.Get(IntKey(i)); !ok {
+			t.Errorf("referenced record was evicted: %d", i)
+		}
+	}
+	if n := c.Stats().Evicted; n != 50 {
+		t.Errorf("incorrect eviction stats: got: %d expected: %d", n, 50)
+	}
+
+	c.Set(IntKey(10), 0, time.Hour)
+	if n := c.Stats().Entries; n != 100 {
+		t.Errorf("overwrite evicted a record: got: %d expected: %d", n, 100)
+	}
+}
+
+func TestWithEviction_LRUTTL(t *testing.T) {
+	var evicted []uint64
+	c := New(0, WithShardCount(1), WithMaxEntries(10), WithEviction(LRUTTL), WithCallbacks(Callbacks{
+		OnEvicted: func(key uint64, _ interface{}, reason Op) {
+			if reason == OpEvict {
+				evicted = append(evicted, key)
+			}
+		},
+	}))
+	defer c.Close()
+
+	for i := 0; i < 10; i++ {
+		c.Set(IntKey(i), i, time.Duration(10-i)*time.Hour)
+	}
+	// Of the equally cold records met by the hand, 0 to 4, 4 expires first.
+	c.Set(IntKey(10), 10, 20*time.Hour)
+	if len(evicted) != 1 || evicted[0] != IntKey(4) {
+		t.Errorf("incorrect evicted records: got: %v expected: %v", evicted, []uint64{IntKey(4)})
+	}
+
+	for i := 1; i < 11; i++ {
+		c.Get(IntKey(i))
+	}
+	c.Set(IntKey(11), 11, time.Minute)
+	if len(evicted) != 2 || evicted[1] != IntKey(0) {
+		t.Errorf("incorrect evicted records: got: %v expected: %v", evicted, []uint64{IntKey(4), IntKey(0)})
+	}
+}
+
+func TestWithMaxEntries(t *testing.T) {
+	c := New(0, WithShardCount(4), WithMaxEntries(1000))
+	defer c.Close()
+	for i := 0; i < 10000; i++ {
+		c.Set(IntKey(i), i, time.Hour)
+	}
+	if n := c.Stats().Entries; n > 1000 || n < 900 {
+		t.Errorf("incorrect number of records: got: %d expected at most: %d", n, 1000)
+	}
+	c.Clear()
+	c.Set(IntKey(1), 1, time.Hour)
+	if _, ok := c.Get(IntKey(1)); !ok {
+		t.Error("record was evicted after Clear")
+	}
+}
+
+// lowestKey is an EvictionPolicy evicting the record of the lowest key.
+type lowestKey struct {
+	maxEntries    int
+	adds, removes int
+}
+
+func (e *lowestKey) OnAdd(uint64, uint32)            { e.adds++ }
+func (e *lowestKey) OnAccess(uint64, uint32)         {}
+func (e *lowestKey) OnRemove(uint64, uint32, uint32) { e.removes++ }
+func (e *lowestKey) Reset(uint32)                    {}
+
+func (e *lowestKey) Victim(keys []uint64, _ []int64) uint32 {
+	victim := uint32(0)
+	for i, key := range keys {
+		if key < keys[victim] {
+			victim = uint32(i)
+		}
+	}
+	return victim
+}
+
+func TestWithEvictionPolicy(t *testing.T) {
+	var policy *lowestKey
+	c := New(0, WithShardCount(1), WithMaxEntries(10), WithEviction(TinyLFU), WithEvictionPolicy(func(maxEntries int) EvictionPolicy {
+		policy = &lowestKey{maxEntries: maxEntries}
+		return policy
+	}))
+	defer c.Close()
+	if policy == nil || policy.maxEntries != 10 {
+		t.Fatalf("incorrect policy: got: %+v expected bound: %d", policy, 10)
+	}
+
+	for i := 1; i <= 20; i++ {
+		c.Set(uint64(i), i, time.Hour)
+	}
+	c.Delete(20)
+	for i := 1; i <= 20; i++ {
+		_, ok := c.Get(uint64(i))
+		if expected := i > 10 && i < 20; ok != expected {
+			t.Errorf("incorrect presence of %d: got: %v expected: %v", i, ok, expected)
+		}
+	}
+	if policy.adds != 20 || policy.removes != 11 {
+		t.Errorf("incorrect number of calls: got: %d adds %d removes expected: %d adds %d removes", policy.adds, policy.removes, 20, 11)
+	}
+}
+
+func TestEviction_NewPolicy(t *testing.T) {
+	if p := NoEviction.NewPolicy(10); p != nil {
+		t.Errorf("incorrect policy of NoEviction: got: %T expected: nil", p)
+	}
+	// A built-in policy wrapped by a custom one evicts like the built-in.
+	c := New(0, WithShardCount(1), WithMaxEntries(10), WithEvictionPolicy(func(maxEntries int) EvictionPolicy {
+		return struct{ EvictionPolicy }{LRUTTL.NewPolicy(maxEntries)}
+	}))
+	defer c.Close()
+	for i := 0; i < 10; i++ {
+		c.Set(IntKey(i), i, time.Duration(i+1)*time.Minute)
+	}
+	c.Set(IntKey(10), 10, time.Hour)
+	if _, ok := c.Get(IntKey(0)); ok {
+		t.Error("record closest to its deadline was not evicted")
+	}
+}
+
+// hitRatio replays a Zipf-distributed get-or-set workload on a cache bounded
+// to 1000 records with policy and returns its hit ratio.
+func hitRatio(policy Eviction) float64 {
+	c := New(0, WithShardCount(1), WithMaxEntries(1000), WithEviction(policy))
+	defer c.Close()
+	zipf := rand.NewZipf(rand.New(rand.NewPCG(1, 2)), 1.01, 1, 100000)
+	hits, ops := 0, 200000
+	for i := 0; i < ops; i++ {
+		key := zipf.Uint64()
+		if _, ok := c.Get(key); ok {
+			hits++
+			continue
+		}
+		c.Set(key, nil, time.Hour)
+	}
+	return float64(hits) / float64(ops)
+}
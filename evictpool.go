@@ -0,0 +1,66 @@
+package ttlswisscache
+
+import "sync/atomic"
+
+// WithAsyncEvictions calls Callbacks.OnEvicted from a pool of workers
+// goroutines instead of under the lock of the record's shard, with up to queue
+// calls waiting for them. Slow callbacks then don't stall writers and the
+// cleanup manager, and may call the cache, but calls are no longer made in the
+// order of the removals. When the queue is full calls are dropped, Stats
+// reports the queue depth and the drops. Calls still queued are dropped by
+// Close, Shutdown waits for them.
+func WithAsyncEvictions(workers, queue int) Option {
+	return func(o *options) {
+		o.evictionWorkers = workers
+		o.evictionQueue = queue
+	}
+}
+
+// evictedCall is an OnEvicted call waiting in the queue.
+type evictedCall struct {
+	key    uint64
+	value  interface{}
+	reason Op
+}
+
+// evictionPool runs the OnEvicted calls, see WithAsyncEvictions.
+type evictionPool struct {
+	fn      func(key uint64, value interface{}, reason Op)
+	queue   chan evictedCall
+	pending atomic.Int64 // Queued and running calls.
+	dropped atomic.Uint64
+}
+
+func newEvictionPool(fn func(key uint64, value interface{}, reason Op), workers, queue int, done <-chan struct{}) *evictionPool {
+	if fn == nil || workers <= 0 {
+		return nil
+	}
+	p := &evictionPool{fn: fn, queue: make(chan evictedCall, max(queue, 1))}
+	for i := 0; i < workers; i++ {
+		go p.work(done)
+	}
+	return p
+}
+
+// enqueue queues a call, or drops it if the queue is full.
+func (p *evictionPool) enqueue(key uint64, value interface{}, reason Op) {
+	p.pending.Add(1)
+	select {
+	case p.queue <- evictedCall{key: key, value: value, reason: reason}:
+	default:
+		p.pending.Add(-1)
+		p.dropped.Add(1)
+	}
+}
+
+func (p *evictionPool) work(done <-chan struct{}) {
+	for {
+		select {
+		case <-done:
+			return
+		case call := <-p.queue:
+			p.fn(call.key, call.value, call.reason)
+			p.pending.Add(-1)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package ttlswisscache
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWithAsyncEvictions(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	evicted := make(chan uint64, 10)
+	var c *Cache
+	c = New(0, WithAsyncEvictions(1, 2), WithCallbacks(Callbacks{
+		OnEvicted: func(key uint64, value interface{}, reason Op) {
+			calls.Add(1)
+			<-release
+			c.Get(key) // Callbacks may call the cache.
+			evicted <- key
+		},
+	}))
+	defer c.Close()
+	for i := 1; i <= 4; i++ {
+		c.Set(IntKey(i), i, time.Hour)
+	}
+
+	c.Delete(IntKey(1))
+	waitUntil(t, "the first call", func() bool { return calls.Load() == 1 })
+	for i := 2; i <= 4; i++ {
+		c.Delete(IntKey(i)) // Not blocked by the callback.
+	}
+	st := c.Stats()
+	if st.EvictedQueued != 2 {
+		t.Errorf("incorrect queue depth: got: %d expected: %d", st.EvictedQueued, 2)
+	}
+	if st.EvictedDropped != 1 {
+		t.Errorf("incorrect number of dropped calls: got: %d expected: %d", st.EvictedDropped, 1)
+	}
+
+	close(release)
+	for _, want := range []uint64{IntKey(1), IntKey(2), IntKey(3)} {
+		if key := <-evicted; key != want {
+			t.Errorf("incorrect evicted key: got: %d expected: %d", key, want)
+		}
+	}
+}
+
+func TestWithAsyncEvictions_Shutdown(t *testing.T) {
+	var calls atomic.Int32
+	c := New(0, WithAsyncEvictions(2, 16), WithCallbacks(Callbacks{
+		OnEvicted: func(uint64, interface{}, Op) {
+			time.Sleep(time.Millisecond)
+			calls.Add(1)
+		},
+	}))
+	for i := 0; i < 10; i++ {
+		c.Set(IntKey(i), i, time.Hour)
+		c.Delete(IntKey(i))
+	}
+	if err := c.Shutdown(context.Background(), nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if n := calls.Load(); n != 10 {
+		t.Errorf("incorrect number of calls before Shutdown returned: got: %d expected: %d", n, 10)
+	}
+}
@@ -0,0 +1,219 @@
+package ttlswisscache
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+// ExportVersion is the version of the format written by Export. Import reads
+// exports of this version and older ones.
+const ExportVersion = 1
+
+// Codec ids recorded in the header of an export, see Export.
+const (
+	CodecCustom  uint8 = 0 // A codec without a CodecID method.
+	CodecGob     uint8 = 1
+	CodecJSON    uint8 = 2
+	CodecMsgpack uint8 = 3
+)
+
+// Frame kinds of an export.
+const (
+	exportEntryFrame uint8 = 1
+	exportEndFrame   uint8 = 2
+)
+
+const (
+	exportMagic       = "TTLSWISS"
+	exportHeaderLen   = 12
+	exportFrameHeader = 1 + 4 + 4
+	exportEntryLen    = 8 + 8
+	maxExportFrame    = 1 << 30
+)
+
+// ErrInvalidExport is returned by Import for a stream that isn't a valid
+// export: wrong magic, newer version, codec id mismatch, corrupted frame or
+// record count mismatch.
+var ErrInvalidExport = errors.New("ttlswisscache: invalid export")
+
+var exportTable = crc32.MakeTable(crc32.Castagnoli)
+
+// Export writes all live records to w in a stable, versioned format readable
+// by later versions of the package and by other languages. Like Snapshot,
+// every shard is copied under its read lock and encoded after it is released.
+//
+// All integers are big endian. The stream starts with a 12 byte header:
+//
+//	magic    [8]byte "TTLSWISS"
+//	version  uint16  ExportVersion
+//	codec    uint8   codec id, see CodecGob, CodecJSON, CodecMsgpack
+//	reserved uint8   0
+//
+// followed by frames:
+//
+//	kind     uint8
+//	length   uint32  length of body
+//	checksum uint32  CRC-32C (Castagnoli) of body
+//	body     [length]byte
+//
+// An entry frame (kind 1) has a body of key uint64, deadline int64 in Unix
+// nanoseconds and the value encoded by the codec. The stream ends with an end
+// frame (kind 2) whose body is the number of entry frames as a uint64. Readers
+// skip frames of unknown kinds, so new kinds don't need a new version.
+//
+// A codec other than the built-in ones may report its id with a CodecID()
+// uint8 method, ids from 128 up are free for applications.
+func (c *Cache) Export(w io.Writer, codec Codec) error {
+	if c.closed.Load() {
+		return ErrClosed
+	}
+	bw := c.bufs.getWriter(w)
+	defer c.bufs.putWriter(bw)
+	buf := c.bufs.getEntries()
+	defer c.bufs.putEntries(buf)
+
+	var header [exportHeaderLen]byte
+	copy(header[:], exportMagic)
+	binary.BigEndian.PutUint16(header[8:], ExportVersion)
+	header[10] = exportCodecID(codec)
+	if _, err := bw.Write(header[:]); err != nil {
+		return err
+	}
+	var (
+		body  []byte
+		count uint64
+	)
+	entries := *buf
+	for _, s := range c.shards.list {
+		entries = s.appendLive(entries[:0], c.clock.unixNano())
+		*buf = entries
+
+		for i := range entries {
+			value, ok := entries[i].item.load()
+			if !ok {
+				continue
+			}
+			data, err := codec.Marshal(value)
+			if err != nil {
+				return fmt.Errorf("ttlswisscache: encode key %d: %w", entries[i].key, err)
+			}
+			body = binary.BigEndian.AppendUint64(body[:0], entries[i].key)
+			body = binary.BigEndian.AppendUint64(body, uint64(entries[i].item.deadline))
+			body = append(body, data...)
+			if err := writeExportFrame(bw, exportEntryFrame, body); err != nil {
+				return err
+			}
+			count++
+		}
+		clearEntries(entries)
+	}
+	if err := writeExportFrame(bw, exportEndFrame, binary.BigEndian.AppendUint64(body[:0], count)); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// Import reads records written by Export with the same codec and stores them
+// with their original deadlines. Records that have expired in the meantime
+// are skipped. Each frame is checked before its record is stored, so a
+// corrupted or truncated stream returns an error after storing the records
+// preceding it. It returns the number of imported records.
+func (c *Cache) Import(r io.Reader, codec Codec) (int, error) {
+	if c.closing.Load() {
+		return 0, ErrClosed
+	}
+	br := c.bufs.getReader(r)
+	defer c.bufs.putReader(br)
+
+	var header [exportHeaderLen]byte
+	if _, err := io.ReadFull(br, header[:]); err != nil {
+		return 0, unexpectedEOF(err)
+	}
+	if string(header[:8]) != exportMagic {
+		return 0, fmt.Errorf("%w: bad magic", ErrInvalidExport)
+	}
+	if version := binary.BigEndian.Uint16(header[8:]); version == 0 || version > ExportVersion {
+		return 0, fmt.Errorf("%w: unsupported version %d", ErrInvalidExport, version)
+	}
+	if id := exportCodecID(codec); header[10] != id {
+		return 0, fmt.Errorf("%w: codec id %d, expected %d", ErrInvalidExport, header[10], id)
+	}
+
+	var (
+		frame [exportFrameHeader]byte
+		count uint64 // Entry frames read.
+		n     int
+	)
+	for {
+		if _, err := io.ReadFull(br, frame[:]); err != nil {
+			return n, unexpectedEOF(err)
+		}
+		size := binary.BigEndian.Uint32(frame[1:])
+		if size > maxExportFrame {
+			return n, fmt.Errorf("%w: frame of %d bytes", ErrInvalidExport, size)
+		}
+		// Each frame gets its own buffer, codecs may return values aliasing it.
+		body := make([]byte, size)
+		if _, err := io.ReadFull(br, body); err != nil {
+			return n, unexpectedEOF(err)
+		}
+		if crc32.Checksum(body, exportTable) != binary.BigEndian.Uint32(frame[5:]) {
+			return n, fmt.Errorf("%w: checksum mismatch after %d records", ErrInvalidExport, count)
+		}
+
+		switch frame[0] {
+		case exportEntryFrame:
+			if size < exportEntryLen {
+				return n, fmt.Errorf("%w: entry frame of %d bytes", ErrInvalidExport, size)
+			}
+			count++
+			key := binary.BigEndian.Uint64(body[0:])
+			deadline := int64(binary.BigEndian.Uint64(body[8:]))
+			if deadline < c.clock.unixNano() {
+				continue
+			}
+			value, err := codec.Unmarshal(body[exportEntryLen:])
+			if err != nil {
+				return n, fmt.Errorf("ttlswisscache: decode key %d: %w", key, err)
+			}
+			c.store(key, item{deadline: deadline, value: value})
+			n++
+		case exportEndFrame:
+			if size != 8 || binary.BigEndian.Uint64(body) != count {
+				return n, fmt.Errorf("%w: record count mismatch", ErrInvalidExport)
+			}
+			return n, nil
+		}
+	}
+}
+
+// writeExportFrame writes a frame of kind with body.
+func writeExportFrame(w io.Writer, kind uint8, body []byte) error {
+	var frame [exportFrameHeader]byte
+	frame[0] = kind
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(body)))
+	binary.BigEndian.PutUint32(frame[5:], crc32.Checksum(body, exportTable))
+	if _, err := w.Write(frame[:]); err != nil {
+		return err
+	}
+	_, err := w.Write(body)
+	return err
+}
+
+// exportCodecID returns the id of codec recorded in an export.
+func exportCodecID(codec Codec) uint8 {
+	switch codec := codec.(type) {
+	case GobCodec, *GobCodec:
+		return CodecGob
+	case JSONCodec, *JSONCodec:
+		return CodecJSON
+	case MsgpackCodec, *MsgpackCodec:
+		return CodecMsgpack
+	case interface{ CodecID() uint8 }:
+		return codec.CodecID()
+	}
+	return CodecCustom
+}
@@ -0,0 +1,107 @@
+package ttlswisscache
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestCache_ExportImport(t *testing.T) {
+	src := New(0)
+	defer src.Close()
+	for i := 0; i < 1000; i++ {
+		src.Set(IntKey(i), "value", time.Hour)
+	}
+	src.Set(StringKey("expired"), "value", -time.Second)
+
+	var buf bytes.Buffer
+	if err := src.Export(&buf, MsgpackCodec{}); err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+	if !bytes.HasPrefix(buf.Bytes(), []byte("TTLSWISS\x00\x01\x03\x00")) {
+		t.Errorf("incorrect header: got: %q", buf.Bytes()[:exportHeaderLen])
+	}
+	export := buf.Bytes()
+
+	dst := New(0)
+	defer dst.Close()
+	n, err := dst.Import(bytes.NewReader(export), MsgpackCodec{})
+	if err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+	if n != 1000 {
+		t.Errorf("incorrect number of imported records: got: %d expected: %d", n, 1000)
+	}
+	if v, ok := dst.Get(IntKey(999)); !ok || v != "value" {
+		t.Errorf("incorrect imported value: got: %v, %v expected: %v", v, ok, "value")
+	}
+	if ttl, _ := dst.TTL(IntKey(1)); ttl < 59*time.Minute {
+		t.Errorf("incorrect imported ttl: got: %v expected about: %v", ttl, time.Hour)
+	}
+
+	other := New(0)
+	defer other.Close()
+	if _, err := other.Import(bytes.NewReader(export), JSONCodec{}); !errors.Is(err, ErrInvalidExport) {
+		t.Errorf("incorrect error of a codec mismatch: got: %v expected: %v", err, ErrInvalidExport)
+	}
+	corrupted := bytes.Clone(export)
+	corrupted[exportHeaderLen+exportFrameHeader+3] ^= 1
+	if _, err := other.Import(bytes.NewReader(corrupted), MsgpackCodec{}); !errors.Is(err, ErrInvalidExport) {
+		t.Errorf("incorrect error of a corrupted export: got: %v expected: %v", err, ErrInvalidExport)
+	}
+	if _, err := other.Import(bytes.NewReader(export[:len(export)-20]), MsgpackCodec{}); err != io.ErrUnexpectedEOF {
+		t.Errorf("incorrect error of a truncated export: got: %v expected: %v", err, io.ErrUnexpectedEOF)
+	}
+	newer := bytes.Clone(export)
+	binary.BigEndian.PutUint16(newer[8:], ExportVersion+1)
+	if _, err := other.Import(bytes.NewReader(newer), MsgpackCodec{}); !errors.Is(err, ErrInvalidExport) {
+		t.Errorf("incorrect error of a newer version: got: %v expected: %v", err, ErrInvalidExport)
+	}
+}
+
+func TestCache_ImportMsgpackBytes(t *testing.T) {
+	src := New(0)
+	defer src.Close()
+	values := []string{"bbbb", "aaaa", "cccc"}
+	for i, v := range values {
+		src.Set(IntKey(i), []byte(v), time.Hour)
+	}
+
+	var buf bytes.Buffer
+	if err := src.Export(&buf, MsgpackCodec{}); err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+	dst := New(0)
+	defer dst.Close()
+	if _, err := dst.Import(&buf, MsgpackCodec{}); err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+	for i, v := range values {
+		got, _ := dst.Get(IntKey(i))
+		if b, _ := got.([]byte); string(b) != v {
+			t.Errorf("incorrect value of key %d: got: %v expected: %v", i, got, v)
+		}
+	}
+}
+
+func TestCache_Import_UnknownFrame(t *testing.T) {
+	var buf bytes.Buffer
+	buf.WriteString("TTLSWISS\x00\x01\x02\x00")
+	writeExportFrame(&buf, 42, []byte("from a later version"))
+	body := binary.BigEndian.AppendUint64(nil, 7)
+	body = binary.BigEndian.AppendUint64(body, uint64(time.Now().Add(time.Hour).UnixNano()))
+	writeExportFrame(&buf, exportEntryFrame, append(body, `"seven"`...))
+	writeExportFrame(&buf, exportEndFrame, binary.BigEndian.AppendUint64(nil, 1))
+
+	c := New(0)
+	defer c.Close()
+	if n, err := c.Import(&buf, JSONCodec{}); err != nil || n != 1 {
+		t.Errorf("incorrect import: got: %d, %v expected: %d", n, err, 1)
+	}
+	if v, _ := c.Get(7); v != "seven" {
+		t.Errorf("incorrect imported value: got: %v expected: %v", v, "seven")
+	}
+}
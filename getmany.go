@@ -0,0 +1,135 @@
+package ttlswisscache
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// minParallelGetMany is the number of keys from which GetMany fans out to shards.
+const minParallelGetMany = 1024
+
+// GetMany returns stored values of the keys.
+// Keys without a record are missing from the result.
+// Large batches are grouped by shard, so every shard is locked once, and the
+// shards are read by up to GOMAXPROCS goroutines.
+//
+// With WithBatchLoader the missing keys are loaded in a single call, see
+// GetManyCtx; keys the loader fails to load are missing from the result.
+func (c *Cache) GetMany(keys []uint64) map[uint64]interface{} {
+	values, _ := c.GetManyCtx(context.Background(), keys)
+	return values
+}
+
+// getMany returns stored values of the keys, see GetMany.
+func (c *Cache) getMany(keys []uint64) map[uint64]interface{} {
+	if len(keys) >= minParallelGetMany {
+		return c.getGrouped(keys, true)
+	}
+	if c.coalescer != nil {
+		return c.coalescer.get(keys)
+	}
+	values := make(map[uint64]interface{}, len(keys))
+	for _, key := range keys {
+		if value, ok := c.Get(key); ok {
+			values[key] = value
+		}
+	}
+	return values
+}
+
+// getGrouped returns stored values of the keys grouped by shard, cloned if
+// clone is true. Otherwise consumed SetOnce values are returned as onceValue,
+// for a single caller of the batch, see coalescer.get. Batches of
+// minParallelGetMany keys or more are read in parallel.
+func (c *Cache) getGrouped(keys []uint64, clone bool) map[uint64]interface{} {
+	values := make(map[uint64]interface{}, len(keys))
+	list := c.shards.list
+	sorted, offsets := c.groupByShard(keys)
+
+	var (
+		mu    sync.Mutex // Guards values.
+		shard atomic.Int32
+		wg    sync.WaitGroup
+	)
+	read := func() {
+		defer wg.Done()
+		var hits, read []snapshotEntry
+		for {
+			i := int(shard.Add(1) - 1)
+			if i >= len(list) {
+				return
+			}
+			lo, hi := offsets[i], offsets[i+1]
+			if lo == hi {
+				continue
+			}
+			s := list[i]
+			hits, read = hits[:0], read[:0]
+			s.RLock()
+			for _, key := range sorted[lo:hi] {
+				if it, ok := s.get(key); ok {
+					read = append(read, snapshotEntry{key: key, item: it})
+				}
+			}
+			s.RUnlock()
+			s.stats.misses.Add(uint64(hi - lo - len(read)))
+			// Records are read like Get once the shard is unlocked, as SetOnce
+			// and SetWithIdle records lock it again.
+			for _, e := range read {
+				value, ok, stored := c.hit(s, e.key, e.item, true)
+				switch {
+				case !ok:
+					continue
+				case stored && clone:
+					value = c.clone(value)
+				case !stored && !clone:
+					value = &onceValue{value: value}
+				}
+				hits = append(hits, snapshotEntry{key: e.key, item: item{value: value}})
+			}
+			clearEntries(read)
+
+			mu.Lock()
+			for _, e := range hits {
+				values[e.key] = e.item.value
+			}
+			mu.Unlock()
+			clearEntries(hits)
+		}
+	}
+	workers := 1
+	if len(keys) >= minParallelGetMany {
+		workers = min(runtime.GOMAXPROCS(0), len(list))
+	}
+	wg.Add(workers)
+	for w := 1; w < workers; w++ {
+		go read()
+	}
+	read()
+	wg.Wait()
+	return values
+}
+
+// groupByShard groups the keys by shard with a counting sort,
+// the keys of shard i are sorted[offsets[i]:offsets[i+1]].
+func (c *Cache) groupByShard(keys []uint64) (sorted []uint64, offsets []int) {
+	offsets = make([]int, len(c.shards.list)+1)
+	shardOf := make([]uint32, len(keys))
+	for i, key := range keys {
+		n := uint32(c.shards.hash(key) >> c.shards.shift)
+		shardOf[i] = n
+		offsets[n+1]++
+	}
+	for i := 1; i < len(offsets); i++ {
+		offsets[i] += offsets[i-1]
+	}
+	next := append([]int(nil), offsets[:len(c.shards.list)]...)
+	sorted = make([]uint64, len(keys))
+	for i, key := range keys {
+		sorted[next[shardOf[i]]] = key
+		next[shardOf[i]]++
+	}
+	return sorted, offsets
+}
@@ -0,0 +1,34 @@
+package ttlswisscache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCache_GetMany_Parallel(t *testing.T) {
+	c := New(0)
+	defer c.Close()
+
+	const n = 4 * minParallelGetMany
+	keys := make([]uint64, 0, 2*n)
+	for i := 0; i < n; i++ {
+		if i%2 == 0 {
+			c.Set(IntKey(i), i, time.Hour)
+		}
+		keys = append(keys, IntKey(i))
+	}
+	keys = append(keys, keys...) // Duplicates.
+
+	values := c.GetMany(keys)
+	if len(values) != n/2 {
+		t.Errorf("incorrect number of values: got: %d expected: %d", len(values), n/2)
+	}
+	for i := 0; i < n; i += 2 {
+		if v := values[IntKey(i)]; v != i {
+			t.Errorf("incorrect value of key %d: got: %v expected: %v", i, v, i)
+		}
+	}
+	if st := c.Stats(); st.Hits != n || st.Misses != n {
+		t.Errorf("incorrect hits and misses: got: %d, %d expected: %d, %d", st.Hits, st.Misses, n, n)
+	}
+}
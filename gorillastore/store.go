@@ -0,0 +1,134 @@
+// Package gorillastore implements the gorilla/sessions Store interface on top of a Cache.
+//
+// Session values stay in the process, the cookie only carries the signed session ID.
+// Every request that loads a session restarts its idle timeout (sliding expiration),
+// so inactive sessions disappear from the cache on their own.
+package gorillastore
+
+import (
+	"encoding/base32"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/securecookie"
+	"github.com/gorilla/sessions"
+
+	ttlcache "github.com/loicalleyne/ttlswisscache"
+)
+
+// ErrNotFound is returned by New when the cookie refers to an expired or unknown session.
+// The returned session is usable as a new one.
+var ErrNotFound = errors.New("gorillastore: session not found")
+
+var base32RawStdEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// Store keeps sessions in a Cache.
+type Store struct {
+	Codecs  []securecookie.Codec
+	Options *sessions.Options // default configuration
+
+	cache *ttlcache.Cache
+	idle  time.Duration
+}
+
+// New creates a store keeping sessions for idle time since their last use.
+// keyPairs sign (and optionally encrypt) the session ID cookie, see securecookie.CodecsFromPairs.
+func New(cache *ttlcache.Cache, idle time.Duration, keyPairs ...[]byte) *Store {
+	s := &Store{
+		Codecs: securecookie.CodecsFromPairs(keyPairs...),
+		Options: &sessions.Options{
+			Path:   "/",
+			MaxAge: 86400 * 30,
+		},
+		cache: cache,
+		idle:  idle,
+	}
+	s.MaxAge(s.Options.MaxAge)
+	return s
+}
+
+// Get returns a session for the given name after adding it to the registry.
+func (s *Store) Get(r *http.Request, name string) (*sessions.Session, error) {
+	return sessions.GetRegistry(r).Get(s, name)
+}
+
+// New returns a session for the given name without adding it to the registry.
+// Loading an existing session extends its idle timeout.
+func (s *Store) New(r *http.Request, name string) (*sessions.Session, error) {
+	session := sessions.NewSession(s, name)
+	opts := *s.Options
+	session.Options = &opts
+	session.IsNew = true
+
+	c, err := r.Cookie(name)
+	if err != nil {
+		return session, nil
+	}
+	var id string
+	if err := securecookie.DecodeMulti(name, c.Value, &id, s.Codecs...); err != nil {
+		return session, err
+	}
+	k := key(name, id)
+	// Outdated sessions waiting for the cleanup manager must not be revived by Expire.
+	if ttl, ok := s.cache.TTL(k); !ok || ttl == 0 {
+		return session, ErrNotFound
+	}
+	v, _ := s.cache.Get(k)
+	values, ok := v.(map[interface{}]interface{})
+	if !ok {
+		return session, ErrNotFound
+	}
+	s.cache.Expire(k, s.idle)
+
+	session.ID = id
+	session.Values = copyValues(values)
+	session.IsNew = false
+	return session, nil
+}
+
+// Save stores the session and writes the session ID cookie.
+// A session with Options.MaxAge <= 0 is removed from the cache and its cookie is deleted.
+func (s *Store) Save(r *http.Request, w http.ResponseWriter, session *sessions.Session) error {
+	if session.Options.MaxAge <= 0 {
+		if session.ID != "" {
+			s.cache.Delete(key(session.Name(), session.ID))
+		}
+		http.SetCookie(w, sessions.NewCookie(session.Name(), "", session.Options))
+		return nil
+	}
+
+	if session.ID == "" {
+		session.ID = base32RawStdEncoding.EncodeToString(securecookie.GenerateRandomKey(32))
+	}
+	encoded, err := securecookie.EncodeMulti(session.Name(), session.ID, s.Codecs...)
+	if err != nil {
+		return err
+	}
+	// The handler may keep modifying session.Values after Save.
+	s.cache.Set(key(session.Name(), session.ID), copyValues(session.Values), s.idle)
+	http.SetCookie(w, sessions.NewCookie(session.Name(), encoded, session.Options))
+	return nil
+}
+
+// MaxAge sets the maximum age for the store and the underlying cookie implementation.
+func (s *Store) MaxAge(age int) {
+	s.Options.MaxAge = age
+	for _, codec := range s.Codecs {
+		if sc, ok := codec.(*securecookie.SecureCookie); ok {
+			sc.MaxAge(age)
+		}
+	}
+}
+
+func key(name, id string) uint64 {
+	return ttlcache.StringKey("gorillastore\x00" + name + "\x00" + id)
+}
+
+func copyValues(values map[interface{}]interface{}) map[interface{}]interface{} {
+	c := make(map[interface{}]interface{}, len(values))
+	for k, v := range values {
+		c[k] = v
+	}
+	return c
+}
@@ -0,0 +1,103 @@
+package gorillastore
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	ttlcache "github.com/loicalleyne/ttlswisscache"
+	"github.com/loicalleyne/ttlswisscache/ttltest"
+)
+
+func TestStore(t *testing.T) {
+	cache := ttlcache.New(time.Hour)
+	defer cache.Close()
+	store := New(cache, time.Minute, []byte("0123456789abcdef0123456789abcdef"))
+
+	// First request creates the session.
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+	session, err := store.Get(req, "app")
+	if err != nil || !session.IsNew {
+		t.Fatalf("expected new session: %v", err)
+	}
+	session.Values["user"] = "alice"
+	if err := session.Save(req, rec); err != nil {
+		t.Fatal(err)
+	}
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("incorrect number of cookies: got: %d expected: %d", len(cookies), 1)
+	}
+
+	// Second request loads it back.
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(cookies[0])
+	session, err = store.Get(req, "app")
+	if err != nil || session.IsNew {
+		t.Fatalf("expected existing session: %v", err)
+	}
+	if session.Values["user"] != "alice" {
+		t.Errorf("incorrect value: got: %v expected: %v", session.Values["user"], "alice")
+	}
+
+	// Deleting the session removes it from the cache.
+	session.Options.MaxAge = -1
+	if err := session.Save(req, httptest.NewRecorder()); err != nil {
+		t.Fatal(err)
+	}
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(cookies[0])
+	session, err = store.New(req, "app")
+	if !errors.Is(err, ErrNotFound) || !session.IsNew {
+		t.Errorf("deleted session was loaded: %v", err)
+	}
+}
+
+func TestStore_SlidingExpiration(t *testing.T) {
+	cache := ttlcache.New(time.Hour)
+	defer cache.Close()
+	store := New(cache, time.Minute, []byte("0123456789abcdef0123456789abcdef"))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+	session, _ := store.New(req, "app")
+	session.Save(req, rec)
+	k := key("app", session.ID)
+
+	cache.Expire(k, time.Second)
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(rec.Result().Cookies()[0])
+	if _, err := store.New(req, "app"); err != nil {
+		t.Fatal(err)
+	}
+	if ttl, _ := cache.TTL(k); ttl <= time.Second {
+		t.Errorf("idle timeout was not extended: got: %v", ttl)
+	}
+}
+
+func TestStore_IdleTimeout(t *testing.T) {
+	clock := ttltest.NewClock()
+	cache := ttlcache.New(time.Hour, ttlcache.WithClock(clock))
+	defer cache.Close()
+	store := New(cache, 50*time.Millisecond, []byte("0123456789abcdef0123456789abcdef"))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+	session, _ := store.New(req, "app")
+	session.Save(req, rec)
+	k := key("app", session.ID)
+
+	// The outdated session waits for the cleanup manager.
+	clock.Advance(time.Second)
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(rec.Result().Cookies()[0])
+	if session, err := store.New(req, "app"); !errors.Is(err, ErrNotFound) || !session.IsNew {
+		t.Errorf("idle session was loaded: %v", err)
+	}
+	if ttl, _ := cache.TTL(k); ttl != 0 {
+		t.Errorf("idle session was revived: got: %v expected: %v", ttl, 0)
+	}
+}
@@ -0,0 +1,71 @@
+// Package gossip keeps per-process caches coherent by broadcasting invalidations.
+//
+// Writes made through an Invalidator are applied locally and announced to the peers,
+// which drop their copy of the key. There is no central broker: delivery is up to
+// the Transport, e.g. the memberlist based one in the memberlistgossip subpackage.
+// Delivery is best effort, peers may serve a stale value until the message arrives
+// or the record expires.
+package gossip
+
+import (
+	"encoding/binary"
+	"time"
+
+	ttlcache "github.com/loicalleyne/ttlswisscache"
+)
+
+const (
+	msgVersion = 1
+	msgLen     = 1 + 8
+)
+
+// Transport delivers messages between peer processes.
+type Transport interface {
+	// Broadcast queues the message for delivery to all peers.
+	Broadcast(msg []byte)
+	// SetHandler registers the function receiving messages broadcast by peers.
+	SetHandler(fn func(msg []byte))
+}
+
+// Invalidator writes to the local cache and invalidates the key on peers.
+type Invalidator struct {
+	cache     *ttlcache.Cache
+	transport Transport
+}
+
+// New creates an invalidator and starts handling messages from peers.
+func New(cache *ttlcache.Cache, transport Transport) *Invalidator {
+	inv := &Invalidator{cache: cache, transport: transport}
+	transport.SetHandler(inv.handle)
+	return inv
+}
+
+// Set stores the value locally and invalidates the key on peers.
+func (inv *Invalidator) Set(key uint64, value interface{}, ttl time.Duration) {
+	inv.cache.Set(key, value, ttl)
+	inv.Invalidate(key)
+}
+
+// Delete removes the record locally and on peers.
+func (inv *Invalidator) Delete(key uint64) {
+	inv.cache.Delete(key)
+	inv.Invalidate(key)
+}
+
+// Invalidate removes the key on peers only.
+// Use it after changing the cache directly.
+func (inv *Invalidator) Invalidate(key uint64) {
+	msg := make([]byte, msgLen)
+	msg[0] = msgVersion
+	binary.BigEndian.PutUint64(msg[1:], key)
+	inv.transport.Broadcast(msg)
+}
+
+// handle applies an invalidation received from a peer.
+// Unknown messages are ignored so peers can be upgraded one by one.
+func (inv *Invalidator) handle(msg []byte) {
+	if len(msg) != msgLen || msg[0] != msgVersion {
+		return
+	}
+	inv.cache.Delete(binary.BigEndian.Uint64(msg[1:]))
+}
@@ -0,0 +1,81 @@
+package gossip
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	ttlcache "github.com/loicalleyne/ttlswisscache"
+)
+
+// bus delivers broadcasts synchronously to every other transport.
+type bus struct {
+	mu    sync.Mutex
+	nodes []*busTransport
+}
+
+type busTransport struct {
+	bus     *bus
+	handler func([]byte)
+}
+
+func (b *bus) join() *busTransport {
+	t := &busTransport{bus: b}
+	b.mu.Lock()
+	b.nodes = append(b.nodes, t)
+	b.mu.Unlock()
+	return t
+}
+
+func (t *busTransport) Broadcast(msg []byte) {
+	t.bus.mu.Lock()
+	defer t.bus.mu.Unlock()
+	for _, n := range t.bus.nodes {
+		if n != t {
+			n.handler(msg)
+		}
+	}
+}
+
+func (t *busTransport) SetHandler(fn func([]byte)) {
+	t.handler = fn
+}
+
+func TestInvalidator(t *testing.T) {
+	var b bus
+	c1, c2 := ttlcache.New(time.Hour), ttlcache.New(time.Hour)
+	defer c1.Close()
+	defer c2.Close()
+	inv1, inv2 := New(c1, b.join()), New(c2, b.join())
+
+	c2.Set(1, "stale", time.Hour)
+	inv1.Set(1, "fresh", time.Hour)
+	if _, ok := c2.Get(1); ok {
+		t.Error("peer kept stale value after Set")
+	}
+	if v, _ := c1.Get(1); v != "fresh" {
+		t.Errorf("incorrect value: got: %v expected: %v", v, "fresh")
+	}
+
+	c2.Set(1, "fresh", time.Hour)
+	inv2.Delete(1)
+	if _, ok := c1.Get(1); ok {
+		t.Error("peer kept value after Delete")
+	}
+	if _, ok := c2.Get(1); ok {
+		t.Error("record was not deleted locally")
+	}
+}
+
+func TestInvalidator_UnknownMessage(t *testing.T) {
+	var b bus
+	c := ttlcache.New(time.Hour)
+	defer c.Close()
+	New(c, b.join())
+	c.Set(1, "value", time.Hour)
+
+	b.join().Broadcast([]byte{99, 0, 0, 0, 0, 0, 0, 0, 1})
+	if _, ok := c.Get(1); !ok {
+		t.Error("unknown message invalidated the key")
+	}
+}
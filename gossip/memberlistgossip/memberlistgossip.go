@@ -0,0 +1,111 @@
+// Package memberlistgossip implements gossip.Transport on top of hashicorp/memberlist.
+package memberlistgossip
+
+import (
+	"sync"
+
+	"github.com/hashicorp/memberlist"
+)
+
+// Transport broadcasts messages to the cluster members.
+// Messages are piggybacked on memberlist gossip and retransmitted
+// a number of times scaled by the cluster size.
+type Transport struct {
+	list  *memberlist.Memberlist
+	queue *memberlist.TransmitLimitedQueue
+
+	mu      sync.RWMutex
+	handler func([]byte)
+}
+
+// New creates a memberlist node with the configuration and joins the existing members.
+// The Delegate of conf is replaced by the transport.
+func New(conf *memberlist.Config, existing ...string) (*Transport, error) {
+	t := &Transport{}
+	conf.Delegate = (*delegate)(t)
+	list, err := memberlist.Create(conf)
+	if err != nil {
+		return nil, err
+	}
+	t.list = list
+	t.queue = &memberlist.TransmitLimitedQueue{
+		NumNodes:       list.NumMembers,
+		RetransmitMult: conf.RetransmitMult,
+	}
+	if len(existing) > 0 {
+		if _, err := list.Join(existing); err != nil {
+			list.Shutdown()
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// Memberlist returns the underlying memberlist, e.g. to inspect members.
+func (t *Transport) Memberlist() *memberlist.Memberlist {
+	return t.list
+}
+
+// Broadcast implements gossip.Transport.
+func (t *Transport) Broadcast(msg []byte) {
+	t.queue.QueueBroadcast(broadcast(msg))
+}
+
+// SetHandler implements gossip.Transport.
+func (t *Transport) SetHandler(fn func([]byte)) {
+	t.mu.Lock()
+	t.handler = fn
+	t.mu.Unlock()
+}
+
+// Shutdown leaves the cluster and stops the node.
+func (t *Transport) Shutdown() error {
+	if err := t.list.Leave(0); err != nil {
+		return err
+	}
+	return t.list.Shutdown()
+}
+
+// delegate implements memberlist.Delegate.
+type delegate Transport
+
+func (d *delegate) NodeMeta(limit int) []byte {
+	return nil
+}
+
+func (d *delegate) NotifyMsg(msg []byte) {
+	d.mu.RLock()
+	fn := d.handler
+	d.mu.RUnlock()
+	if fn != nil {
+		// memberlist reuses the buffer after NotifyMsg returns.
+		fn(append([]byte(nil), msg...))
+	}
+}
+
+func (d *delegate) GetBroadcasts(overhead, limit int) [][]byte {
+	if d.queue == nil {
+		return nil
+	}
+	return d.queue.GetBroadcasts(overhead, limit)
+}
+
+func (d *delegate) LocalState(join bool) []byte {
+	return nil
+}
+
+func (d *delegate) MergeRemoteState(buf []byte, join bool) {}
+
+// broadcast implements memberlist.Broadcast.
+// Invalidations never supersede each other, every one is delivered.
+type broadcast []byte
+
+func (b broadcast) Invalidates(memberlist.Broadcast) bool {
+	return false
+}
+
+func (b broadcast) Message() []byte {
+	return b
+}
+
+func (b broadcast) Finished() {}
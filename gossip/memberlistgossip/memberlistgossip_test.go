@@ -0,0 +1,57 @@
+package memberlistgossip
+
+import (
+	"io"
+	"log"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/memberlist"
+
+	ttlcache "github.com/loicalleyne/ttlswisscache"
+	"github.com/loicalleyne/ttlswisscache/gossip"
+)
+
+func newNode(t *testing.T, name string, existing ...string) *Transport {
+	t.Helper()
+	conf := memberlist.DefaultLocalConfig()
+	conf.Name = name
+	conf.BindAddr = "127.0.0.1"
+	conf.BindPort = 0
+	conf.GossipInterval = 10 * time.Millisecond
+	conf.Logger = log.New(io.Discard, "", 0)
+
+	tr, err := New(conf, existing...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { tr.Shutdown() })
+	return tr
+}
+
+func TestTransport(t *testing.T) {
+	t1 := newNode(t, "node1")
+	addr := t1.Memberlist().LocalNode().Address()
+	t2 := newNode(t, "node2", addr)
+
+	c1, c2 := ttlcache.New(time.Hour), ttlcache.New(time.Hour)
+	defer c1.Close()
+	defer c2.Close()
+	inv1 := gossip.New(c1, t1)
+	gossip.New(c2, t2)
+
+	for i := 0; i < 10; i++ {
+		c2.Set(ttlcache.IntKey(i), strconv.Itoa(i), time.Hour)
+		inv1.Delete(ttlcache.IntKey(i))
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if c2.Stats().Entries == 0 {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Errorf("invalidations were not delivered: %d records left", c2.Stats().Entries)
+}
@@ -0,0 +1,66 @@
+package ttlswisscache
+
+import "time"
+
+// WithGracePeriod keeps outdated records in quarantine for grace before the
+// cleanup manager removes them. Quarantined records are invisible to Get and
+// the other reads, but GetStale still returns them, e.g. to serve stale data
+// while a refresh is running. Stats.Entries counts them.
+func WithGracePeriod(grace time.Duration) Option {
+	return func(o *options) {
+		o.grace = grace
+	}
+}
+
+// quarantined reports whether the record is outdated and only kept for the
+// grace period, see WithGracePeriod.
+func (c *Cache) quarantined(it item) bool {
+	return c.opts.grace > 0 && it.deadline < c.clock.unixNano()
+}
+
+// GetStale returns the stored value of key even if it is outdated, as long as
+// the cleanup manager hasn't removed it yet, for callers preferring stale data
+// over none. age is the time elapsed since the deadline, zero for live records.
+// WithGracePeriod keeps outdated records around for it.
+func (c *Cache) GetStale(key uint64) (value interface{}, age time.Duration, ok bool) {
+	s := c.shards.get(key)
+	s.RLock()
+	it, ok := s.get(key)
+	s.RUnlock()
+	if _, once := it.value.(*onceValue); ok && once {
+		value, found, stored := c.consume(s, key)
+		if !found {
+			return nil, 0, false
+		}
+		if stored {
+			value = c.clone(value)
+		}
+		if now := c.clock.unixNano(); it.deadline < now {
+			age = time.Duration(now - it.deadline)
+		}
+		return value, age, true
+	}
+	if ok {
+		value, ok = it.load()
+	}
+	if !ok {
+		s.stats.misses.Add(1)
+		return nil, 0, false
+	}
+	age = c.staleness(s, it)
+	if age > 0 && c.refresher != nil {
+		c.refresher.schedule(key)
+	}
+	return c.clone(value), age, true
+}
+
+// staleness counts a GetStale hit of the record and returns its age.
+func (c *Cache) staleness(s *shard, it item) time.Duration {
+	s.stats.hits.Add(1)
+	now := c.clock.unixNano()
+	if it.deadline >= now {
+		return 0
+	}
+	s.stats.staleHits.Add(1)
+	return time.Duration(now - it.deadline)
+}
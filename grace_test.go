@@ -0,0 +1,77 @@
+package ttlswisscache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWithGracePeriod(t *testing.T) {
+	clock := &fixedClock{now: time.Unix(1000, 0)}
+	c := New(0, WithClock(clock), WithGracePeriod(time.Minute))
+	defer c.Close()
+
+	c.Set(IntKey(1), "a", time.Second)
+	if _, age, ok := c.GetStale(IntKey(1)); !ok || age != 0 {
+		t.Errorf("incorrect stale read of a live record: got: %v, %v expected: %v, %v", age, ok, 0, true)
+	}
+
+	clock.now = clock.now.Add(11 * time.Second)
+	if _, ok := c.Get(IntKey(1)); ok {
+		t.Error("quarantined record is visible to Get")
+	}
+	if _, ok := c.GetAndDelete(IntKey(1)); ok {
+		t.Error("quarantined record is visible to GetAndDelete")
+	}
+	if got := c.GetMany([]uint64{IntKey(1)}); len(got) != 0 {
+		t.Errorf("quarantined record is visible to GetMany: got: %v", got)
+	}
+	value, age, ok := c.GetStale(IntKey(1))
+	if !ok || value != "a" || age != 10*time.Second {
+		t.Errorf("incorrect stale read: got: %v, %v, %v expected: %v, %v, %v", value, age, ok, "a", 10*time.Second, true)
+	}
+	if n := c.DeleteExpired(); n != 0 {
+		t.Errorf("record was removed during the grace period: got: %d expected: %d", n, 0)
+	}
+
+	clock.now = clock.now.Add(time.Minute)
+	if n := c.DeleteExpired(); n != 1 {
+		t.Errorf("incorrect number of expired records: got: %d expected: %d", n, 1)
+	}
+	if _, _, ok := c.GetStale(IntKey(1)); ok {
+		t.Error("record was kept after the grace period")
+	}
+}
+
+func TestGetStale(t *testing.T) {
+	clock := &fixedClock{now: time.Unix(1000, 0)}
+	c := New(0, WithClock(clock))
+	defer c.Close()
+	ns := c.Namespace("users")
+	keyed := NewKeyed(c, StringKey)
+
+	c.Set(IntKey(1), 1, time.Second)
+	ns.Set(IntKey(1), 2, time.Second)
+	keyed.Set("a", 3, time.Second)
+	clock.now = clock.now.Add(3 * time.Second)
+
+	if v, age, ok := c.GetStale(IntKey(1)); !ok || v != 1 || age != 2*time.Second {
+		t.Errorf("incorrect stale read: got: %v, %v, %v expected: %v, %v, %v", v, age, ok, 1, 2*time.Second, true)
+	}
+	if v, age, ok := ns.GetStale(IntKey(1)); !ok || v != 2 || age != 2*time.Second {
+		t.Errorf("incorrect stale read of a namespace: got: %v, %v, %v expected: %v, %v, %v", v, age, ok, 2, 2*time.Second, true)
+	}
+	if v, age, ok := keyed.GetStale("a"); !ok || v != 3 || age != 2*time.Second {
+		t.Errorf("incorrect stale read of a keyed view: got: %v, %v, %v expected: %v, %v, %v", v, age, ok, 3, 2*time.Second, true)
+	}
+	if _, _, ok := keyed.GetStale("b"); ok {
+		t.Error("stale read of a missing key succeeded")
+	}
+	if st := c.Stats(); st.StaleHits != 3 || st.Hits != 3 || st.Misses != 1 {
+		t.Errorf("incorrect stats: got: %d, %d, %d expected: %d, %d, %d", st.StaleHits, st.Hits, st.Misses, 3, 3, 1)
+	}
+
+	c.DeleteExpired()
+	if _, _, ok := c.GetStale(IntKey(1)); ok {
+		t.Error("stale read of a removed record succeeded")
+	}
+}
@@ -0,0 +1,28 @@
+package ttlswisscache
+
+import "time"
+
+// Key returns the composite key of id in group.
+// It is the key Namespace(group).Key(id) stores id under until the namespace is invalidated.
+func Key(group string, id uint64) uint64 {
+	return hashKey(id ^ StringKey(group))
+}
+
+// groupTag is the tag of group members. User tags can't start with a NUL byte in practice.
+func groupTag(group string) string {
+	return "\x00group\x00" + group
+}
+
+// SetInGroup adds value to the cache under Key(group, id) with given ttl and makes
+// it a member of group for DeleteGroup. The record is read and removed with the
+// composite key like any other.
+func (c *Cache) SetInGroup(group string, id uint64, value interface{}, ttl time.Duration) {
+	c.SetWithTags(Key(group, id), value, ttl, groupTag(group))
+}
+
+// DeleteGroup removes all records set with SetInGroup for group and returns their
+// number. Like InvalidateTag, its work is proportional to the size of the group,
+// not of the cache.
+func (c *Cache) DeleteGroup(group string) int {
+	return c.InvalidateTag(groupTag(group))
+}
@@ -0,0 +1,35 @@
+package ttlswisscache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCache_DeleteGroup(t *testing.T) {
+	c := New(0)
+	defer c.Close()
+	for i := 0; i < 10; i++ {
+		c.SetInGroup("tenant-1", IntKey(i), i, time.Hour)
+		c.SetInGroup("tenant-2", IntKey(i), i, time.Hour)
+	}
+
+	if v, ok := c.Get(Key("tenant-1", IntKey(3))); !ok || v != 3 {
+		t.Errorf("incorrect value: got: %v, %v expected: 3, true", v, ok)
+	}
+	if v, ok := c.Namespace("tenant-1").Get(IntKey(3)); !ok || v != 3 {
+		t.Errorf("incorrect namespace value: got: %v, %v expected: 3, true", v, ok)
+	}
+	if Key("tenant-1", IntKey(1)) == Key("tenant-2", IntKey(1)) {
+		t.Error("keys of different groups are equal")
+	}
+
+	if n := c.DeleteGroup("tenant-1"); n != 10 {
+		t.Errorf("incorrect number of deleted records: got: %d expected: %d", n, 10)
+	}
+	if _, ok := c.Get(Key("tenant-1", IntKey(3))); ok {
+		t.Error("group member was not deleted")
+	}
+	if _, ok := c.Get(Key("tenant-2", IntKey(3))); !ok {
+		t.Error("member of another group was deleted")
+	}
+}
@@ -0,0 +1,4 @@
+// Package cachepb contains the protocol buffer definitions of the cache service.
+package cachepb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative cache.proto
@@ -0,0 +1,144 @@
+package grpccache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	ttlcache "github.com/loicalleyne/ttlswisscache"
+	"github.com/loicalleyne/ttlswisscache/grpccache/cachepb"
+)
+
+// Client accesses a remote cache served by Server.
+type Client struct {
+	rpc   cachepb.CacheClient
+	codec ttlcache.Codec
+}
+
+// NewClient creates a client on top of the connection.
+// codec must match the codec of the server.
+func NewClient(conn grpc.ClientConnInterface, codec ttlcache.Codec) *Client {
+	return &Client{rpc: cachepb.NewCacheClient(conn), codec: codec}
+}
+
+// Get returns the stored value and an existence flag.
+func (c *Client) Get(ctx context.Context, key uint64) (interface{}, bool, error) {
+	return c.get(ctx, key, 0)
+}
+
+// get returns the stored value, tracking the key for the invalidations of
+// trackingID if not zero.
+func (c *Client) get(ctx context.Context, key, trackingID uint64) (interface{}, bool, error) {
+	resp, err := c.rpc.Get(ctx, &cachepb.GetRequest{Key: key, TrackingId: trackingID})
+	if err != nil {
+		return nil, false, err
+	}
+	if !resp.GetFound() {
+		return nil, false, nil
+	}
+	v, err := c.codec.Unmarshal(resp.GetValue())
+	if err != nil {
+		return nil, false, fmt.Errorf("grpccache: decode key %d: %w", key, err)
+	}
+	return v, true, nil
+}
+
+// Set stores the value with given ttl.
+func (c *Client) Set(ctx context.Context, key uint64, value interface{}, ttl time.Duration) error {
+	data, err := c.codec.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("grpccache: encode key %d: %w", key, err)
+	}
+	_, err = c.rpc.Set(ctx, &cachepb.SetRequest{Key: key, Value: data, Ttl: durationpb.New(ttl)})
+	return err
+}
+
+// Delete removes the record and reports whether it existed.
+func (c *Client) Delete(ctx context.Context, key uint64) (bool, error) {
+	resp, err := c.rpc.Delete(ctx, &cachepb.DeleteRequest{Key: key})
+	if err != nil {
+		return false, err
+	}
+	return resp.GetDeleted(), nil
+}
+
+// GetMany returns stored values of the keys.
+// Keys without a record are missing from the result.
+func (c *Client) GetMany(ctx context.Context, keys []uint64) (map[uint64]interface{}, error) {
+	resp, err := c.rpc.GetMany(ctx, &cachepb.GetManyRequest{Keys: keys})
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[uint64]interface{}, len(resp.GetEntries()))
+	for _, e := range resp.GetEntries() {
+		v, err := c.codec.Unmarshal(e.GetValue())
+		if err != nil {
+			return nil, fmt.Errorf("grpccache: decode key %d: %w", e.GetKey(), err)
+		}
+		values[e.GetKey()] = v
+	}
+	return values, nil
+}
+
+// Stats returns the counters of the remote cache.
+func (c *Client) Stats(ctx context.Context) (ttlcache.Stats, error) {
+	resp, err := c.rpc.Stats(ctx, &cachepb.StatsRequest{})
+	if err != nil {
+		return ttlcache.Stats{}, err
+	}
+	return ttlcache.Stats{
+		Entries: int(resp.GetEntries()),
+		Hits:    resp.GetHits(),
+		Misses:  resp.GetMisses(),
+		Sets:    resp.GetSets(),
+		Deletes: resp.GetDeletes(),
+		Expired: resp.GetExpired(),
+	}, nil
+}
+
+// Watch calls fn for every mutation of the remote cache until ctx is cancelled,
+// the stream fails or fn returns an error.
+// buffer sets the number of events buffered on the server, zero picks the default.
+// Cancelling ctx is not reported as an error.
+func (c *Client) Watch(ctx context.Context, buffer int, fn func(ttlcache.Event) error) error {
+	stream, err := c.rpc.Watch(ctx, &cachepb.WatchRequest{Buffer: int32(buffer)})
+	if err != nil {
+		return err
+	}
+	for {
+		msg, err := stream.Recv()
+		if err != nil {
+			if errors.Is(err, io.EOF) || ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		ev := ttlcache.Event{
+			Op:       ttlcache.Op(msg.GetOp()),
+			Key:      msg.GetKey(),
+			Deadline: deadline(msg.GetDeadline()),
+		}
+		if len(msg.GetValue()) > 0 {
+			if ev.Value, err = c.codec.Unmarshal(msg.GetValue()); err != nil {
+				return fmt.Errorf("grpccache: decode key %d: %w", ev.Key, err)
+			}
+		}
+		if err := fn(ev); err != nil {
+			return err
+		}
+	}
+}
+
+// deadline converts an event deadline back to time.
+func deadline(ts *timestamppb.Timestamp) time.Time {
+	if ts == nil {
+		return time.Time{}
+	}
+	return ts.AsTime()
+}
@@ -0,0 +1,128 @@
+package grpccache
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	ttlcache "github.com/loicalleyne/ttlswisscache"
+)
+
+func startServer(t *testing.T) (*ttlcache.Cache, *Client) {
+	t.Helper()
+	cache := ttlcache.New(time.Hour)
+	l := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer()
+	NewServer(cache, ttlcache.MsgpackCodec{}).Register(srv)
+	go srv.Serve(l)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return l.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		conn.Close()
+		srv.Stop()
+		cache.Close()
+	})
+	return cache, NewClient(conn, ttlcache.MsgpackCodec{})
+}
+
+func TestClient(t *testing.T) {
+	cache, client := startServer(t)
+	ctx := context.Background()
+
+	if err := client.Set(ctx, 1, "value", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	v, ok, err := client.Get(ctx, 1)
+	if err != nil || !ok || v != "value" {
+		t.Errorf("incorrect value: got: %v %v %v expected: %v", v, ok, err, "value")
+	}
+	if v, _ := cache.Get(1); v != "value" {
+		t.Errorf("cache missed value set over gRPC: got: %v", v)
+	}
+	if _, ok, _ := client.Get(ctx, 2); ok {
+		t.Error("unexpected value for missing key")
+	}
+
+	values, err := client.GetMany(ctx, []uint64{1, 2})
+	if err != nil || len(values) != 1 || values[1] != "value" {
+		t.Errorf("incorrect values: got: %v %v", values, err)
+	}
+
+	deleted, err := client.Delete(ctx, 1)
+	if err != nil || !deleted {
+		t.Errorf("record was not deleted: %v", err)
+	}
+
+	st, err := client.Stats(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Sets != 1 || st.Deletes != 1 || st.Entries != 0 {
+		t.Errorf("incorrect stats: got: %+v", st)
+	}
+}
+
+func TestClient_Watch(t *testing.T) {
+	cache, client := startServer(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	events := make(chan ttlcache.Event, 2)
+	errStop := errors.New("stop")
+	done := make(chan error, 1)
+	go func() {
+		done <- client.Watch(ctx, 0, func(ev ttlcache.Event) error {
+			events <- ev
+			if ev.Op == ttlcache.OpDelete {
+				return errStop
+			}
+			return nil
+		})
+	}()
+
+	// Wait for the subscription to be established.
+	for {
+		cache.Set(1, "value", time.Hour)
+		select {
+		case ev := <-events:
+			if ev.Op != ttlcache.OpSet || ev.Value != "value" || ev.Deadline.IsZero() {
+				t.Errorf("incorrect event: got: %+v", ev)
+			}
+		case <-time.After(10 * time.Millisecond):
+			continue
+		}
+		break
+	}
+
+	cache.Delete(1)
+	if err := <-done; !errors.Is(err, errStop) {
+		t.Errorf("incorrect error: got: %v expected: %v", err, errStop)
+	}
+}
+
+func TestClient_WatchBufferTooLarge(t *testing.T) {
+	_, client := startServer(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err := client.Watch(ctx, 1<<30, func(ttlcache.Event) error { return nil })
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("incorrect error: got: %v expected: %v", err, codes.InvalidArgument)
+	}
+}
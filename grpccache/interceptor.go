@@ -0,0 +1,64 @@
+package grpccache
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+
+	ttlcache "github.com/loicalleyne/ttlswisscache"
+)
+
+// UnaryClientInterceptor caches responses of idempotent unary calls in the cache.
+// ttls lists the cached methods by full name, e.g. "/pkg.Service/Method",
+// with the ttl of their responses; other methods pass through.
+// Responses are keyed by method and the deterministic encoding of the request.
+// Failed calls are not cached.
+func UnaryClientInterceptor(cache *ttlcache.Cache, ttls map[string]time.Duration) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		ttl, ok := ttls[method]
+		if !ok {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+		reqMsg, ok1 := req.(proto.Message)
+		replyMsg, ok2 := reply.(proto.Message)
+		if !ok1 || !ok2 {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+		key, err := requestKey(method, reqMsg)
+		if err != nil {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+
+		// Other values stored under the key in a shared cache are misses,
+		// proto.Merge panics on messages of another type.
+		if v, ok := cache.Get(key); ok {
+			if msg, ok := v.(proto.Message); ok && msg.ProtoReflect().Descriptor() == replyMsg.ProtoReflect().Descriptor() {
+				proto.Reset(replyMsg)
+				proto.Merge(replyMsg, msg)
+				return nil
+			}
+		}
+		if err := invoker(ctx, method, req, reply, cc, opts...); err != nil {
+			return err
+		}
+		// The caller owns reply and may modify it.
+		cache.Set(key, proto.Clone(replyMsg), ttl)
+		return nil
+	}
+}
+
+// WithResponseCache is a dial option installing UnaryClientInterceptor.
+func WithResponseCache(cache *ttlcache.Cache, ttls map[string]time.Duration) grpc.DialOption {
+	return grpc.WithChainUnaryInterceptor(UnaryClientInterceptor(cache, ttls))
+}
+
+func requestKey(method string, req proto.Message) (uint64, error) {
+	buf := append([]byte(method), 0)
+	buf, err := proto.MarshalOptions{Deterministic: true}.MarshalAppend(buf, req)
+	if err != nil {
+		return 0, err
+	}
+	return ttlcache.BytesKey(buf), nil
+}
@@ -0,0 +1,106 @@
+package grpccache
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+
+	ttlcache "github.com/loicalleyne/ttlswisscache"
+	"github.com/loicalleyne/ttlswisscache/grpccache/cachepb"
+)
+
+// countingServer counts Get calls reaching the server.
+type countingServer struct {
+	*Server
+	gets atomic.Int64
+}
+
+func (s *countingServer) Get(ctx context.Context, req *cachepb.GetRequest) (*cachepb.GetResponse, error) {
+	s.gets.Add(1)
+	return s.Server.Get(ctx, req)
+}
+
+func TestUnaryClientInterceptor(t *testing.T) {
+	remote := ttlcache.New(time.Hour)
+	defer remote.Close()
+	remote.Set(1, "value", time.Hour)
+
+	l := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer()
+	counting := &countingServer{Server: NewServer(remote, ttlcache.MsgpackCodec{})}
+	cachepb.RegisterCacheServer(srv, counting)
+	go srv.Serve(l)
+	defer srv.Stop()
+
+	local := ttlcache.New(time.Hour)
+	defer local.Close()
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return l.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		WithResponseCache(local, map[string]time.Duration{
+			cachepb.Cache_Get_FullMethodName: time.Minute,
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	client := NewClient(conn, ttlcache.MsgpackCodec{})
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		v, ok, err := client.Get(ctx, 1)
+		if err != nil || !ok || v != "value" {
+			t.Errorf("incorrect value: got: %v %v %v expected: %v", v, ok, err, "value")
+		}
+	}
+	client.Get(ctx, 2)
+	if n := counting.gets.Load(); n != 2 {
+		t.Errorf("incorrect number of calls: got: %d expected: %d", n, 2)
+	}
+
+	st, _ := client.Stats(ctx)
+	if st.Hits != 1 || st.Misses != 1 {
+		t.Errorf("incorrect remote stats: got: %+v", st)
+	}
+}
+
+func TestUnaryClientInterceptor_ForeignValue(t *testing.T) {
+	cache := ttlcache.New(time.Hour)
+	defer cache.Close()
+	method := cachepb.Cache_Get_FullMethodName
+	intercept := UnaryClientInterceptor(cache, map[string]time.Duration{method: time.Minute})
+	var calls int
+	invoker := func(_ context.Context, _ string, _, reply interface{}, _ *grpc.ClientConn, _ ...grpc.CallOption) error {
+		calls++
+		reply.(*cachepb.GetResponse).Found = true
+		return nil
+	}
+
+	req := &cachepb.GetRequest{Key: 1}
+	key, err := requestKey(method, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range []interface{}{"other", &cachepb.StatsResponse{Entries: 1}} {
+		cache.Set(key, v, time.Hour)
+		reply := &cachepb.GetResponse{}
+		if err := intercept(context.Background(), method, req, reply, nil, invoker); err != nil {
+			t.Fatal(err)
+		}
+		if !reply.GetFound() {
+			t.Errorf("foreign value %T was served", v)
+		}
+	}
+	if calls != 2 {
+		t.Errorf("incorrect number of calls: got: %d expected: %d", calls, 2)
+	}
+}
@@ -0,0 +1,104 @@
+package grpccache
+
+import (
+	"sync"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	ttlcache "github.com/loicalleyne/ttlswisscache"
+	"github.com/loicalleyne/ttlswisscache/grpccache/cachepb"
+)
+
+// tracker holds the keys read by the client of an Invalidations stream and
+// not invalidated since.
+type tracker struct {
+	mu   sync.Mutex
+	keys map[uint64]struct{}
+}
+
+// Invalidations implements cachepb.CacheServer.
+func (s *Server) Invalidations(req *cachepb.InvalidationsRequest, stream cachepb.Cache_InvalidationsServer) error {
+	buffer, err := s.watchBuffer(req.GetBuffer())
+	if err != nil {
+		return err
+	}
+	events, cancel := s.cache.Subscribe(buffer)
+	defer cancel()
+
+	t := &tracker{keys: make(map[uint64]struct{})}
+	s.mu.Lock()
+	s.lastID++
+	id := s.lastID
+	s.trackers[id] = t
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.trackers, id)
+		s.mu.Unlock()
+	}()
+	if err := stream.Send(&cachepb.Invalidation{TrackingId: id}); err != nil {
+		return err
+	}
+
+	ctx := stream.Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case ev, ok := <-events:
+			if !ok {
+				return status.Error(codes.Unavailable, "cache closed")
+			}
+			// Events are dropped only while the buffer is full, it is at least
+			// nearly full right after the receive following a drop.
+			full := len(events)+1 >= cap(events)
+			msg := t.invalidate(ev, full)
+			if msg == nil {
+				continue
+			}
+			if err := stream.Send(msg); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// track adds keys to the tracker of id, if not zero. Keys are tracked before
+// they are read, so changes racing with the read are reported.
+func (s *Server) track(id uint64, keys ...uint64) error {
+	if id == 0 {
+		return nil
+	}
+	s.mu.Lock()
+	t, ok := s.trackers[id]
+	s.mu.Unlock()
+	if !ok {
+		return status.Errorf(codes.FailedPrecondition, "unknown tracking id %d", id)
+	}
+	t.mu.Lock()
+	for _, key := range keys {
+		t.keys[key] = struct{}{}
+	}
+	t.mu.Unlock()
+	return nil
+}
+
+// invalidate returns the invalidation of the event, nil if it doesn't change
+// a tracked key. all invalidates every tracked key.
+func (t *tracker) invalidate(ev ttlcache.Event, all bool) *cachepb.Invalidation {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.keys) == 0 {
+		return nil
+	}
+	if all || ev.Op == ttlcache.OpClear {
+		clear(t.keys)
+		return &cachepb.Invalidation{All: true}
+	}
+	if _, ok := t.keys[ev.Key]; !ok {
+		return nil
+	}
+	delete(t.keys, ev.Key)
+	return &cachepb.Invalidation{Keys: []uint64{ev.Key}}
+}
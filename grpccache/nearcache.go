@@ -0,0 +1,131 @@
+package grpccache
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	ttlcache "github.com/loicalleyne/ttlswisscache"
+	"github.com/loicalleyne/ttlswisscache/grpccache/cachepb"
+)
+
+// maxReconnectDelay bounds the delay between attempts of NearCache.Run to
+// open the invalidation stream.
+const maxReconnectDelay = 5 * time.Second
+
+// NearCache keeps the values read through a Client in a local cache and drops
+// them once the server reports that they changed, so reads of hot keys don't
+// leave the process while staying coherent with the remote cache.
+//
+// Run maintains the invalidation stream. While it is down values are read from
+// the server and not kept, and the local cache is cleared every time it is
+// interrupted since changes may have been missed.
+type NearCache struct {
+	client *Client
+	local  *ttlcache.Cache
+	ttl    time.Duration
+
+	mu    sync.Mutex
+	id    uint64 // Tracking id of the invalidation stream, 0 while it is down.
+	epoch uint64 // Incremented by every invalidation.
+}
+
+// NewNearCache returns a near-cache keeping the values read through client in
+// local, which must be dedicated to it, for ttl at most.
+func NewNearCache(client *Client, local *ttlcache.Cache, ttl time.Duration) *NearCache {
+	return &NearCache{client: client, local: local, ttl: ttl}
+}
+
+// Run receives the invalidations until ctx is done, reopening the stream when
+// it fails, and returns the context error.
+// buffer sets the number of events buffered on the server, zero picks the default.
+func (n *NearCache) Run(ctx context.Context, buffer int) error {
+	delay := 100 * time.Millisecond
+	for {
+		if n.run(ctx, buffer) {
+			delay = 100 * time.Millisecond
+		}
+		n.mu.Lock()
+		n.id = 0
+		n.epoch++
+		n.local.Clear()
+		n.mu.Unlock()
+
+		t := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		case <-t.C:
+		}
+		delay = min(2*delay, maxReconnectDelay)
+	}
+}
+
+// run applies the invalidations of a stream until it fails and reports
+// whether it was opened.
+func (n *NearCache) run(ctx context.Context, buffer int) bool {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stream, err := n.client.rpc.Invalidations(ctx, &cachepb.InvalidationsRequest{Buffer: int32(buffer)})
+	if err != nil {
+		return false
+	}
+	msg, err := stream.Recv()
+	if err != nil || msg.GetTrackingId() == 0 {
+		return false
+	}
+	n.mu.Lock()
+	n.id = msg.GetTrackingId()
+	n.mu.Unlock()
+	for {
+		msg, err := stream.Recv()
+		if err != nil {
+			return true
+		}
+		n.mu.Lock()
+		n.epoch++
+		if msg.GetAll() {
+			n.local.Clear()
+		}
+		for _, key := range msg.GetKeys() {
+			n.local.Delete(key)
+		}
+		n.mu.Unlock()
+	}
+}
+
+// Get returns the value of key from the local cache or, on miss, from the server.
+func (n *NearCache) Get(ctx context.Context, key uint64) (interface{}, bool, error) {
+	if v, ok := n.local.Get(key); ok {
+		return v, true, nil
+	}
+	n.mu.Lock()
+	id, epoch := n.id, n.epoch
+	n.mu.Unlock()
+
+	v, ok, err := n.client.get(ctx, key, id)
+	if err != nil || !ok || id == 0 {
+		return v, ok, err
+	}
+	// An invalidation received meanwhile may be about the value read.
+	n.mu.Lock()
+	if n.epoch == epoch {
+		n.local.Set(key, v, n.ttl)
+	}
+	n.mu.Unlock()
+	return v, true, nil
+}
+
+// Set stores the value on the server with given ttl.
+// It is kept locally once read again.
+func (n *NearCache) Set(ctx context.Context, key uint64, value interface{}, ttl time.Duration) error {
+	n.local.Delete(key)
+	return n.client.Set(ctx, key, value, ttl)
+}
+
+// Delete removes the record from the server and reports whether it existed.
+func (n *NearCache) Delete(ctx context.Context, key uint64) (bool, error) {
+	n.local.Delete(key)
+	return n.client.Delete(ctx, key)
+}
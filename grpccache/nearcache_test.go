@@ -0,0 +1,99 @@
+package grpccache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	ttlcache "github.com/loicalleyne/ttlswisscache"
+	"github.com/loicalleyne/ttlswisscache/grpccache/cachepb"
+)
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		if cond() {
+			return
+		}
+	}
+	t.Fatalf("timed out waiting for %s", what)
+}
+
+func TestNearCache(t *testing.T) {
+	cache, client := startServer(t)
+	cache.Set(1, "a", time.Hour)
+	cache.Set(2, "b", time.Hour)
+	local := ttlcache.New(time.Hour)
+	defer local.Close()
+	near := NewNearCache(client, local, time.Minute)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go near.Run(ctx, 0)
+	waitFor(t, "the invalidation stream", func() bool {
+		near.mu.Lock()
+		defer near.mu.Unlock()
+		return near.id != 0
+	})
+
+	for key, expected := range map[uint64]string{1: "a", 2: "b"} {
+		if v, ok, err := near.Get(ctx, key); err != nil || !ok || v != expected {
+			t.Errorf("incorrect value: got: %v, %v, %v expected: %v", v, ok, err, expected)
+		}
+	}
+	if v, ok := local.Get(1); !ok || v != "a" {
+		t.Errorf("incorrect local value: got: %v, %v expected: %v", v, ok, "a")
+	}
+
+	cache.Set(1, "c", time.Hour)
+	waitFor(t, "the invalidation of a set", func() bool {
+		_, ok := local.Get(1)
+		return !ok
+	})
+	if _, ok := local.Get(2); !ok {
+		t.Error("unchanged key was invalidated")
+	}
+	if v, _, _ := near.Get(ctx, 1); v != "c" {
+		t.Errorf("incorrect value after a set: got: %v expected: %v", v, "c")
+	}
+
+	cache.Clear()
+	waitFor(t, "the invalidation of a clear", func() bool {
+		return local.Stats().Entries == 0
+	})
+}
+
+func TestTracker(t *testing.T) {
+	tr := &tracker{keys: map[uint64]struct{}{1: {}, 2: {}}}
+	if msg := tr.invalidate(ttlcache.Event{Op: ttlcache.OpSet, Key: 3}, false); msg != nil {
+		t.Errorf("untracked key was invalidated: got: %v", msg)
+	}
+	if msg := tr.invalidate(ttlcache.Event{Op: ttlcache.OpDelete, Key: 1}, false); len(msg.GetKeys()) != 1 || msg.GetKeys()[0] != 1 {
+		t.Errorf("incorrect invalidation: got: %v expected: %v", msg.GetKeys(), []uint64{1})
+	}
+	if msg := tr.invalidate(ttlcache.Event{Op: ttlcache.OpDelete, Key: 1}, false); msg != nil {
+		t.Errorf("key was invalidated twice: got: %v", msg)
+	}
+	if msg := tr.invalidate(ttlcache.Event{Op: ttlcache.OpSet, Key: 3}, true); !msg.GetAll() {
+		t.Error("full buffer didn't invalidate all keys")
+	}
+	if len(tr.keys) != 0 {
+		t.Errorf("incorrect number of tracked keys: got: %d expected: %d", len(tr.keys), 0)
+	}
+}
+
+func TestInvalidations_BufferTooLarge(t *testing.T) {
+	_, client := startServer(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := client.rpc.Invalidations(ctx, &cachepb.InvalidationsRequest{Buffer: 1 << 30})
+	if err == nil {
+		_, err = stream.Recv()
+	}
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("incorrect error: got: %v expected: %v", err, codes.InvalidArgument)
+	}
+}
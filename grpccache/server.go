@@ -0,0 +1,177 @@
+// Package grpccache serves a Cache over gRPC and provides the matching client.
+//
+// The service is defined in cachepb/cache.proto. Keys are the uint64 keys of
+// the cache, values are encoded with a ttlcache.Codec that must be the same
+// on both sides.
+package grpccache
+
+import (
+	"context"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	ttlcache "github.com/loicalleyne/ttlswisscache"
+	"github.com/loicalleyne/ttlswisscache/grpccache/cachepb"
+)
+
+const (
+	defaultWatchBuffer    = 256
+	defaultMaxWatchBuffer = 4096
+)
+
+// Server implements cachepb.CacheServer on top of a Cache.
+type Server struct {
+	cachepb.UnimplementedCacheServer
+
+	cache *ttlcache.Cache
+	codec ttlcache.Codec
+
+	mu       sync.Mutex
+	trackers map[uint64]*tracker // By tracking id.
+	lastID   uint64
+
+	// MaxWatchBuffer is the largest event buffer a client may request for a
+	// stream. Larger requests fail with InvalidArgument.
+	MaxWatchBuffer int
+}
+
+// NewServer creates a service for the cache.
+func NewServer(cache *ttlcache.Cache, codec ttlcache.Codec) *Server {
+	return &Server{
+		cache:          cache,
+		codec:          codec,
+		trackers:       make(map[uint64]*tracker),
+		MaxWatchBuffer: defaultMaxWatchBuffer,
+	}
+}
+
+// Register registers the service on the gRPC server.
+func (s *Server) Register(r grpc.ServiceRegistrar) {
+	cachepb.RegisterCacheServer(r, s)
+}
+
+// Get implements cachepb.CacheServer.
+func (s *Server) Get(_ context.Context, req *cachepb.GetRequest) (*cachepb.GetResponse, error) {
+	if err := s.track(req.GetTrackingId(), req.GetKey()); err != nil {
+		return nil, err
+	}
+	v, ok := s.cache.Get(req.GetKey())
+	if !ok {
+		return &cachepb.GetResponse{}, nil
+	}
+	data, err := s.marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return &cachepb.GetResponse{Found: true, Value: data}, nil
+}
+
+// Set implements cachepb.CacheServer.
+func (s *Server) Set(_ context.Context, req *cachepb.SetRequest) (*cachepb.SetResponse, error) {
+	v, err := s.codec.Unmarshal(req.GetValue())
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "decode value: %v", err)
+	}
+	s.cache.Set(req.GetKey(), v, req.GetTtl().AsDuration())
+	return &cachepb.SetResponse{}, nil
+}
+
+// Delete implements cachepb.CacheServer.
+func (s *Server) Delete(_ context.Context, req *cachepb.DeleteRequest) (*cachepb.DeleteResponse, error) {
+	return &cachepb.DeleteResponse{Deleted: s.cache.Remove(req.GetKey())}, nil
+}
+
+// GetMany implements cachepb.CacheServer.
+func (s *Server) GetMany(_ context.Context, req *cachepb.GetManyRequest) (*cachepb.GetManyResponse, error) {
+	if err := s.track(req.GetTrackingId(), req.GetKeys()...); err != nil {
+		return nil, err
+	}
+	values := s.cache.GetMany(req.GetKeys())
+	resp := &cachepb.GetManyResponse{Entries: make([]*cachepb.Entry, 0, len(values))}
+	for k, v := range values {
+		data, err := s.marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		resp.Entries = append(resp.Entries, &cachepb.Entry{Key: k, Value: data})
+	}
+	return resp, nil
+}
+
+// Stats implements cachepb.CacheServer.
+func (s *Server) Stats(context.Context, *cachepb.StatsRequest) (*cachepb.StatsResponse, error) {
+	st := s.cache.Stats()
+	return &cachepb.StatsResponse{
+		Entries: int64(st.Entries),
+		Hits:    st.Hits,
+		Misses:  st.Misses,
+		Sets:    st.Sets,
+		Deletes: st.Deletes,
+		Expired: st.Expired,
+	}, nil
+}
+
+// Watch implements cachepb.CacheServer.
+func (s *Server) Watch(req *cachepb.WatchRequest, stream cachepb.Cache_WatchServer) error {
+	buffer, err := s.watchBuffer(req.GetBuffer())
+	if err != nil {
+		return err
+	}
+	events, cancel := s.cache.Subscribe(buffer)
+	defer cancel()
+
+	ctx := stream.Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case ev, ok := <-events:
+			if !ok {
+				return status.Error(codes.Unavailable, "cache closed")
+			}
+			msg, err := s.event(ev)
+			if err != nil {
+				return err
+			}
+			if err := stream.Send(msg); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// watchBuffer returns the event buffer of a stream requesting buffer events.
+func (s *Server) watchBuffer(buffer int32) (int, error) {
+	if buffer <= 0 {
+		return defaultWatchBuffer, nil
+	}
+	if int64(buffer) > int64(s.MaxWatchBuffer) {
+		return 0, status.Errorf(codes.InvalidArgument, "buffer %d exceeds the maximum of %d", buffer, s.MaxWatchBuffer)
+	}
+	return int(buffer), nil
+}
+
+func (s *Server) event(ev ttlcache.Event) (*cachepb.Event, error) {
+	msg := &cachepb.Event{Op: cachepb.Event_Op(ev.Op), Key: ev.Key}
+	if ev.Op == ttlcache.OpSet || ev.Op == ttlcache.OpExpire {
+		data, err := s.marshal(ev.Value)
+		if err != nil {
+			return nil, err
+		}
+		msg.Value = data
+		msg.Deadline = timestamppb.New(ev.Deadline)
+	}
+	return msg, nil
+}
+
+func (s *Server) marshal(v interface{}) ([]byte, error) {
+	data, err := s.codec.Marshal(v)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "encode value: %v", err)
+	}
+	return data, nil
+}
@@ -0,0 +1,261 @@
+// Package httpcache provides net/http middleware caching GET responses in a Cache.
+//
+// Responses are keyed by method, host and request URI. Only 200 responses are stored.
+// The response Cache-Control header is honored: no-store, no-cache and private
+// responses are not stored, max-age and s-maxage set the ttl. Responses without
+// directives get the default ttl. Responses with Set-Cookie or Vary: * are never
+// stored. A stored response with Vary is served only to requests with the same
+// values of the listed headers as the request that got it.
+// Requests with Authorization or Cookie headers are served and stored only
+// with public or s-maxage responses, as the others may hold private data.
+// Requests with If-None-Match matching the stored ETag get 304 Not Modified.
+package httpcache
+
+import (
+	"bytes"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	ttlcache "github.com/loicalleyne/ttlswisscache"
+)
+
+// DefaultMaxBodySize is the largest body stored by default.
+const DefaultMaxBodySize = 1 << 20
+
+// Middleware caches responses of the wrapped handlers.
+type Middleware struct {
+	cache *ttlcache.Cache
+	ttl   time.Duration
+
+	// MaxBodySize limits the size of stored bodies, larger responses pass through.
+	MaxBodySize int
+}
+
+// New creates the middleware.
+// ttl applies to responses without a max-age directive.
+func New(cache *ttlcache.Cache, ttl time.Duration) *Middleware {
+	return &Middleware{cache: cache, ttl: ttl, MaxBodySize: DefaultMaxBodySize}
+}
+
+// response is a stored response.
+type response struct {
+	status int
+	header http.Header
+	body   []byte
+	stored time.Time
+	shared bool     // public or s-maxage, served to authorized requests.
+	vary   []string // Request headers listed in Vary.
+	varied []string // Values of vary in the request that got the response.
+}
+
+// matches reports whether resp may be served to r.
+func (resp *response) matches(r *http.Request) bool {
+	if authorized(r) && !resp.shared {
+		return false
+	}
+	for i, name := range resp.vary {
+		if headerValue(r, name) != resp.varied[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Handler wraps next with the cache.
+func (m *Middleware) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			next.ServeHTTP(w, r)
+			return
+		}
+		reqCC := parseCacheControl(r.Header.Get("Cache-Control"))
+		key := Key(r)
+
+		if !reqCC.has("no-cache") && !reqCC.has("no-store") {
+			if v, ok := m.cache.Get(key); ok {
+				// Other values stored under the key in a shared cache are misses.
+				if resp, ok := v.(*response); ok && resp.matches(r) {
+					m.serve(w, r, resp)
+					return
+				}
+			}
+		}
+
+		rec := &recorder{ResponseWriter: w, status: http.StatusOK, limit: m.MaxBodySize}
+		next.ServeHTTP(rec, r)
+		if reqCC.has("no-store") || rec.overflow {
+			return
+		}
+		if ttl, ok := m.ttlFor(rec, r); ok {
+			m.cache.Set(key, newResponse(rec, r), ttl)
+		}
+	})
+}
+
+// Key returns the cache key of the request.
+func Key(r *http.Request) uint64 {
+	return ttlcache.StringKey(r.Method + " " + r.Host + r.URL.RequestURI())
+}
+
+func (m *Middleware) serve(w http.ResponseWriter, r *http.Request, resp *response) {
+	h := w.Header()
+	for k, v := range resp.header {
+		h[k] = v
+	}
+	h.Set("Age", strconv.Itoa(int(time.Since(resp.stored)/time.Second)))
+
+	if etag := resp.header.Get("ETag"); etag != "" && matchETag(r.Header.Get("If-None-Match"), etag) {
+		h.Del("Content-Length")
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.WriteHeader(resp.status)
+	w.Write(resp.body)
+}
+
+// newResponse returns the stored response of the recorded one to r.
+func newResponse(rec *recorder, r *http.Request) *response {
+	cc := parseCacheControl(rec.Header().Get("Cache-Control"))
+	resp := &response{
+		status: rec.status,
+		header: rec.Header().Clone(),
+		body:   rec.body.Bytes(),
+		stored: time.Now(),
+		shared: cc.has("public") || cc.has("s-maxage"),
+		vary:   varyHeaders(rec.Header()),
+	}
+	for _, name := range resp.vary {
+		resp.varied = append(resp.varied, headerValue(r, name))
+	}
+	return resp
+}
+
+// ttlFor returns the ttl of the recorded response to r or false when it must
+// not be stored.
+func (m *Middleware) ttlFor(rec *recorder, r *http.Request) (time.Duration, bool) {
+	h := rec.Header()
+	if rec.status != http.StatusOK || h.Get("Set-Cookie") != "" {
+		return 0, false
+	}
+	for _, name := range varyHeaders(h) {
+		if name == "*" {
+			return 0, false
+		}
+	}
+	cc := parseCacheControl(h.Get("Cache-Control"))
+	if cc.has("no-store") || cc.has("no-cache") || cc.has("private") {
+		return 0, false
+	}
+	if authorized(r) && !cc.has("public") && !cc.has("s-maxage") {
+		return 0, false
+	}
+	for _, directive := range []string{"s-maxage", "max-age"} {
+		if v, ok := cc[directive]; ok {
+			sec, err := strconv.Atoi(v)
+			if err != nil || sec <= 0 {
+				return 0, false
+			}
+			return time.Duration(sec) * time.Second, true
+		}
+	}
+	return m.ttl, m.ttl > 0
+}
+
+// authorized reports whether r carries credentials, so responses to it may be
+// private.
+func authorized(r *http.Request) bool {
+	return r.Header.Get("Authorization") != "" || r.Header.Get("Cookie") != ""
+}
+
+// varyHeaders returns the canonical names of the headers listed in Vary.
+func varyHeaders(h http.Header) []string {
+	var names []string
+	for _, v := range h.Values("Vary") {
+		for _, name := range strings.Split(v, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, http.CanonicalHeaderKey(name))
+			}
+		}
+	}
+	return names
+}
+
+// headerValue returns the values of the request header name joined by commas.
+func headerValue(r *http.Request, name string) string {
+	return strings.Join(r.Header.Values(name), ",")
+}
+
+type cacheControl map[string]string
+
+func parseCacheControl(s string) cacheControl {
+	cc := make(cacheControl)
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, value, _ := strings.Cut(part, "=")
+		cc[strings.ToLower(name)] = strings.Trim(value, `"`)
+	}
+	return cc
+}
+
+func (cc cacheControl) has(directive string) bool {
+	_, ok := cc[directive]
+	return ok
+}
+
+// matchETag implements the weak comparison of If-None-Match.
+func matchETag(header, etag string) bool {
+	if header == "" {
+		return false
+	}
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(header, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// recorder passes the response through and keeps a copy of the body.
+type recorder struct {
+	http.ResponseWriter
+	status      int
+	body        bytes.Buffer
+	limit       int
+	overflow    bool
+	wroteHeader bool
+}
+
+func (r *recorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	if !r.overflow {
+		if r.body.Len()+len(b) > r.limit {
+			r.overflow = true
+			r.body = bytes.Buffer{}
+		} else {
+			r.body.Write(b)
+		}
+	}
+	return r.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (r *recorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
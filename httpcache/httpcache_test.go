@@ -0,0 +1,192 @@
+package httpcache
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	ttlcache "github.com/loicalleyne/ttlswisscache"
+)
+
+func newHandler(t *testing.T, calls *atomic.Int64, header http.Header) http.Handler {
+	t.Helper()
+	cache := ttlcache.New(time.Hour)
+	t.Cleanup(func() { cache.Close() })
+	return New(cache, time.Minute).Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := calls.Add(1)
+		for k, v := range header {
+			w.Header()[k] = v
+		}
+		fmt.Fprintf(w, "response %d", n)
+	}))
+}
+
+func get(h http.Handler, url string, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, url, nil)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestMiddleware(t *testing.T) {
+	tt := []struct {
+		name   string
+		header http.Header
+		calls  int64
+	}{
+		{name: "default ttl", header: nil, calls: 1},
+		{name: "max-age", header: http.Header{"Cache-Control": {"public, max-age=60"}}, calls: 1},
+		{name: "no-store", header: http.Header{"Cache-Control": {"no-store"}}, calls: 3},
+		{name: "private", header: http.Header{"Cache-Control": {"private, max-age=60"}}, calls: 3},
+		{name: "set-cookie", header: http.Header{"Set-Cookie": {"session=1"}}, calls: 3},
+		{name: "vary", header: http.Header{"Vary": {"Accept-Language"}}, calls: 1},
+		{name: "vary all", header: http.Header{"Vary": {"*"}}, calls: 3},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var calls atomic.Int64
+			h := newHandler(t, &calls, tc.header)
+			for i := 0; i < 3; i++ {
+				get(h, "/path?q=1", nil)
+			}
+			if calls.Load() != tc.calls {
+				t.Errorf("incorrect number of handler calls: got: %d expected: %d", calls.Load(), tc.calls)
+			}
+		})
+	}
+}
+
+func TestMiddleware_Keys(t *testing.T) {
+	var calls atomic.Int64
+	h := newHandler(t, &calls, nil)
+
+	first := get(h, "/a", nil)
+	second := get(h, "/a", nil)
+	if first.Body.String() != second.Body.String() {
+		t.Errorf("incorrect cached body: got: %q expected: %q", second.Body.String(), first.Body.String())
+	}
+	if second.Header().Get("Age") == "" {
+		t.Error("cached response misses Age header")
+	}
+	get(h, "/b", nil)
+	get(h, "/a", http.Header{"Cache-Control": {"no-cache"}})
+	if calls.Load() != 3 {
+		t.Errorf("incorrect number of handler calls: got: %d expected: %d", calls.Load(), 3)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/a", nil)
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	if calls.Load() != 4 {
+		t.Error("POST was served from cache")
+	}
+}
+
+func TestMiddleware_Vary(t *testing.T) {
+	var calls atomic.Int64
+	h := newHandler(t, &calls, http.Header{"Vary": {"Accept-Language"}})
+
+	en := get(h, "/a", http.Header{"Accept-Language": {"en"}})
+	fr := get(h, "/a", http.Header{"Accept-Language": {"fr"}})
+	if en.Body.String() == fr.Body.String() {
+		t.Errorf("variant was served to another language: got: %q", fr.Body.String())
+	}
+	if rec := get(h, "/a", http.Header{"Accept-Language": {"fr"}}); rec.Body.String() != fr.Body.String() {
+		t.Errorf("incorrect cached body: got: %q expected: %q", rec.Body.String(), fr.Body.String())
+	}
+	if calls.Load() != 2 {
+		t.Errorf("incorrect number of handler calls: got: %d expected: %d", calls.Load(), 2)
+	}
+}
+
+func TestMiddleware_Authorized(t *testing.T) {
+	tt := []struct {
+		name   string
+		header http.Header
+		calls  int64
+	}{
+		{name: "default ttl", header: nil, calls: 4},
+		{name: "max-age", header: http.Header{"Cache-Control": {"max-age=60"}}, calls: 4},
+		{name: "public", header: http.Header{"Cache-Control": {"public, max-age=60"}}, calls: 1},
+		{name: "s-maxage", header: http.Header{"Cache-Control": {"s-maxage=60"}}, calls: 1},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var calls atomic.Int64
+			h := newHandler(t, &calls, tc.header)
+			get(h, "/a", http.Header{"Authorization": {"Bearer alice"}})
+			get(h, "/a", http.Header{"Authorization": {"Bearer bob"}})
+			get(h, "/a", http.Header{"Cookie": {"session=carol"}})
+			get(h, "/a", nil)
+			if calls.Load() != tc.calls {
+				t.Errorf("incorrect number of handler calls: got: %d expected: %d", calls.Load(), tc.calls)
+			}
+		})
+	}
+
+	// Responses stored for anonymous requests aren't served to authorized ones.
+	var calls atomic.Int64
+	h := newHandler(t, &calls, nil)
+	get(h, "/a", nil)
+	get(h, "/a", http.Header{"Cookie": {"session=carol"}})
+	if calls.Load() != 2 {
+		t.Errorf("incorrect number of handler calls: got: %d expected: %d", calls.Load(), 2)
+	}
+}
+
+func TestMiddleware_ForeignValue(t *testing.T) {
+	cache := ttlcache.New(time.Hour)
+	defer cache.Close()
+	h := New(cache, time.Minute).Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("response"))
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/a", nil)
+	cache.Set(Key(req), "other", time.Hour)
+
+	if rec := get(h, "/a", nil); rec.Body.String() != "response" {
+		t.Errorf("incorrect body: got: %q expected: %q", rec.Body.String(), "response")
+	}
+}
+
+func TestMiddleware_ETag(t *testing.T) {
+	var calls atomic.Int64
+	h := newHandler(t, &calls, http.Header{"Etag": {`"v1"`}})
+
+	get(h, "/a", nil)
+	rec := get(h, "/a", http.Header{"If-None-Match": {`W/"v0", "v1"`}})
+	if rec.Code != http.StatusNotModified {
+		t.Errorf("incorrect status: got: %d expected: %d", rec.Code, http.StatusNotModified)
+	}
+	if rec.Body.Len() != 0 {
+		t.Error("304 response has a body")
+	}
+	rec = get(h, "/a", http.Header{"If-None-Match": {`"v0"`}})
+	if rec.Code != http.StatusOK {
+		t.Errorf("incorrect status: got: %d expected: %d", rec.Code, http.StatusOK)
+	}
+}
+
+func TestMiddleware_MaxBodySize(t *testing.T) {
+	cache := ttlcache.New(time.Hour)
+	defer cache.Close()
+	m := New(cache, time.Minute)
+	m.MaxBodySize = 4
+	var calls atomic.Int64
+	h := m.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Write([]byte("too long"))
+	}))
+
+	get(h, "/a", nil)
+	rec := get(h, "/a", nil)
+	if rec.Body.String() != "too long" || calls.Load() != 2 {
+		t.Errorf("oversized response was cached: calls: %d body: %q", calls.Load(), rec.Body.String())
+	}
+}
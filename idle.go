@@ -0,0 +1,56 @@
+package ttlswisscache
+
+import "time"
+
+// idleSlack is the fraction of the idle timeout a read must move the deadline
+// by to take the shard lock, so frequently read records don't serialize readers.
+const idleSlack = 16
+
+// idleValue is a value expiring when it isn't read for idle, see SetWithIdle.
+type idleValue struct {
+	value  interface{}
+	idle   int64
+	expiry int64 // Unix nano, end of the lifetime.
+}
+
+// SetWithIdle adds value to the cache for at most ttl and until it isn't read
+// for idle, whichever comes first: every Get, GetMany, Lookup or GetCtx extends
+// the deadline up to idle from now, never past the ttl. A read extends the
+// deadline only when it moves it by at least idle/16, so a record may expire
+// up to that early. Unlike other records, reads report a missing record as
+// soon as its deadline has passed, before the cleanup manager removes it. It returns the version
+// of the record like Set.
+func (c *Cache) SetWithIdle(key uint64, value interface{}, ttl, idle time.Duration) uint64 {
+	value, ok := c.limit(key, value)
+	if !ok {
+		c.reject(key)
+		return 0
+	}
+	now := c.clock.unixNano()
+	expiry := now + int64(ttl)
+	return c.store(key, item{
+		deadline: min(now+int64(idle), expiry),
+		value:    &idleValue{value: c.clone(value), idle: int64(idle), expiry: expiry},
+	})
+}
+
+// touch extends the deadline of the idle record of key read with deadline
+// and reports whether the record is still alive.
+func (c *Cache) touch(key uint64, deadline int64, v *idleValue) bool {
+	now := c.clock.unixNano()
+	if deadline < now {
+		return false
+	}
+	next := min(now+v.idle, v.expiry)
+	if next-deadline < v.idle/idleSlack {
+		return true
+	}
+	s := c.shards.get(key)
+	s.Lock()
+	if i, ok := s.index.Get(key); ok && s.values[i] == interface{}(v) && s.deadlines[i] < next {
+		it, _ := s.expire(key, next)
+		c.subs.publish(OpSet, key, it)
+	}
+	s.Unlock()
+	return true
+}
@@ -0,0 +1,87 @@
+package ttlswisscache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestCache_SetWithIdle(t *testing.T) {
+	clock := &fixedClock{now: time.Unix(1000, 0)}
+	c := New(0, WithClock(clock))
+	defer c.Close()
+	c.SetWithIdle(IntKey(1), "session", time.Hour, 10*time.Minute)
+	c.SetWithIdle(IntKey(2), "idle", time.Hour, 10*time.Minute)
+
+	for i := 0; i < 11; i++ {
+		clock.now = clock.now.Add(5 * time.Minute)
+		if _, ok := c.Get(IntKey(1)); !ok {
+			t.Fatalf("record read every 5m expired after %v", time.Duration(i+1)*5*time.Minute)
+		}
+	}
+	if ttl, _ := c.TTL(IntKey(1)); ttl != 5*time.Minute {
+		t.Errorf("deadline was extended past the lifetime: got ttl: %v expected: %v", ttl, 5*time.Minute)
+	}
+	clock.now = clock.now.Add(5*time.Minute + time.Nanosecond)
+	if _, ok := c.Get(IntKey(1)); ok {
+		t.Error("record outlived its lifetime")
+	}
+	if _, ok := c.Get(IntKey(2)); ok {
+		t.Error("idle record didn't expire")
+	}
+	if n := c.DeleteExpired(); n != 2 {
+		t.Errorf("incorrect number of expired records: got: %d expected: %d", n, 2)
+	}
+
+	c.SetWithIdle(IntKey(3), "busy", time.Hour, 16*time.Minute)
+	clock.now = clock.now.Add(30 * time.Second)
+	c.Get(IntKey(3))
+	if ttl, _ := c.TTL(IntKey(3)); ttl != 15*time.Minute+30*time.Second {
+		t.Errorf("deadline was extended by a read within the slack: got ttl: %v expected: %v", ttl, 15*time.Minute+30*time.Second)
+	}
+}
+
+func TestCache_SetWithIdle_GetCtx(t *testing.T) {
+	clock := &fixedClock{now: time.Unix(1000, 0)}
+	c := New(0, WithClock(clock))
+	defer c.Close()
+	c.SetWithIdle(IntKey(1), "session", time.Hour, 10*time.Minute)
+	loader := func(context.Context) (interface{}, time.Duration, error) {
+		return "loaded", time.Hour, nil
+	}
+
+	for i := 0; i < 4; i++ {
+		clock.now = clock.now.Add(5 * time.Minute)
+		if v, _ := c.GetCtx(t.Context(), IntKey(1), loader); v != "session" {
+			t.Fatalf("incorrect value of a record read every 5m after %v: got: %v expected: %v", time.Duration(i+1)*5*time.Minute, v, "session")
+		}
+	}
+	clock.now = clock.now.Add(10*time.Minute + time.Nanosecond)
+	if v, _ := c.GetCtx(t.Context(), IntKey(1), loader); v != "loaded" {
+		t.Errorf("idle record didn't expire: got: %v expected: %v", v, "loaded")
+	}
+}
+
+func TestCache_SetWithIdle_GetMany(t *testing.T) {
+	clock := &fixedClock{now: time.Unix(1000, 0)}
+	c := New(0, WithClock(clock))
+	defer c.Close()
+	keys := make([]uint64, minParallelGetMany+1)
+	for i := range keys {
+		keys[i] = IntKey(i)
+		c.Set(keys[i], i, 2*time.Hour)
+	}
+	c.SetWithIdle(keys[0], "session", time.Hour, 10*time.Minute)
+	c.SetWithIdle(keys[1], "idle", time.Hour, 10*time.Minute)
+
+	read := append([]uint64{keys[0]}, keys[2:]...) // All but the idle record.
+	for i := 0; i < 4; i++ {
+		clock.now = clock.now.Add(5 * time.Minute)
+		if v := c.GetMany(read); v[keys[0]] != "session" {
+			t.Fatalf("incorrect value of a record read every 5m after %v: got: %v expected: %v", time.Duration(i+1)*5*time.Minute, v[keys[0]], "session")
+		}
+	}
+	if v, ok := c.GetMany(keys)[keys[1]]; ok {
+		t.Errorf("idle record didn't expire: got: %v", v)
+	}
+}
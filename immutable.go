@@ -0,0 +1,43 @@
+package ttlswisscache
+
+import "time"
+
+// immutableValue is a value Sets can't overwrite until it expires, see SetImmutable.
+type immutableValue struct {
+	value interface{}
+}
+
+// SetImmutable adds value to the cache with given ttl unless the key holds a
+// live immutable record already, and reports whether it did. Until the
+// record is outdated, writes to the key are ignored: Set returns 0,
+// SetWithTags, SetAsync, Merge and refreshes skip it and Txn fails with
+// ErrImmutable, so a computed-once artifact can't be overwritten by racing
+// writers. Delete still removes it.
+func (c *Cache) SetImmutable(key uint64, value interface{}, ttl time.Duration) bool {
+	value, ok := c.limit(key, value)
+	if !ok {
+		return false
+	}
+	c.immutables.Store(true)
+	return c.store(key, item{
+		deadline: c.clock.unixNano() + int64(ttl),
+		value:    &immutableValue{value: c.clone(value)},
+	}) != 0
+}
+
+// protected reports whether the record of key in the locked shard s refuses
+// writes, see SetImmutable and WithTombstones.
+func (c *Cache) protected(s *shard, key uint64) bool {
+	if !c.immutables.Load() && c.opts.tombstoneTTL <= 0 {
+		return false
+	}
+	i, ok := s.index.Get(key)
+	if !ok {
+		return false
+	}
+	switch s.values[i].(type) {
+	case *immutableValue, tombstoneValue:
+		return s.deadlines[i] >= c.clock.unixNano()
+	}
+	return false
+}
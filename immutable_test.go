@@ -0,0 +1,48 @@
+package ttlswisscache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCache_SetImmutable(t *testing.T) {
+	clock := &fixedClock{now: time.Unix(1000, 0)}
+	c := New(0, WithClock(clock), WithWriteBuffer(16))
+	defer c.Close()
+	if !c.SetImmutable(IntKey(1), "artifact", time.Minute) {
+		t.Error("immutable record wasn't stored")
+	}
+	if c.SetImmutable(IntKey(1), "other", time.Minute) {
+		t.Error("immutable record was overwritten by SetImmutable")
+	}
+	if v := c.Set(IntKey(1), "other", time.Hour); v != 0 {
+		t.Errorf("immutable record was overwritten by Set: got version: %d", v)
+	}
+	c.SetWithTags(IntKey(1), "other", time.Hour, "tag")
+	c.SetAsync(IntKey(1), "other", time.Hour)
+	c.Flush()
+	err := c.Txn(func(tx *Txn) error {
+		tx.Set(IntKey(2), "txn", time.Hour)
+		tx.Set(IntKey(1), "other", time.Hour)
+		return nil
+	})
+	if err != ErrImmutable {
+		t.Errorf("incorrect error of a transaction: got: %v expected: %v", err, ErrImmutable)
+	}
+	if v, _ := c.Get(IntKey(1)); v != "artifact" {
+		t.Errorf("incorrect value of an immutable record: got: %v expected: %v", v, "artifact")
+	}
+	if _, ok := c.Get(IntKey(2)); ok {
+		t.Error("write of another key of a failed transaction was applied")
+	}
+
+	clock.now = clock.now.Add(time.Minute + time.Nanosecond)
+	if v := c.Set(IntKey(1), "next", time.Hour); v == 0 {
+		t.Error("outdated immutable record wasn't overwritten")
+	}
+	c.SetImmutable(IntKey(3), "artifact", time.Hour)
+	c.Delete(IntKey(3))
+	if _, ok := c.Get(IntKey(3)); ok {
+		t.Error("immutable record wasn't deleted")
+	}
+}
@@ -0,0 +1,126 @@
+package resp
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+)
+
+// Conn is a client connection speaking RESP.
+type Conn struct {
+	net.Conn
+	*Reader
+	*Writer
+}
+
+// Dial connects to a RESP server.
+func Dial(ctx context.Context, addr string) (*Conn, error) {
+	var d net.Dialer
+	nc, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return &Conn{Conn: nc, Reader: NewReader(nc), Writer: NewWriter(nc)}, nil
+}
+
+// Do sends the command and reads the reply.
+// The context deadline applies to the round trip.
+// Error replies are returned as a value, use Value.Err to check them.
+func (c *Conn) Do(ctx context.Context, args ...[]byte) (Value, error) {
+	deadline, _ := ctx.Deadline()
+	if err := c.SetDeadline(deadline); err != nil {
+		return Value{}, err
+	}
+	stop := context.AfterFunc(ctx, func() { c.SetDeadline(time.Unix(1, 0)) })
+	defer stop()
+
+	c.WriteCommand(args...)
+	if err := c.Flush(); err != nil {
+		return Value{}, ctxErr(ctx, err)
+	}
+	v, err := c.ReadValue()
+	if err != nil {
+		return Value{}, ctxErr(ctx, err)
+	}
+	return v, nil
+}
+
+func ctxErr(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+// Client is a pool of connections to a RESP server.
+// It is safe for concurrent use.
+type Client struct {
+	addr string
+
+	mu     sync.Mutex
+	idle   []*Conn
+	max    int
+	closed bool
+}
+
+// NewClient creates a client for the server address keeping up to maxIdle idle connections.
+func NewClient(addr string, maxIdle int) *Client {
+	return &Client{addr: addr, max: maxIdle}
+}
+
+// Addr returns the server address.
+func (c *Client) Addr() string {
+	return c.addr
+}
+
+// Do sends the command on a pooled connection and reads the reply.
+// Error replies are returned as errors.
+func (c *Client) Do(ctx context.Context, args ...[]byte) (Value, error) {
+	conn, err := c.get(ctx)
+	if err != nil {
+		return Value{}, err
+	}
+	v, err := conn.Do(ctx, args...)
+	if err != nil {
+		// The connection state is unknown after a failed round trip.
+		conn.Close()
+		return Value{}, err
+	}
+	c.put(conn)
+	return v, v.Err()
+}
+
+// Close closes the idle connections. Connections in use are closed when released.
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	for _, conn := range c.idle {
+		conn.Close()
+	}
+	c.idle = nil
+	return nil
+}
+
+func (c *Client) get(ctx context.Context) (*Conn, error) {
+	c.mu.Lock()
+	if n := len(c.idle); n > 0 {
+		conn := c.idle[n-1]
+		c.idle = c.idle[:n-1]
+		c.mu.Unlock()
+		return conn, nil
+	}
+	c.mu.Unlock()
+	return Dial(ctx, c.addr)
+}
+
+func (c *Client) put(conn *Conn) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed || len(c.idle) >= c.max {
+		conn.Close()
+		return
+	}
+	c.idle = append(c.idle, conn)
+}
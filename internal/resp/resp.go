@@ -0,0 +1,253 @@
+// Package resp implements the subset of the Redis serialization protocol
+// (RESP2) needed by the servers and clients of this module.
+package resp
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// Value types.
+const (
+	SimpleString = '+'
+	Error        = '-'
+	Integer      = ':'
+	BulkString   = '$'
+	Array        = '*'
+)
+
+const (
+	maxBulkLen  = 512 << 20 // Same limit as Redis.
+	maxArrayLen = 1 << 20   // Elements of an array, Redis allows 1M per multibulk.
+	maxDepth    = 8         // Nesting of arrays.
+	// bulkChunk is the size by which the buffer of a bulk string grows while
+	// its payload arrives, so a header alone can't allocate maxBulkLen.
+	bulkChunk = 64 << 10
+)
+
+// ErrProtocol is returned on malformed input.
+var ErrProtocol = errors.New("resp: protocol error")
+
+// Value is a decoded RESP value.
+type Value struct {
+	Type  byte
+	Str   string  // SimpleString and Error
+	Int   int64   // Integer
+	Bulk  []byte  // BulkString
+	Array []Value // Array
+	Null  bool    // Null bulk string or null array
+}
+
+// Err returns the Error value as an error.
+func (v Value) Err() error {
+	if v.Type != Error {
+		return nil
+	}
+	return errors.New(v.Str)
+}
+
+// Reader decodes RESP values.
+type Reader struct {
+	r *bufio.Reader
+}
+
+// NewReader creates a Reader on top of r.
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: bufio.NewReader(r)}
+}
+
+// ReadCommand reads a command as a list of arguments.
+// Both the array form and the inline form used by telnet sessions are accepted.
+// The returned arguments are owned by the caller.
+func (r *Reader) ReadCommand() ([][]byte, error) {
+	b, err := r.r.Peek(1)
+	if err != nil {
+		return nil, err
+	}
+	if b[0] != Array {
+		line, err := r.readLine()
+		if err != nil {
+			return nil, err
+		}
+		// The line points into the read buffer, arguments must outlive it.
+		return bytes.Fields(bytes.Clone(line)), nil
+	}
+
+	v, err := r.ReadValue()
+	if err != nil {
+		return nil, err
+	}
+	args := make([][]byte, len(v.Array))
+	for i, a := range v.Array {
+		if a.Type != BulkString {
+			return nil, ErrProtocol
+		}
+		args[i] = a.Bulk
+	}
+	return args, nil
+}
+
+// ReadValue reads a single value. Arrays longer than 1M elements or nested
+// deeper than 8 levels are rejected with ErrProtocol.
+func (r *Reader) ReadValue() (Value, error) {
+	return r.readValue(0)
+}
+
+// readValue reads a value nested in depth arrays.
+func (r *Reader) readValue(depth int) (Value, error) {
+	line, err := r.readLine()
+	if err != nil {
+		return Value{}, err
+	}
+	if len(line) == 0 {
+		return Value{}, ErrProtocol
+	}
+
+	v := Value{Type: line[0]}
+	switch line[0] {
+	case SimpleString, Error:
+		v.Str = string(line[1:])
+	case Integer:
+		if v.Int, err = strconv.ParseInt(string(line[1:]), 10, 64); err != nil {
+			return Value{}, ErrProtocol
+		}
+	case BulkString:
+		n, err := strconv.Atoi(string(line[1:]))
+		if err != nil || n > maxBulkLen {
+			return Value{}, ErrProtocol
+		}
+		if n < 0 {
+			v.Null = true
+			return v, nil
+		}
+		if v.Bulk, err = r.readBulk(n + 2); err != nil {
+			return Value{}, err
+		}
+		if !bytes.HasSuffix(v.Bulk, []byte("\r\n")) {
+			return Value{}, ErrProtocol
+		}
+		v.Bulk = v.Bulk[:n]
+	case Array:
+		n, err := strconv.Atoi(string(line[1:]))
+		if err != nil || n > maxArrayLen || depth >= maxDepth {
+			return Value{}, ErrProtocol
+		}
+		if n < 0 {
+			v.Null = true
+			return v, nil
+		}
+		// The array grows as elements arrive rather than trusting the header.
+		v.Array = make([]Value, 0, min(n, 64))
+		for i := 0; i < n; i++ {
+			e, err := r.readValue(depth + 1)
+			if err != nil {
+				return Value{}, err
+			}
+			v.Array = append(v.Array, e)
+		}
+	default:
+		return Value{}, ErrProtocol
+	}
+	return v, nil
+}
+
+// readBulk reads n bytes, growing the buffer by bulkChunk as they arrive.
+func (r *Reader) readBulk(n int) ([]byte, error) {
+	buf := make([]byte, 0, min(n, bulkChunk))
+	for len(buf) < n {
+		m := min(n-len(buf), bulkChunk)
+		buf = append(buf, make([]byte, m)...)
+		if _, err := io.ReadFull(r.r, buf[len(buf)-m:]); err != nil {
+			return nil, err
+		}
+	}
+	return buf, nil
+}
+
+func (r *Reader) readLine() ([]byte, error) {
+	line, err := r.r.ReadSlice('\n')
+	if err != nil {
+		if err == bufio.ErrBufferFull {
+			return nil, ErrProtocol
+		}
+		return nil, err
+	}
+	return bytes.TrimRight(line, "\r\n"), nil
+}
+
+// Writer encodes RESP values.
+// Output is buffered, call Flush to send it.
+type Writer struct {
+	w   *bufio.Writer
+	buf []byte
+}
+
+// NewWriter creates a Writer on top of w.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: bufio.NewWriter(w)}
+}
+
+// WriteSimple writes a simple string.
+func (w *Writer) WriteSimple(s string) {
+	w.w.WriteByte(SimpleString)
+	w.w.WriteString(s)
+	w.w.WriteString("\r\n")
+}
+
+// WriteError writes an error message.
+func (w *Writer) WriteError(msg string) {
+	w.w.WriteByte(Error)
+	w.w.WriteString(msg)
+	w.w.WriteString("\r\n")
+}
+
+// WriteInt writes an integer.
+func (w *Writer) WriteInt(n int64) {
+	w.writeHeader(Integer, n)
+}
+
+// WriteBulk writes a bulk string.
+func (w *Writer) WriteBulk(b []byte) {
+	w.writeHeader(BulkString, int64(len(b)))
+	w.w.Write(b)
+	w.w.WriteString("\r\n")
+}
+
+// WriteNull writes a null bulk string.
+func (w *Writer) WriteNull() {
+	w.w.WriteString("$-1\r\n")
+}
+
+// WriteArray writes an array header. The caller writes n values next.
+func (w *Writer) WriteArray(n int) {
+	w.writeHeader(Array, int64(n))
+}
+
+// WriteCommand writes a command in the array form.
+func (w *Writer) WriteCommand(args ...[]byte) {
+	w.WriteArray(len(args))
+	for _, a := range args {
+		w.WriteBulk(a)
+	}
+}
+
+// Flush sends the buffered output.
+func (w *Writer) Flush() error {
+	return w.w.Flush()
+}
+
+func (w *Writer) writeHeader(t byte, n int64) {
+	w.buf = append(w.buf[:0], t)
+	w.buf = strconv.AppendInt(w.buf, n, 10)
+	w.buf = append(w.buf, '\r', '\n')
+	w.w.Write(w.buf)
+}
+
+// Errorf formats an error reply in the Redis style.
+func Errorf(format string, args ...interface{}) string {
+	return "ERR " + fmt.Sprintf(format, args...)
+}
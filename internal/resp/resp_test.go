@@ -0,0 +1,92 @@
+package resp
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestReader_ReadCommand(t *testing.T) {
+	tt := []struct {
+		name     string
+		input    string
+		expected []string
+	}{
+		{name: "array", input: "*2\r\n$3\r\nGET\r\n$3\r\nkey\r\n", expected: []string{"GET", "key"}},
+		{name: "inline", input: "GET key\r\n", expected: []string{"GET", "key"}},
+		{name: "empty bulk", input: "*3\r\n$3\r\nSET\r\n$1\r\nk\r\n$0\r\n\r\n", expected: []string{"SET", "k", ""}},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			args, err := NewReader(strings.NewReader(tc.input)).ReadCommand()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(args) != len(tc.expected) {
+				t.Fatalf("incorrect number of arguments: got: %d expected: %d", len(args), len(tc.expected))
+			}
+			for i := range args {
+				if string(args[i]) != tc.expected[i] {
+					t.Errorf("incorrect argument: got: %q expected: %q", args[i], tc.expected[i])
+				}
+			}
+		})
+	}
+}
+
+func TestReader_Malformed(t *testing.T) {
+	for _, input := range []string{"$3\r\nabcd\r\n", "?\r\n", ":x\r\n"} {
+		if _, err := NewReader(strings.NewReader(input)).ReadValue(); err == nil {
+			t.Errorf("expected error for %q", input)
+		}
+	}
+	if _, err := NewReader(strings.NewReader("*1\r\n:1\r\n")).ReadCommand(); err == nil {
+		t.Error("expected error for non bulk argument")
+	}
+}
+
+func TestReader_Limits(t *testing.T) {
+	tt := []struct {
+		name  string
+		input string
+	}{
+		{name: "array length", input: "*536870912\r\n"},
+		{name: "depth", input: strings.Repeat("*1\r\n", maxDepth+1) + ":1\r\n"},
+		{name: "truncated bulk", input: "$536870000\r\nabc"},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := NewReader(strings.NewReader(tc.input)).ReadValue(); err == nil {
+				t.Errorf("expected error for %q", tc.input)
+			}
+		})
+	}
+	if v, err := NewReader(strings.NewReader(strings.Repeat("*1\r\n", maxDepth) + ":1\r\n")).ReadValue(); err != nil || len(v.Array) != 1 {
+		t.Errorf("incorrect result at the depth limit: got: %v, %v expected: %v", v, err, nil)
+	}
+	big := strings.Repeat("x", 3*bulkChunk+1)
+	if v, err := NewReader(strings.NewReader("$" + strconv.Itoa(len(big)) + "\r\n" + big + "\r\n")).ReadValue(); err != nil || string(v.Bulk) != big {
+		t.Errorf("incorrect bulk of several chunks: got: %d bytes, %v expected: %d bytes, %v", len(v.Bulk), err, len(big), nil)
+	}
+}
+
+func TestWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	w.WriteSimple("OK")
+	w.WriteError("ERR oops")
+	w.WriteInt(-2)
+	w.WriteBulk([]byte("value"))
+	w.WriteNull()
+	w.WriteCommand([]byte("GET"), []byte("key"))
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "+OK\r\n-ERR oops\r\n:-2\r\n$5\r\nvalue\r\n$-1\r\n*2\r\n$3\r\nGET\r\n$3\r\nkey\r\n"
+	if buf.String() != expected {
+		t.Errorf("incorrect output: got: %q expected: %q", buf.String(), expected)
+	}
+}
@@ -0,0 +1,101 @@
+// Package ring implements a consistent hash ring of named nodes.
+package ring
+
+import (
+	"hash/fnv"
+	"sort"
+	"strconv"
+)
+
+// DefaultReplicas is the number of virtual nodes per node.
+const DefaultReplicas = 64
+
+// Ring maps keys to nodes so that adding or removing a node
+// only moves the keys of that node.
+// Ring is not safe for concurrent use, callers replace or lock it on membership changes.
+type Ring struct {
+	replicas int
+	hashes   []uint64
+	nodes    map[uint64]string
+}
+
+// New creates a ring with the given number of virtual nodes per node.
+func New(replicas int, nodes ...string) *Ring {
+	if replicas <= 0 {
+		replicas = DefaultReplicas
+	}
+	r := &Ring{replicas: replicas, nodes: make(map[uint64]string)}
+	r.Add(nodes...)
+	return r
+}
+
+// Add puts the nodes on the ring.
+func (r *Ring) Add(nodes ...string) {
+	for _, node := range nodes {
+		for i := 0; i < r.replicas; i++ {
+			h := hash(strconv.Itoa(i) + node)
+			if _, ok := r.nodes[h]; ok {
+				continue
+			}
+			r.nodes[h] = node
+			r.hashes = append(r.hashes, h)
+		}
+	}
+	sort.Slice(r.hashes, func(i, j int) bool { return r.hashes[i] < r.hashes[j] })
+}
+
+// Remove takes the nodes off the ring.
+func (r *Ring) Remove(nodes ...string) {
+	drop := make(map[string]struct{}, len(nodes))
+	for _, node := range nodes {
+		drop[node] = struct{}{}
+	}
+	hashes := r.hashes[:0]
+	for _, h := range r.hashes {
+		if _, ok := drop[r.nodes[h]]; ok {
+			delete(r.nodes, h)
+			continue
+		}
+		hashes = append(hashes, h)
+	}
+	r.hashes = hashes
+}
+
+// Len returns the number of nodes on the ring.
+func (r *Ring) Len() int {
+	return len(r.hashes) / r.replicas
+}
+
+// Get returns the node owning the key.
+// It returns an empty string for an empty ring.
+func (r *Ring) Get(key string) string {
+	return r.GetHash(hash(key))
+}
+
+// GetHash returns the node owning the key hash.
+func (r *Ring) GetHash(h uint64) string {
+	if len(r.hashes) == 0 {
+		return ""
+	}
+	i := sort.Search(len(r.hashes), func(i int) bool { return r.hashes[i] >= h })
+	if i == len(r.hashes) {
+		i = 0
+	}
+	return r.nodes[r.hashes[i]]
+}
+
+func hash(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	return mix(h.Sum64())
+}
+
+// mix improves the avalanche of fnv for short, similar inputs.
+func mix(k uint64) uint64 {
+	k ^= k >> 33
+	k *= 0xff51afd7ed558ccd
+	k ^= k >> 33
+	k *= 0xc4ceb9fe1a85ec53
+	k ^= k >> 33
+	return k
+}
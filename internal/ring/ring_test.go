@@ -0,0 +1,37 @@
+package ring
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestRing(t *testing.T) {
+	r := New(0, "a", "b", "c")
+	if r.Len() != 3 {
+		t.Errorf("incorrect number of nodes: got: %d expected: %d", r.Len(), 3)
+	}
+
+	owners := make(map[string]string)
+	counts := make(map[string]int)
+	for i := 0; i < 3000; i++ {
+		k := strconv.Itoa(i)
+		owners[k] = r.Get(k)
+		counts[owners[k]]++
+	}
+	for node, n := range counts {
+		if n < 500 {
+			t.Errorf("skewed distribution: node %s owns %d of 3000 keys", node, n)
+		}
+	}
+
+	r.Remove("b")
+	for k, owner := range owners {
+		if owner != "b" && r.Get(k) != owner {
+			t.Errorf("key %s moved from %s to %s", k, owner, r.Get(k))
+		}
+	}
+
+	if New(0).Get("key") != "" {
+		t.Error("empty ring returned a node")
+	}
+}
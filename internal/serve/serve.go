@@ -0,0 +1,105 @@
+// Package serve tracks listeners and connections of the network servers
+// of this module so they can be shut down together.
+package serve
+
+import (
+	"errors"
+	"net"
+	"sync"
+)
+
+// ErrClosed is returned by Serve after Close.
+var ErrClosed = errors.New("server closed")
+
+// Group accepts connections on listeners and runs a handler per connection.
+// The zero value is ready to use.
+type Group struct {
+	mu        sync.Mutex
+	listeners map[net.Listener]struct{}
+	conns     map[net.Conn]struct{}
+	closed    bool
+	wg        sync.WaitGroup
+}
+
+// Serve accepts connections on the listener until Close is called.
+// Every connection is handled in its own goroutine and closed when handle returns.
+func (g *Group) Serve(l net.Listener, handle func(net.Conn)) error {
+	if !g.track(l, nil) {
+		l.Close()
+		return ErrClosed
+	}
+	defer g.untrack(l, nil)
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if g.isClosed() {
+				return ErrClosed
+			}
+			return err
+		}
+		if !g.track(nil, conn) {
+			conn.Close()
+			return ErrClosed
+		}
+		g.wg.Add(1)
+		go func() {
+			defer g.wg.Done()
+			defer g.untrack(nil, conn)
+			defer conn.Close()
+			handle(conn)
+		}()
+	}
+}
+
+// Close stops the listeners, closes active connections and waits for
+// handlers to return.
+func (g *Group) Close() error {
+	g.mu.Lock()
+	g.closed = true
+	for l := range g.listeners {
+		l.Close()
+	}
+	for c := range g.conns {
+		c.Close()
+	}
+	g.mu.Unlock()
+	g.wg.Wait()
+	return nil
+}
+
+func (g *Group) track(l net.Listener, c net.Conn) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.closed {
+		return false
+	}
+	if g.listeners == nil {
+		g.listeners = make(map[net.Listener]struct{})
+		g.conns = make(map[net.Conn]struct{})
+	}
+	if l != nil {
+		g.listeners[l] = struct{}{}
+	}
+	if c != nil {
+		g.conns[c] = struct{}{}
+	}
+	return true
+}
+
+func (g *Group) untrack(l net.Listener, c net.Conn) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if l != nil {
+		delete(g.listeners, l)
+	}
+	if c != nil {
+		delete(g.conns, c)
+	}
+}
+
+func (g *Group) isClosed() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.closed
+}
@@ -68,6 +68,8 @@ func BytesKey(k []byte) uint64 {
 }
 
 // StringKey creates key from string value.
+// Only the hash is stored, so different strings may map to the same key;
+// NewKeyed(c, StringKey) guards against such collisions.
 func StringKey(k string) uint64 {
 	return newKeyFromBytes([]byte(k))
 }
@@ -0,0 +1,146 @@
+package ttlswisscache
+
+import (
+	"time"
+	"unique"
+)
+
+// keyedValue is a value stored with its original key, see Keyed.
+type keyedValue struct {
+	key   interface{} // unique.Handle of the original key.
+	value interface{}
+}
+
+// Keyed is a view of a cache with keys of type K hashed down to uint64.
+// The original key is stored with each record and compared on reads, so
+// two keys with the same hash can't see each other's values: the last one
+// set takes the record and the other one misses. Stored keys are interned
+// with the unique package, so a key set many times is held once.
+//
+// The cache itself, events and statistics report the hashed keys and the
+// plain values. Snapshots and CDC streams don't carry the original keys:
+// restored records are misses for Keyed reads until they are set again.
+type Keyed[K comparable] struct {
+	cache *Cache
+	hash  func(K) uint64
+}
+
+// NewKeyed returns the view of c hashing keys with hash, e.g. StringKey.
+func NewKeyed[K comparable](c *Cache, hash func(K) uint64) *Keyed[K] {
+	return &Keyed[K]{cache: c, hash: hash}
+}
+
+// Get returns the value stored with the key like Cache.Get.
+func (k *Keyed[K]) Get(key K) (interface{}, bool) {
+	h := k.hash(key)
+	s := k.cache.shards.get(h)
+	s.RLock()
+	it, ok := s.get(h)
+	s.RUnlock()
+	if ok {
+		_, ok = k.load(it, key)
+	}
+	return k.cache.found(s, h, it, ok)
+}
+
+// GetStale returns the value of key even if it is outdated, like Cache.GetStale.
+func (k *Keyed[K]) GetStale(key K) (value interface{}, age time.Duration, ok bool) {
+	h := k.hash(key)
+	s := k.cache.shards.get(h)
+	s.RLock()
+	it, ok := s.get(h)
+	s.RUnlock()
+	if ok {
+		value, ok = k.load(it, key)
+	}
+	if !ok {
+		s.stats.misses.Add(1)
+		return nil, 0, false
+	}
+	return k.cache.clone(value), k.cache.staleness(s, it), true
+}
+
+// Set adds value to the cache with given ttl like Cache.Set.
+func (k *Keyed[K]) Set(key K, value interface{}, ttl time.Duration) {
+	c := k.cache
+	h := k.hash(key)
+	value, ok := c.limit(h, value)
+	if !ok {
+		k.Delete(key)
+		return
+	}
+	it := item{
+		deadline: c.clock.unixNano() + int64(ttl),
+		value:    &keyedValue{key: unique.Make(key), value: c.clone(value)},
+	}
+	if c.sets != nil {
+		c.sets.set(h, it)
+	} else {
+		c.store(h, it)
+	}
+}
+
+// TTL returns the remaining time to live of the record of the key like Cache.TTL.
+func (k *Keyed[K]) TTL(key K) (time.Duration, bool) {
+	h := k.hash(key)
+	s := k.cache.shards.get(h)
+	s.RLock()
+	it, ok := s.get(h)
+	s.RUnlock()
+	if ok {
+		_, ok = k.load(it, key)
+	}
+	if !ok {
+		return 0, false
+	}
+	ttl := time.Duration(it.deadline - k.cache.clock.unixNano())
+	if ttl < 0 {
+		ttl = 0
+	}
+	return ttl, true
+}
+
+// GetAndDelete removes the record of the key and returns its value like Cache.GetAndDelete.
+// The record of another key with the same hash is left as is.
+func (k *Keyed[K]) GetAndDelete(key K) (interface{}, bool) {
+	h := k.hash(key)
+	s := k.cache.shards.get(h)
+	s.Lock()
+	it, ok := s.get(h)
+	var value interface{}
+	if ok && !k.cache.quarantined(it) {
+		value, ok = k.load(it, key)
+	} else {
+		ok = false
+	}
+	if !ok {
+		s.Unlock()
+		return nil, false
+	}
+	k.cache.remove(s, h, it)
+	s.Unlock()
+	k.cache.cascade()
+	return value, true
+}
+
+// Delete removes the record of the key like Cache.Delete.
+// With WithTombstones it leaves a tombstone of the key, unless the record
+// belongs to another key with the same hash, which is left as is.
+func (k *Keyed[K]) Delete(key K) {
+	k.cache.delete(k.hash(key), func(value interface{}) bool {
+		if _, buried := value.(tombstoneValue); buried {
+			return false
+		}
+		_, ok := k.load(item{value: value}, key)
+		return !ok
+	}, true)
+}
+
+// load returns the value of the record if it was set for the key.
+func (k *Keyed[K]) load(it item, key K) (interface{}, bool) {
+	kv, ok := it.value.(*keyedValue)
+	if !ok || kv.key != interface{}(unique.Make(key)) {
+		return nil, false
+	}
+	return kv.value, true
+}
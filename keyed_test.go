@@ -0,0 +1,94 @@
+package ttlswisscache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestKeyed(t *testing.T) {
+	c := New(0)
+	defer c.Close()
+	k := NewKeyed(c, StringKey)
+	k.Set("user:1", "alice", time.Hour)
+
+	if v, ok := k.Get("user:1"); !ok || v != "alice" {
+		t.Errorf("incorrect value: got: %v, %v expected: alice, true", v, ok)
+	}
+	if v, ok := c.Get(StringKey("user:1")); !ok || v != "alice" {
+		t.Errorf("incorrect value of the hashed key: got: %v, %v expected: alice, true", v, ok)
+	}
+	if ttl, ok := k.TTL("user:1"); !ok || ttl <= 0 || ttl > time.Hour {
+		t.Errorf("incorrect ttl: got: %v expected: (0, %v]", ttl, time.Hour)
+	}
+	if v, ok := k.GetAndDelete("user:1"); !ok || v != "alice" {
+		t.Errorf("incorrect deleted value: got: %v, %v expected: alice, true", v, ok)
+	}
+	if _, ok := k.Get("user:1"); ok {
+		t.Error("record was not deleted")
+	}
+}
+
+func TestKeyed_Collision(t *testing.T) {
+	c := New(0)
+	defer c.Close()
+	k := NewKeyed(c, func(string) uint64 { return 1 })
+	k.Set("a", "value of a", time.Hour)
+
+	if v, ok := k.Get("b"); ok {
+		t.Errorf("incorrect value of a colliding key: got: %v expected: none", v)
+	}
+	if _, ok := k.TTL("b"); ok {
+		t.Error("incorrect ttl existence of a colliding key")
+	}
+	k.Delete("b")
+	if v, ok := k.Get("a"); !ok || v != "value of a" {
+		t.Errorf("record deleted by a colliding key: got: %v, %v expected: value of a, true", v, ok)
+	}
+
+	k.Set("b", "value of b", time.Hour)
+	if v, ok := k.Get("a"); ok {
+		t.Errorf("incorrect value of an overwritten key: got: %v expected: none", v)
+	}
+	if st := c.Stats(); st.Misses != 2 || st.Hits != 1 {
+		t.Errorf("incorrect stats: got: %+v expected: Hits 1, Misses 2", st)
+	}
+}
+
+func TestKeyed_Cloner(t *testing.T) {
+	c := New(0, WithCloner(func(v interface{}) interface{} {
+		return append([]int(nil), v.([]int)...)
+	}))
+	defer c.Close()
+	k := NewKeyed(c, StringKey)
+	value := []int{1}
+	k.Set("a", value, time.Hour)
+	value[0] = 2
+
+	d := c.Clone()
+	defer d.Close()
+	if v, ok := NewKeyed(d, StringKey).Get("a"); !ok || v.([]int)[0] != 1 {
+		t.Errorf("incorrect cloned value: got: %v, %v expected: [1], true", v, ok)
+	}
+}
+
+func TestKeyed_SetCoalescing(t *testing.T) {
+	c := New(0, WithSetCoalescing(time.Hour))
+	defer c.Close()
+	k := NewKeyed(c, StringKey)
+	k.Set("a", 1, time.Minute)
+	k.Set("a", 2, time.Minute)
+	if v, _ := k.Get("a"); v != 1 {
+		t.Errorf("incorrect value before the flush: got: %v expected: %v", v, 1)
+	}
+	k.Set("b", 1, time.Minute)
+	k.Set("b", 2, time.Minute)
+	k.Delete("b")
+
+	c.Flush()
+	if v, _ := k.Get("a"); v != 2 {
+		t.Errorf("incorrect value after the flush: got: %v expected: %v", v, 2)
+	}
+	if v, ok := k.Get("b"); ok {
+		t.Errorf("deferred set of a deleted key was stored: got: %v", v)
+	}
+}
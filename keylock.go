@@ -0,0 +1,60 @@
+package ttlswisscache
+
+import "sync"
+
+// keyLocks are the mutexes of Cache.Lock, created on demand and dropped
+// once nobody holds or waits for them. They are apart from the record locks,
+// so the cache can be used while a key is locked.
+type keyLocks struct {
+	mu    sync.Mutex
+	locks map[uint64]*keyLock
+}
+
+type keyLock struct {
+	sync.Mutex
+	refs int // Holders and waiters, guarded by keyLocks.mu.
+}
+
+func (l *keyLocks) acquire(key uint64) *keyLock {
+	l.mu.Lock()
+	if l.locks == nil {
+		l.locks = make(map[uint64]*keyLock)
+	}
+	kl, ok := l.locks[key]
+	if !ok {
+		kl = &keyLock{}
+		l.locks[key] = kl
+	}
+	kl.refs++
+	l.mu.Unlock()
+	return kl
+}
+
+func (l *keyLocks) release(key uint64, kl *keyLock) {
+	l.mu.Lock()
+	if kl.refs--; kl.refs == 0 {
+		delete(l.locks, key)
+	}
+	l.mu.Unlock()
+}
+
+// Lock locks the key and returns the function unlocking it, so read-modify-write
+// flows on a key are serialized without a mutex map of their own:
+//
+//	unlock := cache.Lock(key)
+//	defer unlock()
+//	v, _ := cache.Get(key)
+//	cache.Set(key, update(v), ttl)
+//
+// Key locks are advisory: they serialize Lock callers only, other operations on
+// the key are not blocked, and the cache can be used while holding one.
+// The returned function must be called exactly once.
+func (c *Cache) Lock(key uint64) (unlock func()) {
+	l := &c.shards.get(key).keyLocks
+	kl := l.acquire(key)
+	kl.Lock()
+	return func() {
+		kl.Unlock()
+		l.release(key, kl)
+	}
+}
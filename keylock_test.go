@@ -0,0 +1,54 @@
+package ttlswisscache
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCache_Lock(t *testing.T) {
+	c := New(0)
+	defer c.Close()
+	c.Set(IntKey(1), 0, time.Hour)
+
+	const n = 100
+	var wg sync.WaitGroup
+	wg.Add(n)
+	for i := 0; i < n; i++ {
+		go func() {
+			defer wg.Done()
+			unlock := c.Lock(IntKey(1))
+			defer unlock()
+			v, _ := c.Get(IntKey(1))
+			c.Set(IntKey(1), v.(int)+1, time.Hour)
+		}()
+	}
+	wg.Wait()
+
+	if v, _ := c.Get(IntKey(1)); v != n {
+		t.Errorf("incorrect counter: got: %v expected: %v", v, n)
+	}
+	for _, s := range c.shards.list {
+		if len(s.keyLocks.locks) != 0 {
+			t.Errorf("key locks were not released: got: %d", len(s.keyLocks.locks))
+		}
+	}
+}
+
+func TestCache_Lock_OtherKeys(t *testing.T) {
+	c := New(0, WithShardCount(1))
+	defer c.Close()
+
+	unlock := c.Lock(IntKey(1))
+	defer unlock()
+	done := make(chan struct{})
+	go func() {
+		c.Lock(IntKey(2))()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Error("lock of another key is blocked")
+	}
+}
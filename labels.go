@@ -0,0 +1,68 @@
+package ttlswisscache
+
+import (
+	"log/slog"
+	"maps"
+	"slices"
+)
+
+// WithName names the cache in the profiler labels of its cleanup manager,
+// so CPU profiles of processes running many caches tell their cleanups apart.
+// The name is also returned by Name and logged by LogValue.
+// Manager names its caches after their registered names.
+func WithName(name string) Option {
+	return func(o *options) {
+		o.name = name
+	}
+}
+
+// WithLabels attaches labels, e.g. "team": "search", to the cache, merged with
+// those of previous WithLabels options. Like the name of WithName, they are
+// profiler labels of the cleanup manager, available to the CleanupHooks from
+// their context, returned by Labels and logged by LogValue, so exporters and
+// admin endpoints tell the caches of a process apart. The labels "cache" and
+// "phase" are reserved for the name and the phase of the cleanup manager and
+// left out of the profiler labels.
+func WithLabels(labels map[string]string) Option {
+	return func(o *options) {
+		if o.labels == nil {
+			o.labels = make(map[string]string, len(labels))
+		}
+		maps.Copy(o.labels, labels)
+	}
+}
+
+// Name returns the name of the cache, see WithName.
+func (c *Cache) Name() string {
+	return c.opts.name
+}
+
+// Labels returns a copy of the labels of the cache, see WithLabels.
+func (c *Cache) Labels() map[string]string {
+	return maps.Clone(c.opts.labels)
+}
+
+// LogValue implements slog.LogValuer: a cache is logged as a group of its
+// name and labels, e.g. slog.Info("cache full", "cache", c).
+func (c *Cache) LogValue() slog.Value {
+	attrs := make([]slog.Attr, 0, 1+len(c.opts.labels))
+	attrs = append(attrs, slog.String("name", c.opts.name))
+	for _, k := range slices.Sorted(maps.Keys(c.opts.labels)) {
+		attrs = append(attrs, slog.String(k, c.opts.labels[k]))
+	}
+	return slog.GroupValue(attrs...)
+}
+
+// profilerLabels returns the pprof label pairs of the cache named name with
+// labels, in key order, followed by extra pairs.
+func profilerLabels(name string, labels map[string]string, extra ...string) []string {
+	pairs := make([]string, 0, 2+2*len(labels)+len(extra))
+	pairs = append(pairs, "cache", name)
+	for _, k := range slices.Sorted(maps.Keys(labels)) {
+		if k == "cache" || k == "phase" {
+			continue
+		}
+		pairs = append(pairs, k, labels[k])
+	}
+	return append(pairs, extra...)
+}
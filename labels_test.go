@@ -0,0 +1,53 @@
+package ttlswisscache
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"runtime/pprof"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWithLabels(t *testing.T) {
+	c := New(0, WithName("users"), WithLabels(map[string]string{"team": "search", "tier": "1"}), WithLabels(map[string]string{"tier": "2"}))
+	defer c.Close()
+	if name := c.Name(); name != "users" {
+		t.Errorf("incorrect name: got: %v expected: %v", name, "users")
+	}
+	labels := c.Labels()
+	if len(labels) != 2 || labels["team"] != "search" || labels["tier"] != "2" {
+		t.Errorf("incorrect labels: got: %v expected: %v", labels, map[string]string{"team": "search", "tier": "2"})
+	}
+	labels["team"] = "ads"
+	if team := c.Labels()["team"]; team != "search" {
+		t.Errorf("incorrect label after changing a copy: got: %v expected: %v", team, "search")
+	}
+
+	var buf bytes.Buffer
+	slog.New(slog.NewTextHandler(&buf, nil)).Info("full", "cache", c)
+	if expected := "cache.name=users cache.team=search cache.tier=2"; !strings.Contains(buf.String(), expected) {
+		t.Errorf("incorrect log: got: %q expected to contain: %q", buf.String(), expected)
+	}
+}
+
+func TestWithLabels_Profiler(t *testing.T) {
+	labels := make(chan map[string]string, 100)
+	hooks := CleanupHooks{
+		OnSweepStart: func(ctx context.Context) {
+			got := make(map[string]string)
+			pprof.ForLabels(ctx, func(k, v string) bool {
+				got[k] = v
+				return true
+			})
+			labels <- got
+		},
+	}
+	c := New(time.Millisecond, WithName("users"), WithLabels(map[string]string{"team": "search", "phase": "x"}), WithCleanupHooks(hooks))
+	defer c.Close()
+	expected := map[string]string{"cache": "users", "team": "search", "phase": "sweep"}
+	if got := <-labels; len(got) != len(expected) || got["cache"] != "users" || got["team"] != "search" || got["phase"] != "sweep" {
+		t.Errorf("incorrect profiler labels: got: %v expected: %v", got, expected)
+	}
+}
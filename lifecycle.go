@@ -0,0 +1,152 @@
+package ttlswisscache
+
+import (
+	"context"
+	"errors"
+	"runtime/pprof"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrCleanerRunning is returned by RunCleaner when the cleanup manager of the
+// cache already runs.
+var ErrCleanerRunning = errors.New("ttlswisscache: cleaner running")
+
+// WithExternalCleaner leaves the cleanup manager to RunCleaner instead of
+// starting a goroutine for it, so the application runs it under its own
+// lifecycle, e.g. an errgroup, and tests drive it with their context. Outdated
+// records wait for DeleteExpired while RunCleaner doesn't run. The clock of
+// WithCoarseClock keeps its own goroutine.
+func WithExternalCleaner() Option {
+	return func(o *options) {
+		o.externalCleaner = true
+	}
+}
+
+// cleanupSwitch runs, pauses and resumes the cleanup manager.
+type cleanupSwitch struct {
+	mu     sync.Mutex  // Held during every managed cleanup.
+	paused atomic.Bool // Set under mu, read without it by Verify.
+
+	// The cleanup loop, run by RunCleaner with WithExternalCleaner.
+	running    atomic.Bool // Set while the loop runs.
+	external   bool
+	resolution time.Duration
+	labels     []string // Profiler labels.
+	sweep      func(ctx context.Context) int
+	done       <-chan struct{}
+}
+
+// start starts the cleanup manager calling sweep every resolution of o, and
+// updating clock, if any, until done is closed. With WithExternalCleaner only
+// clock is updated, RunCleaner calls sweep.
+func (p *cleanupSwitch) start(o options, done <-chan struct{}, sweep func(ctx context.Context) int, clock *coarseClock) {
+	p.external = o.externalCleaner
+	p.resolution = o.resolution
+	p.labels = profilerLabels(o.name, o.labels, "phase", "clock")
+	p.sweep = p.wrap(sweep)
+	p.done = done
+	resolution := o.resolution
+	if p.external {
+		resolution = 0
+	}
+	if resolution <= 0 && clock == nil {
+		return
+	}
+	p.running.Store(resolution > 0)
+	go cleaner(pprof.WithLabels(context.Background(), pprof.Labels(p.labels...)), done, resolution, p.sweep, clock)
+}
+
+// run runs the cleanup loop on the calling goroutine until ctx is done or the
+// cache is closed, see RunCleaner.
+func (p *cleanupSwitch) run(ctx context.Context) error {
+	if !p.external || !p.running.CompareAndSwap(false, true) {
+		return ErrCleanerRunning
+	}
+	defer p.running.Store(false)
+	pprof.Do(ctx, pprof.Labels(p.labels...), func(ctx context.Context) {
+		cleaner(ctx, p.done, p.resolution, p.sweep, nil)
+	})
+	select {
+	case <-p.done:
+		return nil
+	default:
+		return ctx.Err()
+	}
+}
+
+// wrap returns deleteExpired skipping the calls while paused.
+func (p *cleanupSwitch) wrap(deleteExpired func(ctx context.Context) int) func(ctx context.Context) int {
+	return func(ctx context.Context) int {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		if p.paused.Load() {
+			return 0
+		}
+		return deleteExpired(ctx)
+	}
+}
+
+// set pauses or resumes the cleanups, waiting for a running one to finish.
+func (p *cleanupSwitch) set(paused bool) {
+	p.mu.Lock()
+	p.paused.Store(paused)
+	p.mu.Unlock()
+}
+
+// Stop pauses the cleanup manager and keeps the records, outdated ones
+// included, until Start. A cleanup in progress completes before Stop returns.
+// DeleteExpired can still be called directly. Close is final and can't be undone by Start.
+func (c *Cache) Stop() {
+	c.cleanup.set(true)
+}
+
+// Start resumes the cleanup manager paused by Stop.
+func (c *Cache) Start() {
+	c.cleanup.set(false)
+}
+
+// Stop pauses the cleanup manager like Cache.Stop.
+func (c *BytesCache) Stop() {
+	c.cleanup.set(true)
+}
+
+// Start resumes the cleanup manager paused by Stop.
+func (c *BytesCache) Start() {
+	c.cleanup.set(false)
+}
+
+// Stop pauses the cleanup manager like Cache.Stop.
+func (c *numberCache[T]) Stop() {
+	c.cleanup.set(true)
+}
+
+// Start resumes the cleanup manager paused by Stop.
+func (c *numberCache[T]) Start() {
+	c.cleanup.set(false)
+}
+
+// RunCleaner runs the cleanup manager of a cache created WithExternalCleaner on
+// the calling goroutine, removing outdated records every resolution like the
+// goroutine it otherwise starts, until ctx is done, returning ctx.Err(), or
+// the cache is closed, returning nil:
+//
+//	g.Go(func() error { return cache.RunCleaner(ctx) })
+//
+// It returns ErrCleanerRunning right away without WithExternalCleaner or while
+// another RunCleaner runs. Stop pauses it like the cleanup manager, and it
+// runs with the same profiler labels, added to those of ctx.
+func (c *Cache) RunCleaner(ctx context.Context) error {
+	return c.cleanup.run(ctx)
+}
+
+// RunCleaner runs the cleanup manager like Cache.RunCleaner.
+func (c *BytesCache) RunCleaner(ctx context.Context) error {
+	return c.cleanup.run(ctx)
+}
+
+// RunCleaner runs the cleanup manager like Cache.RunCleaner.
+func (c *numberCache[T]) RunCleaner(ctx context.Context) error {
+	return c.cleanup.run(ctx)
+}
@@ -0,0 +1,79 @@
+package ttlswisscache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCache_StopStart(t *testing.T) {
+	c := New(5 * time.Millisecond)
+	defer c.Close()
+
+	c.Stop()
+	c.Set(IntKey(1), 1, time.Millisecond)
+	time.Sleep(30 * time.Millisecond)
+	if _, ok := c.Get(IntKey(1)); !ok {
+		t.Error("record removed while the cleanup manager was stopped")
+	}
+
+	c.Start()
+	time.Sleep(30 * time.Millisecond)
+	if _, ok := c.Get(IntKey(1)); ok {
+		t.Error("outdated record was not removed after Start")
+	}
+}
+
+func TestInt64Cache_StopStart(t *testing.T) {
+	c := NewInt64Cache(5 * time.Millisecond)
+	defer c.Close()
+
+	c.Stop()
+	c.Set(1, 1, time.Millisecond)
+	time.Sleep(30 * time.Millisecond)
+	if _, ok := c.Get(1); !ok {
+		t.Error("record removed while the cleanup manager was stopped")
+	}
+	c.Start()
+	time.Sleep(30 * time.Millisecond)
+	if _, ok := c.Get(1); ok {
+		t.Error("outdated record was not removed after Start")
+	}
+}
+
+func TestCache_RunCleaner(t *testing.T) {
+	c := New(5*time.Millisecond, WithExternalCleaner())
+	c.Set(IntKey(1), 1, time.Millisecond)
+	time.Sleep(30 * time.Millisecond)
+	if n := c.Stats().Entries; n != 1 {
+		t.Errorf("incorrect number of records before RunCleaner: got: %d expected: %d", n, 1)
+	}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	errs := make(chan error, 1)
+	go func() { errs <- c.RunCleaner(ctx) }()
+	waitUntil(t, "the outdated record removal", func() bool { return c.Stats().Entries == 0 })
+	if err := c.RunCleaner(ctx); !errors.Is(err, ErrCleanerRunning) {
+		t.Errorf("incorrect error of a second RunCleaner: got: %v expected: %v", err, ErrCleanerRunning)
+	}
+	cancel()
+	if err := <-errs; !errors.Is(err, context.Canceled) {
+		t.Errorf("incorrect error once cancelled: got: %v expected: %v", err, context.Canceled)
+	}
+
+	go func() { errs <- c.RunCleaner(t.Context()) }()
+	time.Sleep(5 * time.Millisecond)
+	c.Close()
+	if err := <-errs; err != nil {
+		t.Errorf("incorrect error once closed: got: %v expected: %v", err, nil)
+	}
+}
+
+func TestCache_RunCleaner_Internal(t *testing.T) {
+	c := New(time.Hour)
+	defer c.Close()
+	if err := c.RunCleaner(t.Context()); !errors.Is(err, ErrCleanerRunning) {
+		t.Errorf("incorrect error: got: %v expected: %v", err, ErrCleanerRunning)
+	}
+}
@@ -0,0 +1,148 @@
+package ttlswisscache
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrNotFound is returned by GetCtx for keys cached as missing by SetNotFound.
+// A loader returns it to have GetCtx cache the key as missing.
+var ErrNotFound = errors.New("ttlswisscache: not found")
+
+// WithLoaderTimeout bounds the loader calls of GetCtx, GetManyCtx and Memoize
+// to timeout. Loader calls are also cancelled when the cache is closed, so a
+// hung origin doesn't hold up goroutines forever. No timeout by default.
+func WithLoaderTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.loaderTimeout = timeout
+	}
+}
+
+// loaderContext returns the context of a loader call made with ctx, cancelled
+// when the cache is closed and after the WithLoaderTimeout timeout.
+func (c *Cache) loaderContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	stop := context.AfterFunc(c.base, cancel)
+	if c.opts.loaderTimeout <= 0 {
+		return ctx, func() { stop(); cancel() }
+	}
+	ctx, cancelTimeout := context.WithTimeout(ctx, c.opts.loaderTimeout)
+	return ctx, func() { cancelTimeout(); stop(); cancel() }
+}
+
+// GetCtx returns the stored value of key, loading it on a miss with loader
+// and storing the loaded value with the returned ttl. Concurrent GetCtx calls
+// missing the same key wait for a single loader call, a caller whose context
+// is done returns the context error. The loader is passed the values of ctx of
+// the call starting it but not its cancellation, as other callers may wait for
+// it, see WithLoaderTimeout. Loader errors are
+// returned and not cached, except ErrNotFound which caches the key as missing
+// for the returned ttl, see SetNotFound.
+func (c *Cache) GetCtx(ctx context.Context, key uint64, loader func(context.Context) (interface{}, time.Duration, error)) (interface{}, error) {
+	switch value, res := c.Lookup(key); res {
+	case Hit:
+		return value, nil
+	case NotFound:
+		return nil, ErrNotFound
+	}
+	return c.loads.do(ctx, key, func() (interface{}, error) {
+		ctx, cancel := c.loaderContext(context.WithoutCancel(ctx))
+		defer cancel()
+		start := c.clock.unixNano()
+		value, ttl, err := loader(ctx)
+		switch {
+		case errors.Is(err, ErrNotFound):
+			c.SetNotFound(key, ttl)
+			return nil, ErrNotFound
+		case err != nil:
+			return nil, err
+		}
+		if c.opts.earlyBeta > 0 {
+			c.SetWithRecomputeCost(key, value, ttl, time.Duration(c.clock.unixNano()-start))
+		} else {
+			c.Set(key, value, ttl)
+		}
+		return c.clone(value), nil
+	})
+}
+
+// BatchLoader loads the values of keys missing from the cache, see WithBatchLoader.
+type BatchLoader interface {
+	// LoadBatch returns the values of the keys it found, e.g. with an SQL IN query
+	// or a Redis MGET. Keys missing from the result are left uncached.
+	LoadBatch(ctx context.Context, keys []uint64) (map[uint64]interface{}, error)
+}
+
+// BatchLoaderFunc is a function implementing BatchLoader.
+type BatchLoaderFunc func(ctx context.Context, keys []uint64) (map[uint64]interface{}, error)
+
+// LoadBatch calls f.
+func (f BatchLoaderFunc) LoadBatch(ctx context.Context, keys []uint64) (map[uint64]interface{}, error) {
+	return f(ctx, keys)
+}
+
+// WithBatchLoader makes GetMany and GetManyCtx load the keys they miss with loader
+// in a single call and store the loaded values with the given ttl. Keys cached as
+// missing by SetNotFound are not loaded.
+func WithBatchLoader(loader BatchLoader, ttl time.Duration) Option {
+	return func(o *options) {
+		o.batchLoader = loader
+		o.batchLoaderTTL = ttl
+	}
+}
+
+// GetManyCtx returns stored values of the keys like GetMany. Without WithBatchLoader
+// it always succeeds. With it, the missing keys are passed to the loader with ctx,
+// bounded by WithLoaderTimeout, and the found ones are stored and returned. On a loader error the values found in
+// the cache are returned with the error.
+func (c *Cache) GetManyCtx(ctx context.Context, keys []uint64) (map[uint64]interface{}, error) {
+	values := c.getMany(keys)
+	loader := c.opts.batchLoader
+	if loader == nil || len(values) == len(keys) {
+		return values, nil
+	}
+
+	missing := make(map[uint64]struct{}, len(keys)-len(values))
+	var misses []uint64
+	for _, key := range keys {
+		if _, ok := values[key]; ok {
+			continue
+		}
+		if _, ok := missing[key]; ok || c.notFound(key) {
+			continue
+		}
+		missing[key] = struct{}{}
+		misses = append(misses, key)
+	}
+	if len(misses) == 0 {
+		return values, nil
+	}
+	ctx, cancel := c.loaderContext(ctx)
+	defer cancel()
+	loaded, err := loader.LoadBatch(ctx, misses)
+	if err != nil {
+		return values, err
+	}
+	for key, value := range loaded {
+		if _, ok := missing[key]; !ok {
+			continue
+		}
+		c.Set(key, value, c.opts.batchLoaderTTL)
+		values[key] = value
+	}
+	return values, nil
+}
+
+// notFound reports whether key is cached as missing by SetNotFound.
+func (c *Cache) notFound(key uint64) bool {
+	s := c.shards.get(key)
+	s.RLock()
+	it, ok := s.get(key)
+	s.RUnlock()
+	if !ok {
+		return false
+	}
+	_, ok = it.value.(notFoundValue)
+	return ok
+}
@@ -0,0 +1,215 @@
+package ttlswisscache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWithBatchLoader(t *testing.T) {
+	var batches [][]uint64
+	loader := BatchLoaderFunc(func(_ context.Context, keys []uint64) (map[uint64]interface{}, error) {
+		batches = append(batches, keys)
+		values := make(map[uint64]interface{})
+		for _, key := range keys {
+			if key%2 == 0 {
+				values[key] = int(key) * 10
+			}
+		}
+		values[IntKey(100)] = "not requested"
+		return values, nil
+	})
+	c := New(0, WithBatchLoader(loader, time.Hour))
+	defer c.Close()
+	c.Set(IntKey(1), 1, time.Hour)
+	c.SetNotFound(IntKey(6), time.Hour)
+
+	values := c.GetMany([]uint64{IntKey(1), IntKey(2), IntKey(3), IntKey(4), IntKey(4), IntKey(6)})
+	if len(batches) != 1 || len(batches[0]) != 3 {
+		t.Errorf("incorrect loaded batches: got: %v expected: [[2 3 4]]", batches)
+	}
+	if len(values) != 3 || values[IntKey(1)] != 1 || values[IntKey(2)] != 20 || values[IntKey(4)] != 40 {
+		t.Errorf("incorrect values: got: %v expected: map[1:1 2:20 4:40]", values)
+	}
+	if v, ok := c.Get(IntKey(4)); !ok || v != 40 {
+		t.Errorf("loaded value was not stored: got: %v, %v expected: 40, true", v, ok)
+	}
+	if _, ok := c.Get(IntKey(100)); ok {
+		t.Error("value of a key not requested was stored")
+	}
+
+	c.GetMany([]uint64{IntKey(1), IntKey(2)})
+	if len(batches) != 1 {
+		t.Errorf("loader was called without misses: got: %d batches expected: %d", len(batches), 1)
+	}
+}
+
+func TestWithBatchLoader_Error(t *testing.T) {
+	errBackend := errors.New("backend down")
+	c := New(0, WithBatchLoader(BatchLoaderFunc(func(context.Context, []uint64) (map[uint64]interface{}, error) {
+		return nil, errBackend
+	}), time.Hour))
+	defer c.Close()
+	c.Set(IntKey(1), 1, time.Hour)
+
+	values, err := c.GetManyCtx(context.Background(), []uint64{IntKey(1), IntKey(2)})
+	if err != errBackend {
+		t.Errorf("incorrect error: got: %v expected: %v", err, errBackend)
+	}
+	if len(values) != 1 || values[IntKey(1)] != 1 {
+		t.Errorf("incorrect values: got: %v expected: map[1:1]", values)
+	}
+	if values := c.GetMany([]uint64{IntKey(1), IntKey(2)}); len(values) != 1 {
+		t.Errorf("incorrect values: got: %v expected: map[1:1]", values)
+	}
+}
+
+func TestGetCtx(t *testing.T) {
+	c := New(0)
+	defer c.Close()
+	var calls atomic.Int32
+	release := make(chan struct{})
+	loader := func(ctx context.Context) (interface{}, time.Duration, error) {
+		calls.Add(1)
+		<-release
+		return "loaded", time.Hour, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if v, err := c.GetCtx(context.Background(), IntKey(1), loader); v != "loaded" || err != nil {
+				t.Errorf("incorrect result: got: %v, %v expected: loaded, <nil>", v, err)
+			}
+		}()
+	}
+	waitUntil(t, "the loader call", func() bool { return calls.Load() > 0 })
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := c.GetCtx(ctx, IntKey(1), loader); err != context.Canceled {
+		t.Errorf("incorrect error of a cancelled waiter: got: %v expected: %v", err, context.Canceled)
+	}
+	close(release)
+	wg.Wait()
+	if n := calls.Load(); n != 1 {
+		t.Errorf("incorrect number of loader calls: got: %d expected: %d", n, 1)
+	}
+	if ttl, _ := c.TTL(IntKey(1)); ttl <= 59*time.Minute {
+		t.Errorf("incorrect ttl of the loaded value: got: %v expected: %v", ttl, time.Hour)
+	}
+	if v, err := c.GetCtx(context.Background(), IntKey(1), nil); v != "loaded" || err != nil {
+		t.Errorf("incorrect result of a hit: got: %v, %v expected: loaded, <nil>", v, err)
+	}
+}
+
+func TestGetCtx_Errors(t *testing.T) {
+	c := New(0)
+	defer c.Close()
+	errOrigin := errors.New("origin down")
+	calls := 0
+	failing := func(context.Context) (interface{}, time.Duration, error) {
+		calls++
+		return nil, 0, errOrigin
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := c.GetCtx(context.Background(), IntKey(1), failing); err != errOrigin {
+			t.Errorf("incorrect error: got: %v expected: %v", err, errOrigin)
+		}
+	}
+	if calls != 2 {
+		t.Errorf("loader error was cached: got: %d calls expected: %d", calls, 2)
+	}
+
+	missing := func(context.Context) (interface{}, time.Duration, error) {
+		calls++
+		return nil, time.Minute, ErrNotFound
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := c.GetCtx(context.Background(), IntKey(2), missing); err != ErrNotFound {
+			t.Errorf("incorrect error of a missing key: got: %v expected: %v", err, ErrNotFound)
+		}
+	}
+	if calls != 3 {
+		t.Errorf("missing key was loaded again: got: %d calls expected: %d", calls, 3)
+	}
+	if _, res := c.Lookup(IntKey(2)); res != NotFound {
+		t.Errorf("incorrect lookup of a missing key: got: %v expected: %v", res, NotFound)
+	}
+}
+
+func TestWithLoaderTimeout(t *testing.T) {
+	c := New(0, WithLoaderTimeout(10*time.Millisecond))
+	defer c.Close()
+	hung := func(ctx context.Context) (interface{}, time.Duration, error) {
+		<-ctx.Done()
+		return nil, 0, ctx.Err()
+	}
+	if _, err := c.GetCtx(context.Background(), IntKey(1), hung); err != context.DeadlineExceeded {
+		t.Errorf("incorrect error of a hung loader: got: %v expected: %v", err, context.DeadlineExceeded)
+	}
+	batch := BatchLoaderFunc(func(ctx context.Context, _ []uint64) (map[uint64]interface{}, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	c = New(0, WithLoaderTimeout(10*time.Millisecond), WithBatchLoader(batch, time.Hour))
+	defer c.Close()
+	if _, err := c.GetManyCtx(context.Background(), []uint64{IntKey(1)}); err != context.DeadlineExceeded {
+		t.Errorf("incorrect error of a hung batch loader: got: %v expected: %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestGetCtx_Detached(t *testing.T) {
+	c := New(0)
+	started, release := make(chan struct{}), make(chan struct{})
+	loader := func(ctx context.Context) (interface{}, time.Duration, error) {
+		close(started)
+		select {
+		case <-release:
+			return "loaded", time.Hour, ctx.Err()
+		case <-ctx.Done():
+			return nil, 0, ctx.Err()
+		}
+	}
+
+	// The caller starting the load gives up, the others still get the value.
+	ctx, cancel := context.WithCancel(context.Background())
+	errs := make(chan error)
+	go func() {
+		_, err := c.GetCtx(ctx, IntKey(1), loader)
+		errs <- err
+	}()
+	<-started
+	cancel()
+	if err := <-errs; err != context.Canceled {
+		t.Errorf("incorrect error of the cancelled caller: got: %v expected: %v", err, context.Canceled)
+	}
+	go func() {
+		_, err := c.GetCtx(context.Background(), IntKey(1), loader)
+		errs <- err
+	}()
+	close(release)
+	if err := <-errs; err != nil {
+		t.Errorf("incorrect error of a waiting caller: got: %v expected: %v", err, nil)
+	}
+
+	// Close cancels the loader calls.
+	started = make(chan struct{})
+	go func() {
+		_, err := c.GetCtx(context.Background(), IntKey(2), func(ctx context.Context) (interface{}, time.Duration, error) {
+			close(started)
+			<-ctx.Done()
+			return nil, 0, ctx.Err()
+		})
+		errs <- err
+	}()
+	<-started
+	c.Close()
+	if err := <-errs; err != context.Canceled {
+		t.Errorf("incorrect error after Close: got: %v expected: %v", err, context.Canceled)
+	}
+}
@@ -0,0 +1,117 @@
+// Package locks provides leased locks with fencing tokens, stored in a Cache,
+// to coordinate workers within a process group.
+//
+// A lock is a record holding its token with the lease as its ttl, taken with
+// SetIfAbsent: it is released by Release or once the lease ends, so a crashed
+// holder doesn't block the others for longer than its lease. Every acquisition
+// gets a larger token than the previous ones. A holder passes its token along
+// with its writes, and the protected resource rejects writes with a token
+// smaller than the largest it has seen, so a holder whose lease ended while it
+// was paused can't corrupt it:
+//
+//	lock, err := locker.Acquire(ctx, key, 10*time.Second)
+//	if err != nil {
+//		return err
+//	}
+//	defer lock.Release()
+//	err = store.Write(data, lock.Token())
+package locks
+
+import (
+	"context"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	ttlcache "github.com/loicalleyne/ttlswisscache"
+)
+
+// maxRetryDelay bounds the delay between attempts of Acquire.
+const maxRetryDelay = 100 * time.Millisecond
+
+// Locker hands out locks. It is safe for concurrent use.
+type Locker struct {
+	cache *ttlcache.Cache
+
+	mu    sync.Mutex // Orders acquisitions like their tokens.
+	token uint64     // Last issued token.
+}
+
+// New returns a locker keeping its locks in cache, along with other records
+// if their keys differ. Lockers sharing a cache must not share keys, tokens
+// only increase per locker. Time is read from the cache clock, see ttlcache.WithClock.
+func New(cache *ttlcache.Cache) *Locker {
+	// Tokens keep increasing across restarts with a restored cache.
+	return &Locker{cache: cache, token: uint64(cache.Now().UnixNano())}
+}
+
+// Lock is a lock held until Release or the end of its lease.
+type Lock struct {
+	locker *Locker
+	key    uint64
+	token  uint64
+}
+
+// TryAcquire takes the lock of key for lease, unless it is held.
+func (l *Locker) TryAcquire(key uint64, lease time.Duration) (*Lock, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.cache.SetIfAbsent(key, l.token+1, lease) {
+		return nil, false
+	}
+	l.token++
+	return &Lock{locker: l, key: key, token: l.token}, true
+}
+
+// Acquire takes the lock of key for lease, waiting for it to be released
+// until ctx is done, in which case it returns the context error.
+func (l *Locker) Acquire(ctx context.Context, key uint64, lease time.Duration) (*Lock, error) {
+	delay := time.Millisecond
+	for {
+		if lock, ok := l.TryAcquire(key, lease); ok {
+			return lock, nil
+		}
+		wait := delay/2 + rand.N(delay/2+1)
+		if ttl, ok := l.cache.TTL(key); ok {
+			wait = min(wait, ttl+time.Millisecond)
+		}
+		t := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return nil, ctx.Err()
+		case <-t.C:
+		}
+		delay = min(2*delay, maxRetryDelay)
+	}
+}
+
+// Holder returns the token of the lock of key if it is held.
+func (l *Locker) Holder(key uint64) (uint64, bool) {
+	if ttl, ok := l.cache.TTL(key); !ok || ttl == 0 {
+		return 0, false
+	}
+	v, ok := l.cache.Get(key)
+	if !ok {
+		return 0, false
+	}
+	token, ok := v.(uint64)
+	return token, ok
+}
+
+// Token returns the fencing token of the lock.
+func (lk *Lock) Token() uint64 {
+	return lk.token
+}
+
+// Extend renews the lease of the lock to lease from now and reports whether
+// it is still held. A lock whose lease ended can't be extended.
+func (lk *Lock) Extend(lease time.Duration) bool {
+	return lk.locker.cache.CompareAndSwap(lk.key, lk.token, lk.token, lease)
+}
+
+// Release releases the lock and reports whether it was still held.
+// A lock taken by another holder since the end of the lease is left alone.
+func (lk *Lock) Release() bool {
+	return lk.locker.cache.CompareAndDelete(lk.key, lk.token)
+}
@@ -0,0 +1,108 @@
+package locks
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	ttlcache "github.com/loicalleyne/ttlswisscache"
+	"github.com/loicalleyne/ttlswisscache/ttltest"
+)
+
+func TestLocker(t *testing.T) {
+	clock := ttltest.NewClock()
+	c := ttlcache.New(0, ttlcache.WithClock(clock))
+	defer c.Close()
+	l := New(c)
+	key := ttlcache.StringKey("job:42")
+
+	first, ok := l.TryAcquire(key, time.Minute)
+	if !ok {
+		t.Fatal("free lock was not acquired")
+	}
+	if _, ok := l.TryAcquire(key, time.Minute); ok {
+		t.Error("held lock was acquired")
+	}
+	if token, ok := l.Holder(key); !ok || token != first.Token() {
+		t.Errorf("incorrect holder: got: %d, %v expected: %d, true", token, ok, first.Token())
+	}
+
+	clock.Advance(50 * time.Second)
+	if !first.Extend(time.Minute) {
+		t.Error("held lock was not extended")
+	}
+	clock.Advance(50 * time.Second)
+	if _, ok := l.TryAcquire(key, time.Minute); ok {
+		t.Error("extended lock was acquired")
+	}
+
+	clock.Advance(time.Minute)
+	second, ok := l.TryAcquire(key, time.Minute)
+	if !ok {
+		t.Fatal("lock was not acquired after the end of the lease")
+	}
+	if second.Token() <= first.Token() {
+		t.Errorf("incorrect fencing token: got: %d expected more than %d", second.Token(), first.Token())
+	}
+	if first.Extend(time.Minute) || first.Release() {
+		t.Error("lock of a previous holder was extended or released")
+	}
+	if !second.Release() {
+		t.Error("held lock was not released")
+	}
+	if _, ok := l.Holder(key); ok {
+		t.Error("released lock has a holder")
+	}
+}
+
+func TestLocker_Acquire(t *testing.T) {
+	c := ttlcache.New(0)
+	defer c.Close()
+	l := New(c)
+	key := ttlcache.IntKey(1)
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		holders int
+		tokens  []uint64
+	)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			lock, err := l.Acquire(context.Background(), key, time.Second)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			mu.Lock()
+			holders++
+			if holders > 1 {
+				t.Error("lock was held twice")
+			}
+			tokens = append(tokens, lock.Token())
+			mu.Unlock()
+			time.Sleep(time.Millisecond)
+			mu.Lock()
+			holders--
+			mu.Unlock()
+			lock.Release()
+		}()
+	}
+	wg.Wait()
+	for i := 1; i < len(tokens); i++ {
+		if tokens[i] <= tokens[i-1] {
+			t.Errorf("fencing tokens don't increase: got: %v", tokens)
+		}
+	}
+
+	lock, _ := l.TryAcquire(key, time.Hour)
+	defer lock.Release()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := l.Acquire(ctx, key, time.Second); err != context.DeadlineExceeded {
+		t.Errorf("incorrect error of a held lock: got: %v expected: %v", err, context.DeadlineExceeded)
+	}
+}
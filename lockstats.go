@@ -0,0 +1,106 @@
+package ttlswisscache
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// LockWaitBuckets is the number of buckets of LockStats.Waits.
+const LockWaitBuckets = 6
+
+// LockStats describes the acquisitions of shard locks, see WithLockStats.
+type LockStats struct {
+	Acquired  uint64        // Acquisitions of the lock.
+	Contended uint64        // Acquisitions that waited for another holder.
+	Wait      time.Duration // Total time waited by contended acquisitions.
+	// Contended acquisitions by wait time: under 1µs, 10µs, 100µs, 1ms, 10ms, and longer.
+	Waits [LockWaitBuckets]uint64
+}
+
+// WithLockStats measures the acquisitions of the shard locks, reported by
+// Stats.ReadLocks and Stats.WriteLocks, and per shard by ShardReport. Heavy
+// contention on writes suggests more shards, see WithShardCount, or
+// SetAsync, see WithWriteBuffer; on reads, fewer writes per shard. Costs an
+// atomic increment per lock, and reading the clock when the lock is busy.
+// For the call sites of the waits, enable the mutex profile of the runtime,
+// see runtime.SetMutexProfileFraction: it reports contended shard locks
+// under the cache methods taking them.
+func WithLockStats() Option {
+	return func(o *options) {
+		o.lockStats = true
+	}
+}
+
+// lockCounters are the LockStats of a lock mode of a shard.
+type lockCounters struct {
+	acquired  atomic.Uint64
+	contended atomic.Uint64
+	wait      atomic.Int64
+	waits     [LockWaitBuckets]atomic.Uint64
+}
+
+// lockStats are the lock counters of a shard, see WithLockStats.
+type lockStats struct {
+	read, write lockCounters
+}
+
+// Lock locks the shard for writing.
+func (s *shard) Lock() {
+	if s.locks == nil {
+		s.RWMutex.Lock()
+		return
+	}
+	s.locks.write.acquired.Add(1)
+	if s.RWMutex.TryLock() {
+		return
+	}
+	start := time.Now()
+	s.RWMutex.Lock()
+	s.locks.write.record(time.Since(start))
+}
+
+// RLock locks the shard for reading.
+func (s *shard) RLock() {
+	if s.locks == nil {
+		s.RWMutex.RLock()
+		return
+	}
+	s.locks.read.acquired.Add(1)
+	if s.RWMutex.TryRLock() {
+		return
+	}
+	start := time.Now()
+	s.RWMutex.RLock()
+	s.locks.read.record(time.Since(start))
+}
+
+// record records a contended acquisition that waited for wait.
+func (l *lockCounters) record(wait time.Duration) {
+	l.contended.Add(1)
+	l.wait.Add(int64(wait))
+	b := 0
+	for limit := time.Microsecond; b < LockWaitBuckets-1 && wait >= limit; limit *= 10 {
+		b++
+	}
+	l.waits[b].Add(1)
+}
+
+// add sums the counters of other into st.
+func (st *LockStats) add(other LockStats) {
+	st.Acquired += other.Acquired
+	st.Contended += other.Contended
+	st.Wait += other.Wait
+	for i := range other.Waits {
+		st.Waits[i] += other.Waits[i]
+	}
+}
+
+// addTo adds the counters to st.
+func (l *lockCounters) addTo(st *LockStats) {
+	st.Acquired += l.acquired.Load()
+	st.Contended += l.contended.Load()
+	st.Wait += time.Duration(l.wait.Load())
+	for i := range l.waits {
+		st.Waits[i] += l.waits[i].Load()
+	}
+}
@@ -0,0 +1,41 @@
+package ttlswisscache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWithLockStats(t *testing.T) {
+	c := New(0, WithShardCount(1), WithLockStats())
+	defer c.Close()
+	c.Set(IntKey(1), 1, time.Hour)
+	c.Get(IntKey(1))
+
+	s := c.shards.list[0]
+	s.Lock()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c.Get(IntKey(1))
+	}()
+	time.Sleep(5 * time.Millisecond)
+	s.Unlock()
+	<-done
+
+	st := c.Stats()
+	if st.WriteLocks.Acquired != 2 || st.WriteLocks.Contended != 0 {
+		t.Errorf("incorrect write locks: got: %+v expected: 2 uncontended acquisitions", st.WriteLocks)
+	}
+	if st.ReadLocks.Acquired < 2 || st.ReadLocks.Contended != 1 {
+		t.Errorf("incorrect read locks: got: %+v expected: 1 contended acquisition", st.ReadLocks)
+	}
+	if st.ReadLocks.Wait < time.Millisecond {
+		t.Errorf("incorrect wait: got: %v expected: at least %v", st.ReadLocks.Wait, 5*time.Millisecond)
+	}
+	if n := st.ReadLocks.Waits[4] + st.ReadLocks.Waits[5]; n != 1 {
+		t.Errorf("incorrect wait buckets: got: %v expected: 1 wait of 1ms or more", st.ReadLocks.Waits)
+	}
+	if r := c.ShardReport(); r.Shards[0].ReadLocks.Contended != 1 {
+		t.Errorf("incorrect contended reads of the shard: got: %d expected: %d", r.Shards[0].ReadLocks.Contended, 1)
+	}
+}
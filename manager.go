@@ -0,0 +1,117 @@
+package ttlswisscache
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Manager creates and tracks named caches sharing configuration defaults.
+type Manager struct {
+	resolution time.Duration
+	opts       []Option
+
+	mu     sync.Mutex
+	caches map[string]*Cache
+	closed bool
+}
+
+// NewManager creates a registry of caches. resolution and opts are the defaults
+// every cache is created with.
+func NewManager(resolution time.Duration, opts ...Option) *Manager {
+	return &Manager{
+		resolution: resolution,
+		opts:       opts,
+		caches:     make(map[string]*Cache),
+	}
+}
+
+// Cache returns the cache registered under name. A missing cache is created
+// with the manager defaults followed by opts, which are ignored otherwise.
+// After Close the returned cache is created closed.
+func (m *Manager) Cache(name string, opts ...Option) *Cache {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if c, ok := m.caches[name]; ok {
+		return c
+	}
+	c := New(m.resolution, append(append([]Option{WithName(name)}, m.opts...), opts...)...)
+	if m.closed {
+		c.Close()
+		return c
+	}
+	m.caches[name] = c
+	return c
+}
+
+// Get returns the cache registered under name, if any.
+func (m *Manager) Get(name string) (*Cache, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	c, ok := m.caches[name]
+	return c, ok
+}
+
+// Names returns the names of the registered caches in sorted order.
+func (m *Manager) Names() []string {
+	m.mu.Lock()
+	names := make([]string, 0, len(m.caches))
+	for name := range m.caches {
+		names = append(names, name)
+	}
+	m.mu.Unlock()
+	sort.Strings(names)
+	return names
+}
+
+// Remove unregisters and closes the cache registered under name.
+// It reports whether the cache was registered.
+func (m *Manager) Remove(name string) bool {
+	m.mu.Lock()
+	c, ok := m.caches[name]
+	delete(m.caches, name)
+	m.mu.Unlock()
+	if ok {
+		c.Close()
+	}
+	return ok
+}
+
+// Stats returns the counters of every registered cache by name.
+func (m *Manager) Stats() map[string]Stats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stats := make(map[string]Stats, len(m.caches))
+	for name, c := range m.caches {
+		stats[name] = c.Stats()
+	}
+	return stats
+}
+
+// TotalStats returns the counters of all registered caches summed up.
+func (m *Manager) TotalStats() Stats {
+	var total Stats
+	for _, st := range m.Stats() {
+		total.add(st)
+	}
+	return total
+}
+
+// Close closes and unregisters all caches.
+// Closing a closed manager returns ErrClosed.
+func (m *Manager) Close() error {
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return ErrClosed
+	}
+	m.closed = true
+	caches := m.caches
+	m.caches = make(map[string]*Cache)
+	m.mu.Unlock()
+
+	for _, c := range caches {
+		c.Close()
+	}
+	return nil
+}
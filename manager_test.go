@@ -0,0 +1,61 @@
+package ttlswisscache
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestManager(t *testing.T) {
+	m := NewManager(time.Hour, WithShardCount(4))
+
+	users := m.Cache("users")
+	if m.Cache("users") != users {
+		t.Error("cache was created twice")
+	}
+	sessions := m.Cache("sessions", WithShardCount(8))
+	if n := len(users.shards.list); n != 4 {
+		t.Errorf("incorrect default shard count: got: %d expected: %d", n, 4)
+	}
+	if n := len(sessions.shards.list); n != 8 {
+		t.Errorf("incorrect shard count: got: %d expected: %d", n, 8)
+	}
+	if names := m.Names(); !reflect.DeepEqual(names, []string{"sessions", "users"}) {
+		t.Errorf("incorrect names: got: %v expected: %v", names, []string{"sessions", "users"})
+	}
+
+	users.Set(IntKey(1), 1, time.Hour)
+	sessions.Set(IntKey(1), 1, time.Hour)
+	sessions.Get(IntKey(1))
+	if st := m.Stats()["sessions"]; st.Entries != 1 || st.Hits != 1 {
+		t.Errorf("incorrect sessions stats: got: %+v expected: Entries 1, Hits 1", st)
+	}
+	if st := m.TotalStats(); st.Entries != 2 || st.Sets != 2 || st.Shards != 12 {
+		t.Errorf("incorrect total stats: got: %+v expected: Entries 2, Sets 2, Shards 12", st)
+	}
+
+	if !m.Remove("users") || m.Remove("users") {
+		t.Error("incorrect removal")
+	}
+	if err := users.Close(); err != ErrClosed {
+		t.Errorf("removed cache was not closed: got: %v expected: %v", err, ErrClosed)
+	}
+
+	if err := m.Close(); err != nil {
+		t.Errorf("incorrect close error: got: %v expected: %v", err, nil)
+	}
+	if err := sessions.Close(); err != ErrClosed {
+		t.Errorf("cache was not closed: got: %v expected: %v", err, ErrClosed)
+	}
+	if err := m.Close(); err != ErrClosed {
+		t.Errorf("incorrect second close error: got: %v expected: %v", err, ErrClosed)
+	}
+	late := m.Cache("late")
+	late.Set(IntKey(1), 1, time.Hour)
+	if _, ok := late.Get(IntKey(1)); ok {
+		t.Error("cache created after Close is open")
+	}
+	if _, ok := m.Get("late"); ok {
+		t.Error("cache created after Close was registered")
+	}
+}
@@ -0,0 +1,325 @@
+// Package mcserver exposes a Cache over the memcached text protocol.
+//
+// Supported commands: get, gets, set, delete, touch, flush_all, version and quit.
+// gets replies carry the version of the record as cas unique, see
+// ttlcache.Cache.Version; the cas command itself isn't supported.
+// Keys are hashed with ttlcache.StringKey, so Go code sharing the cache
+// reads the same records with the same key strings.
+package mcserver
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	ttlcache "github.com/loicalleyne/ttlswisscache"
+	"github.com/loicalleyne/ttlswisscache/internal/serve"
+)
+
+const (
+	maxKeyLen = 250
+	// Expiration times above this value are absolute Unix timestamps.
+	maxRelativeExptime = 60 * 60 * 24 * 30
+	maxValueLen        = 1 << 20
+)
+
+// ErrServerClosed is returned by Serve after Close.
+var ErrServerClosed = serve.ErrClosed
+
+// Item is stored for values set with non-zero client flags.
+// Values set with zero flags are stored as plain []byte.
+type Item struct {
+	Value []byte
+	Flags uint32
+}
+
+// Server serves a Cache over the memcached text protocol.
+type Server struct {
+	cache      *ttlcache.Cache
+	defaultTTL time.Duration
+	group      serve.Group
+
+	mu    sync.Mutex
+	flush *time.Timer
+
+	// Codec encodes values that are neither []byte, string nor Item for get replies.
+	Codec ttlcache.Codec
+}
+
+// New creates a server for the cache.
+// defaultTTL is applied to records stored with a zero expiration time.
+func New(cache *ttlcache.Cache, defaultTTL time.Duration) *Server {
+	return &Server{
+		cache:      cache,
+		defaultTTL: defaultTTL,
+		Codec:      ttlcache.MsgpackCodec{},
+	}
+}
+
+// ListenAndServe listens on the TCP address and serves connections.
+func (s *Server) ListenAndServe(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(l)
+}
+
+// Serve accepts connections on the listener until Close is called.
+func (s *Server) Serve(l net.Listener) error {
+	return s.group.Serve(l, s.serveConn)
+}
+
+// Close stops the listeners, closes active connections, cancels delayed
+// flush_all commands and waits for connection handlers to return.
+// The cache is left open.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	if s.flush != nil {
+		s.flush.Stop()
+	}
+	s.mu.Unlock()
+	return s.group.Close()
+}
+
+func (s *Server) serveConn(conn net.Conn) {
+	r := bufio.NewReader(conn)
+	w := bufio.NewWriter(conn)
+	for {
+		line, err := r.ReadSlice('\n')
+		if err != nil {
+			if err == bufio.ErrBufferFull {
+				w.WriteString("CLIENT_ERROR line too long\r\n")
+				w.Flush()
+			}
+			return
+		}
+		fields := bytes.Fields(line)
+		if len(fields) == 0 {
+			w.WriteString("ERROR\r\n")
+		} else if quit := s.exec(r, w, fields); quit {
+			return
+		}
+		if r.Buffered() == 0 {
+			if err := w.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// exec runs a single command and reports whether the connection should be closed.
+// fields point into the read buffer and are only valid until the next read.
+func (s *Server) exec(r *bufio.Reader, w *bufio.Writer, fields [][]byte) (quit bool) {
+	args := fields[1:]
+	switch string(fields[0]) {
+	case "get", "gets":
+		if len(args) == 0 {
+			w.WriteString("ERROR\r\n")
+			return false
+		}
+		cas := string(fields[0]) == "gets"
+		for _, k := range args {
+			s.get(w, k, cas)
+		}
+		w.WriteString("END\r\n")
+	case "set":
+		return s.set(r, w, args)
+	case "delete":
+		if len(args) < 1 || len(args) > 2 {
+			w.WriteString("ERROR\r\n")
+			return false
+		}
+		ok := s.cache.Remove(ttlcache.BytesKey(args[0]))
+		reply(w, noreply(args[1:]), ok, "DELETED\r\n", "NOT_FOUND\r\n")
+	case "touch":
+		if len(args) < 2 || len(args) > 3 {
+			w.WriteString("ERROR\r\n")
+			return false
+		}
+		exptime, err := strconv.ParseInt(string(args[1]), 10, 64)
+		if err != nil {
+			w.WriteString("CLIENT_ERROR invalid exptime argument\r\n")
+			return false
+		}
+		key := ttlcache.BytesKey(args[0])
+		var ok bool
+		if ttl, live := s.ttl(exptime); live {
+			ok = s.cache.Expire(key, ttl)
+		} else {
+			ok = s.cache.Remove(key)
+		}
+		reply(w, noreply(args[2:]), ok, "TOUCHED\r\n", "NOT_FOUND\r\n")
+	case "flush_all":
+		var delay int64
+		if len(args) > 0 && !noreply(args[:1]) {
+			var err error
+			if delay, err = strconv.ParseInt(string(args[0]), 10, 64); err != nil {
+				w.WriteString("CLIENT_ERROR invalid delay argument\r\n")
+				return false
+			}
+			args = args[1:]
+		}
+		s.flushAll(time.Duration(delay) * time.Second)
+		reply(w, noreply(args), true, "OK\r\n", "")
+	case "version":
+		w.WriteString("VERSION ttlswisscache\r\n")
+	case "quit":
+		return true
+	default:
+		w.WriteString("ERROR\r\n")
+	}
+	return false
+}
+
+// get writes the value of k, with its version if cas is true.
+func (s *Server) get(w *bufio.Writer, k []byte, cas bool) {
+	var (
+		v       interface{}
+		version uint64
+		ok      bool
+	)
+	if cas {
+		var r ttlcache.Result
+		v, version, r = s.cache.GetIfChanged(ttlcache.BytesKey(k), 0)
+		ok = r == ttlcache.Hit
+	} else {
+		v, ok = s.cache.Get(ttlcache.BytesKey(k))
+	}
+	if !ok {
+		return
+	}
+
+	var (
+		data  []byte
+		flags uint32
+	)
+	switch v := v.(type) {
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	case Item:
+		data, flags = v.Value, v.Flags
+	default:
+		var err error
+		if data, err = s.Codec.Marshal(v); err != nil {
+			w.WriteString("SERVER_ERROR encode value\r\n")
+			return
+		}
+	}
+
+	w.WriteString("VALUE ")
+	w.Write(k)
+	w.WriteByte(' ')
+	w.WriteString(strconv.FormatUint(uint64(flags), 10))
+	w.WriteByte(' ')
+	w.WriteString(strconv.Itoa(len(data)))
+	if cas {
+		w.WriteByte(' ')
+		w.WriteString(strconv.FormatUint(version, 10))
+	}
+	w.WriteString("\r\n")
+	w.Write(data)
+	w.WriteString("\r\n")
+}
+
+// set handles "set <key> <flags> <exptime> <bytes> [noreply]" followed by the data block.
+func (s *Server) set(r *bufio.Reader, w *bufio.Writer, args [][]byte) (quit bool) {
+	if len(args) < 4 || len(args) > 5 {
+		w.WriteString("ERROR\r\n")
+		return false
+	}
+	flags, err1 := strconv.ParseUint(string(args[1]), 10, 32)
+	exptime, err2 := strconv.ParseInt(string(args[2]), 10, 64)
+	size, err3 := strconv.Atoi(string(args[3]))
+	if err1 != nil || err2 != nil || err3 != nil || size < 0 {
+		w.WriteString("CLIENT_ERROR bad command line format\r\n")
+		return false
+	}
+	if size > maxValueLen {
+		// The data block can't be skipped reliably, drop the connection like memcached does.
+		w.WriteString("SERVER_ERROR object too large for cache\r\n")
+		w.Flush()
+		return true
+	}
+	if len(args[0]) > maxKeyLen {
+		w.WriteString("CLIENT_ERROR key too long\r\n")
+		return false
+	}
+	// The arguments point into the read buffer which is overwritten by reading the data block.
+	key := ttlcache.BytesKey(args[0])
+	quiet := noreply(args[4:])
+
+	data := make([]byte, size+2)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return true
+	}
+	if !bytes.HasSuffix(data, []byte("\r\n")) {
+		w.WriteString("CLIENT_ERROR bad data chunk\r\n")
+		return false
+	}
+	data = data[:size]
+
+	var value interface{} = data
+	if flags != 0 {
+		value = Item{Value: data, Flags: uint32(flags)}
+	}
+	stored := true
+	if ttl, live := s.ttl(exptime); live {
+		_, stored = s.cache.SetChecked(key, value, ttl)
+	} else {
+		s.cache.Delete(key)
+	}
+	reply(w, quiet, stored, "STORED\r\n", "NOT_STORED\r\n")
+	return false
+}
+
+// ttl converts a memcached expiration time to a ttl.
+// The second returned variable is false when the record is already expired.
+func (s *Server) ttl(exptime int64) (time.Duration, bool) {
+	switch {
+	case exptime == 0:
+		return s.defaultTTL, true
+	case exptime < 0:
+		return 0, false
+	case exptime > maxRelativeExptime:
+		ttl := time.Until(time.Unix(exptime, 0))
+		return ttl, ttl > 0
+	default:
+		return time.Duration(exptime) * time.Second, true
+	}
+}
+
+func (s *Server) flushAll(delay time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.flush != nil {
+		s.flush.Stop()
+		s.flush = nil
+	}
+	if delay <= 0 {
+		s.cache.Clear()
+		return
+	}
+	s.flush = time.AfterFunc(delay, s.cache.Clear)
+}
+
+func noreply(args [][]byte) bool {
+	return len(args) == 1 && string(args[0]) == "noreply"
+}
+
+func reply(w *bufio.Writer, quiet, ok bool, success, failure string) {
+	if quiet {
+		return
+	}
+	if ok {
+		w.WriteString(success)
+	} else {
+		w.WriteString(failure)
+	}
+}
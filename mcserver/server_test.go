@@ -0,0 +1,148 @@
+package mcserver
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	ttlcache "github.com/loicalleyne/ttlswisscache"
+)
+
+type client struct {
+	t    *testing.T
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func startServer(t *testing.T, opts ...ttlcache.Option) (*ttlcache.Cache, *client) {
+	t.Helper()
+	cache := ttlcache.New(time.Hour, opts...)
+	srv := New(cache, time.Hour)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go srv.Serve(l)
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		conn.Close()
+		srv.Close()
+		cache.Close()
+	})
+	return cache, &client{t: t, conn: conn, r: bufio.NewReader(conn)}
+}
+
+// do sends the request and reads the number of reply lines.
+func (c *client) do(request string, lines int) string {
+	c.t.Helper()
+	if _, err := c.conn.Write([]byte(request)); err != nil {
+		c.t.Fatal(err)
+	}
+	var b strings.Builder
+	for i := 0; i < lines; i++ {
+		line, err := c.r.ReadString('\n')
+		if err != nil {
+			c.t.Fatal(err)
+		}
+		b.WriteString(line)
+	}
+	return b.String()
+}
+
+func TestServer_Commands(t *testing.T) {
+	cache, c := startServer(t)
+
+	tt := []struct {
+		request  string
+		lines    int
+		expected string
+	}{
+		{request: "get key\r\n", lines: 1, expected: "END\r\n"},
+		{request: "set key 0 60 5\r\nvalue\r\n", lines: 1, expected: "STORED\r\n"},
+		{request: "get key\r\n", lines: 3, expected: "VALUE key 0 5\r\nvalue\r\nEND\r\n"},
+		{request: "set flagged 7 0 2\r\nhi\r\n", lines: 1, expected: "STORED\r\n"},
+		{request: "get key flagged missing\r\n", lines: 5, expected: "VALUE key 0 5\r\nvalue\r\nVALUE flagged 7 2\r\nhi\r\nEND\r\n"},
+		{request: "touch key 120\r\n", lines: 1, expected: "TOUCHED\r\n"},
+		{request: "touch missing 120\r\n", lines: 1, expected: "NOT_FOUND\r\n"},
+		{request: "delete key\r\n", lines: 1, expected: "DELETED\r\n"},
+		{request: "delete key\r\n", lines: 1, expected: "NOT_FOUND\r\n"},
+		{request: "set quiet 0 0 1 noreply\r\nx\r\nget quiet\r\n", lines: 3, expected: "VALUE quiet 0 1\r\nx\r\nEND\r\n"},
+		{request: "flush_all\r\n", lines: 1, expected: "OK\r\n"},
+		{request: "get flagged\r\n", lines: 1, expected: "END\r\n"},
+		{request: "set key 0 0 5\r\ntoo long\r\n", lines: 1, expected: "CLIENT_ERROR bad data chunk\r\n"},
+		{request: "unknown\r\n", lines: 1, expected: "ERROR\r\n"},
+	}
+
+	for _, tc := range tt {
+		if actual := c.do(tc.request, tc.lines); actual != tc.expected {
+			t.Errorf("incorrect reply to %q: got: %q expected: %q", tc.request, actual, tc.expected)
+		}
+	}
+
+	c.do("set shared 0 60 5\r\nvalue\r\n", 1)
+	val, ok := cache.Get(ttlcache.StringKey("shared"))
+	if !ok || string(val.([]byte)) != "value" {
+		t.Errorf("cache missed value set over memcached protocol: got: %v", val)
+	}
+	ttl, _ := cache.TTL(ttlcache.StringKey("shared"))
+	if ttl <= 59*time.Second || ttl > time.Minute {
+		t.Errorf("incorrect ttl: got: %v expected: %v", ttl, time.Minute)
+	}
+}
+
+func TestServer_Gets(t *testing.T) {
+	cache, c := startServer(t)
+	c.do("set key 7 60 5\r\nvalue\r\n", 1)
+	version, _ := cache.Version(ttlcache.StringKey("key"))
+
+	expected := fmt.Sprintf("VALUE key 7 5 %d\r\nvalue\r\nEND\r\n", version)
+	if actual := c.do("gets key missing\r\n", 3); actual != expected {
+		t.Errorf("incorrect reply to gets: got: %q expected: %q", actual, expected)
+	}
+	c.do("set key 7 60 5\r\nother\r\n", 1)
+	expected = fmt.Sprintf("VALUE key 7 5 %d\r\nother\r\nEND\r\n", version+1)
+	if actual := c.do("gets key\r\n", 3); actual != expected {
+		t.Errorf("incorrect reply to gets of an overwritten key: got: %q expected: %q", actual, expected)
+	}
+}
+
+func TestServer_ExpiredSet(t *testing.T) {
+	cache, c := startServer(t)
+	cache.Set(ttlcache.StringKey("key"), []byte("value"), time.Hour)
+
+	if actual := c.do("set key 0 -1 1\r\nx\r\n", 1); actual != "STORED\r\n" {
+		t.Errorf("incorrect reply: got: %q expected: %q", actual, "STORED\r\n")
+	}
+	if _, ok := cache.Get(ttlcache.StringKey("key")); ok {
+		t.Error("record set with negative exptime was not removed")
+	}
+}
+
+func TestServer_NotStored(t *testing.T) {
+	_, c := startServer(t, ttlcache.WithMaxValueSize(4, ttlcache.RejectOversized))
+
+	if actual := c.do("set key 0 0 8\r\ntoo long\r\n", 1); actual != "NOT_STORED\r\n" {
+		t.Errorf("incorrect reply: got: %q expected: %q", actual, "NOT_STORED\r\n")
+	}
+	if actual := c.do("set key 0 0 2\r\nok\r\n", 1); actual != "STORED\r\n" {
+		t.Errorf("incorrect reply: got: %q expected: %q", actual, "STORED\r\n")
+	}
+}
+
+func TestServer_CoalescedSet(t *testing.T) {
+	_, c := startServer(t, ttlcache.WithSetCoalescing(time.Hour))
+
+	for i := 0; i < 2; i++ {
+		if actual := c.do("set key 0 0 2\r\nok\r\n", 1); actual != "STORED\r\n" {
+			t.Errorf("incorrect reply: got: %q expected: %q", actual, "STORED\r\n")
+		}
+	}
+}
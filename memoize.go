@@ -0,0 +1,90 @@
+package ttlswisscache
+
+import (
+	"context"
+	"fmt"
+	"hash/maphash"
+	"sync"
+	"time"
+)
+
+// Memoize returns fn caching its results in c for ttl. Concurrent calls with
+// the same key while fn runs wait for its result instead of calling it again,
+// a caller whose context is done returns the context error. Errors are not cached.
+// fn is passed a loader context of the call starting it, see WithLoaderTimeout.
+//
+// Keys are hashed with a seed of their own per Memoize call and stored with
+// their original value, see NewKeyed, so functions memoized in the same cache
+// neither collide nor see each other's results.
+func Memoize[K comparable, V any](c *Cache, ttl time.Duration, fn func(context.Context, K) (V, error)) func(context.Context, K) (V, error) {
+	seed := maphash.MakeSeed()
+	keys := NewKeyed(c, func(key K) uint64 {
+		return maphash.Comparable(seed, key)
+	})
+	var flights flightGroup[K, V]
+	return func(ctx context.Context, key K) (V, error) {
+		if v, ok := keys.Get(key); ok {
+			value, _ := v.(V)
+			return value, nil
+		}
+		return flights.do(ctx, key, func() (V, error) {
+			ctx, cancel := c.loaderContext(context.WithoutCancel(ctx))
+			defer cancel()
+			value, err := fn(ctx, key)
+			if err == nil {
+				keys.Set(key, value, ttl)
+			}
+			return value, err
+		})
+	}
+}
+
+// flightGroup runs a single call per key at a time.
+type flightGroup[K comparable, V any] struct {
+	mu    sync.Mutex
+	calls map[K]*flight[V]
+}
+
+type flight[V any] struct {
+	done  chan struct{}
+	value V
+	err   error
+}
+
+// do runs fn for key unless a call for key is running, and waits for its result.
+// fn runs in a goroutine of its own, so every caller, including the one
+// starting it, stops waiting when its context is done. A panic of fn is
+// returned as an error to the callers.
+func (g *flightGroup[K, V]) do(ctx context.Context, key K, fn func() (V, error)) (V, error) {
+	g.mu.Lock()
+	f, ok := g.calls[key]
+	if !ok {
+		if g.calls == nil {
+			g.calls = make(map[K]*flight[V])
+		}
+		f = &flight[V]{done: make(chan struct{})}
+		g.calls[key] = f
+		go g.run(key, f, fn)
+	}
+	g.mu.Unlock()
+	select {
+	case <-f.done:
+		return f.value, f.err
+	case <-ctx.Done():
+		var zero V
+		return zero, ctx.Err()
+	}
+}
+
+func (g *flightGroup[K, V]) run(key K, f *flight[V], fn func() (V, error)) {
+	defer func() {
+		if r := recover(); r != nil {
+			f.err = fmt.Errorf("ttlswisscache: loader panicked: %v", r)
+		}
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+		close(f.done)
+	}()
+	f.value, f.err = fn()
+}
@@ -0,0 +1,80 @@
+package ttlswisscache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMemoize(t *testing.T) {
+	c := New(0)
+	defer c.Close()
+	var calls atomic.Int32
+	errOdd := errors.New("odd")
+	square := Memoize(c, time.Hour, func(_ context.Context, n int) (int, error) {
+		calls.Add(1)
+		if n%2 == 1 {
+			return 0, errOdd
+		}
+		return n * n, nil
+	})
+	double := Memoize(c, time.Hour, func(_ context.Context, n int) (int, error) {
+		return 2 * n, nil
+	})
+
+	for i := 0; i < 3; i++ {
+		if v, err := square(context.Background(), 4); v != 16 || err != nil {
+			t.Errorf("incorrect result: got: %v, %v expected: 16, <nil>", v, err)
+		}
+	}
+	if v, _ := double(context.Background(), 4); v != 8 {
+		t.Errorf("incorrect result of another function: got: %v expected: %v", v, 8)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := square(context.Background(), 3); err != errOdd {
+			t.Errorf("incorrect error: got: %v expected: %v", err, errOdd)
+		}
+	}
+	if n := calls.Load(); n != 3 {
+		t.Errorf("incorrect number of calls: got: %d expected: %d", n, 3)
+	}
+}
+
+func TestMemoize_Concurrent(t *testing.T) {
+	c := New(0)
+	defer c.Close()
+	var calls atomic.Int32
+	release := make(chan struct{})
+	slow := Memoize(c, time.Hour, func(_ context.Context, key string) (string, error) {
+		calls.Add(1)
+		<-release
+		return key + "!", nil
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if v, err := slow(context.Background(), "key"); v != "key!" || err != nil {
+				t.Errorf("incorrect result: got: %v, %v expected: key!, <nil>", v, err)
+			}
+		}()
+	}
+	for deadline := time.Now().Add(5 * time.Second); calls.Load() == 0 && time.Now().Before(deadline); {
+		time.Sleep(time.Millisecond)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := slow(ctx, "key"); err != context.Canceled {
+		t.Errorf("incorrect error of a cancelled waiter: got: %v expected: %v", err, context.Canceled)
+	}
+	close(release)
+	wg.Wait()
+	if n := calls.Load(); n != 1 {
+		t.Errorf("incorrect number of calls: got: %d expected: %d", n, 1)
+	}
+}
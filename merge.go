@@ -0,0 +1,70 @@
+package ttlswisscache
+
+// ConflictPolicy resolves keys present in both caches during Merge.
+type ConflictPolicy int
+
+const (
+	// KeepNewestDeadline keeps the record that expires later.
+	KeepNewestDeadline ConflictPolicy = iota
+	// KeepExisting keeps the record already stored in the destination cache.
+	KeepExisting
+	// Overwrite replaces the destination record with the merged one.
+	Overwrite
+)
+
+// Merge copies live records from other into the cache keeping their remaining TTLs.
+// Conflicting keys are resolved according to policy.
+// It returns the number of records written to the cache.
+// other is read one shard at a time, so both caches stay available during the merge.
+// Values are copied with the WithCloner function of the cache, if any, like Set does,
+// and passed to WithRetainer otherwise.
+func (c *Cache) Merge(other *Cache, policy ConflictPolicy) int {
+	if other == c {
+		return 0
+	}
+
+	buf := c.bufs.getEntries()
+	defer c.bufs.putEntries(buf)
+	entries := *buf
+	n := 0
+	for _, src := range other.shards.list {
+		entries = src.appendLive(entries[:0], other.clock.unixNano())
+		for i := range entries {
+			entries[i].item.value = c.adopt(c.clone(entries[i].item.value))
+			if c.merge(entries[i].key, entries[i].item, policy) {
+				n++
+			}
+		}
+		clearEntries(entries)
+	}
+	*buf = entries
+	return n
+}
+
+// merge stores the item unless the policy keeps the existing record.
+func (c *Cache) merge(key uint64, it item, policy ConflictPolicy) bool {
+	s := c.shards.get(key)
+	s.Lock()
+	defer s.Unlock()
+
+	if c.closing.Load() || c.protected(s, key) {
+		return false
+	}
+	if existing, ok := s.get(key); ok {
+		switch policy {
+		case KeepExisting:
+			return false
+		case KeepNewestDeadline:
+			if existing.deadline >= it.deadline {
+				return false
+			}
+		}
+	}
+	s.put(key, it)
+	if c.opts.cloner == nil {
+		s.retain(key, it.value)
+	}
+	c.subs.publish(OpSet, key, it)
+	s.stats.sets.Add(1)
+	return true
+}
@@ -0,0 +1,83 @@
+package ttlswisscache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCache_Merge(t *testing.T) {
+	tt := []struct {
+		name     string
+		policy   ConflictPolicy
+		expected string
+		written  int
+	}{
+		{name: "KeepNewestDeadline", policy: KeepNewestDeadline, expected: "new", written: 2},
+		{name: "KeepExisting", policy: KeepExisting, expected: "old", written: 1},
+		{name: "Overwrite", policy: Overwrite, expected: "new", written: 2},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			dst := New(time.Hour)
+			defer dst.Close()
+			dst.Set(IntKey(1), "old", time.Minute)
+
+			src := New(time.Hour)
+			defer src.Close()
+			src.Set(IntKey(1), "new", time.Hour)
+			src.Set(IntKey(2), "new", time.Hour)
+			src.Set(IntKey(3), "expired", -time.Second)
+
+			written := dst.Merge(src, tc.policy)
+			if written != tc.written {
+				t.Errorf("incorrect number of written records: got: %d expected: %d", written, tc.written)
+			}
+
+			val, _ := dst.Get(IntKey(1))
+			if val != tc.expected {
+				t.Errorf("incorrect value: got: %v expected: %v", val, tc.expected)
+			}
+			if _, ok := dst.Get(IntKey(2)); !ok {
+				t.Error("storage missed merged value")
+			}
+			if _, ok := dst.Get(IntKey(3)); ok {
+				t.Error("expired record was merged")
+			}
+		})
+	}
+}
+
+func TestCache_MergeKeepsOlderOnNewestDeadline(t *testing.T) {
+	dst := New(time.Hour)
+	defer dst.Close()
+	dst.Set(IntKey(1), "old", time.Hour)
+
+	src := New(time.Hour)
+	defer src.Close()
+	src.Set(IntKey(1), "new", time.Minute)
+
+	if written := dst.Merge(src, KeepNewestDeadline); written != 0 {
+		t.Errorf("incorrect number of written records: got: %d expected: %d", written, 0)
+	}
+	if val, _ := dst.Get(IntKey(1)); val != "old" {
+		t.Errorf("incorrect value: got: %v expected: %v", val, "old")
+	}
+}
+
+func TestCache_Merge_Cloner(t *testing.T) {
+	dst := New(0, WithCloner(func(v interface{}) interface{} {
+		return append([]int(nil), v.([]int)...)
+	}))
+	defer dst.Close()
+	src := New(0)
+	defer src.Close()
+	value := []int{1, 2, 3}
+	src.Set(IntKey(1), value, time.Hour)
+
+	dst.Merge(src, Overwrite)
+	value[0] = 100
+	if got, _ := dst.Get(IntKey(1)); got.([]int)[0] != 1 {
+		t.Errorf("merged value is shared with the other cache: got: %v expected: %v", got, []int{1, 2, 3})
+	}
+}
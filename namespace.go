@@ -0,0 +1,199 @@
+package ttlswisscache
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// generationMix spreads namespace generations over the key bits.
+const generationMix = 0x9e3779b97f4a7c15
+
+// Namespace is a view of a cache with its own key space.
+// Namespaces of a cache share its shards and its cleanup manager, so many
+// logical caches cost a single set of them.
+//
+// Keys are mixed with a seed derived from the name. The mixing is a bijection,
+// so keys of a namespace never collide with each other; keys of different
+// namespaces, or of the cache itself, collide with the probability of hash
+// collisions of 64-bit keys. Events and statistics of the cache report the mixed
+// keys, see Key.
+//
+// The generation of the namespace is mixed in as well, so InvalidateNamespace
+// makes all its records unreachable at once.
+type Namespace struct {
+	cache *Cache
+	name  string
+	seed  uint64
+	state *namespaceState
+}
+
+// namespaces holds the state of the namespaces of a cache by name.
+type namespaces struct {
+	mu     sync.Mutex
+	states map[string]*namespaceState
+	stale  atomic.Bool // Set by InvalidateNamespace until the cleanup removes the records.
+}
+
+// namespaceState is shared by the views of a namespace: its generation, quota
+// and statistics, see SetQuota.
+type namespaceState struct {
+	name  string
+	gen   atomic.Uint64
+	quota atomic.Pointer[NamespaceQuota] // nil without SetQuota
+	namespaceCounters
+	mu    sync.Mutex
+	queue []namespacedRecord // Records by age, oldest first, some of them gone.
+}
+
+// Namespace returns the view of the cache named name.
+// Views with the same name share their records, generation, quota and statistics.
+func (c *Cache) Namespace(name string) *Namespace {
+	return &Namespace{cache: c, name: name, seed: StringKey(name), state: c.namespaceState(name)}
+}
+
+// namespaceState returns the state of the namespace named name.
+func (c *Cache) namespaceState(name string) *namespaceState {
+	c.namespaces.mu.Lock()
+	defer c.namespaces.mu.Unlock()
+	if c.namespaces.states == nil {
+		c.namespaces.states = make(map[string]*namespaceState)
+	}
+	state, ok := c.namespaces.states[name]
+	if !ok {
+		state = &namespaceState{name: name}
+		c.namespaces.states[name] = state
+	}
+	return state
+}
+
+// InvalidateNamespace drops all records of the namespace in O(1): the generation
+// of the namespace is incremented, so records written under older generations
+// are not found anymore. The cleanup manager removes them on its next sweep,
+// reported like Delete; without it they take memory until their ttl runs out.
+func (n *Namespace) InvalidateNamespace() {
+	n.state.gen.Add(1)
+	n.cache.namespaces.stale.Store(true)
+}
+
+// deleteStale removes the records written by Namespace.Set under an older
+// generation of their namespace and returns their number, see
+// InvalidateNamespace. Every shard is read locked to find them in turn, then
+// locked to remove them.
+func (c *Cache) deleteStale() int {
+	if !c.namespaces.stale.Swap(false) {
+		return 0
+	}
+	n := 0
+	var keys []uint64
+	for _, s := range c.shards.list {
+		keys = keys[:0]
+		s.RLock()
+		for i, v := range s.values {
+			if nv, ok := v.(*namespacedValue); ok && nv.stale() {
+				keys = append(keys, s.keys[i])
+			}
+		}
+		s.RUnlock()
+		if len(keys) == 0 {
+			continue
+		}
+
+		removed := 0
+		s.Lock()
+		for _, key := range keys {
+			// The key may have been set again since the scan.
+			i, ok := s.index.Get(key)
+			if !ok {
+				continue
+			}
+			if nv, ok := s.values[i].(*namespacedValue); !ok || !nv.stale() {
+				continue
+			}
+			it, _ := s.delete(key)
+			c.subs.publish(OpDelete, key, it)
+			removed++
+		}
+		s.Unlock()
+		s.stats.deletes.Add(uint64(removed))
+		n += removed
+	}
+	c.cascade()
+	return n
+}
+
+// Name returns the name of the namespace.
+func (n *Namespace) Name() string {
+	return n.name
+}
+
+// Key returns the key of the underlying cache storing key in the current generation.
+func (n *Namespace) Key(key uint64) uint64 {
+	return n.keyAt(key, n.state.gen.Load())
+}
+
+// keyAt returns the key of the underlying cache storing key in generation gen.
+func (n *Namespace) keyAt(key, gen uint64) uint64 {
+	return hashKey(key ^ n.seed ^ gen*generationMix)
+}
+
+// Get returns stored record like Cache.Get.
+func (n *Namespace) Get(key uint64) (interface{}, bool) {
+	value, ok := n.cache.Get(n.Key(key))
+	if ok {
+		n.state.hits.Add(1)
+	} else {
+		n.state.misses.Add(1)
+	}
+	return value, ok
+}
+
+// GetStale returns the value of key even if it is outdated, see Cache.GetStale.
+func (n *Namespace) GetStale(key uint64) (interface{}, time.Duration, bool) {
+	return n.cache.GetStale(n.Key(key))
+}
+
+// Set adds value to the namespace with given ttl like Cache.Set, then evicts
+// the oldest records of the namespace while it exceeds its quota. The value is
+// sized by the Sizer for the quota and Stats.
+func (n *Namespace) Set(key uint64, value interface{}, ttl time.Duration) {
+	c := n.cache
+	gen := n.state.gen.Load()
+	key = n.keyAt(key, gen)
+	value, ok := c.limit(key, value)
+	if !ok {
+		c.reject(key)
+		return
+	}
+	it := item{
+		deadline: c.clock.unixNano() + int64(ttl),
+		value:    &namespacedValue{value: c.clone(value), state: n.state, gen: gen, cost: max(c.size(key, value), 0), seq: n.state.seq.Add(1)},
+	}
+	if c.sets != nil {
+		c.sets.set(key, it)
+	} else {
+		c.store(key, it)
+	}
+	n.state.sets.Add(1)
+	c.enforceQuota(n.state)
+}
+
+// TTL returns the remaining time to live of the stored record like Cache.TTL.
+func (n *Namespace) TTL(key uint64) (time.Duration, bool) {
+	return n.cache.TTL(n.Key(key))
+}
+
+// Expire sets a new ttl for the stored record like Cache.Expire.
+func (n *Namespace) Expire(key uint64, ttl time.Duration) bool {
+	return n.cache.Expire(n.Key(key), ttl)
+}
+
+// GetAndDelete removes record from the namespace and returns its value like Cache.GetAndDelete.
+func (n *Namespace) GetAndDelete(key uint64) (interface{}, bool) {
+	return n.cache.GetAndDelete(n.Key(key))
+}
+
+// Delete removes record from the namespace.
+func (n *Namespace) Delete(key uint64) {
+	n.cache.Delete(n.Key(key))
+}
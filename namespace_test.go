@@ -0,0 +1,92 @@
+package ttlswisscache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNamespace(t *testing.T) {
+	c := New(0)
+	defer c.Close()
+	users, orders := c.Namespace("users"), c.Namespace("orders")
+
+	users.Set(IntKey(1), "alice", time.Hour)
+	orders.Set(IntKey(1), "order", time.Hour)
+
+	tt := []struct {
+		ns       *Namespace
+		expected interface{}
+	}{
+		{ns: users, expected: "alice"},
+		{ns: orders, expected: "order"},
+		{ns: c.Namespace("users"), expected: "alice"},
+	}
+	for _, tc := range tt {
+		if v, ok := tc.ns.Get(IntKey(1)); !ok || v != tc.expected {
+			t.Errorf("incorrect value in %s: got: %v, %v expected: %v, true", tc.ns.Name(), v, ok, tc.expected)
+		}
+	}
+	if _, ok := c.Get(IntKey(1)); ok {
+		t.Error("namespaced record is visible in the cache")
+	}
+	if v, ok := c.Get(users.Key(IntKey(1))); !ok || v != "alice" {
+		t.Errorf("incorrect value of the mixed key: got: %v, %v expected: alice, true", v, ok)
+	}
+
+	users.Delete(IntKey(1))
+	if _, ok := users.Get(IntKey(1)); ok {
+		t.Error("record was not deleted")
+	}
+	if _, ok := orders.Get(IntKey(1)); !ok {
+		t.Error("record of another namespace was deleted")
+	}
+}
+
+func TestNamespace_NoCollisions(t *testing.T) {
+	c := New(0)
+	defer c.Close()
+	ns := c.Namespace("ns")
+	seen := make(map[uint64]struct{})
+	for i := 0; i < 100000; i++ {
+		k := ns.Key(IntKey(i))
+		if _, ok := seen[k]; ok {
+			t.Fatalf("keys collide: %d", i)
+		}
+		seen[k] = struct{}{}
+	}
+}
+
+func TestNamespace_InvalidateNamespace(t *testing.T) {
+	c := New(0)
+	defer c.Close()
+	users := c.Namespace("users")
+	orders := c.Namespace("orders")
+	users.Set(IntKey(1), "alice", time.Hour)
+	orders.Set(IntKey(1), "order", time.Hour)
+
+	c.Namespace("users").InvalidateNamespace()
+	if v, ok := users.Get(IntKey(1)); ok {
+		t.Errorf("incorrect value after invalidation: got: %v expected: none", v)
+	}
+	if _, ok := orders.Get(IntKey(1)); !ok {
+		t.Error("record of another namespace was invalidated")
+	}
+
+	users.Set(IntKey(1), "bob", time.Hour)
+	if v, ok := c.Namespace("users").Get(IntKey(1)); !ok || v != "bob" {
+		t.Errorf("incorrect value: got: %v, %v expected: bob, true", v, ok)
+	}
+	if n := c.Stats().Entries; n != 3 {
+		t.Errorf("incorrect entries: got: %d expected: %d", n, 3)
+	}
+	c.sweep(t.Context())
+	if n := c.Stats().Entries; n != 2 {
+		t.Errorf("incorrect entries after the cleanup: got: %d expected: %d", n, 2)
+	}
+	if n := users.Stats().Entries; n != 1 {
+		t.Errorf("incorrect entries of the namespace after the cleanup: got: %d expected: %d", n, 1)
+	}
+	if v, ok := users.Get(IntKey(1)); !ok || v != "bob" {
+		t.Errorf("incorrect value after the cleanup: got: %v, %v expected: bob, true", v, ok)
+	}
+}
@@ -0,0 +1,62 @@
+package ttlswisscache
+
+import "time"
+
+// notFoundValue marks a record caching a missing key, see SetNotFound.
+type notFoundValue struct{}
+
+// Result is the outcome of Lookup and GetIfChanged.
+type Result uint8
+
+const (
+	// Miss reports that the cache knows nothing about the key.
+	Miss Result = iota
+	// Hit reports a stored value.
+	Hit
+	// NotFound reports a key cached as missing by SetNotFound.
+	NotFound
+	// NotModified reports a record unchanged since the version, see GetIfChanged.
+	NotModified
+)
+
+func (r Result) String() string {
+	switch r {
+	case Miss:
+		return "miss"
+	case Hit:
+		return "hit"
+	case NotFound:
+		return "not found"
+	case NotModified:
+		return "not modified"
+	default:
+		return "unknown"
+	}
+}
+
+// SetNotFound caches that key has no value for ttl, typically shorter than the
+// ttl of values, so loaders don't query their source for it again meanwhile.
+// Lookup reports it as NotFound, Get and the other reads as missing.
+// Snapshots and CDC streams skip such records, events report them with a nil
+// value, which Apply caches as missing again.
+func (c *Cache) SetNotFound(key uint64, ttl time.Duration) {
+	c.store(key, item{deadline: c.clock.unixNano() + int64(ttl), value: notFoundValue{}})
+}
+
+// Lookup returns the stored value like Get, with NotFound for keys cached
+// by SetNotFound. NotFound counts as a hit in the statistics.
+func (c *Cache) Lookup(key uint64) (interface{}, Result) {
+	s := c.shards.get(key)
+	s.RLock()
+	it, ok := s.get(key)
+	s.RUnlock()
+	if _, missing := it.value.(notFoundValue); ok && missing && !c.quarantined(it) {
+		s.stats.hits.Add(1)
+		return nil, NotFound
+	}
+	value, ok := c.found(s, key, it, ok)
+	if !ok {
+		return nil, Miss
+	}
+	return value, Hit
+}
@@ -0,0 +1,48 @@
+package ttlswisscache
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestCache_SetNotFound(t *testing.T) {
+	c := New(0, WithCloner(func(v interface{}) interface{} { return v }))
+	defer c.Close()
+	c.Set(IntKey(1), 1, time.Hour)
+	c.SetNotFound(IntKey(2), time.Minute)
+
+	if v, r := c.Lookup(IntKey(1)); r != Hit || v != 1 {
+		t.Errorf("incorrect lookup: got: %v, %v expected: 1, %v", v, r, Hit)
+	}
+	if v, r := c.Lookup(IntKey(2)); r != NotFound || v != nil {
+		t.Errorf("incorrect lookup: got: %v, %v expected: <nil>, %v", v, r, NotFound)
+	}
+	if v, r := c.Lookup(IntKey(3)); r != Miss || v != nil {
+		t.Errorf("incorrect lookup: got: %v, %v expected: <nil>, %v", v, r, Miss)
+	}
+	if v, ok := c.Get(IntKey(2)); ok {
+		t.Errorf("incorrect value of a missing key: got: %v expected: none", v)
+	}
+	if _, ok := c.TTL(IntKey(2)); ok {
+		t.Error("incorrect ttl existence of a missing key")
+	}
+	if st := c.Stats(); st.Hits != 2 || st.Misses != 2 {
+		t.Errorf("incorrect stats: got: %+v expected: Hits 2, Misses 2", st)
+	}
+
+	var buf bytes.Buffer
+	if err := c.Snapshot(&buf, GobCodec{}); err != nil {
+		t.Fatal(err)
+	}
+	d := New(0)
+	defer d.Close()
+	if n, err := d.Restore(&buf, GobCodec{}); err != nil || n != 1 {
+		t.Errorf("incorrect restored records: got: %d, %v expected: 1, <nil>", n, err)
+	}
+
+	c.Set(IntKey(2), 2, time.Hour)
+	if v, r := c.Lookup(IntKey(2)); r != Hit || v != 2 {
+		t.Errorf("incorrect lookup of a found key: got: %v, %v expected: 2, %v", v, r, Hit)
+	}
+}
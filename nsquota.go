@@ -0,0 +1,208 @@
+package ttlswisscache
+
+import "sync/atomic"
+
+// NamespaceQuota bounds the records of a namespace, see Namespace.SetQuota.
+// Zero fields don't bound.
+type NamespaceQuota struct {
+	MaxEntries int   // Records, outdated ones included.
+	MaxCost    int64 // Sum of the sizes of the values estimated by the Sizer, unknown ones count as 0.
+}
+
+// NamespaceStats are the statistics of a namespace, see Namespace.Stats.
+type NamespaceStats struct {
+	Entries int64 // Records, outdated ones included.
+	Cost    int64 // Sum of the sizes of the values estimated by the Sizer.
+	Hits    uint64
+	Misses  uint64
+	Sets    uint64
+	Evicted uint64 // Records evicted by the quota or the WithEviction policy.
+}
+
+// namespaceCounters are the statistics of a namespace. Entries and cost are
+// updated under the locks of the shards holding its records.
+type namespaceCounters struct {
+	entries atomic.Int64
+	cost    atomic.Int64
+	hits    atomic.Uint64
+	misses  atomic.Uint64
+	sets    atomic.Uint64
+	evicted atomic.Uint64
+	seq     atomic.Uint64 // Numbers the records written, see namespacedRecord.
+}
+
+// namespacedValue is a value stored by Namespace.Set, accounted to its namespace.
+type namespacedValue struct {
+	value interface{}
+	state *namespaceState
+	gen   uint64 // Generation of the namespace the value was written under.
+	cost  int64
+	seq   uint64
+}
+
+// stale reports whether the value was written under an older generation of
+// its namespace, see InvalidateNamespace.
+func (nv *namespacedValue) stale() bool {
+	return nv.gen != nv.state.gen.Load()
+}
+
+// namespacedRecord is a record in the queue of a namespace. It is gone once the
+// key holds another value than the one numbered seq.
+type namespacedRecord struct {
+	key uint64
+	seq uint64
+}
+
+// SetQuota bounds the records of the namespace: once Set makes it exceed q,
+// the oldest records written by Set are evicted, reported with OpEvict, until
+// it is within q again, so a noisy namespace evicts its own records rather than
+// those of the others. A zero quota lifts the bound. The records of the
+// namespace are still subject to WithMaxEntries.
+//
+// Records restored from snapshots or written to the mixed keys directly, e.g.
+// with Cache.Set, aren't accounted to the namespace.
+func (n *Namespace) SetQuota(q NamespaceQuota) {
+	if q == (NamespaceQuota{}) {
+		n.state.quota.Store(nil)
+		return
+	}
+	n.state.quota.Store(&q)
+	n.cache.enforceQuota(n.state)
+}
+
+// Quota returns the quota of the namespace, see SetQuota.
+func (n *Namespace) Quota() NamespaceQuota {
+	if q := n.state.quota.Load(); q != nil {
+		return *q
+	}
+	return NamespaceQuota{}
+}
+
+// Stats returns the statistics of the namespace. Hits and misses are those of
+// Namespace.Get, reads of the mixed keys aren't counted.
+func (n *Namespace) Stats() NamespaceStats {
+	st := n.state
+	return NamespaceStats{
+		Entries: st.entries.Load(),
+		Cost:    st.cost.Load(),
+		Hits:    st.hits.Load(),
+		Misses:  st.misses.Load(),
+		Sets:    st.sets.Load(),
+		Evicted: st.evicted.Load(),
+	}
+}
+
+// over reports whether the namespace exceeds its quota.
+func (st *namespaceState) over() bool {
+	q := st.quota.Load()
+	return q != nil && (q.MaxEntries > 0 && st.entries.Load() > int64(q.MaxEntries) ||
+		q.MaxCost > 0 && st.cost.Load() > q.MaxCost)
+}
+
+// enforceQuota evicts the oldest records of the namespace while it exceeds its
+// quota. No shard lock is taken under the lock of the namespace, which
+// account takes under shard locks.
+func (c *Cache) enforceQuota(st *namespaceState) {
+	for st.over() {
+		st.mu.Lock()
+		if len(st.queue) == 0 {
+			st.mu.Unlock()
+			return
+		}
+		r := st.queue[0]
+		st.queue[0] = namespacedRecord{}
+		st.queue = st.queue[1:]
+		st.mu.Unlock()
+		c.evictNamespaced(st, r)
+	}
+	c.compactQueue(st)
+}
+
+// evictNamespaced evicts the record r of the namespace unless it is gone.
+func (c *Cache) evictNamespaced(st *namespaceState, r namespacedRecord) {
+	s := c.shards.get(r.key)
+	s.Lock()
+	defer s.Unlock()
+	i, ok := s.index.Get(r.key)
+	if !ok {
+		return
+	}
+	if nv, ok := s.values[i].(*namespacedValue); !ok || nv.state != st || nv.seq != r.seq {
+		return
+	}
+	it, _ := s.delete(r.key)
+	s.stats.evicted.Add(1)
+	st.evicted.Add(1)
+	c.subs.publish(OpEvict, r.key, it)
+}
+
+// compactQueue drops the gone records from the queue of the namespace once
+// they outnumber the others, so the queue stays proportional to the records.
+func (c *Cache) compactQueue(st *namespaceState) {
+	st.mu.Lock()
+	if int64(len(st.queue)) <= 2*st.entries.Load()+defaultCapacity {
+		st.mu.Unlock()
+		return
+	}
+	queue := st.queue
+	st.queue = nil
+	st.mu.Unlock()
+
+	kept := queue[:0]
+	for _, r := range queue {
+		s := c.shards.get(r.key)
+		s.RLock()
+		i, ok := s.index.Get(r.key)
+		if ok {
+			nv, isNamespaced := s.values[i].(*namespacedValue)
+			ok = isNamespaced && nv.state == st && nv.seq == r.seq
+		}
+		s.RUnlock()
+		if ok {
+			kept = append(kept, r)
+		}
+	}
+	clear(queue[len(kept):])
+
+	st.mu.Lock()
+	st.queue = append(kept, st.queue...) // Records queued meanwhile are newer.
+	st.mu.Unlock()
+}
+
+// account adds the record of key holding value to its namespace, if any, or
+// removes it. The shard of key must be locked.
+func account(key uint64, value interface{}, add bool) {
+	nv, ok := value.(*namespacedValue)
+	if !ok {
+		return
+	}
+	st := nv.state
+	if !add {
+		st.entries.Add(-1)
+		st.cost.Add(-nv.cost)
+		return
+	}
+	st.entries.Add(1)
+	st.cost.Add(nv.cost)
+	st.mu.Lock()
+	st.queue = append(st.queue, namespacedRecord{key: key, seq: nv.seq})
+	st.mu.Unlock()
+}
+
+// adopt rebinds a value stored by Namespace.Set in another cache to the
+// namespace of the same name of c, see Clone and Merge.
+func (c *Cache) adopt(value interface{}) interface{} {
+	nv, ok := value.(*namespacedValue)
+	if !ok {
+		return value
+	}
+	st := c.namespaceState(nv.state.name)
+	if st == nv.state {
+		return value
+	}
+	nv = &namespacedValue{value: nv.value, state: st, gen: nv.gen, cost: nv.cost, seq: st.seq.Add(1)}
+	if nv.stale() {
+		c.namespaces.stale.Store(true)
+	}
+	return nv
+}
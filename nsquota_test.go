@@ -0,0 +1,108 @@
+package ttlswisscache
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNamespace_SetQuota(t *testing.T) {
+	c := New(0, WithShardCount(4))
+	defer c.Close()
+	noisy, quiet := c.Namespace("noisy"), c.Namespace("quiet")
+	noisy.SetQuota(NamespaceQuota{MaxEntries: 10})
+	for i := 0; i < 10; i++ {
+		quiet.Set(IntKey(i), i, time.Hour)
+	}
+
+	for i := 0; i < 100; i++ {
+		noisy.Set(IntKey(i), i, time.Hour)
+	}
+	for i := 0; i < 100; i++ {
+		_, ok := noisy.Get(IntKey(i))
+		if expected := i >= 90; ok != expected {
+			t.Errorf("incorrect presence of %d: got: %v expected: %v", i, ok, expected)
+		}
+	}
+	for i := 0; i < 10; i++ {
+		if _, ok := quiet.Get(IntKey(i)); !ok {
+			t.Errorf("record of another namespace was evicted: %d", i)
+		}
+	}
+
+	st := noisy.Stats()
+	expected := NamespaceStats{Entries: 10, Hits: 10, Misses: 90, Sets: 100, Evicted: 90}
+	if st != expected {
+		t.Errorf("incorrect stats: got: %+v expected: %+v", st, expected)
+	}
+	if n := c.Stats().Evicted; n != 90 {
+		t.Errorf("incorrect cache eviction stats: got: %d expected: %d", n, 90)
+	}
+	if q := noisy.Quota(); q.MaxEntries != 10 {
+		t.Errorf("incorrect quota: got: %+v expected: %d entries", q, 10)
+	}
+
+	noisy.Delete(IntKey(95))
+	c.Delete(noisy.Key(IntKey(96)))
+	if n := noisy.Stats().Entries; n != 8 {
+		t.Errorf("incorrect number of records after Delete: got: %d expected: %d", n, 8)
+	}
+	noisy.SetQuota(NamespaceQuota{MaxEntries: 5})
+	if n := noisy.Stats().Entries; n != 5 {
+		t.Errorf("incorrect number of records after shrinking the quota: got: %d expected: %d", n, 5)
+	}
+	if _, ok := noisy.Get(IntKey(99)); !ok {
+		t.Error("newest record was evicted")
+	}
+	c.Clear()
+	if n := noisy.Stats().Entries; n != 0 {
+		t.Errorf("incorrect number of records after Clear: got: %d expected: %d", n, 0)
+	}
+}
+
+func TestNamespace_SetQuota_Cost(t *testing.T) {
+	c := New(0)
+	defer c.Close()
+	ns := c.Namespace("blobs")
+	ns.SetQuota(NamespaceQuota{MaxCost: 100})
+	ns.Set(1, strings.Repeat("a", 60), time.Hour)
+	ns.Set(2, strings.Repeat("b", 30), time.Hour)
+	if st := ns.Stats(); st.Cost != 90 || st.Entries != 2 {
+		t.Errorf("incorrect stats: got: %+v expected cost: %d", st, 90)
+	}
+	ns.Set(3, strings.Repeat("c", 30), time.Hour)
+	if _, ok := ns.Get(1); ok {
+		t.Error("oldest record over the cost quota was kept")
+	}
+	if st := ns.Stats(); st.Cost != 60 || st.Evicted != 1 {
+		t.Errorf("incorrect stats: got: %+v expected cost: %d", st, 60)
+	}
+	ns.Set(2, "", time.Hour)
+	if n := ns.Stats().Cost; n != 30 {
+		t.Errorf("incorrect cost after an overwrite: got: %d expected: %d", n, 30)
+	}
+}
+
+func TestNamespace_Queue(t *testing.T) {
+	c := New(0)
+	defer c.Close()
+	ns := c.Namespace("counters")
+	for i := 0; i < 10000; i++ {
+		ns.Set(IntKey(i%10), i, time.Hour)
+	}
+	ns.state.mu.Lock()
+	n := len(ns.state.queue)
+	ns.state.mu.Unlock()
+	if n > 2*10+defaultCapacity+1 {
+		t.Errorf("queue of overwritten records wasn't compacted: got: %d records", n)
+	}
+
+	d := c.Clone()
+	defer d.Close()
+	if st := d.Namespace("counters").Stats(); st.Entries != 10 {
+		t.Errorf("incorrect records of the cloned namespace: got: %d expected: %d", st.Entries, 10)
+	}
+	if st := ns.Stats(); st.Entries != 10 {
+		t.Errorf("cloning changed the records of the namespace: got: %d expected: %d", st.Entries, 10)
+	}
+}
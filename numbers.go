@@ -0,0 +1,230 @@
+package ttlswisscache
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mhmtszr/concurrent-swiss-map/swiss"
+)
+
+// Int64Cache is a key-value storage for int64 values with TTL for each record.
+// Values are stored unboxed, so Set doesn't allocate.
+type Int64Cache struct {
+	numberCache[int64]
+}
+
+// NewInt64Cache creates int64 storage.
+// resolution and opts configure the cache like in New.
+func NewInt64Cache(resolution time.Duration, opts ...Option) *Int64Cache {
+	c := &Int64Cache{}
+	c.init(newOptions(append([]Option{WithResolution(resolution)}, opts...)))
+	return c
+}
+
+// Float64Cache is a key-value storage for float64 values with TTL for each record.
+// Values are stored unboxed, so Set doesn't allocate.
+type Float64Cache struct {
+	numberCache[float64]
+}
+
+// NewFloat64Cache creates float64 storage.
+// resolution and opts configure the cache like in New.
+func NewFloat64Cache(resolution time.Duration, opts ...Option) *Float64Cache {
+	c := &Float64Cache{}
+	c.init(newOptions(append([]Option{WithResolution(resolution)}, opts...)))
+	return c
+}
+
+type number interface {
+	~int64 | ~float64
+}
+
+type numberItem[T number] struct {
+	deadline int64 // Unix nano
+	value    T
+}
+
+type numberShard[T number] struct {
+	sync.RWMutex
+	items *swiss.Map[uint64, numberItem[T]]
+	stats counters
+}
+
+// numberCache implements Int64Cache and Float64Cache.
+type numberCache[T number] struct {
+	done    chan struct{}
+	closed  atomic.Bool // Checked under the shard lock by writes.
+	list    []*numberShard[T]
+	shift   uint
+	hash    func(uint64) uint64
+	clock   *coarseClock
+	cleanup cleanupSwitch
+}
+
+func (c *numberCache[T]) init(o options) {
+	c.done = make(chan struct{})
+	c.list = make([]*numberShard[T], o.shardCount)
+	c.shift = shardShift(o.shardCount)
+	c.hash = o.hasher
+	for i := range c.list {
+		c.list[i] = &numberShard[T]{items: swiss.NewMap[uint64, numberItem[T]](perShardCapacity(o.capacity, o.shardCount))}
+	}
+
+	c.clock = newClock(o)
+	c.cleanup.start(o, c.done, func(context.Context) int { return c.DeleteExpired() }, c.clock.coarse())
+}
+
+func (c *numberCache[T]) shard(key uint64) *numberShard[T] {
+	return c.list[c.hash(key)>>c.shift]
+}
+
+// Get returns stored value.
+// The second returned variable is an existence flag like in the map.
+func (c *numberCache[T]) Get(key uint64) (T, bool) {
+	s := c.shard(key)
+	s.RLock()
+	it, ok := s.items.Get(key)
+	s.RUnlock()
+	if !ok {
+		s.stats.misses.Add(1)
+		return 0, false
+	}
+	s.stats.hits.Add(1)
+	return it.value, true
+}
+
+// Set adds value to the cache with given ttl.
+// It is a no-op after Close.
+func (c *numberCache[T]) Set(key uint64, value T, ttl time.Duration) {
+	s := c.shard(key)
+	s.Lock()
+	if c.closed.Load() {
+		s.Unlock()
+		return
+	}
+	s.items.Put(key, numberItem[T]{deadline: c.clock.unixNano() + int64(ttl), value: value})
+	s.Unlock()
+	s.stats.sets.Add(1)
+}
+
+// Add adds delta to the stored value and returns the result.
+// A missing record is created with delta and the given ttl,
+// an existing one keeps its deadline. After Close it is a no-op returning zero.
+func (c *numberCache[T]) Add(key uint64, delta T, ttl time.Duration) T {
+	s := c.shard(key)
+	s.Lock()
+	if c.closed.Load() {
+		s.Unlock()
+		return 0
+	}
+	it, ok := s.items.Get(key)
+	if ok {
+		it.value += delta
+	} else {
+		it = numberItem[T]{deadline: c.clock.unixNano() + int64(ttl), value: delta}
+	}
+	s.items.Put(key, it)
+	s.Unlock()
+	s.stats.sets.Add(1)
+	return it.value
+}
+
+// TTL returns the remaining time to live of the stored record.
+// Outdated records waiting for the cleanup manager report zero.
+func (c *numberCache[T]) TTL(key uint64) (time.Duration, bool) {
+	s := c.shard(key)
+	s.RLock()
+	it, ok := s.items.Get(key)
+	s.RUnlock()
+	if !ok {
+		return 0, false
+	}
+	ttl := time.Duration(it.deadline - c.clock.unixNano())
+	if ttl < 0 {
+		ttl = 0
+	}
+	return ttl, true
+}
+
+// Delete removes record from storage.
+func (c *numberCache[T]) Delete(key uint64) {
+	s := c.shard(key)
+	s.Lock()
+	ok := s.items.Delete(key)
+	s.Unlock()
+	if ok {
+		s.stats.deletes.Add(1)
+	}
+}
+
+// Stats returns a snapshot of the cache counters.
+func (c *numberCache[T]) Stats() Stats {
+	st := Stats{Shards: len(c.list)}
+	for _, s := range c.list {
+		s.RLock()
+		st.Entries += s.items.Count()
+		s.RUnlock()
+		s.stats.addTo(&st)
+	}
+	return st
+}
+
+// Clear removes all items from storage and leaves the cleanup manager running.
+func (c *numberCache[T]) Clear() {
+	for _, s := range c.list {
+		s.Lock()
+		s.items.Clear()
+		s.Unlock()
+	}
+}
+
+// Close stops cleanup manager and removes records from storage.
+// Closing a closed cache returns ErrClosed.
+func (c *numberCache[T]) Close() error {
+	if !c.closed.CompareAndSwap(false, true) {
+		return ErrClosed
+	}
+	close(c.done)
+	c.Clear()
+	return nil
+}
+
+// DeleteExpired removes outdated records from storage and returns their number.
+// The cleanup manager calls it every resolution tick.
+// Like in Cache, shards are scanned under their read lock.
+func (c *numberCache[T]) DeleteExpired() int {
+	now := c.clock.unixNano()
+	var (
+		expired []uint64
+		n       int
+	)
+	for _, s := range c.list {
+		s.RLock()
+		expired = expired[:0]
+		s.items.Iter(func(key uint64, it numberItem[T]) (stop bool) {
+			if it.deadline < now {
+				expired = append(expired, key)
+			}
+			return false
+		})
+		s.RUnlock()
+		if len(expired) == 0 {
+			continue
+		}
+
+		removed := 0
+		s.Lock()
+		for _, key := range expired {
+			if it, ok := s.items.Get(key); ok && it.deadline < now {
+				s.items.Delete(key)
+				removed++
+			}
+		}
+		s.Unlock()
+		s.stats.expired.Add(uint64(removed))
+		n += removed
+	}
+	return n
+}
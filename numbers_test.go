@@ -0,0 +1,77 @@
+package ttlswisscache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestInt64Cache(t *testing.T) {
+	c := NewInt64Cache(time.Hour)
+	defer c.Close()
+
+	c.Set(1, 10, time.Minute)
+	if v, ok := c.Get(1); !ok || v != 10 {
+		t.Errorf("incorrect value: got: %v expected: %v", v, 10)
+	}
+	if v := c.Add(1, 5, time.Hour); v != 15 {
+		t.Errorf("incorrect sum: got: %v expected: %v", v, 15)
+	}
+	if ttl, _ := c.TTL(1); ttl > time.Minute {
+		t.Errorf("Add changed the deadline: got: %v", ttl)
+	}
+	if v := c.Add(2, 3, time.Minute); v != 3 {
+		t.Errorf("incorrect new counter: got: %v expected: %v", v, 3)
+	}
+	c.Delete(1)
+	if _, ok := c.Get(1); ok {
+		t.Error("record was not deleted")
+	}
+
+	c.Set(3, 1, -time.Second)
+	if n := c.DeleteExpired(); n != 1 {
+		t.Errorf("incorrect number of removed records: got: %d expected: %d", n, 1)
+	}
+	st := c.Stats()
+	if st.Entries != 1 || st.Deletes != 1 || st.Expired != 1 {
+		t.Errorf("incorrect stats: got: %+v", st)
+	}
+}
+
+func TestFloat64Cache(t *testing.T) {
+	c := NewFloat64Cache(time.Hour)
+	defer c.Close()
+
+	c.Set(1, 0.5, time.Minute)
+	if v := c.Add(1, 0.25, time.Minute); v != 0.75 {
+		t.Errorf("incorrect sum: got: %v expected: %v", v, 0.75)
+	}
+	c.Clear()
+	if _, ok := c.Get(1); ok {
+		t.Error("record was not cleared")
+	}
+}
+
+func TestInt64Cache_SetAllocs(t *testing.T) {
+	c := NewInt64Cache(time.Hour)
+	defer c.Close()
+	c.Set(1, 1, time.Minute)
+
+	if n := testing.AllocsPerRun(100, func() { c.Set(1, 1<<40, time.Minute) }); n != 0 {
+		t.Errorf("incorrect number of allocations: got: %v expected: %v", n, 0)
+	}
+}
+
+func TestInt64Cache_Closed(t *testing.T) {
+	c := NewInt64Cache(time.Hour)
+	c.Close()
+	if err := c.Close(); err != ErrClosed {
+		t.Errorf("incorrect second close error: got: %v expected: %v", err, ErrClosed)
+	}
+	c.Set(1, 1, time.Hour)
+	if n := c.Add(1, 1, time.Hour); n != 0 {
+		t.Errorf("incorrect sum after close: got: %d expected: %d", n, 0)
+	}
+	if _, ok := c.Get(1); ok {
+		t.Error("record stored after close")
+	}
+}
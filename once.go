@@ -0,0 +1,49 @@
+package ttlswisscache
+
+import "time"
+
+// onceValue is a value removed by the first Get, see SetOnce.
+type onceValue struct {
+	value interface{}
+}
+
+// SetOnce adds value to the cache with given ttl for a single read: the first
+// Get, GetMany, Lookup, GetCtx, GetIfChanged, GetStale or Txn.Get returns it and
+// removes the record like GetAndDelete, so concurrent reads of the key get it
+// only once, e.g. for CSRF nonces, magic links and one-time codes; Txn removes
+// it when the transaction is applied. Other reads, e.g. Peek, Scan or
+// snapshots, see the value without consuming it. It returns the version of the
+// record like Set.
+func (c *Cache) SetOnce(key uint64, value interface{}, ttl time.Duration) uint64 {
+	value, ok := c.limit(key, value)
+	if !ok {
+		c.reject(key)
+		return 0
+	}
+	return c.store(key, item{
+		deadline: c.clock.unixNano() + int64(ttl),
+		value:    &onceValue{value: c.clone(value)},
+	})
+}
+
+// consume returns the value of the record of key in s like hit, removing it
+// if it is still stored by SetOnce. The value of a removed record is handed
+// over to the caller, the WithDestructor function is not called.
+func (c *Cache) consume(s *shard, key uint64) (value interface{}, found, stored bool) {
+	s.Lock()
+	it, ok := s.get(key)
+	if _, once := it.value.(*onceValue); !ok || !once || c.quarantined(it) {
+		s.Unlock()
+		return c.hit(s, key, it, ok) // Removed or set again meanwhile.
+	}
+	c.remove(s, key, it)
+	s.Unlock()
+	value, ok = it.load()
+	if !ok {
+		s.stats.misses.Add(1)
+		return nil, false, false
+	}
+	s.stats.hits.Add(1)
+	c.cascade()
+	return value, true, false
+}
@@ -0,0 +1,176 @@
+package ttlswisscache
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCache_SetOnce(t *testing.T) {
+	var destroyed atomic.Int32
+	c := New(0, WithDestructor(func(uint64, interface{}) { destroyed.Add(1) }))
+	defer c.Close()
+	c.SetOnce(IntKey(1), "nonce", time.Minute)
+
+	if v, r := c.Lookup(IntKey(1)); r != Hit || v != "nonce" {
+		t.Errorf("incorrect looked up value: got: %v, %v expected: nonce, hit", v, r)
+	}
+	if v, r := c.Lookup(IntKey(1)); r != Miss {
+		t.Errorf("one-shot record was looked up twice: got: %v, %v expected: miss", v, r)
+	}
+	c.SetOnce(IntKey(1), "nonce", time.Minute)
+	var hits atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if v, ok := c.Get(IntKey(1)); ok {
+				hits.Add(1)
+				if v != "nonce" {
+					t.Errorf("incorrect value: got: %v expected: %v", v, "nonce")
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if n := hits.Load(); n != 1 {
+		t.Errorf("incorrect number of reads of a one-shot record: got: %d expected: %d", n, 1)
+	}
+	if _, ok := c.TTL(IntKey(1)); ok {
+		t.Error("one-shot record was kept after its read")
+	}
+	if n := destroyed.Load(); n != 0 {
+		t.Errorf("consumed value was destroyed: got: %d calls expected: %d", n, 0)
+	}
+
+	c.SetOnce(IntKey(2), "code", time.Minute)
+	c.Set(IntKey(2), "plain", time.Minute)
+	for i := 0; i < 2; i++ {
+		if v, ok := c.Get(IntKey(2)); !ok || v != "plain" {
+			t.Errorf("incorrect value of an overwritten one-shot record: got: %v, %v expected: plain, true", v, ok)
+		}
+	}
+}
+
+func TestCache_SetOnce_GetMany(t *testing.T) {
+	c := New(0)
+	defer c.Close()
+	keys := make([]uint64, minParallelGetMany)
+	for i := range keys {
+		keys[i] = IntKey(i)
+		c.Set(keys[i], i, time.Minute)
+	}
+	for _, batch := range [][]uint64{keys, keys[:1]} {
+		c.SetOnce(keys[0], "nonce", time.Minute)
+		if v := c.GetMany(batch); v[keys[0]] != "nonce" || len(v) != len(batch) {
+			t.Errorf("incorrect values of %d keys: got: %v, %d values", len(batch), v[keys[0]], len(v))
+		}
+		if v := c.GetMany(batch); len(v) != len(batch)-1 {
+			t.Errorf("one-shot record was read twice: got: %d values expected: %d", len(v), len(batch)-1)
+		}
+	}
+}
+
+func TestCache_SetOnce_GetCtx(t *testing.T) {
+	c := New(0)
+	defer c.Close()
+	c.SetOnce(IntKey(1), "nonce", time.Minute)
+	loader := func(context.Context) (interface{}, time.Duration, error) {
+		return "loaded", time.Minute, nil
+	}
+	for _, expected := range []string{"nonce", "loaded"} {
+		if v, err := c.GetCtx(t.Context(), IntKey(1), loader); err != nil || v != expected {
+			t.Errorf("incorrect value: got: %v, %v expected: %v, %v", v, err, expected, nil)
+		}
+	}
+}
+
+func TestCache_SetOnce_GetManyCoalescing(t *testing.T) {
+	c := New(0, WithGetManyCoalescing(10*time.Millisecond))
+	defer c.Close()
+	c.SetOnce(IntKey(1), "nonce", time.Minute)
+
+	var hits atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if v, ok := c.GetMany([]uint64{IntKey(1)})[IntKey(1)]; ok {
+				hits.Add(1)
+				if v != "nonce" {
+					t.Errorf("incorrect value: got: %v expected: %v", v, "nonce")
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if n := hits.Load(); n != 1 {
+		t.Errorf("incorrect number of reads of a one-shot record: got: %d expected: %d", n, 1)
+	}
+}
+
+func TestCache_SetOnce_GetIfChanged(t *testing.T) {
+	c := New(0)
+	defer c.Close()
+	c.SetOnce(IntKey(1), "nonce", time.Minute)
+
+	if v, _, r := c.GetIfChanged(IntKey(1), 0); r != Hit || v != "nonce" {
+		t.Errorf("incorrect value: got: %v, %v expected: nonce, hit", v, r)
+	}
+	if v, _, r := c.GetIfChanged(IntKey(1), 0); r != Miss {
+		t.Errorf("one-shot record was read twice: got: %v, %v expected: miss", v, r)
+	}
+	if v, ok := c.Get(IntKey(1)); ok {
+		t.Errorf("one-shot record was read twice: got: %v", v)
+	}
+}
+
+func TestCache_SetOnce_GetStale(t *testing.T) {
+	c := New(0)
+	defer c.Close()
+	c.SetOnce(IntKey(1), "nonce", time.Minute)
+
+	if v, age, ok := c.GetStale(IntKey(1)); !ok || v != "nonce" || age != 0 {
+		t.Errorf("incorrect value: got: %v, %v, %v expected: nonce, 0, true", v, age, ok)
+	}
+	if v, _, ok := c.GetStale(IntKey(1)); ok {
+		t.Errorf("one-shot record was read twice: got: %v", v)
+	}
+	if v, ok := c.Get(IntKey(1)); ok {
+		t.Errorf("one-shot record was read twice: got: %v", v)
+	}
+}
+
+func TestCache_SetOnce_Txn(t *testing.T) {
+	var destroyed atomic.Int32
+	c := New(0, WithDestructor(func(uint64, interface{}) { destroyed.Add(1) }))
+	defer c.Close()
+	c.SetOnce(IntKey(1), "nonce", time.Minute)
+
+	var values []interface{}
+	err := c.Txn(func(tx *Txn) error {
+		values = values[:0]
+		for i := 0; i < 2; i++ {
+			if v, ok := tx.Get(IntKey(1)); ok {
+				values = append(values, v)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("transaction failed: %v", err)
+	}
+	if len(values) != 1 || values[0] != "nonce" {
+		t.Errorf("incorrect values read by the transaction: got: %v expected: [nonce]", values)
+	}
+	if v, ok := c.Get(IntKey(1)); ok {
+		t.Errorf("one-shot record was read twice: got: %v", v)
+	}
+	if n := destroyed.Load(); n != 0 {
+		t.Errorf("consumed value was destroyed: got: %d calls expected: %d", n, 0)
+	}
+}
@@ -0,0 +1,242 @@
+package ttlswisscache
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/maphash"
+	"math/bits"
+	"runtime"
+	"time"
+)
+
+// defaultResolution is the cleanup interval of NewCache.
+const defaultResolution = time.Second
+
+// Option configures a cache.
+type Option func(*options)
+
+type options struct {
+	resolution  time.Duration
+	defaultTTL  time.Duration
+	ttlPolicies []ttlPolicy
+	grace       time.Duration
+	callbacks   Callbacks
+	cloner      func(interface{}) interface{}
+	destructor  func(key uint64, value interface{})
+	retainer    func(key uint64, value interface{})
+	bloomFilter int
+	eviction    Eviction
+	maxEntries  int
+
+	sizer        Sizer
+	maxValueSize int64
+	sizePolicy   SizePolicy
+
+	batchLoader    BatchLoader
+	batchLoaderTTL time.Duration
+	coalesceDelay  time.Duration
+	refresh        RefreshFunc
+	refreshOpts    *RefreshOptions // nil without WithRefresh
+	setCoalescing  time.Duration
+	loaderTimeout  time.Duration
+	tombstoneTTL   time.Duration
+	earlyBeta      float64
+	rebuildChurn   float64
+	lockStats      bool
+	verify         func(err error)
+
+	evictionWorkers int
+	evictionQueue   int
+	evictionPolicy  func(maxEntries int) EvictionPolicy
+
+	shardCount  int
+	fixedShards bool // Set by WithShardCount.
+	capacity    int
+	hasher      func(uint64) uint64
+
+	name            string
+	labels          map[string]string
+	cleanupHooks    CleanupHooks
+	externalCleaner bool
+
+	autoCompact bool
+	writeBuffer int
+	writePolicy WritePolicy
+	coarseClock bool
+	clock       Clock
+}
+
+func newOptions(opts []Option) options {
+	o := options{resolution: defaultResolution, hasher: hashKey}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.fixedShards && (o.shardCount <= 0 || bits.OnesCount(uint(o.shardCount)) != 1) {
+		panic(fmt.Sprintf("ttlswisscache: shard count %d is not a power of two", o.shardCount))
+	}
+	if o.shardCount == 0 {
+		o.shardCount = autoShardCount(runtime.GOMAXPROCS(0), o.capacity)
+	}
+	if o.capacity == 0 {
+		o.capacity = defaultCapacity
+	}
+	return o
+}
+
+// WithResolution sets the interval of the cleanup manager.
+// Cleanup operation locks storage so think twice before setting it to small value.
+// A resolution <= 0 disables the cleanup manager, outdated records are then removed
+// only by DeleteExpired and DeleteExpiredBefore.
+func WithResolution(resolution time.Duration) Option {
+	return func(o *options) {
+		o.resolution = resolution
+	}
+}
+
+// WithDefaultTTL sets the ttl of SetDefault for keys no WithTTLPolicy matches.
+func WithDefaultTTL(ttl time.Duration) Option {
+	return func(o *options) {
+		o.defaultTTL = ttl
+	}
+}
+
+// ttlPolicy is a ttl for the keys match reports, see WithTTLPolicy.
+type ttlPolicy struct {
+	match func(key uint64) bool
+	ttl   time.Duration
+}
+
+// WithTTLPolicy sets the ttl of SetDefault and TTLFor for the keys match reports,
+// e.g. KeyRange(lo, hi), so entries written by shared code get the lifetime of
+// their class. Policies are tried in the order of the options, the first match
+// wins; keys no policy matches get the WithDefaultTTL ttl.
+func WithTTLPolicy(match func(key uint64) bool, ttl time.Duration) Option {
+	return func(o *options) {
+		o.ttlPolicies = append(o.ttlPolicies, ttlPolicy{match: match, ttl: ttl})
+	}
+}
+
+// KeyRange returns a WithTTLPolicy match reporting the keys from lo up to, but
+// not including, hi.
+func KeyRange(lo, hi uint64) func(key uint64) bool {
+	return func(key uint64) bool {
+		return key >= lo && key < hi
+	}
+}
+
+// WithCallbacks sets functions the cache calls on mutations, see Callbacks.
+func WithCallbacks(callbacks Callbacks) Option {
+	return func(o *options) {
+		o.callbacks = callbacks
+	}
+}
+
+// WithCloner makes the cache store a copy of every value passed to Set and
+// return a copy from Get, so callers mutating values in place don't change
+// the cached ones. clone must return a deep copy of mutable values.
+// Callbacks, events, snapshots and SetWeak deal with the stored values as is.
+func WithCloner(clone func(value interface{}) interface{}) Option {
+	return func(o *options) {
+		o.cloner = clone
+	}
+}
+
+// WithDestructor sets a function called exactly once with every value leaving the
+// cache: deleted, expired, overwritten, invalidated or cleared, Close included,
+// e.g. to Release reference-counted values like arrow.Record. The cache owns one
+// reference per Set. GetAndDelete hands the value over to the caller instead,
+// callers discarding it use Delete or Remove.
+// The function is called under the lock of the record's shard: it must be fast
+// and must not call the cache. Each cache releases its own references, so values
+// shared by Clone or Merge are released by both caches: take a reference in
+// WithRetainer or WithCloner. Writes dropped after Close are not passed to it,
+// nor are collected weak values.
+func WithDestructor(fn func(key uint64, value interface{})) Option {
+	return func(o *options) {
+		o.destructor = fn
+	}
+}
+
+// WithRetainer sets a function called with every value Clone or Merge shares
+// with the cache without WithCloner, e.g. to Retain reference-counted values,
+// so the cache owns a reference it releases with WithDestructor. Like the
+// destructor it is called under the lock of the record's shard.
+func WithRetainer(fn func(key uint64, value interface{})) Option {
+	return func(o *options) {
+		o.retainer = fn
+	}
+}
+
+// WithShardCount sets the number of independently locked partitions of the key space.
+// More shards lower write contention on many cores, fewer save memory in small caches.
+// n must be a power of two, the constructors of the caches panic otherwise.
+// By default the count is derived from GOMAXPROCS and lowered for small WithCapacity
+// hints, Stats reports the chosen value.
+func WithShardCount(n int) Option {
+	return func(o *options) {
+		o.shardCount = n
+		o.fixedShards = true
+	}
+}
+
+// shardShift returns the shift selecting one of count shards with the top bits of a hash.
+func shardShift(count int) uint {
+	return uint(64 - bits.TrailingZeros(uint(count)))
+}
+
+// WithCapacity sizes the cache for n records up front,
+// so loading a known number of records doesn't rehash the shards repeatedly.
+// It is also the size hint of the automatic shard count.
+func WithCapacity(n int) Option {
+	return func(o *options) {
+		if n > 0 {
+			o.capacity = n
+		}
+	}
+}
+
+// WithHasher sets the function spreading keys across shards. The shard is picked
+// with the top bits of its result. The default is a fixed bit mixer, so an attacker
+// choosing keys can load a single shard; use SeededHasher against such inputs.
+func WithHasher(hash func(key uint64) uint64) Option {
+	return func(o *options) {
+		if hash != nil {
+			o.hasher = hash
+		}
+	}
+}
+
+// SeededHasher returns a keyed hash with a random seed for WithHasher.
+// Shard placement can't be predicted without the seed, at the price of
+// a slower hash than the default one.
+func SeededHasher() func(key uint64) uint64 {
+	seed := maphash.MakeSeed()
+	return func(key uint64) uint64 {
+		var b [8]byte
+		binary.LittleEndian.PutUint64(b[:], key)
+		return maphash.Bytes(seed, b[:])
+	}
+}
+
+// WithAutoCompact makes the cleanup manager call Compact after every cleanup,
+// so memory used by a past peak of records is given back.
+func WithAutoCompact() Option {
+	return func(o *options) {
+		o.autoCompact = true
+	}
+}
+
+// WithWriteBuffer enables SetAsync with a buffer of size pending writes.
+func WithWriteBuffer(size int) Option {
+	return func(o *options) {
+		o.writeBuffer = size
+	}
+}
+
+// WithWritePolicy sets what SetAsync does when the write buffer is full,
+// BlockWhenFull by default. Stats reports the queue depth and the drops.
+func WithWritePolicy(policy WritePolicy) Option {
+	return func(o *options) {
+		o.writePolicy = policy
+	}
+}
@@ -0,0 +1,295 @@
+package ttlswisscache
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWithShardCount(t *testing.T) {
+	for _, n := range []int{1, 2, 128} {
+		c := New(time.Hour, WithShardCount(n))
+		if got := len(c.shards.list); got != n {
+			t.Errorf("incorrect number of shards: got: %d expected: %d", got, n)
+		}
+		for i := 0; i < 1000; i++ {
+			c.Set(IntKey(i), i, time.Hour)
+		}
+		for i := 0; i < 1000; i++ {
+			if v, ok := c.Get(IntKey(i)); !ok || v != i {
+				t.Errorf("incorrect value with %d shards: got: %v expected: %v", n, v, i)
+			}
+		}
+		c.Close()
+	}
+}
+
+func TestWithShardCount_Invalid(t *testing.T) {
+	for _, n := range []int{0, 3, -4, 96} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("shard count %d was accepted", n)
+				}
+			}()
+			New(0, WithShardCount(n)).Close()
+		}()
+	}
+}
+
+func TestWithCapacity(t *testing.T) {
+	c := New(time.Hour, WithCapacity(100000))
+	defer c.Close()
+	n := len(c.shards.list)
+	for _, s := range c.shards.list {
+		if s.capacity < uint32(100000/n) {
+			t.Errorf("incorrect shard capacity: got: %d expected at least: %d", s.capacity, 100000/n)
+		}
+	}
+}
+
+func TestCache_Reserve(t *testing.T) {
+	c := New(time.Hour)
+	defer c.Close()
+	for i := 0; i < 100; i++ {
+		c.Set(IntKey(i), i, time.Hour)
+	}
+
+	c.Reserve(100000)
+	n := len(c.shards.list)
+	for _, s := range c.shards.list {
+		if s.capacity < uint32(100000/n) {
+			t.Errorf("incorrect shard capacity: got: %d expected at least: %d", s.capacity, 100000/n)
+		}
+	}
+	for i := 0; i < 100; i++ {
+		if v, ok := c.Get(IntKey(i)); !ok || v != i {
+			t.Errorf("record lost by Reserve: got: %v expected: %v", v, i)
+		}
+	}
+
+	// A smaller reservation keeps the shards.
+	before := c.shards.list[0].index
+	c.Reserve(10)
+	if c.shards.list[0].index != before {
+		t.Error("Reserve shrank a shard")
+	}
+}
+
+func TestWithHasher(t *testing.T) {
+	// A hasher mapping every key to the first shard.
+	c := New(time.Hour, WithHasher(func(uint64) uint64 { return 0 }))
+	defer c.Close()
+	for i := 0; i < 100; i++ {
+		c.Set(IntKey(i), i, time.Hour)
+	}
+	if n := c.shards.list[0].index.Count(); n != 100 {
+		t.Errorf("incorrect number of records in the first shard: got: %d expected: %d", n, 100)
+	}
+
+	seeded := New(time.Hour, WithHasher(SeededHasher()))
+	defer seeded.Close()
+	for i := 0; i < 1000; i++ {
+		seeded.Set(IntKey(i), i, time.Hour)
+	}
+	for i, s := range seeded.shards.list {
+		if s.count() == 0 {
+			t.Errorf("seeded hasher left shard %d empty", i)
+		}
+	}
+	if v, ok := seeded.Get(IntKey(7)); !ok || v != 7 {
+		t.Errorf("incorrect value: got: %v expected: %v", v, 7)
+	}
+}
+
+func TestWithAutoCompact(t *testing.T) {
+	c := New(10*time.Millisecond, WithAutoCompact())
+	defer c.Close()
+	for i := 0; i < 10000; i++ {
+		c.Set(IntKey(i), i, time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	for _, s := range c.shards.list {
+		s.RLock()
+		capacity := s.capacity
+		s.RUnlock()
+		if capacity > 4*s.reserved {
+			t.Errorf("shard was not compacted: got capacity: %d", capacity)
+		}
+	}
+}
+
+func TestAutoShardCount(t *testing.T) {
+	tt := []struct {
+		procs    int
+		capacity int
+		expected int
+	}{
+		{procs: 1, expected: minShardCount},
+		{procs: 8, expected: 32},
+		{procs: 12, expected: 64},
+		{procs: 1000, expected: maxShardCount},
+		{procs: 8, capacity: 1 << 20, expected: 32},
+		{procs: 8, capacity: 4096, expected: 16},
+		{procs: 8, capacity: 100, expected: 1},
+	}
+
+	for _, tc := range tt {
+		if n := autoShardCount(tc.procs, tc.capacity); n != tc.expected {
+			t.Errorf("incorrect shard count for %d procs and capacity %d: got: %d expected: %d", tc.procs, tc.capacity, n, tc.expected)
+		}
+	}
+}
+
+func TestNewCache(t *testing.T) {
+	c := NewCache(WithResolution(5*time.Millisecond), WithDefaultTTL(time.Millisecond))
+	defer c.Close()
+
+	c.SetDefault(IntKey(1), 1)
+	if ttl, ok := c.TTL(IntKey(1)); !ok || ttl > time.Millisecond {
+		t.Errorf("incorrect ttl: got: %v, %v expected at most: %v", ttl, ok, time.Millisecond)
+	}
+	time.Sleep(30 * time.Millisecond)
+	if _, ok := c.Get(IntKey(1)); ok {
+		t.Error("outdated record was not removed")
+	}
+}
+
+func TestWithCallbacks(t *testing.T) {
+	type call struct {
+		key    uint64
+		value  interface{}
+		reason Op
+	}
+	var calls []call
+	c := NewCache(WithResolution(0), WithCallbacks(Callbacks{
+		OnSet: func(key uint64, value interface{}) {
+			calls = append(calls, call{key: key, value: value, reason: OpSet})
+		},
+		OnEvicted: func(key uint64, value interface{}, reason Op) {
+			calls = append(calls, call{key: key, value: value, reason: reason})
+		},
+	}))
+	defer c.Close()
+
+	c.Set(IntKey(1), 1, time.Hour)
+	c.Set(IntKey(2), 2, -time.Hour)
+	c.Set(IntKey(3), 3, time.Hour)
+	c.Delete(IntKey(1))
+	c.DeleteExpired()
+	c.ClearWithCallbacks()
+
+	expected := []call{
+		{key: IntKey(1), value: 1, reason: OpSet},
+		{key: IntKey(2), value: 2, reason: OpSet},
+		{key: IntKey(3), value: 3, reason: OpSet},
+		{key: IntKey(1), value: 1, reason: OpDelete},
+		{key: IntKey(2), value: 2, reason: OpExpire},
+		{key: IntKey(3), value: 3, reason: OpDelete},
+	}
+	if !reflect.DeepEqual(calls, expected) {
+		t.Errorf("incorrect callbacks: got: %v expected: %v", calls, expected)
+	}
+}
+
+func TestWithCloner(t *testing.T) {
+	c := New(0, WithCloner(func(v interface{}) interface{} {
+		return append([]int(nil), v.([]int)...)
+	}))
+	defer c.Close()
+
+	value := []int{1, 2, 3}
+	c.Set(IntKey(1), value, time.Hour)
+	value[0] = 100
+	got, _ := c.Get(IntKey(1))
+	got.([]int)[1] = 200
+
+	many := c.GetMany([]uint64{IntKey(1)})
+	if many[IntKey(1)].([]int)[0] != 1 {
+		t.Errorf("incorrect value: got: %v expected: %v", many[IntKey(1)], []int{1, 2, 3})
+	}
+	if got, _ := c.Get(IntKey(1)); !reflect.DeepEqual(got, []int{1, 2, 3}) {
+		t.Errorf("cached value was mutated: got: %v expected: %v", got, []int{1, 2, 3})
+	}
+}
+
+// refValue is a reference-counted value like arrow.Record.
+type refValue struct {
+	refs int
+}
+
+func (v *refValue) Release() {
+	v.refs--
+}
+
+func TestWithDestructor(t *testing.T) {
+	released := map[uint64]int{}
+	c := New(0, WithDestructor(func(key uint64, value interface{}) {
+		value.(*refValue).Release()
+		released[key]++
+	}))
+
+	values := make([]*refValue, 8)
+	for i := range values {
+		values[i] = &refValue{refs: 1}
+		c.Set(IntKey(i), values[i], time.Hour)
+	}
+	c.Set(IntKey(0), &refValue{refs: 1}, time.Hour) // overwrite
+	c.Delete(IntKey(1))
+	c.Expire(IntKey(2), -time.Second)
+	c.DeleteExpired()
+	values[3].refs++ // every Set hands a reference over
+	c.SetWithTags(IntKey(3), values[3], time.Hour, "t")
+	c.InvalidateTag("t")
+	if v, ok := c.GetAndDelete(IntKey(4)); !ok || v != values[4] {
+		t.Errorf("incorrect value: got: %v expected: %v", v, values[4])
+	}
+	values[4].Release() // handed over by GetAndDelete
+	if !c.Remove(IntKey(5)) {
+		t.Error("Remove missed an existing key")
+	}
+	c.Close()
+
+	for i, v := range values {
+		if v.refs != 0 {
+			t.Errorf("incorrect references of value %d: got: %d expected: %d", i, v.refs, 0)
+		}
+	}
+	if n := released[IntKey(4)]; n != 0 {
+		t.Errorf("value taken by GetAndDelete was released: got: %d", n)
+	}
+	if n := released[IntKey(0)]; n != 2 {
+		t.Errorf("incorrect releases of an overwritten key: got: %d expected: %d", n, 2)
+	}
+}
+
+func TestWithTTLPolicy(t *testing.T) {
+	flags := StringKey("flags")
+	c := New(0,
+		WithDefaultTTL(time.Hour),
+		WithTTLPolicy(KeyRange(1000, 2000), time.Minute),
+		WithTTLPolicy(func(key uint64) bool { return key == flags }, time.Second),
+		WithTTLPolicy(KeyRange(0, 1500), 2*time.Hour),
+	)
+	defer c.Close()
+
+	for _, tc := range []struct {
+		key uint64
+		ttl time.Duration
+	}{
+		{IntKey(1), 2 * time.Hour},
+		{IntKey(1000), time.Minute},
+		{IntKey(1999), time.Minute},
+		{IntKey(2000), time.Hour},
+		{flags, time.Second},
+	} {
+		if ttl := c.TTLFor(tc.key); ttl != tc.ttl {
+			t.Errorf("incorrect ttl of key %d: got: %v expected: %v", tc.key, ttl, tc.ttl)
+		}
+	}
+	c.SetDefault(IntKey(1500), "profile")
+	if ttl, ok := c.TTL(IntKey(1500)); !ok || ttl > time.Minute || ttl < time.Minute-time.Second {
+		t.Errorf("incorrect ttl after SetDefault: got: %v expected: about %v", ttl, time.Minute)
+	}
+}
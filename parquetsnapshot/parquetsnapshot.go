@@ -0,0 +1,136 @@
+// Package parquetsnapshot writes cache snapshots as Parquet files, so cache
+// contents can be queried offline with DuckDB, Spark or pandas, e.g. for
+// capacity planning and hit-pattern analysis.
+//
+// A file has a row per live record with its key, deadline, value encoded by a
+// ttlcache.Codec, version (see ttlcache.Cache.GetIfChanged) and encoded size.
+// The time of the snapshot is stored in the key-value metadata of the file under
+// WrittenAtKey.
+// Records are read from the cache in batches with Scan, so writers are blocked
+// only for the time of a batch.
+package parquetsnapshot
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/apache/arrow-go/v18/parquet"
+	"github.com/apache/arrow-go/v18/parquet/compress"
+	"github.com/apache/arrow-go/v18/parquet/pqarrow"
+
+	ttlcache "github.com/loicalleyne/ttlswisscache"
+)
+
+// batchRows is the number of records of a row group.
+const batchRows = 64 * 1024
+
+// WrittenAtKey is the metadata key of the snapshot time, in RFC 3339 format.
+const WrittenAtKey = "ttlswisscache.written_at"
+
+var fields = []arrow.Field{
+	{Name: "key", Type: arrow.PrimitiveTypes.Uint64},
+	{Name: "deadline", Type: &arrow.TimestampType{Unit: arrow.Microsecond, TimeZone: "UTC"}},
+	{Name: "value", Type: arrow.BinaryTypes.Binary},
+	{Name: "version", Type: arrow.PrimitiveTypes.Uint64},
+	{Name: "size", Type: arrow.PrimitiveTypes.Int64},
+}
+
+// Write writes the live records of cache to w as a Parquet file, encoding the
+// values with codec, and returns the number of records written. A nil props
+// writes Snappy-compressed columns.
+func Write(w io.Writer, cache *ttlcache.Cache, codec ttlcache.Codec, props *parquet.WriterProperties) (int, error) {
+	if props == nil {
+		props = parquet.NewWriterProperties(parquet.WithCompression(compress.Codecs.Snappy))
+	}
+	schema := arrow.NewSchema(fields, nil)
+	// The file writer closes writers implementing io.Closer, w is the caller's.
+	fw, err := pqarrow.NewFileWriter(schema, struct{ io.Writer }{w}, props, pqarrow.NewArrowWriterProperties(pqarrow.WithStoreSchema()))
+	if err != nil {
+		return 0, err
+	}
+	if err := fw.AppendKeyValueMetadata(WrittenAtKey, cache.Now().UTC().Format(time.RFC3339Nano)); err != nil {
+		fw.Close()
+		return 0, err
+	}
+	b := array.NewRecordBuilder(memory.DefaultAllocator, schema)
+	defer b.Release()
+	keys := b.Field(0).(*array.Uint64Builder)
+	deadlines := b.Field(1).(*array.TimestampBuilder)
+	values := b.Field(2).(*array.BinaryBuilder)
+	versions := b.Field(3).(*array.Uint64Builder)
+	sizes := b.Field(4).(*array.Int64Builder)
+
+	n := 0
+	for cur := (ttlcache.Cursor{}); !cur.Done(); {
+		var entries []ttlcache.Entry
+		entries, cur = cache.Scan(cur, batchRows)
+		if len(entries) == 0 {
+			continue
+		}
+		for _, e := range entries {
+			data, err := codec.Marshal(e.Value)
+			if err != nil {
+				fw.Close()
+				return n, fmt.Errorf("parquetsnapshot: encode key %d: %w", e.Key, err)
+			}
+			keys.Append(e.Key)
+			deadlines.Append(arrow.Timestamp(e.Deadline.UnixMicro()))
+			values.Append(data)
+			versions.Append(e.Version)
+			sizes.Append(int64(len(data)))
+		}
+		rec := b.NewRecord()
+		err := fw.Write(rec)
+		rec.Release()
+		if err != nil {
+			fw.Close()
+			return n, err
+		}
+		n += len(entries)
+	}
+	return n, fw.Close()
+}
+
+// WriteEvery writes a snapshot of cache to dir every interval until ctx is
+// done, then returns ctx.Err(). Files are named after the time of the
+// snapshot, e.g. cache-20240102T150405Z.parquet, and appear once complete.
+// It stops at the first error writing a file and returns it.
+func WriteEvery(ctx context.Context, cache *ttlcache.Cache, codec ttlcache.Codec, dir string, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+		name := filepath.Join(dir, "cache-"+cache.Now().UTC().Format("20060102T150405Z")+".parquet")
+		if err := writeFile(name, cache, codec); err != nil {
+			return err
+		}
+	}
+}
+
+// writeFile writes a snapshot to a temporary file renamed to name once complete.
+func writeFile(name string, cache *ttlcache.Cache, codec ttlcache.Codec) error {
+	f, err := os.CreateTemp(filepath.Dir(name), ".cache-*.parquet")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := Write(f, cache, codec, nil); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), name)
+}
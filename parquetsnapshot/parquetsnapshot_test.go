@@ -0,0 +1,97 @@
+package parquetsnapshot
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/apache/arrow-go/v18/parquet/file"
+	"github.com/apache/arrow-go/v18/parquet/pqarrow"
+
+	ttlcache "github.com/loicalleyne/ttlswisscache"
+)
+
+func TestWrite(t *testing.T) {
+	c := ttlcache.New(0)
+	defer c.Close()
+	for i := 0; i < 100; i++ {
+		c.Set(ttlcache.IntKey(i), i, time.Hour)
+	}
+	version := c.Set(ttlcache.IntKey(42), "forty-two", time.Hour)
+
+	var buf bytes.Buffer
+	n, err := Write(&buf, c, ttlcache.JSONCodec{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 100 {
+		t.Errorf("incorrect number of records: got: %d expected: %d", n, 100)
+	}
+
+	tbl, err := pqarrow.ReadTable(context.Background(), bytes.NewReader(buf.Bytes()), nil, pqarrow.ArrowReadProperties{}, memory.DefaultAllocator)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tbl.Release()
+	if tbl.NumRows() != 100 || tbl.NumCols() != 5 {
+		t.Errorf("incorrect table shape: got: %d x %d expected: %d x %d", tbl.NumRows(), tbl.NumCols(), 100, 5)
+	}
+	rdr, err := file.NewParquetReader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := rdr.MetaData().KeyValueMetadata().FindValue(WrittenAtKey); v == nil || *v == "" {
+		t.Error("snapshot time is missing from the metadata")
+	}
+
+	tr := array.NewTableReader(tbl, -1)
+	defer tr.Release()
+	found := false
+	for tr.Next() {
+		rec := tr.Record()
+		keys := rec.Column(0).(*array.Uint64)
+		for i := 0; i < keys.Len(); i++ {
+			if keys.Value(i) != ttlcache.IntKey(42) {
+				continue
+			}
+			found = true
+			if v := string(rec.Column(2).(*array.Binary).Value(i)); v != `"forty-two"` {
+				t.Errorf("incorrect value: got: %s expected: %s", v, `"forty-two"`)
+			}
+			if v := rec.Column(3).(*array.Uint64).Value(i); v != version {
+				t.Errorf("incorrect version: got: %d expected: %d", v, version)
+			}
+			if v := rec.Column(4).(*array.Int64).Value(i); v != 11 {
+				t.Errorf("incorrect size: got: %d expected: %d", v, 11)
+			}
+		}
+	}
+	if !found {
+		t.Error("record is missing")
+	}
+}
+
+func TestWriteEvery(t *testing.T) {
+	c := ttlcache.New(0)
+	defer c.Close()
+	c.Set(1, 1, time.Hour)
+	dir := t.TempDir()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1500*time.Millisecond)
+	defer cancel()
+	if err := WriteEvery(ctx, c, ttlcache.JSONCodec{}, dir, time.Second); err != context.DeadlineExceeded {
+		t.Errorf("incorrect error: got: %v expected: %v", err, context.DeadlineExceeded)
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "*"))
+	if len(files) != 1 || filepath.Ext(files[0]) != ".parquet" {
+		t.Errorf("incorrect files: got: %v expected a single snapshot", files)
+	}
+	if fi, err := os.Stat(files[0]); err != nil || fi.Size() == 0 {
+		t.Errorf("snapshot is empty: %v", err)
+	}
+}
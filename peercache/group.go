@@ -0,0 +1,123 @@
+// Package peercache turns independent per-process caches into a cooperating tier.
+//
+// Every key is owned by one process, chosen by consistent hashing.
+// A Group serves hits from the local cache, fetches misses for keys owned by another
+// process from that peer and runs its Loader only for the keys it owns.
+// Concurrent misses for the same key are deduplicated, so a key is loaded
+// at most once at a time across the whole tier.
+package peercache
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+
+	ttlcache "github.com/loicalleyne/ttlswisscache"
+)
+
+// ErrNotFound can be returned by a Loader for missing keys.
+var ErrNotFound = errors.New("peercache: not found")
+
+// Loader loads the value of a key owned by this process and returns its ttl.
+type Loader func(ctx context.Context, key string) ([]byte, time.Duration, error)
+
+// Peer fetches values from the process owning the key.
+type Peer interface {
+	Fetch(ctx context.Context, group, key string) ([]byte, time.Duration, error)
+}
+
+// Picker selects the owner of a key.
+// It returns false when the key is owned by this process.
+type Picker interface {
+	Pick(key string) (Peer, bool)
+}
+
+// Group is a named key space spread across peers.
+type Group struct {
+	name   string
+	cache  *ttlcache.Cache
+	loader Loader
+	picker Picker
+	flight singleflight.Group
+}
+
+// NewGroup creates a group storing values in the cache.
+// The cache can be shared by several groups, keys are prefixed with the group name.
+// An HTTPPool picker also starts serving the group to its peers.
+func NewGroup(name string, cache *ttlcache.Cache, loader Loader, picker Picker) *Group {
+	g := &Group{name: name, cache: cache, loader: loader, picker: picker}
+	if r, ok := picker.(interface{ register(*Group) }); ok {
+		r.register(g)
+	}
+	return g
+}
+
+// Name returns the group name.
+func (g *Group) Name() string {
+	return g.name
+}
+
+// Get returns the value of the key from the local cache, the owning peer or the loader.
+func (g *Group) Get(ctx context.Context, key string) ([]byte, error) {
+	if v, ok := g.cache.Get(g.key(key)); ok {
+		return v.([]byte), nil
+	}
+
+	v, err, _ := g.flight.Do(key, func() (interface{}, error) {
+		if peer, ok := g.picker.Pick(key); ok {
+			value, ttl, err := peer.Fetch(ctx, g.name, key)
+			if err == nil {
+				g.cache.Set(g.key(key), value, ttl)
+				return value, nil
+			}
+			if errors.Is(err, ErrNotFound) {
+				return nil, err
+			}
+			// The owner is unreachable, load locally rather than failing.
+		}
+		return g.load(ctx, key)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.([]byte), nil
+}
+
+// Remove deletes the key from the local cache.
+// Copies held by other peers expire with their ttl.
+func (g *Group) Remove(key string) {
+	g.cache.Delete(g.key(key))
+}
+
+// getLocal serves a key owned by this process to a peer.
+func (g *Group) getLocal(ctx context.Context, key string) ([]byte, time.Duration, error) {
+	k := g.key(key)
+	if v, ok := g.cache.Get(k); ok {
+		if ttl, ok := g.cache.TTL(k); ok && ttl > 0 {
+			return v.([]byte), ttl, nil
+		}
+	}
+	v, err, _ := g.flight.Do(key, func() (interface{}, error) {
+		return g.load(ctx, key)
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	ttl, _ := g.cache.TTL(k)
+	return v.([]byte), ttl, nil
+}
+
+func (g *Group) load(ctx context.Context, key string) ([]byte, error) {
+	value, ttl, err := g.loader(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	g.cache.Set(g.key(key), value, ttl)
+	return value, nil
+}
+
+func (g *Group) key(key string) uint64 {
+	return ttlcache.StringKey(g.name + "\x00" + key)
+}
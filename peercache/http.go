@@ -0,0 +1,156 @@
+package peercache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/loicalleyne/ttlswisscache/internal/ring"
+)
+
+// DefaultBasePath is the path prefix of peer requests.
+const DefaultBasePath = "/_ttlcache/"
+
+const ttlHeader = "X-Ttlcache-Ttl"
+
+// HTTPPool picks peers by consistent hashing and talks to them over HTTP.
+// It also serves the groups of this process to the other peers,
+// mount it on DefaultBasePath.
+type HTTPPool struct {
+	self   string
+	client *http.Client
+
+	mu     sync.RWMutex
+	ring   *ring.Ring
+	peers  map[string]*httpPeer
+	groups map[string]*Group
+}
+
+// NewHTTPPool creates a pool for the process reachable at self, e.g. "http://10.0.0.1:8080".
+// A nil client uses http.DefaultClient.
+func NewHTTPPool(self string, client *http.Client) *HTTPPool {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &HTTPPool{
+		self:   self,
+		client: client,
+		ring:   ring.New(ring.DefaultReplicas, self),
+		groups: make(map[string]*Group),
+	}
+}
+
+// Set replaces the peer list. It should contain self.
+func (p *HTTPPool) Set(peers ...string) {
+	r := ring.New(ring.DefaultReplicas, peers...)
+	m := make(map[string]*httpPeer, len(peers))
+	for _, peer := range peers {
+		m[peer] = &httpPeer{base: strings.TrimSuffix(peer, "/") + DefaultBasePath, client: p.client}
+	}
+
+	p.mu.Lock()
+	p.ring = r
+	p.peers = m
+	p.mu.Unlock()
+}
+
+// Pick implements Picker.
+func (p *HTTPPool) Pick(key string) (Peer, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	owner := p.ring.Get(key)
+	if owner == "" || owner == p.self {
+		return nil, false
+	}
+	return p.peers[owner], true
+}
+
+func (p *HTTPPool) register(g *Group) {
+	p.mu.Lock()
+	p.groups[g.name] = g
+	p.mu.Unlock()
+}
+
+// ServeHTTP serves GET {DefaultBasePath}{group}/{key} requests from peers.
+func (p *HTTPPool) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	rest, ok := strings.CutPrefix(r.URL.EscapedPath(), DefaultBasePath)
+	name, escKey, ok2 := strings.Cut(rest, "/")
+	if !ok || !ok2 {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	key, err := url.PathUnescape(escKey)
+	if err != nil {
+		http.Error(w, "bad key", http.StatusBadRequest)
+		return
+	}
+
+	p.mu.RLock()
+	g := p.groups[name]
+	p.mu.RUnlock()
+	if g == nil {
+		http.Error(w, "no such group: "+name, http.StatusBadRequest)
+		return
+	}
+
+	value, ttl, err := g.getLocal(r.Context(), key)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set(ttlHeader, ttl.String())
+	w.Write(value)
+}
+
+type httpPeer struct {
+	base   string
+	client *http.Client
+}
+
+// Fetch implements Peer.
+func (p *httpPeer) Fetch(ctx context.Context, group, key string) ([]byte, time.Duration, error) {
+	u := p.base + url.PathEscape(group) + "/" + url.PathEscape(key)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, 0, ErrNotFound
+	default:
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, 0, fmt.Errorf("peercache: peer %s: %s: %s", p.base, resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	ttl, err := time.ParseDuration(resp.Header.Get(ttlHeader))
+	if err != nil {
+		return nil, 0, fmt.Errorf("peercache: peer %s: invalid ttl: %w", p.base, err)
+	}
+	value, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, err
+	}
+	return value, ttl, nil
+}
@@ -0,0 +1,135 @@
+package peercache
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	ttlcache "github.com/loicalleyne/ttlswisscache"
+)
+
+type node struct {
+	cache *ttlcache.Cache
+	pool  *HTTPPool
+	group *Group
+	loads atomic.Int64
+}
+
+func startNodes(t *testing.T, n int) []*node {
+	t.Helper()
+	servers := make([]*httptest.Server, n)
+	urls := make([]string, n)
+	for i := range servers {
+		servers[i] = httptest.NewUnstartedServer(nil)
+		servers[i].Start()
+		urls[i] = servers[i].URL
+	}
+
+	nodes := make([]*node, n)
+	for i := range nodes {
+		nd := &node{cache: ttlcache.New(time.Hour)}
+		nd.pool = NewHTTPPool(urls[i], nil)
+		nd.pool.Set(urls...)
+		nd.group = NewGroup("test", nd.cache, func(_ context.Context, key string) ([]byte, time.Duration, error) {
+			nd.loads.Add(1)
+			if key == "missing" {
+				return nil, 0, ErrNotFound
+			}
+			return []byte("value of " + key), time.Hour, nil
+		}, nd.pool)
+		servers[i].Config.Handler = nd.pool
+		nodes[i] = nd
+	}
+
+	t.Cleanup(func() {
+		for i := range servers {
+			servers[i].Close()
+			nodes[i].cache.Close()
+		}
+	})
+	return nodes
+}
+
+func TestGroup_Get(t *testing.T) {
+	nodes := startNodes(t, 3)
+	ctx := context.Background()
+
+	for i := 0; i < 100; i++ {
+		key := strconv.Itoa(i)
+		for _, nd := range nodes {
+			v, err := nd.group.Get(ctx, key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(v) != "value of "+key {
+				t.Errorf("incorrect value: got: %s expected: %s", v, "value of "+key)
+			}
+		}
+	}
+
+	var loads int64
+	for _, nd := range nodes {
+		if nd.loads.Load() == 0 {
+			t.Error("node owns no keys")
+		}
+		loads += nd.loads.Load()
+	}
+	if loads != 100 {
+		t.Errorf("incorrect number of loads: got: %d expected: %d", loads, 100)
+	}
+
+	if _, err := nodes[0].group.Get(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("incorrect error: got: %v expected: %v", err, ErrNotFound)
+	}
+}
+
+func TestGroup_Dedup(t *testing.T) {
+	nodes := startNodes(t, 2)
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		for _, nd := range nodes {
+			wg.Add(1)
+			go func(g *Group) {
+				defer wg.Done()
+				if _, err := g.Get(ctx, "hot"); err != nil {
+					t.Error(err)
+				}
+			}(nd.group)
+		}
+	}
+	wg.Wait()
+
+	if loads := nodes[0].loads.Load() + nodes[1].loads.Load(); loads != 1 {
+		t.Errorf("incorrect number of loads: got: %d expected: %d", loads, 1)
+	}
+}
+
+func TestGroup_PeerDown(t *testing.T) {
+	cache := ttlcache.New(time.Hour)
+	defer cache.Close()
+
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	pool := NewHTTPPool("http://self", nil)
+	pool.Set("http://self", down.URL)
+	g := NewGroup("test", cache, func(_ context.Context, key string) ([]byte, time.Duration, error) {
+		return []byte(key), time.Hour, nil
+	}, pool)
+
+	for i := 0; i < 20; i++ {
+		key := strconv.Itoa(i)
+		v, err := g.Get(context.Background(), key)
+		if err != nil || string(v) != key {
+			t.Errorf("incorrect value: got: %s %v expected: %s", v, err, key)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package ttlswisscache
+
+import (
+	"bufio"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// maxPooledEntries keeps a cleanup of one huge shard from pinning its buffer forever.
+const maxPooledEntries = 1 << 16
+
+// buffers recycles the scratch memory of cleanup, snapshots and merges,
+// so sustained usage doesn't allocate it on every call.
+// The counters are reported by Stats.
+type buffers struct {
+	entries sync.Pool // *[]snapshotEntry
+	writers sync.Pool // *bufio.Writer
+	readers sync.Pool // *bufio.Reader
+
+	allocs atomic.Uint64
+	reuses atomic.Uint64
+}
+
+func (b *buffers) getEntries() *[]snapshotEntry {
+	if v := b.entries.Get(); v != nil {
+		b.reuses.Add(1)
+		return v.(*[]snapshotEntry)
+	}
+	b.allocs.Add(1)
+	entries := make([]snapshotEntry, 0, defaultCapacity)
+	return &entries
+}
+
+func (b *buffers) putEntries(entries *[]snapshotEntry) {
+	if cap(*entries) > maxPooledEntries {
+		return
+	}
+	clearEntries(*entries)
+	*entries = (*entries)[:0]
+	b.entries.Put(entries)
+}
+
+func (b *buffers) getWriter(w io.Writer) *bufio.Writer {
+	if v := b.writers.Get(); v != nil {
+		b.reuses.Add(1)
+		bw := v.(*bufio.Writer)
+		bw.Reset(w)
+		return bw
+	}
+	b.allocs.Add(1)
+	return bufio.NewWriter(w)
+}
+
+func (b *buffers) putWriter(bw *bufio.Writer) {
+	bw.Reset(nil)
+	b.writers.Put(bw)
+}
+
+func (b *buffers) getReader(r io.Reader) *bufio.Reader {
+	if v := b.readers.Get(); v != nil {
+		b.reuses.Add(1)
+		br := v.(*bufio.Reader)
+		br.Reset(r)
+		return br
+	}
+	b.allocs.Add(1)
+	return bufio.NewReader(r)
+}
+
+func (b *buffers) putReader(br *bufio.Reader) {
+	br.Reset(nil)
+	b.readers.Put(br)
+}
@@ -0,0 +1,51 @@
+package ttlswisscache
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestCache_BufferReuse(t *testing.T) {
+	c := New(0)
+	defer c.Close()
+
+	for i := 0; i < 100; i++ {
+		for k := 0; k < 100; k++ {
+			c.Set(IntKey(k), k, -time.Second)
+		}
+		c.DeleteExpired()
+	}
+	st := c.Stats()
+	if st.BufferReuses == 0 || st.BufferAllocs > st.BufferReuses {
+		t.Errorf("buffers are not reused: got: %d allocs %d reuses", st.BufferAllocs, st.BufferReuses)
+	}
+
+	var buf bytes.Buffer
+	c.Set(IntKey(1), 1, time.Hour)
+	c.Snapshot(&buf, GobCodec{})
+	before := c.Stats().BufferReuses
+	for i := 0; i < 10; i++ {
+		c.Snapshot(&buf, GobCodec{})
+	}
+	// sync.Pool may drop buffers, only check that some were reused.
+	if c.Stats().BufferReuses == before {
+		t.Error("snapshot buffers are not reused")
+	}
+}
+
+func TestCache_DeleteExpiredAllocs(t *testing.T) {
+	c := New(0)
+	defer c.Close()
+	c.DeleteExpired()
+
+	n := testing.AllocsPerRun(100, func() {
+		for k := 0; k < 10; k++ {
+			c.Set(IntKey(k), nil, -time.Second)
+		}
+		c.DeleteExpired()
+	})
+	if n != 0 {
+		t.Errorf("incorrect number of allocations: got: %v expected: %v", n, 0)
+	}
+}
@@ -0,0 +1,84 @@
+// Package ratelimit limits the rate of events per key with counters stored in
+// an Int64Cache.
+//
+// A Limiter uses a sliding window of burst/rate: the events of the current
+// window are added to those of the previous one weighted by the part of it
+// still in the sliding window. Counters are updated with atomic increments and
+// expire with the cache ttl, so idle keys take no memory.
+package ratelimit
+
+import (
+	"time"
+
+	ttlcache "github.com/loicalleyne/ttlswisscache"
+)
+
+// windowMix spreads window numbers over the key bits.
+const windowMix = 0x9e3779b97f4a7c15
+
+// Limiter allows up to burst events at once per key and rate events per second
+// on average. It is safe for concurrent use.
+type Limiter struct {
+	cache  *ttlcache.Int64Cache
+	window time.Duration
+	burst  int64
+}
+
+// New returns a limiter keeping its counters in cache, which should be dedicated
+// to it. Time is read from the cache clock, see ttlcache.WithClock.
+// rate and burst must be positive.
+func New(cache *ttlcache.Int64Cache, rate float64, burst int) *Limiter {
+	window := time.Duration(float64(burst) / rate * float64(time.Second))
+	return &Limiter{cache: cache, window: max(window, 1), burst: int64(burst)}
+}
+
+// Allow reports whether an event for key may happen now and records it if so.
+func (l *Limiter) Allow(key uint64) bool {
+	return l.AllowN(key, 1)
+}
+
+// AllowN reports whether n events for key may happen now and records them if so.
+// Denied events are not recorded.
+func (l *Limiter) AllowN(key uint64, n int) bool {
+	w, elapsed := l.now()
+	cur := windowKey(key, w)
+	count := l.cache.Add(cur, int64(n), 2*l.window)
+	if l.estimate(key, w, elapsed, count) > float64(l.burst) {
+		l.cache.Add(cur, -int64(n), 2*l.window)
+		return false
+	}
+	return true
+}
+
+// Remaining returns the number of events for key allowed now.
+func (l *Limiter) Remaining(key uint64) int {
+	w, elapsed := l.now()
+	count, _ := l.cache.Get(windowKey(key, w))
+	return max(int(float64(l.burst)-l.estimate(key, w, elapsed, count)), 0)
+}
+
+// Reset forgets the events for key.
+func (l *Limiter) Reset(key uint64) {
+	w, _ := l.now()
+	l.cache.Delete(windowKey(key, w))
+	l.cache.Delete(windowKey(key, w-1))
+}
+
+// now returns the current window and the time elapsed in it.
+func (l *Limiter) now() (int64, time.Duration) {
+	now := l.cache.Now().UnixNano()
+	return now / int64(l.window), time.Duration(now % int64(l.window))
+}
+
+// estimate returns the number of events in the sliding window ending now,
+// given count events in the current window w.
+func (l *Limiter) estimate(key uint64, w int64, elapsed time.Duration, count int64) float64 {
+	prev, _ := l.cache.Get(windowKey(key, w-1))
+	weight := float64(l.window-elapsed) / float64(l.window)
+	return float64(prev)*weight + float64(count)
+}
+
+// windowKey returns the key of the counter of key for window w.
+func windowKey(key uint64, w int64) uint64 {
+	return key ^ uint64(w)*windowMix
+}
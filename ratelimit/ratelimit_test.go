@@ -0,0 +1,91 @@
+package ratelimit
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	ttlcache "github.com/loicalleyne/ttlswisscache"
+	"github.com/loicalleyne/ttlswisscache/ttltest"
+)
+
+func newLimiter(t *testing.T, rate float64, burst int) (*Limiter, *ttltest.Clock) {
+	clock := ttltest.NewClock()
+	c := ttlcache.NewInt64Cache(0, ttlcache.WithClock(clock))
+	t.Cleanup(func() { c.Close() })
+	return New(c, rate, burst), clock
+}
+
+func TestLimiter(t *testing.T) {
+	l, clock := newLimiter(t, 10, 5)
+	key := ttlcache.StringKey("client")
+
+	for i := 0; i < 5; i++ {
+		if !l.Allow(key) {
+			t.Errorf("event %d was denied", i)
+		}
+	}
+	if l.Allow(key) {
+		t.Error("event over burst was allowed")
+	}
+	if n := l.Remaining(key); n != 0 {
+		t.Errorf("incorrect remaining: got: %d expected: %d", n, 0)
+	}
+	if !l.Allow(ttlcache.StringKey("other")) {
+		t.Error("event of another key was denied")
+	}
+
+	// Half of the previous window is still in the sliding window.
+	clock.Advance(750 * time.Millisecond)
+	if n := l.Remaining(key); n != 2 {
+		t.Errorf("incorrect remaining: got: %d expected: %d", n, 2)
+	}
+	if l.AllowN(key, 3) {
+		t.Error("events over the sliding window were allowed")
+	}
+	if !l.AllowN(key, 2) {
+		t.Error("events within the sliding window were denied")
+	}
+
+	clock.Advance(time.Second)
+	if n := l.Remaining(key); n != 5 {
+		t.Errorf("incorrect remaining after idle time: got: %d expected: %d", n, 5)
+	}
+}
+
+func TestLimiter_Reset(t *testing.T) {
+	l, _ := newLimiter(t, 1, 2)
+	key := ttlcache.IntKey(1)
+	l.AllowN(key, 2)
+	l.Reset(key)
+	if !l.Allow(key) {
+		t.Error("event after reset was denied")
+	}
+}
+
+func TestLimiter_Concurrent(t *testing.T) {
+	l, _ := newLimiter(t, 1, 100)
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		allowed int
+	)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				if l.Allow(ttlcache.IntKey(1)) {
+					mu.Lock()
+					allowed++
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	// Concurrent denied events may deny others while they are refunded, never allow more.
+	if allowed > 100 || allowed == 0 {
+		t.Errorf("incorrect number of allowed events: got: %d expected: (0, %d]", allowed, 100)
+	}
+}
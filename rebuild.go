@@ -0,0 +1,68 @@
+package ttlswisscache
+
+// WithRebuild makes the cleanup manager rebuild the map of a shard once the
+// records removed from it since the map was built reach churn times its
+// capacity, e.g. 1. Swiss maps leave deleted slots behind which lengthen probes
+// until the map grows again, so caches with a steady number of records and a
+// high turnover otherwise keep them for their whole uptime. At most one shard
+// is rebuilt per cleanup, under its lock, so the other shards aren't held up
+// meanwhile. Rebuilt shards are shrunk like by
+// Compact. Stats.Rebuilds counts the rebuilds.
+func WithRebuild(churn float64) Option {
+	return func(o *options) {
+		o.rebuildChurn = churn
+	}
+}
+
+// Rebuild rebuilds the maps of the shards whose removed records since the
+// map was built reach churn times its capacity, see WithRebuild, and returns
+// their number. Each shard is locked while it is rebuilt.
+func (c *Cache) Rebuild(churn float64) int {
+	n := 0
+	for _, s := range c.shards.list {
+		if c.rebuild(s, churn) {
+			n++
+		}
+	}
+	return n
+}
+
+// rebuildNext rebuilds the first shard due for a rebuild after the one rebuilt
+// last, for the cleanup manager.
+func (c *Cache) rebuildNext() {
+	list := c.shards.list
+	start := int(c.rebuildCursor.Load())
+	for i := range list {
+		j := (start + i) % len(list)
+		if c.rebuild(list[j], c.opts.rebuildChurn) {
+			c.rebuildCursor.Store(uint32(j + 1))
+			return
+		}
+	}
+}
+
+// rebuild rebuilds the map of s if it is due and reports whether it did.
+func (c *Cache) rebuild(s *shard, churn float64) bool {
+	s.RLock()
+	due := s.due(churn)
+	s.RUnlock()
+	if !due {
+		return false
+	}
+	s.Lock()
+	due = s.due(churn)
+	if due && !s.compact() {
+		s.resize(s.capacity)
+	}
+	s.Unlock()
+	if due {
+		c.rebuilds.Add(1)
+	}
+	return due
+}
+
+// due reports whether the records removed since the map was built reach
+// churn times its capacity. The shard must be locked.
+func (s *shard) due(churn float64) bool {
+	return s.churn > 0 && float64(s.churn) >= churn*float64(s.capacity)
+}
@@ -0,0 +1,54 @@
+package ttlswisscache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCache_Rebuild(t *testing.T) {
+	c := New(0, WithShardCount(2), WithCapacity(128))
+	defer c.Close()
+	for i := 0; i < 1000; i++ {
+		c.Set(IntKey(i%50), i, time.Hour)
+		if i%2 == 1 {
+			c.Delete(IntKey(i % 50))
+		}
+	}
+	if n := c.Rebuild(100); n != 0 {
+		t.Errorf("incorrect number of shards rebuilt below the churn: got: %d expected: %d", n, 0)
+	}
+	if n := c.Rebuild(1); n != 2 {
+		t.Errorf("incorrect number of rebuilt shards: got: %d expected: %d", n, 2)
+	}
+	if n := c.Rebuild(1); n != 0 {
+		t.Errorf("rebuilt shards were rebuilt again: got: %d expected: %d", n, 0)
+	}
+	if st := c.Stats(); st.Rebuilds != 2 || st.Entries != 25 {
+		t.Errorf("incorrect stats: got: %d rebuilds, %d entries expected: 2 rebuilds, 25 entries", st.Rebuilds, st.Entries)
+	}
+	for i := 0; i < 50; i++ {
+		if _, ok := c.Get(IntKey(i)); ok != (i%2 == 0) {
+			t.Errorf("incorrect existence of record %d after the rebuild: got: %v", i, ok)
+		}
+	}
+}
+
+func TestWithRebuild(t *testing.T) {
+	clock := &fixedClock{now: time.Unix(1000, 0)}
+	c := New(0, WithClock(clock), WithShardCount(4), WithCapacity(64), WithRebuild(1))
+	defer c.Close()
+	for i := 0; i < 1000; i++ {
+		c.Set(IntKey(i), i, time.Second)
+		c.Delete(IntKey(i))
+	}
+	for i := 1; i <= 4; i++ {
+		c.sweep(t.Context())
+		if n := c.Stats().Rebuilds; n != uint64(i) {
+			t.Errorf("incorrect number of rebuilds after %d cleanups: got: %d expected: %d", i, n, i)
+		}
+	}
+	c.sweep(t.Context())
+	if n := c.Stats().Rebuilds; n != 4 {
+		t.Errorf("incorrect number of rebuilds: got: %d expected: %d", n, 4)
+	}
+}
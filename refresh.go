@@ -0,0 +1,272 @@
+package ttlswisscache
+
+import (
+	"context"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// RefreshFunc loads the current value of key and its ttl for a refresh,
+// see WithRefresh.
+type RefreshFunc func(ctx context.Context, key uint64) (value interface{}, ttl time.Duration, err error)
+
+// RefreshOptions configure the refresh workers of WithRefresh.
+// Zero fields take the defaults.
+type RefreshOptions struct {
+	// Ahead is how long before their deadline records read by Get are refreshed,
+	// zero refreshes only outdated records returned by GetStale.
+	Ahead time.Duration
+	// Workers bounds the number of concurrent refreshes, 4 by default.
+	Workers int
+	// Queue bounds the number of refreshes waiting for a worker, 1024 by default.
+	// Refreshes over it are dropped.
+	Queue int
+	// Jitter delays every refresh by a random duration up to Jitter, so records
+	// set together aren't refreshed together.
+	Jitter time.Duration
+	// Timeout bounds every call of the RefreshFunc, 10s by default.
+	Timeout time.Duration
+	// Backoff is the delay before a key whose refresh failed is refreshed again,
+	// doubled by every consecutive failure up to MaxBackoff. 1s and 1m by default.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+}
+
+// WithRefresh refreshes records in the background with fn, or the function
+// the record was stored with by SetWithRefresher: records read by Get
+// within RefreshOptions.Ahead of their deadline (refresh-ahead) and outdated
+// records returned by GetStale (stale-while-revalidate), usually with
+// WithGracePeriod. A bounded pool of workers runs the refreshes, a key is
+// refreshed once at a time, and failing keys back off, so refresh traffic
+// can't stampede the origin. A refreshed value replaces the record only if it
+// still exists. Refresh schedules a refresh explicitly.
+// fn may be nil if only records stored by SetWithRefresher are refreshed.
+func WithRefresh(fn RefreshFunc, opts RefreshOptions) Option {
+	return func(o *options) {
+		o.refresh = fn
+		o.refreshOpts = &opts
+	}
+}
+
+// refreshedValue is a value stored with its refresh function, see SetWithRefresher.
+type refreshedValue struct {
+	value interface{}
+	fn    RefreshFunc
+}
+
+// SetWithRefresher adds value with given ttl like Set, along with the function
+// refreshing it, so records backed by different origins are each refreshed
+// from their own by the workers of WithRefresh. Without WithRefresh the
+// record is never refreshed. The function is kept by the refreshed records
+// and dropped by a Set of the key. Snapshots don't keep it.
+func (c *Cache) SetWithRefresher(key uint64, value interface{}, ttl time.Duration, fn RefreshFunc) uint64 {
+	value, ok := c.limit(key, value)
+	if !ok {
+		c.reject(key)
+		return 0
+	}
+	return c.store(key, item{
+		deadline: c.clock.unixNano() + int64(ttl),
+		value:    &refreshedValue{value: c.clone(value), fn: fn},
+	})
+}
+
+// refreshFunc returns the function of the record of key stored by
+// SetWithRefresher, nil for other records.
+func (c *Cache) refreshFunc(key uint64) RefreshFunc {
+	s := c.shards.get(key)
+	s.RLock()
+	defer s.RUnlock()
+	i, ok := s.index.Get(key)
+	if !ok {
+		return nil
+	}
+	if v, ok := s.values[i].(*refreshedValue); ok {
+		return v.fn
+	}
+	return nil
+}
+
+// refresher runs the refreshes of WithRefresh.
+type refresher struct {
+	cache *Cache
+	fn    RefreshFunc
+	opts  RefreshOptions
+	queue chan uint64
+
+	mu       sync.Mutex
+	pending  map[uint64]struct{} // Keys scheduled or being refreshed.
+	failures map[uint64]refreshFailure
+
+	refreshes atomic.Uint64
+	errors    atomic.Uint64
+	dropped   atomic.Uint64
+}
+
+// refreshFailure tracks the consecutive failures of the refreshes of a key.
+type refreshFailure struct {
+	count int
+	retry int64 // Unix nano of the cache clock before which the key isn't refreshed.
+}
+
+func newRefresher(c *Cache, fn RefreshFunc, o *RefreshOptions) *refresher {
+	if o == nil {
+		return nil
+	}
+	opts := *o
+	if opts.Workers <= 0 {
+		opts.Workers = 4
+	}
+	if opts.Queue <= 0 {
+		opts.Queue = 1024
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 10 * time.Second
+	}
+	if opts.Backoff <= 0 {
+		opts.Backoff = time.Second
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = time.Minute
+	}
+	r := &refresher{
+		cache:    c,
+		fn:       fn,
+		opts:     opts,
+		queue:    make(chan uint64, opts.Queue),
+		pending:  make(map[uint64]struct{}),
+		failures: make(map[uint64]refreshFailure),
+	}
+	for i := 0; i < opts.Workers; i++ {
+		go r.work(c.base)
+	}
+	return r
+}
+
+// Refresh schedules a refresh of key with the WithRefresh function and reports
+// whether it did. A key is not scheduled while a refresh of it is pending or
+// during its backoff. Without WithRefresh it reports false.
+func (c *Cache) Refresh(key uint64) bool {
+	return c.refresher != nil && c.refresher.schedule(key)
+}
+
+// ahead schedules a refresh of key if its deadline is within RefreshOptions.Ahead.
+func (r *refresher) ahead(key uint64, deadline int64) {
+	if r.opts.Ahead > 0 && deadline-r.cache.clock.unixNano() < int64(r.opts.Ahead) {
+		r.schedule(key)
+	}
+}
+
+func (r *refresher) schedule(key uint64) bool {
+	now := r.cache.clock.unixNano()
+	r.mu.Lock()
+	if _, ok := r.pending[key]; ok {
+		r.mu.Unlock()
+		return false
+	}
+	if f, ok := r.failures[key]; ok && now < f.retry {
+		r.mu.Unlock()
+		return false
+	}
+	r.pending[key] = struct{}{}
+	r.mu.Unlock()
+
+	if r.opts.Jitter > 0 {
+		time.AfterFunc(rand.N(r.opts.Jitter), func() { r.enqueue(key) })
+		return true
+	}
+	r.enqueue(key)
+	return true
+}
+
+func (r *refresher) enqueue(key uint64) {
+	select {
+	case <-r.cache.done:
+	case r.queue <- key:
+		return
+	default:
+		r.dropped.Add(1)
+	}
+	r.mu.Lock()
+	delete(r.pending, key)
+	r.mu.Unlock()
+}
+
+func (r *refresher) work(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case key := <-r.queue:
+			r.refresh(ctx, key)
+		}
+	}
+}
+
+func (r *refresher) refresh(ctx context.Context, key uint64) {
+	own := r.cache.refreshFunc(key)
+	fn := r.fn
+	if own != nil {
+		fn = own
+	}
+	if fn == nil {
+		r.mu.Lock()
+		delete(r.pending, key)
+		r.mu.Unlock()
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, r.opts.Timeout)
+	value, ttl, err := fn(ctx, key)
+	cancel()
+	if err == nil {
+		r.cache.replace(key, value, ttl, own)
+		r.refreshes.Add(1)
+	} else {
+		r.errors.Add(1)
+	}
+
+	now := r.cache.clock.unixNano()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.pending, key)
+	if err == nil {
+		delete(r.failures, key)
+		return
+	}
+	f := r.failures[key]
+	backoff := min(r.opts.Backoff<<min(f.count, 30), r.opts.MaxBackoff)
+	r.failures[key] = refreshFailure{count: f.count + 1, retry: now + int64(backoff)}
+	// Keys failing once and never refreshed again would accumulate.
+	if len(r.failures) > 4*cap(r.queue) {
+		for k, f := range r.failures {
+			if f.retry < now {
+				delete(r.failures, k)
+			}
+		}
+	}
+}
+
+// replace stores value with given ttl, and fn if not nil, if the cache holds
+// a record of key, outdated or not, so refreshes don't bring deleted records back.
+func (c *Cache) replace(key uint64, value interface{}, ttl time.Duration, fn RefreshFunc) {
+	value, ok := c.limit(key, value)
+	if !ok {
+		return
+	}
+	it := item{deadline: c.clock.unixNano() + int64(ttl), value: c.clone(value)}
+	if fn != nil {
+		it.value = &refreshedValue{value: it.value, fn: fn}
+	}
+	s := c.shards.get(key)
+	s.Lock()
+	if _, ok := s.index.Get(key); !ok || c.closing.Load() || c.protected(s, key) {
+		s.Unlock()
+		return
+	}
+	s.put(key, it)
+	c.subs.publish(OpSet, key, it)
+	s.Unlock()
+	s.stats.sets.Add(1)
+}
@@ -0,0 +1,167 @@
+package ttlswisscache
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func waitUntil(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		if cond() {
+			return
+		}
+	}
+	t.Fatalf("timed out waiting for %s", what)
+}
+
+func TestWithRefresh_Ahead(t *testing.T) {
+	clock := &fixedClock{now: time.Unix(1000, 0)}
+	var calls atomic.Int32
+	release := make(chan struct{})
+	fn := func(_ context.Context, key uint64) (interface{}, time.Duration, error) {
+		calls.Add(1)
+		<-release
+		return "fresh", time.Hour, nil
+	}
+	c := New(0, WithClock(clock), WithRefresh(fn, RefreshOptions{Ahead: time.Minute}))
+	defer c.Close()
+	c.Set(IntKey(1), "old", 5*time.Minute)
+
+	c.Get(IntKey(1))
+	if calls.Load() != 0 {
+		t.Error("record far from its deadline was refreshed")
+	}
+	clock.now = clock.now.Add(4*time.Minute + 30*time.Second)
+	for i := 0; i < 10; i++ {
+		if v, _ := c.Get(IntKey(1)); v != "old" {
+			t.Errorf("incorrect value during the refresh: got: %v expected: %v", v, "old")
+		}
+	}
+	close(release)
+	waitUntil(t, "the refresh", func() bool {
+		v, _ := c.Get(IntKey(1))
+		return v == "fresh"
+	})
+	if n := calls.Load(); n != 1 {
+		t.Errorf("incorrect number of refreshes: got: %d expected: %d", n, 1)
+	}
+	if ttl, _ := c.TTL(IntKey(1)); ttl != time.Hour {
+		t.Errorf("incorrect ttl after the refresh: got: %v expected: %v", ttl, time.Hour)
+	}
+	if st := c.Stats(); st.Refreshes != 1 {
+		t.Errorf("incorrect number of refreshes: got: %d expected: %d", st.Refreshes, 1)
+	}
+}
+
+func TestWithRefresh_Stale(t *testing.T) {
+	clock := &fixedClock{now: time.Unix(1000, 0)}
+	done := make(chan uint64, 10)
+	fn := func(_ context.Context, key uint64) (interface{}, time.Duration, error) {
+		defer func() { done <- key }()
+		return "fresh", time.Hour, nil
+	}
+	c := New(0, WithClock(clock), WithGracePeriod(time.Hour), WithRefresh(fn, RefreshOptions{}))
+	defer c.Close()
+	c.Set(IntKey(1), "old", time.Minute)
+
+	c.GetStale(IntKey(1))
+	clock.now = clock.now.Add(2 * time.Minute)
+	if v, _, _ := c.GetStale(IntKey(1)); v != "old" {
+		t.Errorf("incorrect stale value: got: %v expected: %v", v, "old")
+	}
+	if key := <-done; key != IntKey(1) {
+		t.Errorf("incorrect refreshed key: got: %d expected: %d", key, IntKey(1))
+	}
+	waitUntil(t, "the refresh", func() bool {
+		v, ok := c.Get(IntKey(1))
+		return ok && v == "fresh"
+	})
+
+	// Deleted records are not brought back.
+	c.Delete(IntKey(1))
+	c.Refresh(IntKey(1))
+	<-done
+	if _, ok := c.Get(IntKey(1)); ok {
+		t.Error("refresh stored a deleted record")
+	}
+}
+
+func TestWithRefresh_Backoff(t *testing.T) {
+	clock := &fixedClock{now: time.Unix(1000, 0)}
+	done := make(chan struct{}, 10)
+	fn := func(context.Context, uint64) (interface{}, time.Duration, error) {
+		defer func() { done <- struct{}{} }()
+		return nil, 0, errors.New("origin down")
+	}
+	c := New(0, WithClock(clock), WithRefresh(fn, RefreshOptions{Backoff: time.Second, MaxBackoff: 3 * time.Second}))
+	defer c.Close()
+	c.Set(IntKey(1), "old", time.Hour)
+
+	for _, backoff := range []time.Duration{time.Second, 2 * time.Second, 3 * time.Second, 3 * time.Second} {
+		if !c.Refresh(IntKey(1)) {
+			t.Fatal("refresh was not scheduled")
+		}
+		<-done
+		waitUntil(t, "the end of the refresh", func() bool {
+			c.refresher.mu.Lock()
+			defer c.refresher.mu.Unlock()
+			return len(c.refresher.pending) == 0
+		})
+		clock.now = clock.now.Add(backoff - time.Nanosecond)
+		if c.Refresh(IntKey(1)) {
+			t.Errorf("refresh was scheduled during the backoff of %v", backoff)
+		}
+		clock.now = clock.now.Add(time.Nanosecond)
+	}
+	if st := c.Stats(); st.RefreshErrors != 4 {
+		t.Errorf("incorrect number of failed refreshes: got: %d expected: %d", st.RefreshErrors, 4)
+	}
+	if v, _ := c.Get(IntKey(1)); v != "old" {
+		t.Errorf("failed refresh changed the value: got: %v expected: %v", v, "old")
+	}
+}
+
+func TestSetWithRefresher(t *testing.T) {
+	clock := &fixedClock{now: time.Unix(1000, 0)}
+	origin := func(value string) RefreshFunc {
+		return func(context.Context, uint64) (interface{}, time.Duration, error) {
+			return value, time.Hour, nil
+		}
+	}
+	c := New(0, WithClock(clock), WithRefresh(nil, RefreshOptions{}))
+	defer c.Close()
+	c.SetWithRefresher(IntKey(1), "old", time.Minute, origin("users"))
+	c.SetWithRefresher(IntKey(2), "old", time.Minute, origin("orders"))
+	c.Set(IntKey(3), "old", time.Minute)
+
+	if v, _ := c.Get(IntKey(1)); v != "old" {
+		t.Errorf("incorrect value: got: %v expected: %v", v, "old")
+	}
+	for _, key := range []uint64{IntKey(1), IntKey(2), IntKey(3)} {
+		c.Refresh(key)
+	}
+	waitUntil(t, "the refreshes", func() bool {
+		v1, _ := c.Get(IntKey(1))
+		v2, _ := c.Get(IntKey(2))
+		return v1 == "users" && v2 == "orders"
+	})
+	if v, _ := c.Get(IntKey(3)); v != "old" {
+		t.Errorf("record without a refresh function was refreshed: got: %v expected: %v", v, "old")
+	}
+
+	// The refreshed record keeps its function, a Set drops it.
+	c.Set(IntKey(1), "old", time.Minute)
+	c.Refresh(IntKey(1))
+	c.Refresh(IntKey(2))
+	waitUntil(t, "the second refresh", func() bool { return c.Stats().Refreshes == 3 })
+	if v, _ := c.Get(IntKey(2)); v != "orders" {
+		t.Errorf("incorrect value after the second refresh: got: %v expected: %v", v, "orders")
+	}
+	if v, _ := c.Get(IntKey(1)); v != "old" {
+		t.Errorf("record set without a refresh function was refreshed: got: %v expected: %v", v, "old")
+	}
+}
@@ -0,0 +1,99 @@
+// Package raftlog implements replicated.Log on top of hashicorp/raft.
+package raftlog
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/hashicorp/raft"
+
+	"github.com/loicalleyne/ttlswisscache/replicated"
+)
+
+// Log proposes commands to a raft cluster.
+type Log struct {
+	raft *raft.Raft
+}
+
+// New wraps r. The FSM of r must be created with FSM.
+func New(r *raft.Raft) *Log {
+	return &Log{raft: r}
+}
+
+// Raft returns the underlying raft node, e.g. to add voters.
+func (l *Log) Raft() *raft.Raft {
+	return l.raft
+}
+
+// Apply implements replicated.Log.
+// The context deadline becomes the raft enqueue timeout.
+func (l *Log) Apply(ctx context.Context, cmd []byte) error {
+	var timeout time.Duration
+	if deadline, ok := ctx.Deadline(); ok {
+		if timeout = time.Until(deadline); timeout <= 0 {
+			return context.DeadlineExceeded
+		}
+	}
+	f := l.raft.Apply(cmd, timeout)
+	if err := f.Error(); err != nil {
+		if errors.Is(err, raft.ErrNotLeader) || errors.Is(err, raft.ErrLeadershipLost) {
+			return replicated.ErrNotLeader
+		}
+		return err
+	}
+	if err, ok := f.Response().(error); ok {
+		return err
+	}
+	return nil
+}
+
+// IsLeader implements replicated.Log.
+func (l *Log) IsLeader() bool {
+	return l.raft.State() == raft.Leader
+}
+
+// FSM adapts the state machine to raft.
+func FSM(sm replicated.StateMachine) raft.FSM {
+	return fsm{sm}
+}
+
+type fsm struct {
+	sm replicated.StateMachine
+}
+
+func (f fsm) Apply(l *raft.Log) interface{} {
+	if l.Type != raft.LogCommand {
+		return nil
+	}
+	return f.sm.ApplyCommand(l.Data)
+}
+
+// Snapshot encodes the state right away: raft applies new commands
+// while the snapshot is being persisted.
+func (f fsm) Snapshot() (raft.FSMSnapshot, error) {
+	var buf bytes.Buffer
+	if err := f.sm.Snapshot(&buf); err != nil {
+		return nil, err
+	}
+	return snapshot(buf.Bytes()), nil
+}
+
+func (f fsm) Restore(rc io.ReadCloser) error {
+	defer rc.Close()
+	return f.sm.Restore(rc)
+}
+
+type snapshot []byte
+
+func (s snapshot) Persist(sink raft.SnapshotSink) error {
+	if _, err := sink.Write(s); err != nil {
+		sink.Cancel()
+		return err
+	}
+	return sink.Close()
+}
+
+func (snapshot) Release() {}
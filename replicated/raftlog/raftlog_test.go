@@ -0,0 +1,112 @@
+package raftlog
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/raft"
+
+	ttlcache "github.com/loicalleyne/ttlswisscache"
+	"github.com/loicalleyne/ttlswisscache/replicated"
+)
+
+func TestLog(t *testing.T) {
+	const n = 3
+	var (
+		nodes      [n]*replicated.Cache
+		rafts      [n]*raft.Raft
+		transports [n]*raft.InmemTransport
+		servers    []raft.Server
+	)
+	for i := range transports {
+		_, transports[i] = raft.NewInmemTransport(raft.ServerAddress(fmt.Sprint("node", i)))
+		servers = append(servers, raft.Server{
+			ID:      raft.ServerID(fmt.Sprint("node", i)),
+			Address: transports[i].LocalAddr(),
+		})
+	}
+	for i := range transports {
+		for j := range transports {
+			if i != j {
+				transports[i].Connect(transports[j].LocalAddr(), transports[j])
+			}
+		}
+	}
+	for i := range nodes {
+		conf := raft.DefaultConfig()
+		conf.LocalID = servers[i].ID
+		conf.HeartbeatTimeout = 50 * time.Millisecond
+		conf.ElectionTimeout = 50 * time.Millisecond
+		conf.LeaderLeaseTimeout = 50 * time.Millisecond
+		conf.CommitTimeout = 5 * time.Millisecond
+		conf.LogOutput = io.Discard
+
+		store := raft.NewInmemStore()
+		nodes[i] = replicated.New(ttlcache.GobCodec{})
+		r, err := raft.NewRaft(conf, FSM(nodes[i]), store, store, raft.NewInmemSnapshotStore(), transports[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		rafts[i] = r
+		nodes[i].Start(New(r), 10*time.Millisecond)
+		defer func(i int) {
+			rafts[i].Shutdown().Error()
+			nodes[i].Close()
+		}(i)
+	}
+	if err := rafts[0].BootstrapCluster(raft.Configuration{Servers: servers}).Error(); err != nil {
+		t.Fatal(err)
+	}
+
+	var leader *replicated.Cache
+	for deadline := time.Now().Add(5 * time.Second); leader == nil; {
+		if time.Now().After(deadline) {
+			t.Fatal("no leader elected")
+		}
+		for i, r := range rafts {
+			if r.State() == raft.Leader {
+				leader = nodes[i]
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := leader.Set(ctx, 1, "a", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if err := leader.Set(ctx, 2, "b", 20*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+
+	waitFor(t, "replication", func() bool {
+		for _, node := range nodes {
+			if v, _ := node.Get(1); v != "a" {
+				return false
+			}
+		}
+		return true
+	})
+	waitFor(t, "expiration", func() bool {
+		for _, node := range nodes {
+			if _, ok := node.Get(2); ok {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); !cond(); {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,182 @@
+// Package replicated keeps a Cache identical on every node of a small cluster.
+//
+// Writes are proposed to a replicated Log and applied by every node in log order.
+// Deadlines are computed once, by the node proposing the write, and records expire
+// only when the leader proposes an expiration with its clock, so all nodes drop the
+// same records at the same log position. This suits locks and leases that must not
+// outlive their TTL on one node and not on another.
+//
+// The Log is an interface, the raftlog subpackage implements it with hashicorp/raft.
+package replicated
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	ttlcache "github.com/loicalleyne/ttlswisscache"
+)
+
+// ErrNotLeader is returned by writes on a node that can't propose commands.
+var ErrNotLeader = errors.New("replicated: not the leader")
+
+// Log replicates commands to every node in the same order.
+type Log interface {
+	// Apply proposes the command and returns once it is committed
+	// and applied by the local state machine.
+	Apply(ctx context.Context, cmd []byte) error
+	// IsLeader reports whether the node accepts writes.
+	IsLeader() bool
+}
+
+// StateMachine is driven by the Log. It is implemented by Cache.
+type StateMachine interface {
+	// ApplyCommand applies a committed command.
+	ApplyCommand(cmd []byte) error
+	// Snapshot writes the whole state to w.
+	Snapshot(w io.Writer) error
+	// Restore replaces the state with a snapshot.
+	Restore(r io.Reader) error
+}
+
+// Cache is a replicated cache node.
+// Reads are served by the local copy and may lag behind the leader.
+type Cache struct {
+	cache *ttlcache.Cache
+	codec ttlcache.Codec
+
+	log  Log
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// New creates a node. The cleanup manager of the local cache is disabled,
+// expiration is driven by the leader once Start is called.
+// codec encodes values in commands and snapshots.
+func New(codec ttlcache.Codec) *Cache {
+	return &Cache{
+		cache: ttlcache.New(0),
+		codec: codec,
+		done:  make(chan struct{}),
+	}
+}
+
+// Start attaches the log and, while the node is the leader, proposes an
+// expiration every resolution tick. It must be called once before writing.
+func (c *Cache) Start(log Log, resolution time.Duration) {
+	c.log = log
+	c.wg.Add(1)
+	go c.expirer(resolution)
+}
+
+// Close stops the node and clears the local copy. The log is not closed.
+func (c *Cache) Close() error {
+	close(c.done)
+	c.wg.Wait()
+	return c.cache.Close()
+}
+
+// Local returns the local copy. Changing it directly breaks replication,
+// use it for reads, Subscribe and Stats.
+func (c *Cache) Local() *ttlcache.Cache {
+	return c.cache
+}
+
+// Get returns the value stored on this node.
+func (c *Cache) Get(key uint64) (interface{}, bool) {
+	return c.cache.Get(key)
+}
+
+// TTL returns the remaining time to live of the record on this node.
+func (c *Cache) TTL(key uint64) (time.Duration, bool) {
+	return c.cache.TTL(key)
+}
+
+// Set replicates the value with the given ttl.
+func (c *Cache) Set(ctx context.Context, key uint64, value interface{}, ttl time.Duration) error {
+	return c.propose(ctx, ttlcache.Event{
+		Op:       ttlcache.OpSet,
+		Key:      key,
+		Value:    value,
+		Deadline: time.Now().Add(ttl),
+	})
+}
+
+// Delete removes the record on all nodes.
+func (c *Cache) Delete(ctx context.Context, key uint64) error {
+	return c.propose(ctx, ttlcache.Event{Op: ttlcache.OpDelete, Key: key})
+}
+
+// Clear removes all records on all nodes.
+func (c *Cache) Clear(ctx context.Context) error {
+	return c.propose(ctx, ttlcache.Event{Op: ttlcache.OpClear})
+}
+
+// propose encodes the event as a CDC record and applies it through the log.
+func (c *Cache) propose(ctx context.Context, ev ttlcache.Event) error {
+	if c.log == nil || !c.log.IsLeader() {
+		return ErrNotLeader
+	}
+	var buf bytes.Buffer
+	cw := ttlcache.NewChangeWriter(&buf, c.codec)
+	if err := cw.Write(ev); err != nil {
+		return err
+	}
+	if err := cw.Flush(); err != nil {
+		return err
+	}
+	return c.log.Apply(ctx, buf.Bytes())
+}
+
+// ApplyCommand implements StateMachine.
+// An OpExpire command removes the records with a deadline before its own.
+func (c *Cache) ApplyCommand(cmd []byte) error {
+	ev, err := ttlcache.NewChangeReader(bytes.NewReader(cmd), c.codec).Next()
+	if err != nil {
+		return err
+	}
+	if ev.Op == ttlcache.OpExpire {
+		c.cache.DeleteExpiredBefore(ev.Deadline)
+		return nil
+	}
+	c.cache.Apply(ev)
+	return nil
+}
+
+// Snapshot implements StateMachine.
+// Records outdated by the clock of this node are written too, they are
+// dropped by the expirations of the leader.
+func (c *Cache) Snapshot(w io.Writer) error {
+	return c.cache.SnapshotAll(w, c.codec)
+}
+
+// Restore implements StateMachine.
+// Records are restored with their deadlines whatever the clock of this node.
+func (c *Cache) Restore(r io.Reader) error {
+	c.cache.Clear()
+	_, err := c.cache.RestoreAll(r, c.codec)
+	return err
+}
+
+func (c *Cache) expirer(resolution time.Duration) {
+	defer c.wg.Done()
+	ticker := time.NewTicker(resolution)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if !c.log.IsLeader() {
+				continue
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), resolution)
+			// A failed expiration is retried with a later clock at the next tick.
+			_ = c.propose(ctx, ttlcache.Event{Op: ttlcache.OpExpire, Deadline: time.Now()})
+			cancel()
+		case <-c.done:
+			return
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package replicated
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	ttlcache "github.com/loicalleyne/ttlswisscache"
+)
+
+// memLog applies commands to every node synchronously.
+type memLog struct {
+	mu     sync.Mutex
+	nodes  []StateMachine
+	leader bool
+}
+
+func (l *memLog) Apply(_ context.Context, cmd []byte) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, n := range l.nodes {
+		if err := n.ApplyCommand(cmd); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (l *memLog) IsLeader() bool { return l.leader }
+
+func cluster(t *testing.T, resolution time.Duration) (leader, follower *Cache) {
+	leader, follower = New(ttlcache.GobCodec{}), New(ttlcache.GobCodec{})
+	nodes := []StateMachine{leader, follower}
+	leader.Start(&memLog{nodes: nodes, leader: true}, resolution)
+	follower.Start(&memLog{nodes: nodes}, resolution)
+	t.Cleanup(func() {
+		leader.Close()
+		follower.Close()
+	})
+	return leader, follower
+}
+
+func TestCache_Replication(t *testing.T) {
+	leader, follower := cluster(t, time.Hour)
+	ctx := context.Background()
+
+	if err := leader.Set(ctx, 1, "a", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := follower.Get(1); !ok || v != "a" {
+		t.Errorf("incorrect replicated value: got: %v expected: %v", v, "a")
+	}
+	lt, _ := leader.TTL(1)
+	ft, _ := follower.TTL(1)
+	if d := lt - ft; d < -time.Millisecond || d > time.Millisecond {
+		t.Errorf("nodes disagree on the deadline: got: %v and %v", lt, ft)
+	}
+
+	if err := leader.Delete(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := follower.Get(1); ok {
+		t.Error("delete was not replicated")
+	}
+
+	if err := follower.Set(ctx, 2, "b", time.Minute); !errors.Is(err, ErrNotLeader) {
+		t.Errorf("incorrect follower write error: got: %v expected: %v", err, ErrNotLeader)
+	}
+}
+
+func TestCache_Expire(t *testing.T) {
+	leader, follower := cluster(t, 10*time.Millisecond)
+	ctx := context.Background()
+	leader.Set(ctx, 1, "a", time.Millisecond)
+	leader.Set(ctx, 2, "b", time.Hour)
+
+	// The follower never expires records by itself.
+	time.Sleep(50 * time.Millisecond)
+	for _, c := range []*Cache{leader, follower} {
+		if _, ok := c.Get(1); ok {
+			t.Error("record was not expired")
+		}
+		if _, ok := c.Get(2); !ok {
+			t.Error("live record was expired")
+		}
+	}
+}
+
+func TestCache_SnapshotRestore(t *testing.T) {
+	leader, follower := cluster(t, time.Hour)
+	leader.Set(context.Background(), 1, "a", time.Minute)
+
+	var buf bytes.Buffer
+	if err := leader.Snapshot(&buf); err != nil {
+		t.Fatal(err)
+	}
+	follower.Local().Set(2, "stale", time.Minute)
+	if err := follower.Restore(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := follower.Get(1); !ok || v != "a" {
+		t.Errorf("incorrect restored value: got: %v expected: %v", v, "a")
+	}
+	if _, ok := follower.Get(2); ok {
+		t.Error("restore kept previous state")
+	}
+}
+
+func TestCache_SnapshotOutdated(t *testing.T) {
+	leader, follower := cluster(t, time.Hour)
+	// Outdated by the clock of the nodes, but not expired by the leader yet.
+	leader.Set(context.Background(), 1, "a", -time.Second)
+
+	var buf bytes.Buffer
+	if err := follower.Snapshot(&buf); err != nil {
+		t.Fatal(err)
+	}
+	other := New(ttlcache.GobCodec{})
+	defer other.Close()
+	if err := other.Restore(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := other.TTL(1); !ok {
+		t.Error("record not expired by the leader was dropped")
+	}
+}
@@ -0,0 +1,58 @@
+package ttlswisscache
+
+// recordOverhead is the storage of a record besides its value: key, deadline,
+// version, interface header and its slot in the map.
+const recordOverhead = 8 + 8 + 8 + 16 + 13
+
+// ShardStats describes a shard in a ShardReport.
+type ShardStats struct {
+	Entries int    // Records, including outdated ones waiting for cleanup.
+	Bytes   int64  // Estimated size of the records, see WithSizer.
+	Hits    uint64 // Reads that found a record.
+	Misses  uint64 // Reads that didn't find a record.
+	// Lock acquisitions, see WithLockStats.
+	ReadLocks  LockStats
+	WriteLocks LockStats
+}
+
+// ShardReport describes how records and reads are distributed over the shards.
+type ShardReport struct {
+	Shards []ShardStats
+	// Skew is the number of records of the largest shard divided by the mean:
+	// 1 means a perfect distribution, the number of shards the worst one.
+	// It is 0 for an empty cache.
+	Skew float64
+}
+
+// ShardReport returns the distribution of the records over the shards, to
+// detect pathological keys or validate a WithHasher hash. Values of unknown
+// size only count for their storage overhead. Every shard is read locked in
+// turn while its values are sized.
+func (c *Cache) ShardReport() ShardReport {
+	r := ShardReport{Shards: make([]ShardStats, len(c.shards.list))}
+	total, largest := 0, 0
+	for i, s := range c.shards.list {
+		st := &r.Shards[i]
+		s.RLock()
+		st.Entries = s.count()
+		st.Bytes = int64(st.Entries) * recordOverhead
+		for j, key := range s.keys {
+			if value, ok := (item{value: s.values[j]}).load(); ok {
+				st.Bytes += max(c.size(key, value), 0)
+			}
+		}
+		s.RUnlock()
+		st.Hits = s.stats.hits.Load()
+		st.Misses = s.stats.misses.Load()
+		if s.locks != nil {
+			s.locks.read.addTo(&st.ReadLocks)
+			s.locks.write.addTo(&st.WriteLocks)
+		}
+		total += st.Entries
+		largest = max(largest, st.Entries)
+	}
+	if total > 0 {
+		r.Skew = float64(largest) * float64(len(r.Shards)) / float64(total)
+	}
+	return r
+}
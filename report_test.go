@@ -0,0 +1,45 @@
+package ttlswisscache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCache_ShardReport(t *testing.T) {
+	c := New(0, WithShardCount(4))
+	defer c.Close()
+	if r := c.ShardReport(); len(r.Shards) != 4 || r.Skew != 0 {
+		t.Errorf("incorrect report of an empty cache: got: %d, %v expected: %d, %v", len(r.Shards), r.Skew, 4, 0)
+	}
+
+	for i := 0; i < 4000; i++ {
+		c.Set(IntKey(i), "abcd", time.Hour)
+	}
+	c.Get(IntKey(1))
+	c.Get(IntKey(-1))
+	r := c.ShardReport()
+	entries, hits, misses := 0, uint64(0), uint64(0)
+	for _, st := range r.Shards {
+		entries += st.Entries
+		hits += st.Hits
+		misses += st.Misses
+		if st.Bytes != int64(st.Entries)*(recordOverhead+4) {
+			t.Errorf("incorrect size: got: %d expected: %d", st.Bytes, int64(st.Entries)*(recordOverhead+4))
+		}
+	}
+	if entries != 4000 || hits != 1 || misses != 1 {
+		t.Errorf("incorrect totals: got: %d, %d, %d expected: %d, %d, %d", entries, hits, misses, 4000, 1, 1)
+	}
+	if r.Skew < 1 || r.Skew > 1.2 {
+		t.Errorf("incorrect skew: got: %v expected about: %v", r.Skew, 1)
+	}
+
+	skewed := New(0, WithShardCount(4), WithHasher(func(uint64) uint64 { return 0 }))
+	defer skewed.Close()
+	for i := 0; i < 100; i++ {
+		skewed.Set(IntKey(i), i, time.Hour)
+	}
+	if r := skewed.ShardReport(); r.Skew != 4 {
+		t.Errorf("incorrect skew of a single shard: got: %v expected: %v", r.Skew, 4)
+	}
+}
@@ -0,0 +1,168 @@
+package respserver
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	ttlcache "github.com/loicalleyne/ttlswisscache"
+	"github.com/loicalleyne/ttlswisscache/internal/resp"
+)
+
+// exec runs a single command and reports whether the connection should be closed.
+func (s *Server) exec(w *resp.Writer, args [][]byte) (quit bool) {
+	name := strings.ToUpper(string(args[0]))
+	args = args[1:]
+
+	switch name {
+	case "PING":
+		if len(args) > 1 {
+			w.WriteError(wrongArgs(name))
+		} else if len(args) == 1 {
+			w.WriteBulk(args[0])
+		} else {
+			w.WriteSimple("PONG")
+		}
+	case "QUIT":
+		w.WriteSimple("OK")
+		return true
+	case "GET":
+		if len(args) != 1 {
+			w.WriteError(wrongArgs(name))
+			return false
+		}
+		s.get(w, args[0])
+	case "SET":
+		if len(args) < 2 {
+			w.WriteError(wrongArgs(name))
+			return false
+		}
+		s.set(w, args)
+	case "DEL":
+		if len(args) == 0 {
+			w.WriteError(wrongArgs(name))
+			return false
+		}
+		var n int64
+		for _, k := range args {
+			if s.cache.Remove(key(k)) {
+				n++
+			}
+		}
+		w.WriteInt(n)
+	case "EXISTS":
+		if len(args) == 0 {
+			w.WriteError(wrongArgs(name))
+			return false
+		}
+		var n int64
+		for _, k := range args {
+			if _, ok := s.cache.TTL(key(k)); ok {
+				n++
+			}
+		}
+		w.WriteInt(n)
+	case "TTL", "PTTL":
+		if len(args) != 1 {
+			w.WriteError(wrongArgs(name))
+			return false
+		}
+		ttl, ok := s.cache.TTL(key(args[0]))
+		switch {
+		case !ok:
+			w.WriteInt(-2)
+		case name == "TTL":
+			w.WriteInt(int64((ttl + time.Second - 1) / time.Second))
+		default:
+			w.WriteInt(int64((ttl + time.Millisecond - 1) / time.Millisecond))
+		}
+	case "EXPIRE":
+		if len(args) != 2 {
+			w.WriteError(wrongArgs(name))
+			return false
+		}
+		sec, err := strconv.ParseInt(string(args[1]), 10, 64)
+		if err != nil {
+			w.WriteError(resp.Errorf("value is not an integer or out of range"))
+			return false
+		}
+		k := key(args[0])
+		if sec <= 0 {
+			w.WriteInt(boolInt(s.cache.Remove(k)))
+			return false
+		}
+		if sec > math.MaxInt64/int64(time.Second) {
+			w.WriteError(resp.Errorf("invalid expire time in 'expire' command"))
+			return false
+		}
+		w.WriteInt(boolInt(s.cache.Expire(k, time.Duration(sec)*time.Second)))
+	default:
+		w.WriteError(resp.Errorf("unknown command '%s'", strings.ToLower(name)))
+	}
+	return false
+}
+
+func (s *Server) get(w *resp.Writer, k []byte) {
+	v, ok := s.cache.Get(key(k))
+	if !ok {
+		w.WriteNull()
+		return
+	}
+	switch v := v.(type) {
+	case []byte:
+		w.WriteBulk(v)
+	case string:
+		w.WriteBulk([]byte(v))
+	default:
+		data, err := s.Codec.Marshal(v)
+		if err != nil {
+			w.WriteError(resp.Errorf("encode value: %v", err))
+			return
+		}
+		w.WriteBulk(data)
+	}
+}
+
+func (s *Server) set(w *resp.Writer, args [][]byte) {
+	ttl := s.defaultTTL
+	for i := 2; i < len(args); i++ {
+		opt := strings.ToUpper(string(args[i]))
+		if (opt != "EX" && opt != "PX") || i+1 == len(args) {
+			w.WriteError(resp.Errorf("syntax error"))
+			return
+		}
+		i++
+		unit := time.Millisecond
+		if opt == "EX" {
+			unit = time.Second
+		}
+		n, err := strconv.ParseInt(string(args[i]), 10, 64)
+		if err != nil || n <= 0 || n > math.MaxInt64/int64(unit) {
+			w.WriteError(resp.Errorf("invalid expire time in 'set' command"))
+			return
+		}
+		ttl = time.Duration(n) * unit
+	}
+	// Like a SET whose condition failed, a rejected write replies nil.
+	if _, ok := s.cache.SetChecked(key(args[0]), args[1], ttl); !ok {
+		w.WriteNull()
+		return
+	}
+	w.WriteSimple("OK")
+}
+
+func key(k []byte) uint64 {
+	return ttlcache.BytesKey(k)
+}
+
+func wrongArgs(name string) string {
+	return resp.Errorf("wrong number of arguments for '%s' command", strings.ToLower(name))
+}
+
+func boolInt(b bool) int64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,81 @@
+// Package respserver exposes a Cache over the Redis wire protocol.
+//
+// Supported commands: PING, QUIT, GET, SET (with EX/PX), DEL, EXISTS, TTL, PTTL and EXPIRE.
+// Keys are hashed with ttlcache.StringKey, so Go code sharing the cache
+// reads the same records with the same key strings.
+package respserver
+
+import (
+	"errors"
+	"net"
+	"time"
+
+	ttlcache "github.com/loicalleyne/ttlswisscache"
+	"github.com/loicalleyne/ttlswisscache/internal/resp"
+	"github.com/loicalleyne/ttlswisscache/internal/serve"
+)
+
+// ErrServerClosed is returned by Serve after Close.
+var ErrServerClosed = serve.ErrClosed
+
+// Server serves a Cache over RESP.
+type Server struct {
+	cache      *ttlcache.Cache
+	defaultTTL time.Duration
+	group      serve.Group
+
+	// Codec encodes values that are neither []byte nor string for GET replies.
+	Codec ttlcache.Codec
+}
+
+// New creates a server for the cache.
+// defaultTTL is applied to SET commands without an expiration.
+func New(cache *ttlcache.Cache, defaultTTL time.Duration) *Server {
+	return &Server{
+		cache:      cache,
+		defaultTTL: defaultTTL,
+		Codec:      ttlcache.MsgpackCodec{},
+	}
+}
+
+// ListenAndServe listens on the TCP address and serves connections.
+func (s *Server) ListenAndServe(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(l)
+}
+
+// Serve accepts connections on the listener until Close is called.
+func (s *Server) Serve(l net.Listener) error {
+	return s.group.Serve(l, s.serveConn)
+}
+
+// Close stops the listeners, closes active connections and waits for
+// connection handlers to return. The cache is left open.
+func (s *Server) Close() error {
+	return s.group.Close()
+}
+
+func (s *Server) serveConn(conn net.Conn) {
+	r := resp.NewReader(conn)
+	w := resp.NewWriter(conn)
+	for {
+		args, err := r.ReadCommand()
+		if err != nil {
+			if errors.Is(err, resp.ErrProtocol) {
+				w.WriteError(resp.Errorf("protocol error"))
+				w.Flush()
+			}
+			return
+		}
+		if len(args) == 0 {
+			continue
+		}
+		quit := s.exec(w, args)
+		if err := w.Flush(); err != nil || quit {
+			return
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package respserver
+
+import (
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	ttlcache "github.com/loicalleyne/ttlswisscache"
+	"github.com/loicalleyne/ttlswisscache/internal/resp"
+)
+
+func startServer(t *testing.T, opts ...ttlcache.Option) (*ttlcache.Cache, *resp.Reader, *resp.Writer) {
+	t.Helper()
+	cache := ttlcache.New(time.Hour, opts...)
+	srv := New(cache, time.Hour)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go srv.Serve(l)
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		conn.Close()
+		srv.Close()
+		cache.Close()
+	})
+	return cache, resp.NewReader(conn), resp.NewWriter(conn)
+}
+
+func do(t *testing.T, r *resp.Reader, w *resp.Writer, args ...string) resp.Value {
+	t.Helper()
+	b := make([][]byte, len(args))
+	for i, a := range args {
+		b[i] = []byte(a)
+	}
+	w.WriteCommand(b...)
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	v, err := r.ReadValue()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestServer_Commands(t *testing.T) {
+	cache, r, w := startServer(t)
+
+	if v := do(t, r, w, "PING"); v.Str != "PONG" {
+		t.Errorf("incorrect reply: got: %v expected: %v", v.Str, "PONG")
+	}
+	if v := do(t, r, w, "GET", "key"); !v.Null {
+		t.Error("expected null reply for missing key")
+	}
+	if v := do(t, r, w, "SET", "key", "value", "EX", "60"); v.Str != "OK" {
+		t.Errorf("incorrect reply: got: %v expected: %v", v.Str, "OK")
+	}
+	if v := do(t, r, w, "GET", "key"); string(v.Bulk) != "value" {
+		t.Errorf("incorrect value: got: %s expected: %s", v.Bulk, "value")
+	}
+	if v := do(t, r, w, "TTL", "key"); v.Int != 60 {
+		t.Errorf("incorrect ttl: got: %d expected: %d", v.Int, 60)
+	}
+	if v := do(t, r, w, "EXPIRE", "key", "120"); v.Int != 1 {
+		t.Errorf("incorrect reply: got: %d expected: %d", v.Int, 1)
+	}
+	if v := do(t, r, w, "TTL", "key"); v.Int != 120 {
+		t.Errorf("incorrect ttl: got: %d expected: %d", v.Int, 120)
+	}
+	if v := do(t, r, w, "EXISTS", "key", "missing"); v.Int != 1 {
+		t.Errorf("incorrect reply: got: %d expected: %d", v.Int, 1)
+	}
+
+	val, ok := cache.Get(ttlcache.StringKey("key"))
+	if !ok || string(val.([]byte)) != "value" {
+		t.Errorf("cache missed value set over RESP: got: %v", val)
+	}
+
+	if v := do(t, r, w, "DEL", "key", "missing"); v.Int != 1 {
+		t.Errorf("incorrect reply: got: %d expected: %d", v.Int, 1)
+	}
+	if v := do(t, r, w, "TTL", "key"); v.Int != -2 {
+		t.Errorf("incorrect ttl: got: %d expected: %d", v.Int, -2)
+	}
+	if v := do(t, r, w, "FLUSHALL"); v.Type != resp.Error {
+		t.Error("expected error for unknown command")
+	}
+	if v := do(t, r, w, "SET", "key", "value", "NX"); v.Type != resp.Error {
+		t.Error("expected error for unsupported option")
+	}
+}
+
+func TestServer_RejectedSet(t *testing.T) {
+	_, r, w := startServer(t, ttlcache.WithMaxValueSize(4, ttlcache.RejectOversized))
+
+	if v := do(t, r, w, "SET", "key", "too long"); !v.Null {
+		t.Errorf("incorrect reply of a rejected set: got: %+v expected: null", v)
+	}
+	if v := do(t, r, w, "SET", "key", "ok"); v.Str != "OK" {
+		t.Errorf("incorrect reply: got: %v expected: %v", v.Str, "OK")
+	}
+}
+
+func TestServer_ExpireOverflow(t *testing.T) {
+	_, r, w := startServer(t)
+	do(t, r, w, "SET", "key", "value")
+
+	for _, args := range [][]string{
+		{"SET", "key", "value", "EX", "9223372037"},
+		{"SET", "key", "value", "PX", "9223372036855"},
+		{"EXPIRE", "key", "9223372037"},
+	} {
+		if v := do(t, r, w, args...); v.Type != resp.Error {
+			t.Errorf("expected error for %v: got: %+v", args, v)
+		}
+	}
+	if v := do(t, r, w, "GET", "key"); string(v.Bulk) != "value" {
+		t.Errorf("incorrect value: got: %s expected: %s", v.Bulk, "value")
+	}
+}
+
+func TestServer_GoValues(t *testing.T) {
+	cache, r, w := startServer(t)
+	cache.Set(ttlcache.StringKey("n"), 42, time.Hour)
+
+	v := do(t, r, w, "GET", "n")
+	decoded, err := ttlcache.MsgpackCodec{}.Unmarshal(v.Bulk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(decoded) != "42" {
+		t.Errorf("incorrect value: got: %#v expected: %v", decoded, 42)
+	}
+}
@@ -0,0 +1,277 @@
+// Package s3snapshot stores cache snapshots in S3-compatible object storage,
+// e.g. AWS S3, MinIO or GCS through its XML API, so warm state survives the
+// replacement of instances in autoscaled fleets:
+//
+//	target := s3snapshot.New(s3snapshot.Config{...})
+//	err := cache.SnapshotTo(ctx, target, ttlcache.GobCodec{})
+//	// On the next instance:
+//	n, err := cache.RestoreFrom(ctx, target, ttlcache.GobCodec{})
+//
+// Snapshots are streamed with multipart uploads, so only a part is buffered
+// at a time. Requests are signed with AWS Signature Version 4.
+package s3snapshot
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	ttlcache "github.com/loicalleyne/ttlswisscache"
+)
+
+// MinPartSize is the smallest part S3 accepts, but for the last one.
+const MinPartSize = 5 << 20
+
+// defaultPartSize is the part size of a zero Config.PartSize.
+const defaultPartSize = 16 << 20
+
+// Config locates the snapshot object and holds the credentials.
+type Config struct {
+	// Endpoint is the base URL of the service, e.g. https://s3.eu-west-1.amazonaws.com.
+	// Objects are addressed path-style: Endpoint/Bucket/Key.
+	Endpoint string
+	Region   string
+	Bucket   string
+	Key      string
+
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string // Optional, for temporary credentials.
+
+	// PartSize is the size of the uploaded parts, 16 MiB by default and no less
+	// than MinPartSize. Snapshots smaller than a part are uploaded at once.
+	PartSize int
+	// Client sends the requests, http.DefaultClient if nil.
+	Client *http.Client
+}
+
+// Target is a ttlcache.SnapshotTarget storing the snapshot in an object.
+// It is safe for concurrent use.
+type Target struct {
+	cfg Config
+	now func() time.Time
+}
+
+var _ ttlcache.SnapshotTarget = (*Target)(nil)
+
+// New returns a target storing the snapshot as described by cfg.
+func New(cfg Config) *Target {
+	if cfg.PartSize == 0 {
+		cfg.PartSize = defaultPartSize
+	}
+	cfg.PartSize = max(cfg.PartSize, MinPartSize)
+	if cfg.Client == nil {
+		cfg.Client = http.DefaultClient
+	}
+	cfg.Endpoint = strings.TrimRight(cfg.Endpoint, "/")
+	return &Target{cfg: cfg, now: time.Now}
+}
+
+// Error is a failed request.
+type Error struct {
+	Op         string // Operation, e.g. UploadPart.
+	StatusCode int
+	Code       string // S3 error code, e.g. NoSuchKey.
+	Message    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("s3snapshot: %s: %d %s: %s", e.Op, e.StatusCode, e.Code, e.Message)
+}
+
+// ErrNotFound is returned by Load when no snapshot is stored.
+var ErrNotFound = errors.New("s3snapshot: no snapshot")
+
+// Store uploads the snapshot written by write. The object is replaced only if
+// write and the upload succeed, a failed multipart upload is aborted.
+func (t *Target) Store(ctx context.Context, write func(w io.Writer) error) error {
+	u := &uploader{ctx: ctx, t: t, buf: make([]byte, 0, t.cfg.PartSize)}
+	if err := write(u); err != nil {
+		u.abort()
+		return err
+	}
+	if u.err != nil {
+		u.abort()
+		return u.err
+	}
+	if u.uploadID == "" {
+		_, err := t.do(ctx, "PutObject", http.MethodPut, nil, u.buf)
+		return err
+	}
+	if err := u.flush(); err != nil {
+		u.abort()
+		return err
+	}
+	if err := u.complete(); err != nil {
+		u.abort()
+		return err
+	}
+	return nil
+}
+
+// Load downloads the snapshot and streams it to read.
+// It returns ErrNotFound if there is none.
+func (t *Target) Load(ctx context.Context, read func(r io.Reader) error) error {
+	resp, err := t.send(ctx, http.MethodGet, nil, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return responseError("GetObject", resp)
+	}
+	return read(resp.Body)
+}
+
+// uploader buffers a part and uploads it once full.
+type uploader struct {
+	ctx      context.Context
+	t        *Target
+	buf      []byte
+	uploadID string
+	parts    []completedPart
+	err      error
+}
+
+func (u *uploader) Write(p []byte) (int, error) {
+	if u.err != nil {
+		return 0, u.err
+	}
+	n := len(p)
+	for len(p) > 0 {
+		// A full part is uploaded once more data follows, so that a snapshot
+		// of a single part is uploaded at once.
+		if len(u.buf) == cap(u.buf) {
+			if u.err = u.flush(); u.err != nil {
+				return n - len(p), u.err
+			}
+		}
+		c := copy(u.buf[len(u.buf):cap(u.buf)], p)
+		u.buf = u.buf[:len(u.buf)+c]
+		p = p[c:]
+	}
+	return n, nil
+}
+
+// flush uploads the buffered part, starting the multipart upload if needed.
+func (u *uploader) flush() error {
+	if u.uploadID == "" {
+		body, err := u.t.do(u.ctx, "CreateMultipartUpload", http.MethodPost, url.Values{"uploads": {""}}, nil)
+		if err != nil {
+			return err
+		}
+		var res struct {
+			UploadID string `xml:"UploadId"`
+		}
+		if err := xml.Unmarshal(body, &res); err != nil || res.UploadID == "" {
+			return fmt.Errorf("s3snapshot: CreateMultipartUpload: invalid response: %v", err)
+		}
+		u.uploadID = res.UploadID
+	}
+	number := len(u.parts) + 1
+	q := url.Values{"partNumber": {strconv.Itoa(number)}, "uploadId": {u.uploadID}}
+	resp, err := u.t.send(u.ctx, http.MethodPut, q, u.buf)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return responseError("UploadPart", resp)
+	}
+	u.parts = append(u.parts, completedPart{PartNumber: number, ETag: resp.Header.Get("ETag")})
+	u.buf = u.buf[:0]
+	return nil
+}
+
+type completedPart struct {
+	PartNumber int
+	ETag       string
+}
+
+func (u *uploader) complete() error {
+	body, err := xml.Marshal(struct {
+		XMLName xml.Name        `xml:"CompleteMultipartUpload"`
+		Parts   []completedPart `xml:"Part"`
+	}{Parts: u.parts})
+	if err != nil {
+		return err
+	}
+	res, err := u.t.do(u.ctx, "CompleteMultipartUpload", http.MethodPost, url.Values{"uploadId": {u.uploadID}}, body)
+	if err != nil {
+		return err
+	}
+	// S3 may report a failure with a 200 status.
+	if bytes.Contains(res, []byte("<Error>")) {
+		return decodeError("CompleteMultipartUpload", http.StatusOK, res)
+	}
+	return nil
+}
+
+// abort cancels the multipart upload, if any, so its parts aren't billed.
+func (u *uploader) abort() {
+	if u.uploadID == "" {
+		return
+	}
+	// The upload is aborted even if ctx is done.
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(u.ctx), 30*time.Second)
+	defer cancel()
+	_, _ = u.t.do(ctx, "AbortMultipartUpload", http.MethodDelete, url.Values{"uploadId": {u.uploadID}}, nil)
+}
+
+// do sends a request and returns the body of a successful response.
+func (t *Target) do(ctx context.Context, op, method string, q url.Values, body []byte) ([]byte, error) {
+	resp, err := t.send(ctx, method, q, body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return nil, responseError(op, resp)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// send sends a signed request on the object.
+func (t *Target) send(ctx context.Context, method string, q url.Values, body []byte) (*http.Response, error) {
+	u, err := url.Parse(t.cfg.Endpoint + "/" + t.cfg.Bucket + "/" + t.cfg.Key)
+	if err != nil {
+		return nil, err
+	}
+	u.RawQuery = canonicalQuery(q)
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = int64(len(body))
+	payloadHash := emptySHA256
+	if len(body) > 0 {
+		payloadHash = hashHex(body)
+	}
+	t.sign(req, payloadHash, t.now())
+	return t.cfg.Client.Do(req)
+}
+
+func responseError(op string, resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	return decodeError(op, resp.StatusCode, body)
+}
+
+func decodeError(op string, status int, body []byte) error {
+	var res struct {
+		Code    string
+		Message string
+	}
+	_ = xml.Unmarshal(body, &res)
+	return &Error{Op: op, StatusCode: status, Code: res.Code, Message: res.Message}
+}
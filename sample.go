@@ -0,0 +1,59 @@
+package ttlswisscache
+
+import (
+	"math/rand/v2"
+	"slices"
+)
+
+// RandomKeys returns the keys of up to n records picked uniformly at random,
+// without repetition, e.g. to audit the cache content or to build a sampled
+// eviction policy. Outdated records are left out, so fewer keys are returned
+// if some of the picked ones are outdated or removed meanwhile.
+// Every shard is read locked twice: to count the records and to pick them.
+func (c *Cache) RandomKeys(n int) []uint64 {
+	counts := make([]int, len(c.shards.list))
+	total := 0
+	for i, s := range c.shards.list {
+		s.RLock()
+		counts[i] = s.count()
+		s.RUnlock()
+		total += counts[i]
+	}
+	n = min(n, total)
+	if n <= 0 {
+		return nil
+	}
+
+	// Floyd's algorithm picks n distinct slots out of all the shards.
+	picked := make(map[int]struct{}, n)
+	for j := total - n; j < total; j++ {
+		p := rand.IntN(j + 1)
+		if _, ok := picked[p]; ok {
+			p = j
+		}
+		picked[p] = struct{}{}
+	}
+	slots := make([]int, 0, n)
+	for p := range picked {
+		slots = append(slots, p)
+	}
+	slices.Sort(slots)
+
+	now := c.clock.unixNano()
+	keys := make([]uint64, 0, n)
+	base := 0
+	for i, s := range c.shards.list {
+		end := base + counts[i]
+		s.RLock()
+		for len(slots) > 0 && slots[0] < end {
+			// The shard may have shrunk since it was counted.
+			if j := slots[0] - base; j < s.count() && s.deadlines[j] >= now {
+				keys = append(keys, s.keys[j])
+			}
+			slots = slots[1:]
+		}
+		s.RUnlock()
+		base = end
+	}
+	return keys
+}
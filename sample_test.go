@@ -0,0 +1,40 @@
+package ttlswisscache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCache_RandomKeys(t *testing.T) {
+	c := New(0)
+	defer c.Close()
+	if keys := c.RandomKeys(10); len(keys) != 0 {
+		t.Errorf("incorrect number of keys of an empty cache: got: %d expected: %d", len(keys), 0)
+	}
+
+	for i := 0; i < 1000; i++ {
+		c.Set(IntKey(i), i, time.Hour)
+	}
+	for i := 1000; i < 2000; i++ {
+		c.Set(IntKey(i), i, -time.Second)
+	}
+
+	seen := make(map[uint64]struct{})
+	for _, key := range c.RandomKeys(500) {
+		if _, ok := c.Get(key); !ok {
+			t.Errorf("outdated record was sampled: %d", key)
+		}
+		if _, ok := seen[key]; ok {
+			t.Errorf("key was sampled twice: %d", key)
+		}
+		seen[key] = struct{}{}
+	}
+	if len(seen) < 150 || len(seen) > 350 {
+		t.Errorf("incorrect number of live keys sampled: got: %d expected about: %d", len(seen), 250)
+	}
+
+	c.DeleteExpired()
+	if keys := c.RandomKeys(5000); len(keys) != 1000 {
+		t.Errorf("incorrect number of keys: got: %d expected: %d", len(keys), 1000)
+	}
+}
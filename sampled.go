@@ -0,0 +1,66 @@
+package ttlswisscache
+
+import (
+	"math/rand/v2"
+	"sync/atomic"
+)
+
+// lruSamples is the number of records SampledLRU compares.
+const lruSamples = 5
+
+// sampledLRU implements SampledLRU. Time is counted in stored records, so reads
+// only store the current tick and don't contend on a clock.
+type sampledLRU struct {
+	used []atomic.Uint32 // Tick of the last use, by slot.
+	tick atomic.Uint32   // Advanced under the shard write lock.
+}
+
+func (e *sampledLRU) OnAdd(_ uint64, i uint32) {
+	if int(i) >= len(e.used) {
+		used := make([]atomic.Uint32, max(2*len(e.used), int(i)+1))
+		for j := range e.used {
+			used[j].Store(e.used[j].Load())
+		}
+		e.used = used
+	}
+	e.used[i].Store(e.tick.Add(1))
+}
+
+func (e *sampledLRU) OnAccess(_ uint64, i uint32) {
+	if i == NoSlot {
+		return
+	}
+	// Testing first spares the cache line of records read again.
+	if tick := e.tick.Load(); e.used[i].Load() != tick {
+		e.used[i].Store(tick)
+	}
+}
+
+func (e *sampledLRU) OnRemove(_ uint64, i, last uint32) {
+	e.used[i].Store(e.used[last].Load())
+}
+
+// victim returns the least recently used of lruSamples random records.
+// Ticks are compared relative to the current one, so they may wrap around.
+func (e *sampledLRU) Victim(keys []uint64, _ []int64) uint32 {
+	n := uint32(len(keys))
+	now := e.tick.Load()
+	victim, oldest := uint32(0), uint32(0)
+	for j := 0; j < lruSamples; j++ {
+		i := rand.Uint32N(n)
+		if age := now - e.used[i].Load(); j == 0 || age > oldest {
+			victim, oldest = i, age
+		}
+	}
+	return victim
+}
+
+func (e *sampledLRU) Reset(n uint32) {
+	for j := range e.used {
+		e.used[j].Store(0)
+	}
+	if n > 0 {
+		e.OnAdd(0, n-1)
+	}
+	e.tick.Store(0)
+}
@@ -0,0 +1,26 @@
+package ttlswisscache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWithEviction_SampledLRU(t *testing.T) {
+	sampled, clock := hitRatio(SampledLRU), hitRatio(CLOCK)
+	if sampled < clock*0.9 {
+		t.Errorf("SampledLRU is far behind CLOCK: got: %.3f expected at least: %.3f", sampled, clock*0.9)
+	}
+
+	c := New(0, WithShardCount(1), WithMaxEntries(100), WithEviction(SampledLRU))
+	defer c.Close()
+	for i := 0; i < 1000; i++ {
+		c.Set(IntKey(i), i, time.Hour)
+		c.Get(IntKey(0))
+	}
+	if _, ok := c.Get(IntKey(0)); !ok {
+		t.Error("most recently used record was evicted")
+	}
+	if n := c.Stats().Entries; n != 100 {
+		t.Errorf("incorrect number of records: got: %d expected: %d", n, 100)
+	}
+}
@@ -0,0 +1,148 @@
+package ttlswisscache
+
+import (
+	"math"
+	"slices"
+	"time"
+)
+
+// Entry is a record returned by Scan and RangeSnapshot.
+type Entry struct {
+	Key      uint64
+	Value    interface{}
+	TTL      time.Duration // Remaining ttl at the time of the scan.
+	Deadline time.Time
+	Version  uint64 // See GetIfChanged.
+}
+
+// Cursor is the position of a Scan. The zero Cursor starts a new iteration.
+type Cursor struct {
+	shard int
+	from  uint64 // Smallest key of the shard not returned yet.
+	done  bool
+}
+
+// Done reports whether the iteration is over.
+func (c Cursor) Done() bool {
+	return c.done
+}
+
+// Scan returns up to count live records from cursor and the cursor to resume
+// from, iterating over a large cache in small chunks:
+//
+//	for cur := (ttlcache.Cursor{}); !cur.Done(); {
+//		var entries []ttlcache.Entry
+//		entries, cur = cache.Scan(cur, 100)
+//		...
+//	}
+//
+// Like Redis SCAN, a full iteration returns every record present from its start
+// to its end, records added or removed meanwhile may or may not be returned.
+// Shards are visited in turn and their keys in increasing order, so no record
+// is returned twice. No lock is held between calls, but each call read locks a
+// shard for a scan of all its keys.
+func (c *Cache) Scan(cursor Cursor, count int) ([]Entry, Cursor) {
+	count = max(count, 1)
+	now := c.clock.unixNano()
+	var (
+		entries []Entry
+		keys    []uint64
+	)
+	for !cursor.done && len(entries) < count {
+		s := c.shards.list[cursor.shard]
+		want := count - len(entries)
+		limit := math.MaxInt // Candidates kept before dropping all but the first want.
+		if want <= math.MaxInt/2 {
+			limit = 2 * want
+		}
+		keys = keys[:0]
+		bound := uint64(math.MaxUint64) // Keys above it can't be among the first want.
+		s.RLock()
+		if n := min(limit, len(s.keys)); cap(keys) < n {
+			keys = make([]uint64, 0, n)
+		}
+		for i, key := range s.keys {
+			if key < cursor.from || key > bound || s.deadlines[i] < now {
+				continue
+			}
+			keys = append(keys, key)
+			if len(keys) == limit {
+				keys, bound = smallest(keys, want)
+			}
+		}
+		keys, _ = smallest(keys, want)
+		for _, key := range keys {
+			// Reading the slot doesn't count as an access for the eviction policy.
+			i, _ := s.index.Get(key)
+			if value, ok := (item{value: s.values[i]}).load(); ok {
+				entries = append(entries, Entry{
+					Key:      key,
+					Value:    c.clone(value),
+					TTL:      time.Duration(s.deadlines[i] - now),
+					Deadline: time.Unix(0, s.deadlines[i]),
+					Version:  s.versions[i],
+				})
+			}
+		}
+		s.RUnlock()
+
+		if len(keys) == want && keys[len(keys)-1] < math.MaxUint64 {
+			cursor.from = keys[len(keys)-1] + 1
+			continue
+		}
+		// The shard is exhausted.
+		cursor.shard++
+		cursor.from = 0
+		cursor.done = cursor.shard == len(c.shards.list)
+	}
+	return entries, cursor
+}
+
+// RangeSnapshot calls fn with the live records of the cache, one shard after
+// the other, until fn returns false. Each shard is copied under its read lock
+// before fn sees any of its records, so fn observes every shard at a single
+// point in time and may call Set, Delete or any other method of the cache
+// without deadlocking or making the iteration skip records. Changes made by fn
+// are visible in the shards not copied yet. Copying costs one Entry per record
+// of the largest shard.
+func (c *Cache) RangeSnapshot(fn func(Entry) bool) {
+	var entries []Entry
+	for _, s := range c.shards.list {
+		entries = entries[:0]
+		now := c.clock.unixNano()
+		s.RLock()
+		for i, key := range s.keys {
+			if s.deadlines[i] < now {
+				continue
+			}
+			if value, ok := (item{value: s.values[i]}).load(); ok {
+				entries = append(entries, Entry{
+					Key:      key,
+					Value:    value,
+					TTL:      time.Duration(s.deadlines[i] - now),
+					Deadline: time.Unix(0, s.deadlines[i]),
+					Version:  s.versions[i],
+				})
+			}
+		}
+		s.RUnlock()
+		for i := range entries {
+			entries[i].Value = c.clone(entries[i].Value)
+			if !fn(entries[i]) {
+				return
+			}
+			entries[i] = Entry{} // Don't keep the value past the call.
+		}
+	}
+}
+
+// smallest sorts keys and truncates them to the n smallest.
+// It returns the largest key kept once there are n of them.
+func smallest(keys []uint64, n int) ([]uint64, uint64) {
+	slices.Sort(keys)
+	if len(keys) < n {
+		return keys, math.MaxUint64
+	}
+	keys = keys[:n]
+	return keys, keys[n-1]
+}
@@ -0,0 +1,104 @@
+package ttlswisscache
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestCache_Scan(t *testing.T) {
+	c := New(0)
+	defer c.Close()
+	for i := 0; i < 1000; i++ {
+		c.Set(IntKey(i), i, time.Hour)
+	}
+	c.Set(IntKey(-1), -1, -time.Second)
+
+	seen := make(map[uint64]int)
+	calls := 0
+	for cur := (Cursor{}); !cur.Done(); calls++ {
+		var entries []Entry
+		entries, cur = c.Scan(cur, 64)
+		if len(entries) > 64 {
+			t.Errorf("incorrect number of entries: got: %d expected at most: %d", len(entries), 64)
+		}
+		for _, e := range entries {
+			if _, ok := seen[e.Key]; ok {
+				t.Errorf("key was returned twice: %d", e.Key)
+			}
+			seen[e.Key] = e.Value.(int)
+			if e.TTL <= 0 || e.TTL > time.Hour {
+				t.Errorf("incorrect ttl: got: %v expected at most: %v", e.TTL, time.Hour)
+			}
+		}
+		// Writes between calls don't disturb the iteration.
+		c.Delete(IntKey(1000 + calls))
+		c.Set(IntKey(1001+calls), 0, time.Hour)
+	}
+	for i := 0; i < 1000; i++ {
+		if v, ok := seen[IntKey(i)]; !ok || v != i {
+			t.Errorf("incorrect value of key %d: got: %v, %v expected: %v, %v", i, v, ok, i, true)
+		}
+	}
+	if _, ok := seen[IntKey(-1)]; ok {
+		t.Error("outdated record was returned")
+	}
+	if calls < 1000/64 {
+		t.Errorf("incorrect number of calls: got: %d expected at least: %d", calls, 1000/64)
+	}
+}
+
+func TestCache_Scan_LargeCount(t *testing.T) {
+	c := New(0)
+	defer c.Close()
+	for i := 0; i < 100; i++ {
+		c.Set(IntKey(i), i, time.Hour)
+	}
+
+	for _, count := range []int{1 << 40, math.MaxInt} {
+		entries, cur := c.Scan(Cursor{}, count)
+		if len(entries) != 100 || !cur.Done() {
+			t.Errorf("incorrect scan of count %d: got: %d entries, done: %v expected: %d, %v", count, len(entries), cur.Done(), 100, true)
+		}
+	}
+}
+
+func TestCache_RangeSnapshot(t *testing.T) {
+	c := New(0, WithShardCount(4))
+	defer c.Close()
+	for i := 0; i < 1000; i++ {
+		c.Set(uint64(i), i, time.Hour)
+	}
+	c.Set(5000, "outdated", -time.Second)
+
+	seen := make(map[uint64]int)
+	c.RangeSnapshot(func(e Entry) bool {
+		if e.Key == 5000 {
+			t.Error("outdated record was visited")
+		}
+		if e.Key < 1000 {
+			seen[e.Key]++
+			if e.Value != int(e.Key) {
+				t.Errorf("incorrect value of %d: got: %v expected: %v", e.Key, e.Value, e.Key)
+			}
+		}
+		// Writing to the cache neither deadlocks nor disturbs the iteration.
+		c.Delete(e.Key)
+		c.Set(e.Key+10000, nil, time.Hour)
+		return true
+	})
+	for i := uint64(0); i < 1000; i++ {
+		if seen[i] != 1 {
+			t.Errorf("incorrect number of visits of %d: got: %d expected: %d", i, seen[i], 1)
+		}
+	}
+
+	n := 0
+	c.RangeSnapshot(func(Entry) bool {
+		n++
+		return n < 10
+	})
+	if n != 10 {
+		t.Errorf("incorrect number of calls after stopping: got: %d expected: %d", n, 10)
+	}
+}
@@ -0,0 +1,91 @@
+// Package scheduler runs functions after a delay, using the expiration of cache
+// records as a lightweight in-process delay queue.
+//
+// Each scheduled function is a record of a dedicated cache with the delay as its
+// ttl. The cleanup manager removes the record once it is outdated and the function
+// runs in its own goroutine. Functions thus run up to the resolution late.
+package scheduler
+
+import (
+	"sync"
+	"time"
+
+	ttlcache "github.com/loicalleyne/ttlswisscache"
+)
+
+// Scheduler runs functions once their delay has passed.
+// It is safe for concurrent use.
+type Scheduler struct {
+	cache *ttlcache.Cache
+
+	mu      sync.Mutex
+	closed  bool
+	running sync.WaitGroup
+}
+
+// New creates a scheduler checking for due functions every resolution.
+// opts configure the underlying cache like in ttlcache.New, callbacks set by
+// them are replaced.
+func New(resolution time.Duration, opts ...ttlcache.Option) *Scheduler {
+	s := &Scheduler{}
+	opts = append(opts, ttlcache.WithCallbacks(ttlcache.Callbacks{OnEvicted: s.evicted}))
+	s.cache = ttlcache.New(resolution, opts...)
+	return s
+}
+
+// Schedule runs fn after delay unless cancelled.
+// A function already scheduled under key is replaced.
+func (s *Scheduler) Schedule(key uint64, delay time.Duration, fn func()) {
+	s.cache.Set(key, fn, delay)
+}
+
+// Cancel removes the function scheduled under key.
+// It reports whether there was one not started yet.
+func (s *Scheduler) Cancel(key uint64) bool {
+	_, ok := s.cache.GetAndDelete(key)
+	return ok
+}
+
+// Remaining returns the time left before the function scheduled under key runs.
+func (s *Scheduler) Remaining(key uint64) (time.Duration, bool) {
+	return s.cache.TTL(key)
+}
+
+// RunDue starts the functions whose delay has passed and returns their number,
+// without waiting for the cleanup manager, e.g. in tests using ttlcache.WithClock.
+func (s *Scheduler) RunDue() int {
+	return s.cache.DeleteExpired()
+}
+
+// Close drops the functions not started yet and waits for the running ones.
+// Closing a closed scheduler returns ttlcache.ErrClosed.
+func (s *Scheduler) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return ttlcache.ErrClosed
+	}
+	s.closed = true
+	s.mu.Unlock()
+	err := s.cache.Close()
+	s.running.Wait()
+	return err
+}
+
+// evicted starts the functions of expired records.
+func (s *Scheduler) evicted(_ uint64, value interface{}, reason ttlcache.Op) {
+	fn, ok := value.(func())
+	if reason != ttlcache.OpExpire || !ok {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	s.running.Add(1)
+	go func() {
+		defer s.running.Done()
+		fn()
+	}()
+}
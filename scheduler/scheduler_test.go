@@ -0,0 +1,56 @@
+package scheduler
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	ttlcache "github.com/loicalleyne/ttlswisscache"
+	"github.com/loicalleyne/ttlswisscache/ttltest"
+)
+
+func TestScheduler(t *testing.T) {
+	clock := ttltest.NewClock()
+	s := New(0, ttlcache.WithClock(clock))
+	var ran atomic.Int32
+	s.Schedule(ttlcache.IntKey(1), time.Minute, func() { ran.Add(1) })
+	s.Schedule(ttlcache.IntKey(2), time.Minute, func() { ran.Add(10) })
+	s.Schedule(ttlcache.IntKey(3), time.Hour, func() { ran.Add(100) })
+
+	if d, ok := s.Remaining(ttlcache.IntKey(1)); !ok || d != time.Minute {
+		t.Errorf("incorrect remaining time: got: %v, %v expected: %v, true", d, ok, time.Minute)
+	}
+	if !s.Cancel(ttlcache.IntKey(2)) {
+		t.Error("scheduled function was not cancelled")
+	}
+	if s.Cancel(ttlcache.IntKey(2)) {
+		t.Error("function was cancelled twice")
+	}
+
+	clock.Advance(time.Minute + time.Second)
+	if n := s.RunDue(); n != 1 {
+		t.Errorf("incorrect number of started functions: got: %d expected: %d", n, 1)
+	}
+	if err := s.Close(); err != nil {
+		t.Errorf("incorrect close error: got: %v expected: %v", err, nil)
+	}
+	if n := ran.Load(); n != 1 {
+		t.Errorf("incorrect functions run: got: %d expected: %d", n, 1)
+	}
+	if err := s.Close(); err != ttlcache.ErrClosed {
+		t.Errorf("incorrect second close error: got: %v expected: %v", err, ttlcache.ErrClosed)
+	}
+}
+
+func TestScheduler_CleanupManager(t *testing.T) {
+	s := New(5 * time.Millisecond)
+	defer s.Close()
+	done := make(chan struct{})
+	s.Schedule(ttlcache.IntKey(1), time.Millisecond, func() { close(done) })
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Error("scheduled function did not run")
+	}
+}
@@ -0,0 +1,115 @@
+// Package sessionstore keeps sessions behind random tokens in a cache.
+//
+// A session expires after an idle timeout, extended by every Get, and at the latest
+// after a maximum lifetime since its creation. Sessions are tagged with their user,
+// so all sessions of a user can be listed and revoked at once, e.g. to log out
+// everywhere after a password change.
+package sessionstore
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"time"
+
+	ttlcache "github.com/loicalleyne/ttlswisscache"
+)
+
+// tokenSize is the number of random bytes of a token.
+const tokenSize = 32
+
+// Session is a stored session.
+type Session struct {
+	Token   string
+	User    string
+	Created time.Time
+	Data    interface{}
+}
+
+// Store creates and looks up sessions. It is safe for concurrent use.
+type Store struct {
+	cache    *ttlcache.Cache
+	idle     time.Duration
+	lifetime time.Duration
+}
+
+// New returns a store keeping sessions in cache for idle without use and
+// lifetime at most. Time is read from the cache clock, see ttlcache.WithClock.
+func New(cache *ttlcache.Cache, idle, lifetime time.Duration) *Store {
+	return &Store{cache: cache, idle: idle, lifetime: lifetime}
+}
+
+// Create starts a session of user holding data and returns it.
+// The token is 256 random bits encoded in URL-safe base64.
+func (st *Store) Create(user string, data interface{}) (Session, error) {
+	b := make([]byte, tokenSize)
+	if _, err := rand.Read(b); err != nil {
+		return Session{}, err
+	}
+	s := Session{
+		Token:   base64.RawURLEncoding.EncodeToString(b),
+		User:    user,
+		Created: st.cache.Now(),
+		Data:    data,
+	}
+	st.cache.SetWithTags(ttlcache.StringKey(s.Token), s, min(st.idle, st.lifetime), userTag(user))
+	return s, nil
+}
+
+// Get returns the session of token and extends its idle timeout,
+// up to the end of its lifetime.
+func (st *Store) Get(token string) (Session, bool) {
+	key := ttlcache.StringKey(token)
+	s, ok := st.load(key)
+	if !ok || subtle.ConstantTimeCompare([]byte(s.Token), []byte(token)) != 1 {
+		return Session{}, false
+	}
+	left := s.Created.Add(st.lifetime).Sub(st.cache.Now())
+	if left <= 0 {
+		st.cache.Delete(key)
+		return Session{}, false
+	}
+	st.cache.Expire(key, min(st.idle, left))
+	return s, true
+}
+
+// Revoke ends the session of token. It reports whether there was one.
+func (st *Store) Revoke(token string) bool {
+	if _, ok := st.Get(token); !ok {
+		return false
+	}
+	st.cache.Delete(ttlcache.StringKey(token))
+	return true
+}
+
+// Sessions returns the live sessions of user, in no particular order.
+// They are not extended.
+func (st *Store) Sessions(user string) []Session {
+	var sessions []Session
+	for _, key := range st.cache.Tagged(userTag(user)) {
+		if s, ok := st.load(key); ok {
+			sessions = append(sessions, s)
+		}
+	}
+	return sessions
+}
+
+// RevokeAll ends all sessions of user and returns their number.
+func (st *Store) RevokeAll(user string) int {
+	return st.cache.InvalidateTag(userTag(user))
+}
+
+// load returns the session stored under key unless it is outdated.
+func (st *Store) load(key uint64) (Session, bool) {
+	if ttl, ok := st.cache.TTL(key); !ok || ttl == 0 {
+		return Session{}, false
+	}
+	v, _ := st.cache.Get(key)
+	s, ok := v.(Session)
+	return s, ok
+}
+
+// userTag returns the tag of the sessions of user.
+func userTag(user string) string {
+	return "\x00session\x00" + user
+}
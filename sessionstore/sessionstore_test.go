@@ -0,0 +1,85 @@
+package sessionstore
+
+import (
+	"testing"
+	"time"
+
+	ttlcache "github.com/loicalleyne/ttlswisscache"
+	"github.com/loicalleyne/ttlswisscache/ttltest"
+)
+
+func newStore(t *testing.T, idle, lifetime time.Duration) (*Store, *ttltest.Harness) {
+	h := ttltest.New(t)
+	return New(h.Cache, idle, lifetime), h
+}
+
+func TestStore(t *testing.T) {
+	st, h := newStore(t, 10*time.Minute, time.Hour)
+	s, err := st.Create("alice", "data")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Token) != 43 || s.User != "alice" || !s.Created.Equal(ttltest.Start) {
+		t.Errorf("incorrect session: got: %+v", s)
+	}
+	other, _ := st.Create("alice", nil)
+	if other.Token == s.Token {
+		t.Error("sessions share a token")
+	}
+
+	got, ok := st.Get(s.Token)
+	if !ok || got.Data != "data" {
+		t.Errorf("incorrect session: got: %+v, %v expected data: data", got, ok)
+	}
+	if _, ok := st.Get(s.Token + "x"); ok {
+		t.Error("session was found with a wrong token")
+	}
+
+	// Every Get extends the idle timeout.
+	for i := 0; i < 3; i++ {
+		h.AdvanceTime(9 * time.Minute)
+		if _, ok := st.Get(s.Token); !ok {
+			t.Errorf("session expired while in use after %d minutes", 9*(i+1))
+		}
+	}
+	if _, ok := st.Get(other.Token); ok {
+		t.Error("idle session did not expire")
+	}
+}
+
+func TestStore_Lifetime(t *testing.T) {
+	st, h := newStore(t, 10*time.Minute, 30*time.Minute)
+	s, _ := st.Create("alice", nil)
+	for i := 0; i < 3; i++ {
+		h.AdvanceTime(9 * time.Minute)
+		st.Get(s.Token)
+	}
+	h.AssertTTL(ttlcache.StringKey(s.Token), 3*time.Minute)
+	h.AdvanceTime(3*time.Minute + time.Nanosecond)
+	if _, ok := st.Get(s.Token); ok {
+		t.Error("session outlived its lifetime")
+	}
+}
+
+func TestStore_RevokeAll(t *testing.T) {
+	st, _ := newStore(t, time.Hour, time.Hour)
+	a1, _ := st.Create("alice", nil)
+	st.Create("alice", nil)
+	b, _ := st.Create("bob", nil)
+
+	if n := len(st.Sessions("alice")); n != 2 {
+		t.Errorf("incorrect number of sessions: got: %d expected: %d", n, 2)
+	}
+	if !st.Revoke(a1.Token) || st.Revoke(a1.Token) {
+		t.Error("incorrect revocation of a session")
+	}
+	if n := st.RevokeAll("alice"); n != 1 {
+		t.Errorf("incorrect number of revoked sessions: got: %d expected: %d", n, 1)
+	}
+	if n := len(st.Sessions("alice")); n != 0 {
+		t.Errorf("incorrect number of sessions after revocation: got: %d expected: %d", n, 0)
+	}
+	if _, ok := st.Get(b.Token); !ok {
+		t.Error("session of another user was revoked")
+	}
+}
@@ -0,0 +1,146 @@
+package ttlswisscache
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// WithSetCoalescing merges the Sets of a key following each other within
+// window, e.g. 10ms, into a single store keeping the last value. The first Set
+// of a key is stored right away, later ones wait for the end of the window and
+// replace each other meanwhile, so keys updated in rapid bursts are stored and
+// published to subscribers once per window instead of once per Set. Deferred
+// Sets of a shard are stored under a single lock.
+//
+// Until a deferred Set is stored reads return the previous value. Delete and
+// Clear discard deferred Sets, Flush stores them right away. Other writes, e.g.
+// SetAsync, TrySet or CompareAndSwap, aren't coalesced and may be overwritten
+// by a deferred Set of the same key.
+func WithSetCoalescing(window time.Duration) Option {
+	return func(o *options) {
+		o.setCoalescing = window
+	}
+}
+
+// setCoalescer defers the Sets of recently stored keys, see WithSetCoalescing.
+type setCoalescer struct {
+	cache     *Cache
+	window    time.Duration
+	shards    []setShard // One per shard of the cache.
+	coalesced atomic.Uint64
+}
+
+// setShard holds the deferred Sets of the keys of a shard. Its lock is taken
+// before the shard lock, so deferred Sets are stored in order with the others.
+type setShard struct {
+	mu      sync.Mutex
+	recent  map[uint64]struct{} // Keys stored since the last flush.
+	pending map[uint64]item     // Deferred Sets.
+	timer   *time.Timer         // Running while recent isn't empty.
+}
+
+func newSetCoalescer(c *Cache, window time.Duration) *setCoalescer {
+	if window <= 0 {
+		return nil
+	}
+	return &setCoalescer{cache: c, window: window, shards: make([]setShard, len(c.shards.list))}
+}
+
+// set stores it, or defers it if key was stored within the window.
+// It returns the version of the record, 0 and true if deferred.
+func (sc *setCoalescer) set(key uint64, it item) (version uint64, deferred bool) {
+	i := int(sc.cache.shards.hash(key) >> sc.cache.shards.shift)
+	ss := &sc.shards[i]
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	if _, ok := ss.recent[key]; ok {
+		if _, ok := ss.pending[key]; ok {
+			sc.coalesced.Add(1)
+		}
+		if ss.pending == nil {
+			ss.pending = make(map[uint64]item)
+		}
+		ss.pending[key] = it
+		return 0, true
+	}
+	if ss.recent == nil {
+		ss.recent = make(map[uint64]struct{})
+	}
+	ss.recent[key] = struct{}{}
+	if ss.timer == nil {
+		ss.timer = time.AfterFunc(sc.window, func() { sc.tick(i) })
+	}
+	return sc.cache.store(key, it), false
+}
+
+// tick stores the deferred Sets of shard i at the end of a window. Their keys
+// are the recent ones of the next window, the timer stops once there are none.
+func (sc *setCoalescer) tick(i int) {
+	ss := &sc.shards[i]
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	clear(ss.recent)
+	for key := range ss.pending {
+		ss.recent[key] = struct{}{}
+	}
+	sc.store(i)
+	if len(ss.recent) == 0 || sc.cache.closed.Load() {
+		ss.timer = nil
+		return
+	}
+	ss.timer.Reset(sc.window)
+}
+
+// store stores the deferred Sets of the locked shard i.
+func (sc *setCoalescer) store(i int) {
+	ss := &sc.shards[i]
+	if len(ss.pending) == 0 {
+		return
+	}
+	c := sc.cache
+	s := c.shards.list[i]
+	s.Lock()
+	sets := 0
+	if !c.closed.Load() {
+		for key, it := range ss.pending {
+			if c.protected(s, key) {
+				continue
+			}
+			s.put(key, it)
+			c.subs.publish(OpSet, key, it)
+			sets++
+		}
+	}
+	s.Unlock()
+	s.stats.sets.Add(uint64(sets))
+	clear(ss.pending)
+}
+
+// flush stores the deferred Sets of every shard.
+func (sc *setCoalescer) flush() {
+	for i := range sc.shards {
+		ss := &sc.shards[i]
+		ss.mu.Lock()
+		sc.store(i)
+		ss.mu.Unlock()
+	}
+}
+
+// discard drops the deferred Set of key. The shard lock of key must not be held.
+func (sc *setCoalescer) discard(key uint64) {
+	ss := &sc.shards[sc.cache.shards.hash(key)>>sc.cache.shards.shift]
+	ss.mu.Lock()
+	delete(ss.pending, key)
+	ss.mu.Unlock()
+}
+
+// discardAll drops the deferred Sets of every shard.
+func (sc *setCoalescer) discardAll() {
+	for i := range sc.shards {
+		ss := &sc.shards[i]
+		ss.mu.Lock()
+		clear(ss.pending)
+		ss.mu.Unlock()
+	}
+}
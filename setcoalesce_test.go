@@ -0,0 +1,67 @@
+package ttlswisscache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWithSetCoalescing(t *testing.T) {
+	c := New(0, WithSetCoalescing(time.Hour))
+	defer c.Close()
+	events, cancel := c.Subscribe(16)
+	defer cancel()
+
+	if v := c.Set(IntKey(1), 1, time.Minute); v == 0 {
+		t.Error("first set was deferred")
+	}
+	for i := 2; i <= 5; i++ {
+		if v := c.Set(IntKey(1), i, time.Minute); v != 0 {
+			t.Errorf("set %d was stored: got version: %d", i, v)
+		}
+	}
+	if v, _ := c.Get(IntKey(1)); v != 1 {
+		t.Errorf("incorrect value before the flush: got: %v expected: %v", v, 1)
+	}
+	c.Set(IntKey(2), "a", time.Minute)
+	c.Set(IntKey(2), "b", time.Minute)
+	c.Delete(IntKey(2))
+
+	c.Flush()
+	if v, _ := c.Get(IntKey(1)); v != 5 {
+		t.Errorf("incorrect value after the flush: got: %v expected: %v", v, 5)
+	}
+	if _, ok := c.Get(IntKey(2)); ok {
+		t.Error("deleted key was stored by the flush")
+	}
+	st := c.Stats()
+	if st.Sets != 3 {
+		t.Errorf("incorrect number of sets: got: %d expected: %d", st.Sets, 3)
+	}
+	if st.Coalesced != 3 {
+		t.Errorf("incorrect number of coalesced sets: got: %d expected: %d", st.Coalesced, 3)
+	}
+	var sets []interface{}
+	for len(events) > 0 {
+		if e := <-events; e.Op == OpSet && e.Key == IntKey(1) {
+			sets = append(sets, e.Value)
+		}
+	}
+	if len(sets) != 2 || sets[0] != 1 || sets[1] != 5 {
+		t.Errorf("incorrect set events: got: %v expected: %v", sets, []interface{}{1, 5})
+	}
+}
+
+func TestWithSetCoalescing_Window(t *testing.T) {
+	c := New(0, WithSetCoalescing(5*time.Millisecond))
+	defer c.Close()
+	c.Set(IntKey(1), 1, time.Minute)
+	c.Set(IntKey(1), 2, time.Minute)
+	waitUntil(t, "the deferred set", func() bool {
+		v, _ := c.Get(IntKey(1))
+		return v == 2
+	})
+	time.Sleep(20 * time.Millisecond)
+	if v := c.Set(IntKey(1), 3, time.Minute); v == 0 {
+		t.Error("set of a key quiet for a window was deferred")
+	}
+}
@@ -0,0 +1,366 @@
+package ttlswisscache
+
+import (
+	"math/bits"
+	"sync"
+
+	"github.com/mhmtszr/concurrent-swiss-map/swiss"
+)
+
+const (
+	// shardsPerProc is the number of shards per GOMAXPROCS when no shard count is set.
+	shardsPerProc = 4
+	// minShardCount and maxShardCount bound the automatic shard count.
+	minShardCount = 8
+	maxShardCount = 1024
+	// minShardRecords is the size hint of WithCapacity, per shard,
+	// below which the automatic shard count is lowered.
+	minShardRecords = 256
+)
+
+// shard is a lock-protected partition of the key space.
+//
+// Readers take the read lock. A seqlock or RCU read path doesn't fit the swiss
+// map: a reader racing a rehash can index past the end of the groups, and copying
+// the map on every write costs more than the lock saves. The cleanup manager scans
+// under the read lock instead, so the write lock is only held for map updates.
+type shard struct {
+	sync.RWMutex
+	// Records are stored as a struct of arrays: the map only holds the slot of
+	// every key, and deadlines are a dense slice the cleanup manager scans without
+	// touching keys or values. Deleting a record moves the last slot into its place.
+	index     *swiss.Map[uint64, uint32]
+	keys      []uint64
+	deadlines []int64 // Unix nano
+	values    []interface{}
+	versions  []uint64 // version of the shard when the value was stored.
+	// capacity is the size of the map, as far as the shard can tell: the map
+	// doesn't expose it, so it is the larger of its initial size and the peak
+	// number of records. The map never shrinks by itself.
+	capacity uint32
+	reserved uint32 // Compact doesn't shrink the map below it.
+	churn    uint32 // Records removed since the map was built, see WithRebuild.
+	version  uint64 // Incremented on every change of the records, see Txn.
+	stats    counters
+	locks    *lockStats // nil without WithLockStats
+	keyLocks keyLocks
+	// Tags of the records, see SetWithTags; nil until a record is tagged.
+	tags   map[uint64][]string
+	tagged map[string]map[uint64]struct{}
+	// destructor is called with the values leaving the shard, see WithDestructor.
+	destructor func(key uint64, value interface{})
+	// retainer is called with the values shared by other caches, see WithRetainer.
+	retainer func(key uint64, value interface{})
+	filter   *bloomFilter // Shared by the shards, nil without WithBloomFilter.
+	// Eviction of records past maxCount, see WithEvictionPolicy; nil without it.
+	evictor  EvictionPolicy
+	maxCount uint32
+	evicted  func(key uint64, it item) // Reports evictions.
+}
+
+func newShard(capacity uint32) *shard {
+	s := &shard{reserved: capacity}
+	s.resize(capacity)
+	return s
+}
+
+// count returns the number of records. The shard must be locked.
+func (s *shard) count() int {
+	return len(s.keys)
+}
+
+// get returns the record of the key. The shard must be locked.
+func (s *shard) get(key uint64) (item, bool) {
+	i, ok := s.index.Get(key)
+	if s.evictor != nil {
+		if !ok {
+			i = NoSlot
+		}
+		s.evictor.OnAccess(key, i)
+	}
+	if !ok {
+		return item{}, false
+	}
+	return item{deadline: s.deadlines[i], value: s.values[i]}, true
+}
+
+// put stores the item and tracks the growth of the map.
+// The shard must be locked.
+func (s *shard) put(key uint64, it item) {
+	s.version++
+	if i, ok := s.index.Get(key); ok {
+		if s.tags != nil {
+			s.untag(key)
+		}
+		s.release(key, s.values[i])
+		account(key, s.values[i], false)
+		account(key, it.value, true)
+		s.deadlines[i] = it.deadline
+		s.values[i] = it.value
+		s.versions[i] = s.version
+		if s.evictor != nil {
+			s.evictor.OnAccess(key, i)
+		}
+		return
+	}
+	if s.evictor != nil && uint32(len(s.keys)) >= s.maxCount {
+		s.evict()
+	}
+	if s.evictor != nil {
+		s.evictor.OnAdd(key, uint32(len(s.keys)))
+	}
+	s.index.Put(key, uint32(len(s.keys)))
+	if s.filter != nil {
+		s.filter.add(key)
+	}
+	account(key, it.value, true)
+	s.keys = append(s.keys, key)
+	s.deadlines = append(s.deadlines, it.deadline)
+	s.values = append(s.values, it.value)
+	s.versions = append(s.versions, s.version)
+	if n := uint32(len(s.keys)); n > s.capacity {
+		s.capacity = n
+	}
+}
+
+// expire sets the deadline of the record of the key and returns the record.
+// Unlike put it keeps the tags. Tombstones are left as is, see WithTombstones.
+// The shard must be locked.
+func (s *shard) expire(key uint64, deadline int64) (item, bool) {
+	i, ok := s.index.Get(key)
+	if !ok {
+		return item{}, false
+	}
+	if _, buried := s.values[i].(tombstoneValue); buried {
+		return item{}, false
+	}
+	s.version++
+	s.deadlines[i] = deadline
+	return item{deadline: deadline, value: s.values[i]}, true
+}
+
+// delete removes the record of the key and returns it.
+// The shard must be locked.
+func (s *shard) delete(key uint64) (item, bool) {
+	it, ok := s.take(key)
+	if ok {
+		s.release(key, it.value)
+	}
+	return it, ok
+}
+
+// take removes the record of the key and returns it like delete, handing
+// the value over to the caller instead of the destructor.
+// The shard must be locked.
+func (s *shard) take(key uint64) (item, bool) {
+	i, ok := s.index.Get(key)
+	if !ok {
+		return item{}, false
+	}
+	it := item{deadline: s.deadlines[i], value: s.values[i]}
+	s.index.Delete(key)
+	account(key, it.value, false)
+	s.churn++
+	if s.filter != nil {
+		s.filter.remove(key)
+	}
+	s.version++
+	if s.tags != nil {
+		s.untag(key)
+	}
+
+	last := uint32(len(s.keys) - 1)
+	if s.evictor != nil {
+		s.evictor.OnRemove(key, i, last)
+	}
+	if i != last {
+		s.keys[i] = s.keys[last]
+		s.deadlines[i] = s.deadlines[last]
+		s.values[i] = s.values[last]
+		s.versions[i] = s.versions[last]
+		s.index.Put(s.keys[i], i)
+	}
+	s.values[last] = nil
+	s.keys = s.keys[:last]
+	s.deadlines = s.deadlines[:last]
+	s.values = s.values[:last]
+	s.versions = s.versions[:last]
+	return it, true
+}
+
+// clear removes all records and keeps the allocated memory.
+// The shard must be locked.
+func (s *shard) clear() {
+	for i, key := range s.keys {
+		s.release(key, s.values[i])
+		account(key, s.values[i], false)
+	}
+	if s.filter != nil {
+		for _, key := range s.keys {
+			s.filter.remove(key)
+		}
+	}
+	s.version++
+	s.tags, s.tagged = nil, nil
+	s.index.Clear()
+	s.churn = 0
+	clear(s.values)
+	s.keys = s.keys[:0]
+	s.deadlines = s.deadlines[:0]
+	s.values = s.values[:0]
+	s.versions = s.versions[:0]
+	if s.evictor != nil {
+		s.evictor.Reset(0)
+	}
+}
+
+// retain passes the value of a record shared with another cache to the
+// retainer, if any, see Clone and Merge. The shard must be locked.
+func (s *shard) retain(key uint64, value interface{}) {
+	if s.retainer == nil {
+		return
+	}
+	if v, ok := (item{value: value}).load(); ok {
+		s.retainer(key, v)
+	}
+}
+
+// release passes the value of a record leaving the shard to the destructor, if any.
+// Collected weak values and SetNotFound markers are not passed.
+func (s *shard) release(key uint64, value interface{}) {
+	if s.destructor == nil {
+		return
+	}
+	if v, ok := (item{value: value}).load(); ok {
+		s.destructor(key, v)
+	}
+}
+
+// resize moves the records to storage sized for capacity records.
+// The shard must be locked.
+func (s *shard) resize(capacity uint32) {
+	if capacity < uint32(len(s.keys)) {
+		capacity = uint32(len(s.keys))
+	}
+	s.index = swiss.NewMap[uint64, uint32](capacity)
+	for i, key := range s.keys {
+		s.index.Put(key, uint32(i))
+	}
+	s.keys = append(make([]uint64, 0, capacity), s.keys...)
+	s.deadlines = append(make([]int64, 0, capacity), s.deadlines...)
+	s.values = append(make([]interface{}, 0, capacity), s.values...)
+	s.versions = append(make([]uint64, 0, capacity), s.versions...)
+	s.capacity = capacity
+	s.churn = 0
+}
+
+// compact shrinks the storage when it is more than four times larger than the
+// records and the reservation need. It reports whether it was rebuilt.
+// The shard must be locked.
+func (s *shard) compact() bool {
+	n := len(s.keys)
+	need := uint32(n + n/8 + 1)
+	if need < s.reserved {
+		need = s.reserved
+	}
+	if s.capacity <= 4*need {
+		return false
+	}
+	s.resize(need)
+	return true
+}
+
+// appendExpired appends the keys of the records outdated at now to dst.
+// The shard must be locked.
+func (s *shard) appendExpired(dst []snapshotEntry, now int64) []snapshotEntry {
+	for i, deadline := range s.deadlines {
+		if deadline < now {
+			dst = append(dst, snapshotEntry{key: s.keys[i]})
+		}
+	}
+	return dst
+}
+
+// snapshotEntry is a record copied out of a shard.
+type snapshotEntry struct {
+	key  uint64
+	item item
+}
+
+// appendLive copies the records that are not outdated at now to dst
+// under the shard read lock.
+func (s *shard) appendLive(dst []snapshotEntry, now int64) []snapshotEntry {
+	s.RLock()
+	for i, deadline := range s.deadlines {
+		if deadline >= now {
+			dst = append(dst, snapshotEntry{key: s.keys[i], item: item{deadline: deadline, value: s.values[i]}})
+		}
+	}
+	s.RUnlock()
+	return dst
+}
+
+// clearEntries drops references to values so they can be collected.
+func clearEntries(entries []snapshotEntry) {
+	for i := range entries {
+		entries[i] = snapshotEntry{}
+	}
+}
+
+// shards splits the key space into a power of two number of partitions.
+type shards struct {
+	list  []*shard
+	shift uint
+	hash  func(uint64) uint64
+}
+
+func newShards(count, capacity int, hash func(uint64) uint64) shards {
+	s := shards{
+		list:  make([]*shard, count),
+		shift: shardShift(count),
+		hash:  hash,
+	}
+	perShard := perShardCapacity(capacity, count)
+	for i := range s.list {
+		s.list[i] = newShard(perShard)
+	}
+	return s
+}
+
+// autoShardCount returns the shard count for procs CPUs: shardsPerProc shards
+// per CPU, rounded up to a power of two. If capacity, the expected number of
+// records, is known and leaves less than minShardRecords records per shard,
+// the count is lowered, down to a single shard.
+func autoShardCount(procs, capacity int) int {
+	n := 1 << bits.Len(uint(shardsPerProc*procs-1))
+	n = min(max(n, minShardCount), maxShardCount)
+	if capacity > 0 {
+		for n > 1 && capacity/n < minShardRecords {
+			n >>= 1
+		}
+	}
+	return n
+}
+
+// perShardCapacity splits capacity between count shards.
+// Keys don't spread perfectly evenly, so every shard gets some headroom.
+func perShardCapacity(capacity, count int) uint32 {
+	n := capacity / count
+	return uint32(n + n/8 + 1)
+}
+
+// get returns the shard owning the key.
+func (s shards) get(key uint64) *shard {
+	return s.list[s.hash(key)>>s.shift]
+}
+
+// hashKey spreads the bits of the key so sequential keys are distributed
+// evenly across shards.
+func hashKey(k uint64) uint64 {
+	k ^= k >> 33
+	k *= 0xff51afd7ed558ccd
+	k ^= k >> 33
+	k *= 0xc4ceb9fe1a85ec53
+	k ^= k >> 33
+	return k
+}
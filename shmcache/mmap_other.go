@@ -0,0 +1,16 @@
+//go:build !(linux || darwin || freebsd)
+
+package shmcache
+
+import (
+	"errors"
+	"os"
+)
+
+func mmap(*os.File, int) ([]byte, error) {
+	return nil, errors.ErrUnsupported
+}
+
+func munmap([]byte) error {
+	return errors.ErrUnsupported
+}
@@ -0,0 +1,531 @@
+// Package shmcache is an experimental []byte cache with TTL in a shared memory
+// segment, so processes of a host, e.g. pre-forked workers or a sidecar,
+// share one cache without a network hop.
+//
+// The segment is a file mapped by every process, typically under /dev/shm,
+// holding fixed-size slots grouped in buckets of Ways slots: a key lives in
+// the bucket its hash picks, whose index of keys, deadlines and lengths is
+// scanned without touching the values. A Set into a full bucket evicts the
+// record of the bucket closest to its deadline. Every bucket has a spinlock
+// in the segment, taken by the processes with atomic instructions for a
+// lease of one second: a lock left by a process that died or stopped holding
+// it is broken by the next process waiting for it once the lease is over,
+// and a record written by a dying process reads as missing. Leases are read
+// from the system clock of the processes, which must not jump by more than
+// the lease, and a process must not be suspended for that long, e.g. by
+// SIGSTOP or a debugger, while it holds a lock. Locks don't depend on process
+// ids, so processes in other PID namespaces, e.g. containers, can share a
+// segment.
+//
+//	c, err := shmcache.Open("/dev/shm/myapp-cache", shmcache.Config{Slots: 1 << 16, SlotSize: 1024})
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	defer c.Close()
+//	c.Set(ttlcache.StringKey("user:1"), data, time.Minute)
+//
+// The layout is native endian and only meant for processes of the same build
+// on one host. Deadlines are read from the system clock of each process.
+package shmcache
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"os"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unsafe"
+
+	ttlcache "github.com/loicalleyne/ttlswisscache"
+)
+
+const (
+	// Ways is the number of slots of a bucket.
+	Ways = 8
+	// Version is the version of the segment layout.
+	Version = 2
+
+	magic     = "TTLSHM01"
+	headerLen = 128
+	// Offsets in the header.
+	versionOff  = 8
+	bucketsOff  = 12
+	slotSizeOff = 16
+	countersOff = 24 // hits, misses, sets, deletes, evicted, expired.
+	// Offsets in a bucket: lock word, then the index of keys, deadlines and
+	// lengths, then the values.
+	keysOff      = 8
+	deadlinesOff = keysOff + 8*Ways
+	lensOff      = deadlinesOff + 8*Ways
+	valuesOff    = (lensOff + 4*Ways + 7) &^ 7
+
+	// spinsPerCheck is the number of spins on a busy bucket lock between
+	// checks of its lease.
+	spinsPerCheck = 1 << 12
+	// lockLease is the time a bucket lock may be held, after which another
+	// process breaks it. A lock word holds the token of its holder in the high
+	// bits and the end of the lease in the leaseBits low bits, in milliseconds
+	// modulo 2^leaseBits, so a holder whose lock was broken can't release the
+	// lock of the next one.
+	lockLease = time.Second
+	leaseBits = 40
+	// openTimeout bounds the wait for another process initializing a segment.
+	openTimeout = time.Second
+)
+
+// Counters in the header.
+const (
+	hits = iota
+	misses
+	sets
+	deletes
+	evicted
+	expired
+)
+
+// magicWords are the words of the magic, written atomically.
+var magicWords = [2]uint32{
+	binary.NativeEndian.Uint32([]byte(magic[0:4])),
+	binary.NativeEndian.Uint32([]byte(magic[4:8])),
+}
+
+var (
+	// ErrIncompatible is returned by Open for a segment of another layout or
+	// configuration.
+	ErrIncompatible = errors.New("shmcache: incompatible segment")
+	// ErrClosed is returned by Set after Close.
+	ErrClosed = errors.New("shmcache: cache closed")
+)
+
+// Config sizes a segment.
+type Config struct {
+	// Slots is the number of records, rounded up to a multiple of Ways.
+	Slots int
+	// SlotSize is the largest value stored, rounded up to a multiple of 8.
+	SlotSize int
+	// Clock, if set, replaces the system clock, e.g. ttltest.Clock in tests.
+	// Processes sharing a segment must agree on the time.
+	Clock ttlcache.Clock
+}
+
+// Cache is a []byte cache in a shared memory segment. It is safe for use by
+// goroutines of many processes. Unlike ttlcache.BytesCache, outdated records
+// read as missing right away: their slots are reused by Sets, DeleteExpired
+// only frees them for Len and Stats.
+type Cache struct {
+	mu         sync.RWMutex // Write locked by Close, which unmaps data.
+	data       []byte
+	file       *os.File
+	buckets    int
+	slotSize   int
+	bucketSize int
+	token      uint64 // Identifies the locks held by c, see lockLease.
+	clock      ttlcache.Clock
+}
+
+// Open maps the segment at path, creating it sized by cfg if it doesn't
+// exist. An existing segment must have the sizes of cfg, otherwise Open
+// returns ErrIncompatible. Processes opening a new segment at the same time
+// wait for the one creating it. Remove the file to drop the segment.
+func Open(path string, cfg Config) (*Cache, error) {
+	if cfg.Slots <= 0 || cfg.SlotSize <= 0 {
+		return nil, fmt.Errorf("shmcache: slots and slot size must be positive")
+	}
+	c := &Cache{
+		buckets:  (cfg.Slots + Ways - 1) / Ways,
+		slotSize: (cfg.SlotSize + 7) &^ 7,
+		token:    rand.Uint64N(1<<(64-leaseBits)-1) + 1,
+		clock:    cfg.Clock,
+	}
+	c.bucketSize = valuesOff + Ways*c.slotSize
+	size := headerLen + c.buckets*c.bucketSize
+
+	created := true
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0o600)
+	if errors.Is(err, os.ErrExist) {
+		created = false
+		f, err = os.OpenFile(path, os.O_RDWR, 0)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if created {
+		err = f.Truncate(int64(size))
+	} else {
+		err = c.validate(f)
+	}
+	if err == nil {
+		c.data, err = mmap(f, size)
+	}
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	c.file = f
+
+	if created {
+		*c.u32(versionOff) = Version
+		*c.u32(bucketsOff) = uint32(c.buckets)
+		*c.u32(slotSizeOff) = uint32(c.slotSize)
+		// The magic is written last: it tells the other processes the
+		// segment is ready.
+		for i, w := range magicWords {
+			atomic.StoreUint32(c.u32(4*i), w)
+		}
+	}
+	return c, nil
+}
+
+// validate waits for the magic of the existing segment f, written by the
+// process creating it, and checks its layout against c.
+func (c *Cache) validate(f *os.File) error {
+	var header [countersOff]byte
+	for deadline := time.Now().Add(openTimeout); ; time.Sleep(time.Millisecond) {
+		if _, err := f.ReadAt(header[:], 0); err != nil && err != io.EOF {
+			return err
+		}
+		if string(header[:len(magic)]) == magic {
+			break
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%w: missing magic", ErrIncompatible)
+		}
+	}
+	version := binary.NativeEndian.Uint32(header[versionOff:])
+	buckets := binary.NativeEndian.Uint32(header[bucketsOff:])
+	slotSize := binary.NativeEndian.Uint32(header[slotSizeOff:])
+	if version != Version || int(buckets) != c.buckets || int(slotSize) != c.slotSize {
+		return fmt.Errorf("%w: version %d, %d buckets of %d byte slots, expected version %d, %d buckets of %d byte slots",
+			ErrIncompatible, version, buckets, slotSize, Version, c.buckets, c.slotSize)
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if size := headerLen + c.buckets*c.bucketSize; fi.Size() != int64(size) {
+		return fmt.Errorf("%w: %d bytes, expected %d", ErrIncompatible, fi.Size(), size)
+	}
+	return nil
+}
+
+// Get returns a copy of the value of key, false if it is missing or outdated.
+func (c *Cache) Get(key uint64) ([]byte, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.data == nil {
+		return nil, false
+	}
+	b := c.bucket(key)
+	now := c.now()
+	lease := c.lock(b)
+	i, ok := c.find(b, key, now)
+	var value []byte
+	if ok {
+		value = append(make([]byte, 0, *c.len(b, i)), c.value(b, i)...)
+	}
+	c.unlock(b, lease)
+	if !ok {
+		c.count(misses)
+		return nil, false
+	}
+	c.count(hits)
+	return value, true
+}
+
+// Set stores a copy of value under key for ttl. It returns
+// ttlcache.ErrValueTooLarge for values longer than the slot size and ErrClosed
+// after Close.
+func (c *Cache) Set(key uint64, value []byte, ttl time.Duration) error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.data == nil {
+		return ErrClosed
+	}
+	if len(value) > c.slotSize {
+		return ttlcache.ErrValueTooLarge
+	}
+	b := c.bucket(key)
+	now := c.now()
+	lease := c.lock(b)
+	i, evict := c.slot(b, key, now)
+	// The record reads as missing until its deadline is written, so a
+	// process dying meanwhile leaves the slot empty.
+	deadline := c.deadline(b, i)
+	atomic.StoreInt64(deadline, 0)
+	*c.key(b, i) = key
+	*c.len(b, i) = uint32(len(value))
+	copy(c.data[b+valuesOff+i*c.slotSize:], value)
+	atomic.StoreInt64(deadline, max(now+int64(ttl), 1))
+	c.unlock(b, lease)
+	if evict {
+		c.count(evicted)
+	}
+	c.count(sets)
+	return nil
+}
+
+// TTL returns the remaining time to live of key, false if it is missing or
+// outdated.
+func (c *Cache) TTL(key uint64) (time.Duration, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.data == nil {
+		return 0, false
+	}
+	b := c.bucket(key)
+	now := c.now()
+	lease := c.lock(b)
+	defer c.unlock(b, lease)
+	i, ok := c.find(b, key, now)
+	if !ok {
+		return 0, false
+	}
+	return time.Duration(*c.deadline(b, i) - now), true
+}
+
+// Delete removes the record of key.
+func (c *Cache) Delete(key uint64) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.data == nil {
+		return
+	}
+	b := c.bucket(key)
+	lease := c.lock(b)
+	i, ok := c.find(b, key, c.now())
+	if ok {
+		*c.deadline(b, i) = 0
+	}
+	c.unlock(b, lease)
+	if ok {
+		c.count(deletes)
+	}
+}
+
+// Len returns the number of records, including outdated ones waiting for
+// DeleteExpired or a Set to reuse their slot.
+func (c *Cache) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.entries()
+}
+
+// DeleteExpired frees the slots of the outdated records and returns their
+// number.
+func (c *Cache) DeleteExpired() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	now := c.now()
+	n := 0
+	c.each(func(deadline *int64) {
+		if *deadline != 0 && *deadline < now {
+			*deadline = 0
+			n++
+		}
+	})
+	if n > 0 {
+		atomic.AddUint64(c.counter(expired), uint64(n))
+	}
+	return n
+}
+
+// Clear removes every record of the segment, for all processes.
+func (c *Cache) Clear() {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	c.each(func(deadline *int64) {
+		*deadline = 0
+	})
+}
+
+// Stats returns the counters of the segment, shared by the processes using it.
+// Shards is the number of buckets.
+func (c *Cache) Stats() ttlcache.Stats {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.data == nil {
+		return ttlcache.Stats{}
+	}
+	st := ttlcache.Stats{Shards: c.buckets, Entries: c.entries()}
+	st.Hits = atomic.LoadUint64(c.counter(hits))
+	st.Misses = atomic.LoadUint64(c.counter(misses))
+	st.Sets = atomic.LoadUint64(c.counter(sets))
+	st.Deletes = atomic.LoadUint64(c.counter(deletes))
+	st.Evicted = atomic.LoadUint64(c.counter(evicted))
+	st.Expired = atomic.LoadUint64(c.counter(expired))
+	return st
+}
+
+// Close unmaps the segment, which stays available to the other processes.
+// Reads miss and writes return ErrClosed afterwards. Closing a closed cache
+// returns ErrClosed.
+func (c *Cache) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.data == nil {
+		return ErrClosed
+	}
+	err := munmap(c.data)
+	c.data = nil
+	if cerr := c.file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// entries returns the number of records. c.mu must be read locked.
+func (c *Cache) entries() int {
+	n := 0
+	c.each(func(deadline *int64) {
+		if *deadline != 0 {
+			n++
+		}
+	})
+	return n
+}
+
+// each calls fn with the deadline of every slot under the lock of its bucket,
+// unless the cache is closed. c.mu must be read locked.
+func (c *Cache) each(fn func(deadline *int64)) {
+	if c.data == nil {
+		return
+	}
+	for n := 0; n < c.buckets; n++ {
+		b := headerLen + n*c.bucketSize
+		lease := c.lock(b)
+		for i := 0; i < Ways; i++ {
+			fn(c.deadline(b, i))
+		}
+		c.unlock(b, lease)
+	}
+}
+
+// find returns the slot of the live record of key in the locked bucket b.
+func (c *Cache) find(b int, key uint64, now int64) (int, bool) {
+	for i := 0; i < Ways; i++ {
+		if *c.key(b, i) == key {
+			if deadline := *c.deadline(b, i); deadline != 0 && deadline >= now {
+				return i, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// slot returns the slot of the locked bucket b to store key in: the one of
+// its record, else a free or outdated one, else the one closest to its
+// deadline, which is evicted.
+func (c *Cache) slot(b int, key uint64, now int64) (i int, evict bool) {
+	free, oldest := -1, 0
+	for j := 0; j < Ways; j++ {
+		deadline := *c.deadline(b, j)
+		if deadline != 0 && *c.key(b, j) == key {
+			return j, false
+		}
+		if free < 0 && (deadline == 0 || deadline < now) {
+			free = j
+		}
+		if deadline < *c.deadline(b, oldest) {
+			oldest = j
+		}
+	}
+	if free >= 0 {
+		return free, false
+	}
+	return oldest, true
+}
+
+// lock takes the spinlock of bucket b, breaking it if the lease of its holder
+// is over, and returns the lock word to pass to unlock.
+func (c *Cache) lock(b int) uint64 {
+	w := (*uint64)(unsafe.Pointer(&c.data[b]))
+	for spins := 1; ; spins++ {
+		lease := c.lease()
+		if atomic.CompareAndSwapUint64(w, 0, lease) {
+			return lease
+		}
+		if spins%spinsPerCheck == 0 {
+			if held := atomic.LoadUint64(w); held != 0 && over(held, lease) && atomic.CompareAndSwapUint64(w, held, lease) {
+				return lease
+			}
+		}
+		runtime.Gosched()
+	}
+}
+
+// unlock releases the lock of bucket b taken with lease, unless it was broken.
+func (c *Cache) unlock(b int, lease uint64) {
+	atomic.CompareAndSwapUint64((*uint64)(unsafe.Pointer(&c.data[b])), lease, 0)
+}
+
+// lease returns the lock word of c for a lease starting now.
+func (c *Cache) lease() uint64 {
+	end := uint64(time.Now().Add(lockLease).UnixMilli())
+	return c.token<<leaseBits | end&(1<<leaseBits-1)
+}
+
+// over reports whether the lease of the lock word held ended before the one of
+// the lock word lease, which started now.
+func over(held, lease uint64) bool {
+	// Shifted out of the token, the difference of the ends is signed modulo
+	// 2^leaseBits.
+	return int64((lease-held)<<(64-leaseBits)) > int64(lockLease.Milliseconds())<<(64-leaseBits)
+}
+
+// bucket returns the offset of the bucket of key.
+func (c *Cache) bucket(key uint64) int {
+	return headerLen + int(hash(key)%uint64(c.buckets))*c.bucketSize
+}
+
+func (c *Cache) key(b, i int) *uint64 {
+	return (*uint64)(unsafe.Pointer(&c.data[b+keysOff+8*i]))
+}
+
+func (c *Cache) deadline(b, i int) *int64 {
+	return (*int64)(unsafe.Pointer(&c.data[b+deadlinesOff+8*i]))
+}
+
+func (c *Cache) len(b, i int) *uint32 {
+	return c.u32(b + lensOff + 4*i)
+}
+
+// value returns the value in slot i of bucket b, in the segment.
+func (c *Cache) value(b, i int) []byte {
+	off := b + valuesOff + i*c.slotSize
+	return c.data[off : off+int(*c.len(b, i))]
+}
+
+func (c *Cache) u32(off int) *uint32 {
+	return (*uint32)(unsafe.Pointer(&c.data[off]))
+}
+
+func (c *Cache) counter(n int) *uint64 {
+	return (*uint64)(unsafe.Pointer(&c.data[countersOff+8*n]))
+}
+
+// count increments counter n of the segment.
+func (c *Cache) count(n int) {
+	atomic.AddUint64(c.counter(n), 1)
+}
+
+func (c *Cache) now() int64 {
+	if c.clock != nil {
+		return c.clock.Now().UnixNano()
+	}
+	return time.Now().UnixNano()
+}
+
+// hash spreads keys over the buckets, the same way in every process.
+func hash(k uint64) uint64 {
+	k ^= k >> 33
+	k *= 0xff51afd7ed558ccd
+	k ^= k >> 33
+	k *= 0xc4ceb9fe1a85ec53
+	k ^= k >> 33
+	return k
+}
@@ -0,0 +1,222 @@
+package shmcache
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+	"unsafe"
+
+	ttlcache "github.com/loicalleyne/ttlswisscache"
+	"github.com/loicalleyne/ttlswisscache/ttltest"
+)
+
+func open(t *testing.T, path string, cfg Config) *Cache {
+	t.Helper()
+	c, err := Open(path, cfg)
+	if err != nil {
+		t.Fatalf("opening the segment failed: %v", err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func TestCache(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "segment")
+	clock := ttltest.NewClock()
+	cfg := Config{Slots: 64, SlotSize: 16, Clock: clock}
+	a := open(t, path, cfg)
+	b := open(t, path, cfg)
+
+	if err := a.Set(1, []byte("one"), time.Minute); err != nil {
+		t.Fatalf("incorrect error: got: %v expected: %v", err, nil)
+	}
+	if value, ok := b.Get(1); !ok || string(value) != "one" {
+		t.Errorf("incorrect value of the other mapping: got: %q, %v expected: %q, %v", value, ok, "one", true)
+	}
+	if err := b.Set(1, []byte("uno"), time.Minute); err != nil {
+		t.Fatalf("incorrect error: got: %v expected: %v", err, nil)
+	}
+	if value, _ := a.Get(1); string(value) != "uno" {
+		t.Errorf("incorrect value after an overwrite: got: %q expected: %q", value, "uno")
+	}
+	if ttl, ok := a.TTL(1); !ok || ttl != time.Minute {
+		t.Errorf("incorrect ttl: got: %v, %v expected: %v, %v", ttl, ok, time.Minute, true)
+	}
+	if err := a.Set(2, make([]byte, 17), time.Minute); !errors.Is(err, ttlcache.ErrValueTooLarge) {
+		t.Errorf("incorrect error of an oversized value: got: %v expected: %v", err, ttlcache.ErrValueTooLarge)
+	}
+
+	a.Set(2, []byte("two"), time.Second)
+	clock.Advance(2 * time.Second)
+	if _, ok := b.Get(2); ok {
+		t.Error("outdated record found")
+	}
+	if n := b.Len(); n != 2 {
+		t.Errorf("incorrect length before DeleteExpired: got: %d expected: %d", n, 2)
+	}
+	if n := b.DeleteExpired(); n != 1 {
+		t.Errorf("incorrect number of removed records: got: %d expected: %d", n, 1)
+	}
+	b.Delete(1)
+	if _, ok := a.Get(1); ok {
+		t.Error("deleted record found")
+	}
+
+	st := a.Stats()
+	expected := ttlcache.Stats{Shards: 8, Hits: 2, Misses: 2, Sets: 3, Deletes: 1, Expired: 1}
+	if st != expected {
+		t.Errorf("incorrect stats: got: %+v expected: %+v", st, expected)
+	}
+
+	a.Set(3, []byte("three"), time.Minute)
+	b.Clear()
+	if n := a.Len(); n != 0 {
+		t.Errorf("incorrect length after Clear: got: %d expected: %d", n, 0)
+	}
+	a.Close()
+	if err := a.Set(1, nil, time.Minute); !errors.Is(err, ErrClosed) {
+		t.Errorf("incorrect error once closed: got: %v expected: %v", err, ErrClosed)
+	}
+	if _, ok := a.Get(1); ok {
+		t.Error("record found once closed")
+	}
+	if err := a.Close(); !errors.Is(err, ErrClosed) {
+		t.Errorf("incorrect error of a second Close: got: %v expected: %v", err, ErrClosed)
+	}
+}
+
+func TestCache_Evict(t *testing.T) {
+	c := open(t, filepath.Join(t.TempDir(), "segment"), Config{Slots: Ways, SlotSize: 8})
+	for i := 0; i < Ways; i++ {
+		c.Set(uint64(i), []byte{byte(i)}, time.Duration(i+1)*time.Minute)
+	}
+	c.Set(100, []byte{100}, time.Hour)
+	// The record closest to its deadline is evicted.
+	if _, ok := c.Get(0); ok {
+		t.Error("record closest to its deadline not evicted")
+	}
+	for _, key := range []uint64{1, Ways - 1, 100} {
+		if _, ok := c.Get(key); !ok {
+			t.Errorf("incorrect presence of %d: got: %v expected: %v", key, ok, true)
+		}
+	}
+	if st := c.Stats(); st.Evicted != 1 || st.Entries != Ways {
+		t.Errorf("incorrect stats: got: %+v", st)
+	}
+}
+
+func TestOpen_Incompatible(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "segment")
+	open(t, path, Config{Slots: 64, SlotSize: 16})
+	if _, err := Open(path, Config{Slots: 64, SlotSize: 32}); !errors.Is(err, ErrIncompatible) {
+		t.Errorf("incorrect error of another slot size: got: %v expected: %v", err, ErrIncompatible)
+	}
+	if _, err := Open(path, Config{}); err == nil {
+		t.Error("empty config accepted")
+	}
+}
+
+func TestCache_DeadHolder(t *testing.T) {
+	c := open(t, filepath.Join(t.TempDir(), "segment"), Config{Slots: Ways, SlotSize: 8})
+	w := (*uint64)(unsafe.Pointer(&c.data[headerLen]))
+	// A lock of another process whose lease is over.
+	dead := &Cache{token: c.token%(1<<(64-leaseBits)-1) + 1}
+	held := dead.lease() - uint64(2*lockLease.Milliseconds())
+	*w = held
+	done := make(chan struct{})
+	go func() {
+		c.Set(1, []byte("one"), time.Minute)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("lock of a dead process not released")
+	}
+
+	// The late holder doesn't release the lock of the one breaking its lease.
+	*w = held
+	lease := c.lock(headerLen)
+	c.unlock(headerLen, held)
+	if got := atomic.LoadUint64(w); got != lease {
+		t.Errorf("incorrect lock word after the unlock of a broken lock: got: %x expected: %x", got, lease)
+	}
+	c.unlock(headerLen, lease)
+	if got := atomic.LoadUint64(w); got != 0 {
+		t.Errorf("incorrect lock word after the unlock: got: %x expected: %x", got, 0)
+	}
+}
+
+func TestOver(t *testing.T) {
+	now := (&Cache{token: 1}).lease()
+	for _, tc := range []struct {
+		held uint64
+		over bool
+	}{
+		{held: now, over: false},
+		{held: now - uint64(lockLease.Milliseconds()), over: false},
+		{held: now - uint64(lockLease.Milliseconds()) - 1, over: true},
+		{held: now + 10, over: false},
+		{held: 2<<leaseBits | (now-uint64(time.Hour.Milliseconds()))&(1<<leaseBits-1), over: true},
+	} {
+		if got := over(tc.held, now); got != tc.over {
+			t.Errorf("incorrect end of the lease %x at %x: got: %v expected: %v", tc.held, now, got, tc.over)
+		}
+	}
+}
+
+func TestCache_Concurrent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "segment")
+	a := open(t, path, Config{Slots: 1024, SlotSize: 8})
+	b := open(t, path, Config{Slots: 1024, SlotSize: 8})
+	var wg sync.WaitGroup
+	for g, c := range []*Cache{a, b, a, b} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				key := uint64(i % 100)
+				c.Set(key, []byte(strconv.Itoa(g)), time.Minute)
+				if value, ok := c.Get(key); ok && len(value) != 1 {
+					t.Errorf("incorrect value: got: %q", value)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if n := a.Len(); n != 100 {
+		t.Errorf("incorrect length: got: %d expected: %d", n, 100)
+	}
+}
+
+// TestCache_Processes shares a segment with a child process.
+func TestCache_Processes(t *testing.T) {
+	if path := os.Getenv("SHMCACHE_SEGMENT"); path != "" {
+		c, err := Open(path, Config{Slots: 64, SlotSize: 16})
+		if err != nil {
+			os.Exit(2)
+		}
+		value, _ := c.Get(1)
+		c.Set(2, append(value, '!'), time.Minute)
+		c.Close()
+		os.Exit(0)
+	}
+
+	path := filepath.Join(t.TempDir(), "segment")
+	c := open(t, path, Config{Slots: 64, SlotSize: 16})
+	c.Set(1, []byte("parent"), time.Minute)
+	cmd := exec.Command(os.Args[0], "-test.run=^TestCache_Processes$")
+	cmd.Env = append(os.Environ(), "SHMCACHE_SEGMENT="+path)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("child process failed: %v: %s", err, out)
+	}
+	if value, ok := c.Get(2); !ok || string(value) != "parent!" {
+		t.Errorf("incorrect value of the child process: got: %q, %v expected: %q, %v", value, ok, "parent!", true)
+	}
+}
@@ -0,0 +1,50 @@
+package ttlswisscache
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// drainInterval is how often Shutdown checks whether subscribers have caught up.
+const drainInterval = time.Millisecond
+
+// Shutdown closes the cache gracefully. New writes are refused, writes queued by
+// SetAsync are applied, and Shutdown waits for event subscribers, e.g. CDC streams,
+// to receive the pending events. If w is not nil, a final snapshot encoded with
+// codec is then written to w. Finally the cache is closed.
+//
+// When ctx is done before the cache is drained, the remaining steps are skipped,
+// the cache is closed anyway and the context error is returned.
+// Shutdown returns ErrClosed if the cache is already shut down or closed.
+func (c *Cache) Shutdown(ctx context.Context, w io.Writer, codec Codec) error {
+	if !c.closing.CompareAndSwap(false, true) {
+		return ErrClosed
+	}
+	err := c.drain(ctx)
+	if err == nil && w != nil {
+		err = c.Snapshot(w, codec)
+	}
+	c.Close() // Fails only if Close was called in the meantime.
+	return err
+}
+
+// drain waits for the write buffer and the subscribers.
+func (c *Cache) drain(ctx context.Context) error {
+	if err := c.flush(ctx); err != nil {
+		return err
+	}
+	if c.subs.pending() == 0 {
+		return nil
+	}
+	ticker := time.NewTicker(drainInterval)
+	defer ticker.Stop()
+	for c.subs.pending() > 0 {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
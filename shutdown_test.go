@@ -0,0 +1,67 @@
+package ttlswisscache
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCache_Shutdown(t *testing.T) {
+	c := New(time.Hour, WithWriteBuffer(1024))
+	events, _ := c.Subscribe(1024)
+	for i := 0; i < 100; i++ {
+		c.SetAsync(IntKey(i), i, time.Hour)
+	}
+	received := make(chan int)
+	go func() {
+		n := 0
+		for ev := range events {
+			if ev.Op == OpSet {
+				n++
+			}
+		}
+		received <- n
+	}()
+
+	var buf bytes.Buffer
+	if err := c.Shutdown(context.Background(), &buf, GobCodec{}); err != nil {
+		t.Fatalf("incorrect shutdown error: got: %v expected: %v", err, nil)
+	}
+	if n := <-received; n != 100 {
+		t.Errorf("incorrect number of received events: got: %d expected: %d", n, 100)
+	}
+	if err := c.Shutdown(context.Background(), nil, nil); err != ErrClosed {
+		t.Errorf("incorrect second shutdown error: got: %v expected: %v", err, ErrClosed)
+	}
+	c.Set(IntKey(1), 1, time.Hour)
+	if _, ok := c.Get(IntKey(1)); ok {
+		t.Error("record stored after shutdown")
+	}
+
+	restored := New(0)
+	defer restored.Close()
+	if n, err := restored.Restore(&buf, GobCodec{}); err != nil || n != 100 {
+		t.Errorf("incorrect final snapshot: got: %d, %v expected: %d, %v", n, err, 100, nil)
+	}
+}
+
+func TestCache_Shutdown_Deadline(t *testing.T) {
+	c := New(time.Hour)
+	c.Subscribe(16) // Never read.
+	c.Set(IntKey(1), 1, time.Hour)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	var buf bytes.Buffer
+	if err := c.Shutdown(ctx, &buf, GobCodec{}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("incorrect shutdown error: got: %v expected: %v", err, context.DeadlineExceeded)
+	}
+	if buf.Len() != 0 {
+		t.Error("snapshot written after the deadline")
+	}
+	if err := c.Close(); err != ErrClosed {
+		t.Errorf("cache was not closed: got: %v expected: %v", err, ErrClosed)
+	}
+}
@@ -0,0 +1,169 @@
+package ttlswisscache
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// maxSnapshotValue guards Restore against corrupted value lengths.
+const maxSnapshotValue = 1 << 30
+
+// Snapshot writes all live records to w using codec for the values.
+// Every shard is copied under its read lock and encoded after the lock
+// is released, so writers are blocked only for the time of a shard copy
+// and not for the whole stream. The view is consistent per shard.
+//
+// Each record is written as key (8 bytes), deadline in Unix nano (8 bytes),
+// value length (uvarint) and the encoded value. All integers are big endian.
+func (c *Cache) Snapshot(w io.Writer, codec Codec) error {
+	return c.snapshot(w, codec, true)
+}
+
+// SnapshotAll writes all records to w like Snapshot, outdated ones waiting for
+// the cleanup manager included, so replicas expiring records with a shared
+// logical clock can copy each other, see DeleteExpiredBefore and RestoreAll.
+func (c *Cache) SnapshotAll(w io.Writer, codec Codec) error {
+	return c.snapshot(w, codec, false)
+}
+
+// snapshot is Snapshot, writing the outdated records too unless live is true.
+func (c *Cache) snapshot(w io.Writer, codec Codec, live bool) error {
+	if c.closed.Load() {
+		return ErrClosed
+	}
+	bw := c.bufs.getWriter(w)
+	defer c.bufs.putWriter(bw)
+	buf := c.bufs.getEntries()
+	defer c.bufs.putEntries(buf)
+	var header [16 + binary.MaxVarintLen64]byte
+	entries := *buf
+	for _, s := range c.shards.list {
+		now := int64(math.MinInt64)
+		if live {
+			now = c.clock.unixNano()
+		}
+		entries = s.appendLive(entries[:0], now)
+		*buf = entries
+
+		for i := range entries {
+			value, ok := entries[i].item.load()
+			if !ok {
+				continue
+			}
+			data, err := codec.Marshal(value)
+			if err != nil {
+				return fmt.Errorf("ttlswisscache: encode key %d: %w", entries[i].key, err)
+			}
+			binary.BigEndian.PutUint64(header[0:], entries[i].key)
+			binary.BigEndian.PutUint64(header[8:], uint64(entries[i].item.deadline))
+			n := 16 + binary.PutUvarint(header[16:], uint64(len(data)))
+			if _, err := bw.Write(header[:n]); err != nil {
+				return err
+			}
+			if _, err := bw.Write(data); err != nil {
+				return err
+			}
+		}
+		clearEntries(entries)
+	}
+	return bw.Flush()
+}
+
+// Restore reads records written by Snapshot and stores them with their
+// original deadlines. Records that have expired in the meantime are skipped.
+// It returns the number of restored records. Values longer than 1GiB are
+// reported as corrupted.
+func (c *Cache) Restore(r io.Reader, codec Codec) (int, error) {
+	return c.restore(r, codec, true)
+}
+
+// RestoreAll reads records written by Snapshot or SnapshotAll like Restore,
+// outdated ones included, see SnapshotAll.
+func (c *Cache) RestoreAll(r io.Reader, codec Codec) (int, error) {
+	return c.restore(r, codec, false)
+}
+
+// restore is Restore, storing the outdated records too unless live is true.
+func (c *Cache) restore(r io.Reader, codec Codec, live bool) (int, error) {
+	if c.closing.Load() {
+		return 0, ErrClosed
+	}
+	br := c.bufs.getReader(r)
+	defer c.bufs.putReader(br)
+	var (
+		header [16]byte
+		n      int
+	)
+	for {
+		if _, err := io.ReadFull(br, header[:]); err != nil {
+			if err == io.EOF {
+				return n, nil
+			}
+			return n, unexpectedEOF(err)
+		}
+		size, err := binary.ReadUvarint(br)
+		if err != nil {
+			return n, unexpectedEOF(err)
+		}
+		key := binary.BigEndian.Uint64(header[0:])
+		if size > maxSnapshotValue {
+			return n, fmt.Errorf("ttlswisscache: invalid value length %d of key %d", size, key)
+		}
+		// Each record gets its own buffer, codecs may return values aliasing it.
+		data := make([]byte, size)
+		if _, err := io.ReadFull(br, data); err != nil {
+			return n, unexpectedEOF(err)
+		}
+
+		deadline := int64(binary.BigEndian.Uint64(header[8:]))
+		if live && deadline < c.clock.unixNano() {
+			continue
+		}
+		value, err := codec.Unmarshal(data)
+		if err != nil {
+			return n, fmt.Errorf("ttlswisscache: decode key %d: %w", key, err)
+		}
+		c.store(key, item{deadline: deadline, value: value})
+		n++
+	}
+}
+
+// SnapshotTarget stores snapshots outside the process, e.g. in object storage,
+// see SnapshotTo and RestoreFrom.
+type SnapshotTarget interface {
+	// Store calls write with a writer of a new snapshot and keeps it only if
+	// write succeeds, replacing the previous one.
+	Store(ctx context.Context, write func(w io.Writer) error) error
+	// Load calls read with a reader of the stored snapshot.
+	Load(ctx context.Context, read func(r io.Reader) error) error
+}
+
+// SnapshotTo stores a Snapshot of the live records in target.
+func (c *Cache) SnapshotTo(ctx context.Context, target SnapshotTarget, codec Codec) error {
+	return target.Store(ctx, func(w io.Writer) error {
+		return c.Snapshot(w, codec)
+	})
+}
+
+// RestoreFrom loads the snapshot stored in target like Restore and returns
+// the number of restored records.
+func (c *Cache) RestoreFrom(ctx context.Context, target SnapshotTarget, codec Codec) (int, error) {
+	var n int
+	err := target.Load(ctx, func(r io.Reader) error {
+		var err error
+		n, err = c.Restore(r, codec)
+		return err
+	})
+	return n, err
+}
+
+func unexpectedEOF(err error) error {
+	if errors.Is(err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
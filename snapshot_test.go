@@ -0,0 +1,192 @@
+package ttlswisscache
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestCache_SnapshotRestore(t *testing.T) {
+	codecs := []struct {
+		name  string
+		codec Codec
+	}{
+		{name: "gob", codec: GobCodec{}},
+		{name: "json", codec: JSONCodec{}},
+	}
+
+	for _, tc := range codecs {
+		t.Run(tc.name, func(t *testing.T) {
+			src := New(time.Hour)
+			defer src.Close()
+			for i := 0; i < 1000; i++ {
+				src.Set(IntKey(i), "value", time.Hour)
+			}
+			src.Set(StringKey("expired"), "value", -time.Second)
+
+			var buf bytes.Buffer
+			if err := src.Snapshot(&buf, tc.codec); err != nil {
+				t.Fatalf("snapshot failed: %v", err)
+			}
+
+			dst := New(time.Hour)
+			defer dst.Close()
+			n, err := dst.Restore(&buf, tc.codec)
+			if err != nil {
+				t.Fatalf("restore failed: %v", err)
+			}
+			if n != 1000 {
+				t.Errorf("incorrect number of restored records: got: %d expected: %d", n, 1000)
+			}
+
+			val, ok := dst.Get(IntKey(42))
+			if !ok {
+				t.Fatal("restored storage missed expected value")
+			}
+			if val != "value" {
+				t.Errorf("incorrect value: got: %v expected: %v", val, "value")
+			}
+			if _, ok := dst.Get(StringKey("expired")); ok {
+				t.Error("expired record was restored")
+			}
+		})
+	}
+}
+
+func TestCache_SnapshotAll(t *testing.T) {
+	clock := &fixedClock{now: time.Now()}
+	src := New(0, WithClock(clock))
+	defer src.Close()
+	src.Set(IntKey(1), "live", time.Hour)
+	src.Set(IntKey(2), "outdated", -time.Second)
+
+	var buf bytes.Buffer
+	if err := src.SnapshotAll(&buf, GobCodec{}); err != nil {
+		t.Fatalf("snapshot failed: %v", err)
+	}
+	dst := New(0, WithClock(clock))
+	defer dst.Close()
+	if n, err := dst.RestoreAll(&buf, GobCodec{}); err != nil || n != 2 {
+		t.Errorf("incorrect number of restored records: got: %d, %v expected: %d", n, err, 2)
+	}
+	if _, age, ok := dst.GetStale(IntKey(2)); !ok || age != time.Second {
+		t.Errorf("incorrect age of the outdated record: got: %v, %v expected: %v", age, ok, time.Second)
+	}
+}
+
+func TestCache_RestoreTruncated(t *testing.T) {
+	src := New(time.Hour)
+	defer src.Close()
+	src.Set(IntKey(1), "value", time.Hour)
+
+	var buf bytes.Buffer
+	if err := src.Snapshot(&buf, GobCodec{}); err != nil {
+		t.Fatalf("snapshot failed: %v", err)
+	}
+
+	dst := New(time.Hour)
+	defer dst.Close()
+	truncated := bytes.NewReader(buf.Bytes()[:buf.Len()-1])
+	if _, err := dst.Restore(truncated, GobCodec{}); err == nil {
+		t.Error("expected error on truncated snapshot")
+	}
+}
+
+func TestCache_RestoreOversized(t *testing.T) {
+	var record [16 + binary.MaxVarintLen64]byte
+	binary.BigEndian.PutUint64(record[0:], IntKey(1))
+	binary.BigEndian.PutUint64(record[8:], uint64(time.Now().Add(time.Hour).UnixNano()))
+	n := 16 + binary.PutUvarint(record[16:], 1<<62)
+
+	dst := New(time.Hour)
+	defer dst.Close()
+	if _, err := dst.Restore(bytes.NewReader(record[:n]), GobCodec{}); err == nil {
+		t.Error("expected error on a corrupted value length")
+	}
+}
+
+func TestCache_RestoreMsgpackBytes(t *testing.T) {
+	src := New(time.Hour)
+	defer src.Close()
+	values := []string{"bbbb", "aaaa", "cccc"}
+	for i, v := range values {
+		src.Set(IntKey(i), []byte(v), time.Hour)
+	}
+
+	var buf bytes.Buffer
+	if err := src.Snapshot(&buf, MsgpackCodec{}); err != nil {
+		t.Fatalf("snapshot failed: %v", err)
+	}
+	dst := New(time.Hour)
+	defer dst.Close()
+	if _, err := dst.Restore(&buf, MsgpackCodec{}); err != nil {
+		t.Fatalf("restore failed: %v", err)
+	}
+	for i, v := range values {
+		got, _ := dst.Get(IntKey(i))
+		if b, _ := got.([]byte); string(b) != v {
+			t.Errorf("incorrect value of key %d: got: %v expected: %v", i, got, v)
+		}
+	}
+}
+
+func TestCache_SnapshotConcurrentWrites(t *testing.T) {
+	c := New(time.Hour)
+	defer c.Close()
+	for i := 0; i < 10000; i++ {
+		c.Set(IntKey(i), i, time.Hour)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 10000; i++ {
+			c.Set(IntKey(i), i, time.Hour)
+		}
+	}()
+
+	var buf bytes.Buffer
+	if err := c.Snapshot(&buf, GobCodec{}); err != nil {
+		t.Fatalf("snapshot failed: %v", err)
+	}
+	<-done
+}
+
+// memoryTarget keeps a snapshot in memory.
+type memoryTarget struct{ data []byte }
+
+func (m *memoryTarget) Store(_ context.Context, write func(w io.Writer) error) error {
+	var buf bytes.Buffer
+	if err := write(&buf); err != nil {
+		return err
+	}
+	m.data = buf.Bytes()
+	return nil
+}
+
+func (m *memoryTarget) Load(_ context.Context, read func(r io.Reader) error) error {
+	return read(bytes.NewReader(m.data))
+}
+
+func TestCache_SnapshotTo(t *testing.T) {
+	c := New(0)
+	defer c.Close()
+	c.Set(IntKey(1), "a", time.Hour)
+	c.Set(IntKey(2), "b", time.Hour)
+
+	target := &memoryTarget{}
+	if err := c.SnapshotTo(context.Background(), target, GobCodec{}); err != nil {
+		t.Fatal(err)
+	}
+	c.Clear()
+	n, err := c.RestoreFrom(context.Background(), target, GobCodec{})
+	if err != nil || n != 2 {
+		t.Errorf("incorrect restore: got: %d, %v expected: %d, %v", n, err, 2, nil)
+	}
+	if v, _ := c.Get(IntKey(2)); v != "b" {
+		t.Errorf("incorrect value: got: %v expected: %v", v, "b")
+	}
+}
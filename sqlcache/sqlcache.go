@@ -0,0 +1,147 @@
+// Package sqlcache caches database/sql query results in a Cache.
+//
+// Results are keyed by the statement and its arguments and hold the scanned rows,
+// so a cached query doesn't touch the database nor keep a connection busy.
+// Writers invalidate a single result with Invalidate or every result of a statement
+// with InvalidateQuery; the latter bumps a per-statement generation so stale results
+// are never read again and expire on their own.
+package sqlcache
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"hash/fnv"
+	"io"
+	"sync"
+	"time"
+
+	ttlcache "github.com/loicalleyne/ttlswisscache"
+)
+
+// Querier is implemented by *sql.DB, *sql.Conn and *sql.Tx.
+type Querier interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// Result is a fully scanned query result.
+// It is shared between callers and must not be modified.
+type Result struct {
+	Columns []string
+	Rows    [][]interface{}
+}
+
+// QueryCache caches query results.
+type QueryCache struct {
+	cache *ttlcache.Cache
+
+	mu          sync.RWMutex
+	generations map[string]uint64
+}
+
+// New creates a query cache storing results in cache.
+func New(cache *ttlcache.Cache) *QueryCache {
+	return &QueryCache{cache: cache, generations: make(map[string]uint64)}
+}
+
+// CachedQuery returns the cached result of query or runs it on db and caches
+// the scanned rows for ttl. Errors are not cached.
+func (q *QueryCache) CachedQuery(ctx context.Context, db Querier, ttl time.Duration, query string, args ...interface{}) (*Result, error) {
+	key := q.key(query, args)
+	if v, ok := q.cache.Get(key); ok {
+		// Other values stored under the key in a shared cache are misses.
+		if res, ok := v.(*Result); ok {
+			return res, nil
+		}
+	}
+	res, err := scan(ctx, db, query, args)
+	if err != nil {
+		return nil, err
+	}
+	q.cache.Set(key, res, ttl)
+	return res, nil
+}
+
+// Invalidate removes the cached result of query with args.
+func (q *QueryCache) Invalidate(query string, args ...interface{}) {
+	q.cache.Delete(q.key(query, args))
+}
+
+// InvalidateQuery makes every cached result of query stale, whatever the arguments.
+func (q *QueryCache) InvalidateQuery(query string) {
+	q.mu.Lock()
+	q.generations[query]++
+	q.mu.Unlock()
+}
+
+// Key returns the cache key of query with args in the given generation.
+// The query, the names, types and values of the arguments are hashed with
+// their length, so no two argument lists share an encoding. Arguments are
+// converted like database/sql does first: pointers are dereferenced and
+// driver.Valuer values hashed by their Value.
+func Key(generation uint64, query string, args ...interface{}) uint64 {
+	h := fnv.New64a()
+	h.Write(binary.BigEndian.AppendUint64(nil, generation))
+	writeField(h, query)
+	for _, arg := range args {
+		if v, ok := arg.(sql.NamedArg); ok {
+			h.Write([]byte{1})
+			writeField(h, v.Name)
+			arg = v.Value
+		} else {
+			h.Write([]byte{0})
+		}
+		if v, err := driver.DefaultParameterConverter.ConvertValue(arg); err == nil {
+			arg = v
+		}
+		writeField(h, fmt.Sprintf("%T", arg))
+		writeField(h, fmt.Sprintf("%v", arg))
+	}
+	return h.Sum64()
+}
+
+// writeField writes s to h prefixed with its length.
+func writeField(h hash.Hash64, s string) {
+	h.Write(binary.AppendUvarint(nil, uint64(len(s))))
+	io.WriteString(h, s)
+}
+
+func (q *QueryCache) key(query string, args []interface{}) uint64 {
+	q.mu.RLock()
+	gen := q.generations[query]
+	q.mu.RUnlock()
+	return Key(gen, query, args...)
+}
+
+func scan(ctx context.Context, db Querier, query string, args []interface{}) (*Result, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	res := &Result{Columns: columns}
+	for rows.Next() {
+		// Scanning into *interface{} copies driver-owned []byte values.
+		row := make([]interface{}, len(columns))
+		dest := make([]interface{}, len(columns))
+		for i := range row {
+			dest[i] = &row[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		res.Rows = append(res.Rows, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
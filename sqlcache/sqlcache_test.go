@@ -0,0 +1,165 @@
+package sqlcache
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	ttlcache "github.com/loicalleyne/ttlswisscache"
+)
+
+// fakeDriver answers every query with one row holding the query and its first argument.
+type fakeDriver struct{ queries atomic.Int32 }
+
+func (d *fakeDriver) Open(string) (driver.Conn, error) { return fakeConn{d}, nil }
+
+type fakeConn struct{ d *fakeDriver }
+
+func (c fakeConn) Prepare(query string) (driver.Stmt, error) { return fakeStmt{c.d, query}, nil }
+func (fakeConn) Close() error                                { return nil }
+func (fakeConn) Begin() (driver.Tx, error)                   { return nil, errors.New("not supported") }
+
+type fakeStmt struct {
+	d     *fakeDriver
+	query string
+}
+
+func (fakeStmt) Close() error  { return nil }
+func (fakeStmt) NumInput() int { return -1 }
+func (fakeStmt) Exec([]driver.Value) (driver.Result, error) {
+	return nil, errors.New("not supported")
+}
+func (s fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.d.queries.Add(1)
+	return &fakeRows{values: []driver.Value{[]byte(s.query), args[0]}}, nil
+}
+
+type fakeRows struct {
+	values []driver.Value
+	done   bool
+}
+
+func (*fakeRows) Columns() []string { return []string{"query", "arg"} }
+func (*fakeRows) Close() error      { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	copy(dest, r.values)
+	return nil
+}
+
+var drv = &fakeDriver{}
+
+func init() { sql.Register("sqlcache-fake", drv) }
+
+func TestCachedQuery(t *testing.T) {
+	db, err := sql.Open("sqlcache-fake", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	cache := ttlcache.New(time.Hour)
+	defer cache.Close()
+	q := New(cache)
+	ctx := context.Background()
+	const query = "SELECT name FROM users WHERE id = ?"
+
+	run := func(arg int64) *Result {
+		t.Helper()
+		res, err := q.CachedQuery(ctx, db, time.Minute, query, arg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+
+	start := drv.queries.Load()
+	res := run(1)
+	run(1)
+	if n := drv.queries.Load() - start; n != 1 {
+		t.Errorf("incorrect number of queries: got: %d expected: %d", n, 1)
+	}
+	if len(res.Rows) != 1 || string(res.Rows[0][0].([]byte)) != query || res.Rows[0][1] != int64(1) {
+		t.Errorf("incorrect result: got: %v", res.Rows)
+	}
+
+	run(2)
+	if n := drv.queries.Load() - start; n != 2 {
+		t.Errorf("incorrect number of queries: got: %d expected: %d", n, 2)
+	}
+
+	q.Invalidate(query, int64(1))
+	run(1)
+	run(2)
+	if n := drv.queries.Load() - start; n != 3 {
+		t.Errorf("incorrect number of queries after Invalidate: got: %d expected: %d", n, 3)
+	}
+
+	q.InvalidateQuery(query)
+	run(1)
+	run(2)
+	if n := drv.queries.Load() - start; n != 5 {
+		t.Errorf("incorrect number of queries after InvalidateQuery: got: %d expected: %d", n, 5)
+	}
+}
+
+func TestCachedQuery_ForeignValue(t *testing.T) {
+	db, err := sql.Open("sqlcache-fake", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	cache := ttlcache.New(time.Hour)
+	defer cache.Close()
+	q := New(cache)
+	const query = "SELECT 1"
+	cache.Set(Key(0, query, int64(1)), "other", time.Hour)
+
+	res, err := q.CachedQuery(context.Background(), db, time.Minute, query, int64(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Rows) != 1 {
+		t.Errorf("incorrect result: got: %v", res.Rows)
+	}
+}
+
+func TestKey(t *testing.T) {
+	tt := []struct {
+		a, b  []interface{}
+		equal bool
+	}{
+		{[]interface{}{1}, []interface{}{1}, true},
+		{[]interface{}{1}, []interface{}{2}, false},
+		{[]interface{}{1}, []interface{}{"1"}, false},
+		{[]interface{}{sql.Named("id", 1)}, []interface{}{sql.Named("uid", 1)}, false},
+		{[]interface{}{"a\x00int:5"}, []interface{}{"a", 5}, false},
+		{[]interface{}{"a", "b"}, []interface{}{"a\x00string:b"}, false},
+		{[]interface{}{sql.Named("id", 1)}, []interface{}{"\x00id=", 1}, false},
+	}
+	for _, tc := range tt {
+		if got := Key(0, "q", tc.a...) == Key(0, "q", tc.b...); got != tc.equal {
+			t.Errorf("incorrect key equality for %v and %v: got: %v expected: %v", tc.a, tc.b, got, tc.equal)
+		}
+	}
+	one, other := int64(1), int64(1)
+	if Key(0, "q", &one) != Key(0, "q", &other) {
+		t.Error("pointer arguments were hashed by address")
+	}
+	if Key(0, "q", &one) != Key(0, "q", one) {
+		t.Error("pointer argument was not dereferenced")
+	}
+	if Key(0, "q\x00int:1") == Key(0, "q", 1) {
+		t.Error("query and arguments share an encoding")
+	}
+	if Key(0, "q", 1) == Key(1, "q", 1) {
+		t.Error("generations share a key")
+	}
+}
@@ -0,0 +1,143 @@
+package ttlswisscache
+
+import "sync/atomic"
+
+// Stats contains cache counters.
+// Counters are cumulative since the cache was created.
+type Stats struct {
+	Shards    int    // Number of shards, see WithShardCount.
+	Entries   int    // Records in storage, including outdated ones waiting for cleanup.
+	Hits      uint64 // Get calls that found a record.
+	Misses    uint64 // Get calls that didn't find a record.
+	Sets      uint64 // Stored records.
+	Deletes   uint64 // Records removed by the user.
+	Expired   uint64 // Outdated records removed by the cleanup manager.
+	Collected uint64 // Weak records removed once their value was collected, see SetWeak.
+	StaleHits uint64 // GetStale calls that returned an outdated record, also counted as hits.
+	Oversized uint64 // Values over WithMaxValueSize, rejected or truncated.
+	Evicted   uint64 // Records removed by the eviction policy, see WithMaxEntries.
+
+	EarlyExpirations uint64 // Reads missing a record before its deadline, see WithEarlyExpiration.
+	Busy             uint64 // TryGet and TrySet calls that gave up on a locked shard.
+
+	// Writes of SetAsync, see WithWriteBuffer and WithWritePolicy.
+	AsyncQueued  int    // Writes waiting in the buffer.
+	AsyncDropped uint64 // Writes discarded because the buffer was full.
+
+	// Refreshes of WithRefresh.
+	Refreshes      uint64 // Successful refreshes.
+	RefreshErrors  uint64 // Failed refreshes.
+	RefreshDropped uint64 // Refreshes discarded because the queue was full.
+
+	// Calls of OnEvicted, see WithAsyncEvictions.
+	EvictedQueued  int    // Calls waiting for a worker.
+	EvictedDropped uint64 // Calls discarded because the queue was full.
+
+	EventsDropped uint64 // Events lost by subscriptions with a full buffer, see SubscribeWith.
+	Coalesced     uint64 // Sets replaced by a later Set within the window, see WithSetCoalescing.
+	Rebuilds      uint64 // Shard maps rebuilt after churn, see WithRebuild.
+
+	// Shard locks, see WithLockStats.
+	ReadLocks  LockStats
+	WriteLocks LockStats
+
+	// Scratch buffers of cleanup, snapshots and merges are pooled.
+	BufferAllocs uint64 // Buffers allocated because the pool was empty.
+	BufferReuses uint64 // Buffers taken from the pool.
+}
+
+// counters are kept per shard to avoid a single contention point.
+type counters struct {
+	hits      atomic.Uint64
+	misses    atomic.Uint64
+	sets      atomic.Uint64
+	deletes   atomic.Uint64
+	expired   atomic.Uint64
+	collected atomic.Uint64
+	staleHits atomic.Uint64
+	oversized atomic.Uint64
+	evicted   atomic.Uint64
+	early     atomic.Uint64
+	busy      atomic.Uint64
+}
+
+// Stats returns a snapshot of the cache counters.
+func (c *Cache) Stats() Stats {
+	st := Stats{Shards: len(c.shards.list)}
+	for _, s := range c.shards.list {
+		s.RLock()
+		st.Entries += s.count()
+		s.RUnlock()
+		s.stats.addTo(&st)
+		if s.locks != nil {
+			s.locks.read.addTo(&st.ReadLocks)
+			s.locks.write.addTo(&st.WriteLocks)
+		}
+	}
+	if c.writes != nil {
+		st.AsyncQueued = len(c.writes.ch)
+		st.AsyncDropped = c.writes.dropped.Load()
+	}
+	if c.refresher != nil {
+		st.Refreshes = c.refresher.refreshes.Load()
+		st.RefreshErrors = c.refresher.errors.Load()
+		st.RefreshDropped = c.refresher.dropped.Load()
+	}
+	st.EventsDropped = c.subs.dropped.Load()
+	st.Rebuilds = c.rebuilds.Load()
+	if p := c.subs.evictions; p != nil {
+		st.EvictedQueued = len(p.queue)
+		st.EvictedDropped = p.dropped.Load()
+	}
+	if c.sets != nil {
+		st.Coalesced = c.sets.coalesced.Load()
+	}
+	st.BufferAllocs = c.bufs.allocs.Load()
+	st.BufferReuses = c.bufs.reuses.Load()
+	return st
+}
+
+func (c *counters) addTo(st *Stats) {
+	st.Hits += c.hits.Load()
+	st.Misses += c.misses.Load()
+	st.Sets += c.sets.Load()
+	st.Deletes += c.deletes.Load()
+	st.Expired += c.expired.Load()
+	st.Collected += c.collected.Load()
+	st.StaleHits += c.staleHits.Load()
+	st.Oversized += c.oversized.Load()
+	st.Evicted += c.evicted.Load()
+	st.EarlyExpirations += c.early.Load()
+	st.Busy += c.busy.Load()
+}
+
+// add sums the counters of other into st.
+func (st *Stats) add(other Stats) {
+	st.Shards += other.Shards
+	st.Entries += other.Entries
+	st.Hits += other.Hits
+	st.Misses += other.Misses
+	st.Sets += other.Sets
+	st.Deletes += other.Deletes
+	st.Expired += other.Expired
+	st.Collected += other.Collected
+	st.StaleHits += other.StaleHits
+	st.Oversized += other.Oversized
+	st.Evicted += other.Evicted
+	st.EarlyExpirations += other.EarlyExpirations
+	st.Busy += other.Busy
+	st.AsyncQueued += other.AsyncQueued
+	st.AsyncDropped += other.AsyncDropped
+	st.Refreshes += other.Refreshes
+	st.RefreshErrors += other.RefreshErrors
+	st.RefreshDropped += other.RefreshDropped
+	st.EvictedQueued += other.EvictedQueued
+	st.EvictedDropped += other.EvictedDropped
+	st.EventsDropped += other.EventsDropped
+	st.Coalesced += other.Coalesced
+	st.Rebuilds += other.Rebuilds
+	st.ReadLocks.add(other.ReadLocks)
+	st.WriteLocks.add(other.WriteLocks)
+	st.BufferAllocs += other.BufferAllocs
+	st.BufferReuses += other.BufferReuses
+}
@@ -0,0 +1,59 @@
+package ttlswisscache
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCache_Stats(t *testing.T) {
+	c := New(10*time.Millisecond, WithShardCount(16))
+	defer c.Close()
+
+	c.Set(IntKey(1), 1, time.Hour)
+	c.Set(IntKey(2), 2, time.Hour)
+	c.Set(IntKey(3), 3, time.Millisecond)
+	c.Get(IntKey(1))
+	c.Get(IntKey(4))
+	c.Delete(IntKey(2))
+	time.Sleep(50 * time.Millisecond)
+
+	expected := Stats{Shards: 16, Entries: 1, Hits: 1, Misses: 1, Sets: 3, Deletes: 1, Expired: 1}
+	st := c.Stats()
+	st.BufferAllocs, st.BufferReuses = 0, 0 // Depend on the cleaner ticks.
+	if st != expected {
+		t.Errorf("incorrect stats: got: %+v expected: %+v", st, expected)
+	}
+}
+
+// setAll sets every number in v, recursively, to n.
+func setAll(v reflect.Value, n int) {
+	switch v.Kind() {
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			setAll(v.Field(i), n)
+		}
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			setAll(v.Index(i), n)
+		}
+	case reflect.Int, reflect.Int64:
+		v.SetInt(int64(n))
+	case reflect.Uint64:
+		v.SetUint(uint64(n))
+	default:
+		panic("unexpected kind of a Stats field: " + v.Kind().String())
+	}
+}
+
+func TestStats_Add(t *testing.T) {
+	var one, two Stats
+	setAll(reflect.ValueOf(&one).Elem(), 1)
+	setAll(reflect.ValueOf(&two).Elem(), 2)
+
+	sum := one
+	sum.add(one)
+	if sum != two {
+		t.Errorf("fields were left out of the sum: got: %+v expected: %+v", sum, two)
+	}
+}
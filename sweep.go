@@ -0,0 +1,71 @@
+package ttlswisscache
+
+import (
+	"context"
+	"runtime/pprof"
+	"runtime/trace"
+	"time"
+)
+
+// CleanupHooks are functions the cleanup manager of a Cache calls around
+// every sweep, e.g. to export metrics or start tracing spans, see WithCleanupHooks.
+// ctx carries the profiler labels of the sweep. Direct calls of DeleteExpired
+// are not reported.
+type CleanupHooks struct {
+	OnSweepStart func(ctx context.Context)
+	OnSweepEnd   func(ctx context.Context, stats SweepStats)
+}
+
+// SweepStats describes a sweep of the cleanup manager.
+type SweepStats struct {
+	Scanned  int // Records scanned, outdated or not.
+	Removed  int // Outdated records removed.
+	Duration time.Duration
+}
+
+// WithCleanupHooks sets functions the cleanup manager of a Cache calls around sweeps.
+//
+// The cleanup goroutine runs with the pprof labels "cache", the name set
+// by WithName, those of WithLabels, and "phase": "sweep" while removing
+// outdated records, "namespaces" while removing the records of invalidated
+// namespace generations, "compact" within WithAutoCompact and "clock" otherwise,
+// so hooks can read them from ctx with pprof.Label to label their metrics.
+// Sweeps are also runtime/trace regions.
+func WithCleanupHooks(hooks CleanupHooks) Option {
+	return func(o *options) {
+		o.cleanupHooks = hooks
+	}
+}
+
+// withPhase runs f with the profiler labels and the trace region of phase.
+func withPhase(ctx context.Context, phase string, f func(ctx context.Context)) {
+	pprof.Do(ctx, pprof.Labels("phase", phase), func(ctx context.Context) {
+		trace.WithRegion(ctx, "ttlcache."+phase, func() { f(ctx) })
+	})
+}
+
+// sweep is a cleanup of the cleanup manager, reported to the cleanup hooks.
+func (c *Cache) sweep(ctx context.Context) int {
+	hooks := c.opts.cleanupHooks
+	if hooks.OnSweepStart != nil {
+		hooks.OnSweepStart(ctx)
+	}
+	start := time.Now()
+	removed, scanned := c.deleteExpired(c.clock.unixNano() - int64(c.opts.grace))
+	if c.namespaces.stale.Load() {
+		withPhase(ctx, "namespaces", func(context.Context) { c.deleteStale() })
+	}
+	if c.opts.autoCompact {
+		withPhase(ctx, "compact", func(context.Context) { c.Compact() })
+	}
+	if c.opts.rebuildChurn > 0 {
+		withPhase(ctx, "rebuild", func(context.Context) { c.rebuildNext() })
+	}
+	if c.opts.verify != nil {
+		c.verifyPhase(ctx)
+	}
+	if hooks.OnSweepEnd != nil {
+		hooks.OnSweepEnd(ctx, SweepStats{Scanned: scanned, Removed: removed, Duration: time.Since(start)})
+	}
+	return removed
+}
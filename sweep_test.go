@@ -0,0 +1,51 @@
+package ttlswisscache
+
+import (
+	"context"
+	"runtime/pprof"
+	"testing"
+	"time"
+)
+
+func TestWithCleanupHooks(t *testing.T) {
+	started := make(chan string, 100)
+	ended := make(chan SweepStats, 100)
+	hooks := CleanupHooks{
+		OnSweepStart: func(ctx context.Context) {
+			name, _ := pprof.Label(ctx, "cache")
+			phase, _ := pprof.Label(ctx, "phase")
+			started <- name + "/" + phase
+		},
+		OnSweepEnd: func(_ context.Context, stats SweepStats) { ended <- stats },
+	}
+	c := New(10*time.Millisecond, WithName("sessions"), WithCleanupHooks(hooks))
+	defer c.Close()
+	c.Set(IntKey(1), 1, time.Millisecond)
+	c.Set(IntKey(2), 2, time.Millisecond)
+	c.Set(IntKey(3), 3, time.Hour)
+
+	if labels := <-started; labels != "sessions/sweep" {
+		t.Errorf("incorrect profiler labels: got: %v expected: %v", labels, "sessions/sweep")
+	}
+	removed := 0
+	deadline := time.After(time.Second)
+	for removed < 2 {
+		select {
+		case stats := <-ended:
+			removed += stats.Removed
+			if stats.Scanned < 1 || stats.Scanned > 3 {
+				t.Errorf("incorrect number of scanned records: got: %d expected: 1 to 3", stats.Scanned)
+			}
+		case <-deadline:
+			t.Fatalf("incorrect number of removed records: got: %d expected: %d", removed, 2)
+		}
+	}
+}
+
+func TestManager_Name(t *testing.T) {
+	m := NewManager(time.Hour)
+	defer m.Close()
+	if name := m.Cache("users").opts.name; name != "users" {
+		t.Errorf("incorrect cache name: got: %v expected: %v", name, "users")
+	}
+}
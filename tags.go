@@ -0,0 +1,107 @@
+package ttlswisscache
+
+import "time"
+
+// SetWithTags adds value to the cache with given ttl like Set and attaches tags
+// to the record, so InvalidateTag removes it. Tags belong to the record: setting
+// the key again without them, deleting it or its expiration drops them.
+// Snapshots and CDC streams don't carry tags.
+func (c *Cache) SetWithTags(key uint64, value interface{}, ttl time.Duration, tags ...string) {
+	c.setWithTags(key, value, ttl, tags)
+}
+
+// setWithTags is SetWithTags reporting whether the record was stored.
+func (c *Cache) setWithTags(key uint64, value interface{}, ttl time.Duration, tags []string) bool {
+	value, ok := c.limit(key, value)
+	if !ok {
+		c.reject(key)
+		return false
+	}
+	it := item{deadline: c.clock.unixNano() + int64(ttl), value: c.clone(value)}
+	s := c.shards.get(key)
+	s.Lock()
+	if c.closing.Load() || c.protected(s, key) {
+		s.Unlock()
+		return false
+	}
+	s.put(key, it)
+	if len(tags) > 0 {
+		s.tag(key, tags)
+	}
+	c.subs.publish(OpSet, key, it)
+	s.Unlock()
+	s.stats.sets.Add(1)
+	return true
+}
+
+// InvalidateTag removes all records carrying tag and returns their number.
+// Removals are reported like Delete. Every shard is locked in turn, the work
+// otherwise is proportional to the number of tagged records.
+func (c *Cache) InvalidateTag(tag string) int {
+	n := 0
+	var keys []uint64
+	for _, s := range c.shards.list {
+		s.Lock()
+		keys = keys[:0]
+		for key := range s.tagged[tag] {
+			keys = append(keys, key)
+		}
+		for _, key := range keys {
+			if it, ok := s.delete(key); ok {
+				c.subs.publish(OpDelete, key, it)
+			}
+		}
+		s.Unlock()
+		s.stats.deletes.Add(uint64(len(keys)))
+		n += len(keys)
+	}
+	c.cascade()
+	return n
+}
+
+// Tagged returns the keys of the records carrying tag, in no particular order.
+// Every shard is read locked in turn.
+func (c *Cache) Tagged(tag string) []uint64 {
+	var keys []uint64
+	for _, s := range c.shards.list {
+		s.RLock()
+		for key := range s.tagged[tag] {
+			keys = append(keys, key)
+		}
+		s.RUnlock()
+	}
+	return keys
+}
+
+// tag attaches tags to the record of the key. The shard must be locked.
+func (s *shard) tag(key uint64, tags []string) {
+	if s.tags == nil {
+		s.tags = make(map[uint64][]string)
+		s.tagged = make(map[string]map[uint64]struct{})
+	}
+	s.tags[key] = append([]string(nil), tags...)
+	for _, tag := range tags {
+		keys, ok := s.tagged[tag]
+		if !ok {
+			keys = make(map[uint64]struct{})
+			s.tagged[tag] = keys
+		}
+		keys[key] = struct{}{}
+	}
+}
+
+// untag drops the tags of the record of the key. The shard must be locked.
+func (s *shard) untag(key uint64) {
+	tags, ok := s.tags[key]
+	if !ok {
+		return
+	}
+	delete(s.tags, key)
+	for _, tag := range tags {
+		keys := s.tagged[tag]
+		delete(keys, key)
+		if len(keys) == 0 {
+			delete(s.tagged, tag)
+		}
+	}
+}
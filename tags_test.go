@@ -0,0 +1,92 @@
+package ttlswisscache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCache_InvalidateTag(t *testing.T) {
+	c := New(0)
+	defer c.Close()
+
+	for i := 0; i < 100; i++ {
+		tags := []string{"users"}
+		if i%2 == 0 {
+			tags = append(tags, "even")
+		}
+		c.SetWithTags(IntKey(i), i, time.Hour, tags...)
+	}
+	c.Set(IntKey(0), 0, time.Hour) // Drops the tags of key 0.
+	c.Delete(IntKey(2))
+	c.Set(IntKey(100), 100, time.Hour)
+
+	if n := c.InvalidateTag("even"); n != 48 {
+		t.Errorf("incorrect number of invalidated records: got: %d expected: %d", n, 48)
+	}
+	if _, ok := c.Get(IntKey(0)); !ok {
+		t.Error("record set again without tags was invalidated")
+	}
+	if _, ok := c.Get(IntKey(4)); ok {
+		t.Error("tagged record was not invalidated")
+	}
+	if n := c.InvalidateTag("even"); n != 0 {
+		t.Errorf("incorrect number of records invalidated again: got: %d expected: %d", n, 0)
+	}
+
+	d := c.Clone()
+	defer d.Close()
+	if n := c.InvalidateTag("users"); n != 50 {
+		t.Errorf("incorrect number of invalidated records: got: %d expected: %d", n, 50)
+	}
+	if n := d.InvalidateTag("users"); n != 50 {
+		t.Errorf("incorrect number of invalidated records in the clone: got: %d expected: %d", n, 50)
+	}
+	if st := c.Stats(); st.Entries != 2 {
+		t.Errorf("incorrect number of records: got: %d expected: %d", st.Entries, 2)
+	}
+	for _, s := range c.shards.list {
+		if len(s.tags) != 0 || len(s.tagged) != 0 {
+			t.Errorf("tag index was not emptied: got: %d keys, %d tags", len(s.tags), len(s.tagged))
+		}
+	}
+}
+
+func TestCache_InvalidateTag_Expired(t *testing.T) {
+	c := New(0)
+	defer c.Close()
+	c.SetWithTags(IntKey(1), 1, -time.Hour, "t")
+	c.DeleteExpired()
+	for _, s := range c.shards.list {
+		if len(s.tags) != 0 {
+			t.Error("tags of an expired record were kept")
+		}
+	}
+}
+
+func TestCache_SetWithTags_Expire(t *testing.T) {
+	c := New(0)
+	defer c.Close()
+	c.SetWithTags(IntKey(1), 1, time.Minute, "a")
+	c.Expire(IntKey(1), time.Hour)
+
+	if n := c.InvalidateTag("a"); n != 1 {
+		t.Errorf("incorrect number of invalidated records: got: %d expected: %d", n, 1)
+	}
+}
+
+func TestCache_Tagged(t *testing.T) {
+	c := New(0)
+	defer c.Close()
+	c.SetWithTags(IntKey(1), 1, time.Hour, "a", "b")
+	c.SetWithTags(IntKey(2), 2, time.Hour, "a")
+
+	if keys := c.Tagged("a"); len(keys) != 2 {
+		t.Errorf("incorrect tagged keys: got: %v expected: 2 keys", keys)
+	}
+	if keys := c.Tagged("b"); len(keys) != 1 || keys[0] != IntKey(1) {
+		t.Errorf("incorrect tagged keys: got: %v expected: [%d]", keys, IntKey(1))
+	}
+	if keys := c.Tagged("c"); len(keys) != 0 {
+		t.Errorf("incorrect tagged keys: got: %v expected: []", keys)
+	}
+}
@@ -0,0 +1,94 @@
+package redisbackend
+
+import (
+	"bytes"
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	ttlcache "github.com/loicalleyne/ttlswisscache"
+	"github.com/loicalleyne/ttlswisscache/internal/resp"
+)
+
+// maxReconnectDelay bounds the delay between attempts of Listen to resubscribe.
+const maxReconnectDelay = 5 * time.Second
+
+// Listen subscribes to the Redis keyspace notifications of the keys of the
+// backend and deletes them from l1, usually tiered.Cache.L1, whenever they are
+// set, deleted, expired or evicted in Redis. It keeps the local tier coherent
+// with the shared one until ctx is done and returns the context error.
+//
+// Redis only publishes the notifications when enabled, e.g. with
+// "CONFIG SET notify-keyspace-events Kgx$e". Writes of the process itself are
+// notified too, so they also drop the value just written to l1.
+// Notifications sent while disconnected are lost, so l1 is cleared every
+// time Listen subscribes: l1 must be dedicated to the backend.
+// db is the number of the Redis database of the backend, 0 by default.
+func (b *Backend) Listen(ctx context.Context, l1 *ttlcache.Cache, db int) error {
+	delay := 100 * time.Millisecond
+	for {
+		// Failed connections are retried until ctx is done.
+		if b.listen(ctx, l1, db) {
+			delay = 100 * time.Millisecond
+		}
+		t := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		case <-t.C:
+		}
+		delay = min(2*delay, maxReconnectDelay)
+	}
+}
+
+// listen handles the notifications of a connection until it fails and reports
+// whether it subscribed.
+func (b *Backend) listen(ctx context.Context, l1 *ttlcache.Cache, db int) bool {
+	conn, err := resp.Dial(ctx, b.client.Addr())
+	if err != nil {
+		return false
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	channel := "__keyspace@" + strconv.Itoa(db) + "__:"
+	pattern := channel + escapeGlob(b.prefix) + "*"
+	conn.WriteCommand([]byte("PSUBSCRIBE"), []byte(pattern))
+	if err := conn.Flush(); err != nil {
+		return false
+	}
+	if v, err := conn.ReadValue(); err != nil || v.Err() != nil {
+		return false
+	}
+	l1.Clear()
+
+	prefix := []byte(channel + b.prefix)
+	for {
+		v, err := conn.ReadValue()
+		if err != nil {
+			return true
+		}
+		// Messages are "pmessage", pattern, channel and event.
+		if len(v.Array) != 4 || string(v.Array[0].Bulk) != "pmessage" {
+			continue
+		}
+		if key, ok := bytes.CutPrefix(v.Array[2].Bulk, prefix); ok {
+			l1.Delete(ttlcache.StringKey(string(key)))
+		}
+	}
+}
+
+// escapeGlob escapes the glob-style pattern characters of s.
+func escapeGlob(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune(`*?[]\`, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
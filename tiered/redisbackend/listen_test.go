@@ -0,0 +1,124 @@
+package redisbackend
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	ttlcache "github.com/loicalleyne/ttlswisscache"
+	"github.com/loicalleyne/ttlswisscache/internal/resp"
+)
+
+// subscription is a connection subscribed to pattern.
+type subscription struct {
+	net.Conn
+	*resp.Writer
+	pattern string
+}
+
+// pubsubServer accepts subscriptions, the tests publish the notifications.
+func pubsubServer(t *testing.T) (string, <-chan subscription) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	subs := make(chan subscription, 4)
+	go func() {
+		for {
+			nc, err := l.Accept()
+			if err != nil {
+				return
+			}
+			args, err := resp.NewReader(nc).ReadCommand()
+			if err != nil || len(args) != 2 {
+				nc.Close()
+				continue
+			}
+			w := resp.NewWriter(nc)
+			w.WriteArray(3)
+			w.WriteBulk([]byte("psubscribe"))
+			w.WriteBulk(args[1])
+			w.WriteInt(1)
+			w.Flush()
+			subs <- subscription{Conn: nc, Writer: w, pattern: string(args[1])}
+		}
+	}()
+	return l.Addr().String(), subs
+}
+
+func (s subscription) notify(channel, event string) {
+	s.WriteArray(4)
+	s.WriteBulk([]byte("pmessage"))
+	s.WriteBulk([]byte(s.pattern))
+	s.WriteBulk([]byte(channel))
+	s.WriteBulk([]byte(event))
+	s.Flush()
+}
+
+func waitMissing(t *testing.T, l1 *ttlcache.Cache, key string) {
+	t.Helper()
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		if _, ok := l1.Get(ttlcache.StringKey(key)); !ok {
+			return
+		}
+	}
+	t.Errorf("key %q was not invalidated", key)
+}
+
+func TestBackend_Listen(t *testing.T) {
+	addr, subs := pubsubServer(t)
+	b := New(addr, "test:")
+	defer b.Close()
+	l1 := ttlcache.New(time.Hour)
+	defer l1.Close()
+	l1.Set(ttlcache.StringKey("old"), 1, time.Hour)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- b.Listen(ctx, l1, 2) }()
+
+	sub := <-subs
+	defer sub.Close()
+	if sub.pattern != "__keyspace@2__:test:*" {
+		t.Errorf("incorrect pattern: got: %v expected: %v", sub.pattern, "__keyspace@2__:test:*")
+	}
+	waitMissing(t, l1, "old") // Cleared once subscribed.
+
+	for _, key := range []string{"a", "b", "c"} {
+		l1.Set(ttlcache.StringKey(key), key, time.Hour)
+	}
+	sub.notify("__keyspace@2__:test:a", "set")
+	sub.notify("__keyspace@2__:test:b", "expired")
+	waitMissing(t, l1, "a")
+	waitMissing(t, l1, "b")
+	if _, ok := l1.Get(ttlcache.StringKey("c")); !ok {
+		t.Error("key without notification was invalidated")
+	}
+
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("incorrect error: got: %v expected: %v", err, context.Canceled)
+	}
+}
+
+func TestBackend_ListenReconnect(t *testing.T) {
+	addr, subs := pubsubServer(t)
+	b := New(addr, "")
+	defer b.Close()
+	l1 := ttlcache.New(time.Hour)
+	defer l1.Close()
+	l1.Set(ttlcache.StringKey("old"), 1, time.Hour)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go b.Listen(ctx, l1, 0)
+
+	sub := <-subs
+	waitMissing(t, l1, "old")
+	l1.Set(ttlcache.StringKey("k"), 1, time.Hour)
+	sub.Close() // Notifications may be lost until the next subscription.
+	sub = <-subs
+	defer sub.Close()
+	waitMissing(t, l1, "k")
+}
@@ -0,0 +1,63 @@
+// Package redisbackend implements tiered.Backend on top of Redis.
+// It speaks RESP directly and has no dependencies outside this module.
+package redisbackend
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/loicalleyne/ttlswisscache/internal/resp"
+)
+
+const defaultMaxIdle = 16
+
+// Backend stores values in Redis.
+type Backend struct {
+	client *resp.Client
+	prefix string
+}
+
+// New creates a backend for the Redis server address.
+// prefix is prepended to every key, use it to share a Redis database.
+func New(addr, prefix string) *Backend {
+	return &Backend{client: resp.NewClient(addr, defaultMaxIdle), prefix: prefix}
+}
+
+// Get implements tiered.Backend.
+func (b *Backend) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	v, err := b.client.Do(ctx, []byte("GET"), b.key(key))
+	if err != nil {
+		return nil, false, err
+	}
+	if v.Null {
+		return nil, false, nil
+	}
+	return v.Bulk, true, nil
+}
+
+// Set implements tiered.Backend.
+// A ttl below one millisecond stores the value without expiration.
+func (b *Backend) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	args := [][]byte{[]byte("SET"), b.key(key), value}
+	if ms := ttl.Milliseconds(); ms > 0 {
+		args = append(args, []byte("PX"), strconv.AppendInt(nil, ms, 10))
+	}
+	_, err := b.client.Do(ctx, args...)
+	return err
+}
+
+// Delete implements tiered.Backend.
+func (b *Backend) Delete(ctx context.Context, key string) error {
+	_, err := b.client.Do(ctx, []byte("DEL"), b.key(key))
+	return err
+}
+
+// Close closes idle connections.
+func (b *Backend) Close() error {
+	return b.client.Close()
+}
+
+func (b *Backend) key(key string) []byte {
+	return []byte(b.prefix + key)
+}
@@ -0,0 +1,62 @@
+package redisbackend
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	ttlcache "github.com/loicalleyne/ttlswisscache"
+	"github.com/loicalleyne/ttlswisscache/respserver"
+)
+
+// The tests run against respserver instead of a real Redis.
+func startServer(t *testing.T) *Backend {
+	t.Helper()
+	cache := ttlcache.New(time.Hour)
+	srv := respserver.New(cache, time.Hour)
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go srv.Serve(l)
+
+	b := New(l.Addr().String(), "test:")
+	t.Cleanup(func() {
+		b.Close()
+		srv.Close()
+		cache.Close()
+	})
+	return b
+}
+
+func TestBackend(t *testing.T) {
+	ctx := context.Background()
+	b := startServer(t)
+
+	if _, ok, err := b.Get(ctx, "key"); err != nil || ok {
+		t.Errorf("unexpected value for missing key: %v %v", ok, err)
+	}
+	if err := b.Set(ctx, "key", []byte("value"), time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	v, ok, err := b.Get(ctx, "key")
+	if err != nil || !ok || string(v) != "value" {
+		t.Errorf("incorrect value: got: %s %v %v expected: %s", v, ok, err, "value")
+	}
+	if err := b.Delete(ctx, "key"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, _ := b.Get(ctx, "key"); ok {
+		t.Error("record was not deleted")
+	}
+}
+
+func TestBackend_ContextDeadline(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	b := startServer(t)
+	if _, _, err := b.Get(ctx, "key"); err == nil {
+		t.Error("expected error for cancelled context")
+	}
+}
@@ -0,0 +1,59 @@
+package tiered
+
+import (
+	"context"
+	"sync"
+)
+
+// Session gives a unit of work, e.g. a request, read-your-writes consistency:
+// keys it has written are read from L2, bypassing L1, so it never reads back
+// a value older than its own write. L1 may hold one when a concurrent Get
+// copies a value read from L2 before the write, or when L1 of another process
+// serves the next request of the session.
+// A Session is safe for concurrent use and meant to be short-lived.
+type Session struct {
+	cache *Cache
+
+	mu      sync.Mutex
+	written map[string]struct{}
+}
+
+// Session returns a new session on the cache.
+func (c *Cache) Session() *Session {
+	return &Session{cache: c, written: make(map[string]struct{})}
+}
+
+// Get returns the value like Cache.Get, from L2 if the session wrote the key.
+func (s *Session) Get(ctx context.Context, key string) (interface{}, bool, error) {
+	if !s.wrote(key) {
+		return s.cache.Get(ctx, key)
+	}
+	return s.cache.load(ctx, key)
+}
+
+// Set writes the value like Cache.Set and tracks the key.
+func (s *Session) Set(ctx context.Context, key string, value interface{}) error {
+	s.track(key)
+	return s.cache.Set(ctx, key, value)
+}
+
+// Delete removes the key like Cache.Delete and tracks it.
+func (s *Session) Delete(ctx context.Context, key string) error {
+	s.track(key)
+	return s.cache.Delete(ctx, key)
+}
+
+// track tracks key before it is written, so reads racing with the write
+// bypass L1 too.
+func (s *Session) track(key string) {
+	s.mu.Lock()
+	s.written[key] = struct{}{}
+	s.mu.Unlock()
+}
+
+func (s *Session) wrote(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.written[key]
+	return ok
+}
@@ -0,0 +1,45 @@
+package tiered
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	ttlcache "github.com/loicalleyne/ttlswisscache"
+)
+
+func TestSession(t *testing.T) {
+	ctx := context.Background()
+	l1 := ttlcache.New(time.Hour)
+	defer l1.Close()
+	c := New(l1, newMemBackend(), ttlcache.GobCodec{}, time.Minute, time.Hour)
+	s := c.Session()
+
+	if err := s.Set(ctx, "user", "new"); err != nil {
+		t.Fatal(err)
+	}
+	// A concurrent Get copied the value it read before the write.
+	l1.Set(ttlcache.StringKey("user"), "old", time.Minute)
+	if v, _, _ := c.Get(ctx, "user"); v != "old" {
+		t.Errorf("incorrect value outside the session: got: %v expected: %v", v, "old")
+	}
+	if v, ok, err := s.Get(ctx, "user"); err != nil || !ok || v != "new" {
+		t.Errorf("incorrect value in the session: got: %v, %v, %v expected: %v", v, ok, err, "new")
+	}
+	if v, _ := l1.Get(ttlcache.StringKey("user")); v != "new" {
+		t.Errorf("incorrect L1 value after a session read: got: %v expected: %v", v, "new")
+	}
+
+	if err := s.Delete(ctx, "user"); err != nil {
+		t.Fatal(err)
+	}
+	l1.Set(ttlcache.StringKey("user"), "old", time.Minute)
+	if _, ok, _ := s.Get(ctx, "user"); ok {
+		t.Error("deleted key was read in the session")
+	}
+
+	l1.Set(ttlcache.StringKey("other"), "l1", time.Minute)
+	if v, _, _ := s.Get(ctx, "other"); v != "l1" {
+		t.Errorf("incorrect value of a key not written: got: %v expected: %v", v, "l1")
+	}
+}
@@ -0,0 +1,96 @@
+// Package tiered combines a local Cache (L1) with a shared second tier (L2), e.g. Redis.
+//
+// Get consults L1 first and falls back to L2 on miss, copying the value into L1.
+// Set and Delete write through to both tiers. Each tier has its own ttl, typically
+// a short one for L1 so processes pick up changes made by others.
+package tiered
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	ttlcache "github.com/loicalleyne/ttlswisscache"
+)
+
+// Backend is the second tier.
+type Backend interface {
+	// Get returns the stored value and an existence flag.
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set stores the value with given ttl.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Delete removes the key.
+	Delete(ctx context.Context, key string) error
+}
+
+// Cache is a two-tier cache.
+// L1 holds decoded values, L2 holds values encoded with the codec.
+type Cache struct {
+	l1    *ttlcache.Cache
+	l2    Backend
+	codec ttlcache.Codec
+	l1TTL time.Duration
+	l2TTL time.Duration
+}
+
+// New creates a two-tier cache with independent ttls per tier.
+func New(l1 *ttlcache.Cache, l2 Backend, codec ttlcache.Codec, l1TTL, l2TTL time.Duration) *Cache {
+	return &Cache{l1: l1, l2: l2, codec: codec, l1TTL: l1TTL, l2TTL: l2TTL}
+}
+
+// Get returns the value from L1 or, on miss, from L2.
+// The second returned variable is an existence flag.
+func (c *Cache) Get(ctx context.Context, key string) (interface{}, bool, error) {
+	k := ttlcache.StringKey(key)
+	if v, ok := c.l1.Get(k); ok {
+		return v, true, nil
+	}
+	return c.load(ctx, key)
+}
+
+// load returns the value from L2, copying it into L1.
+func (c *Cache) load(ctx context.Context, key string) (interface{}, bool, error) {
+	data, ok, err := c.l2.Get(ctx, key)
+	if err != nil || !ok {
+		return nil, false, err
+	}
+	v, err := c.codec.Unmarshal(data)
+	if err != nil {
+		return nil, false, fmt.Errorf("tiered: decode %q: %w", key, err)
+	}
+	c.l1.Set(ttlcache.StringKey(key), v, c.l1TTL)
+	return v, true, nil
+}
+
+// Set writes the value to L2 and then to L1. L1 gets the value decoded from
+// its encoding, like values loaded from L2, so Get returns the same types
+// whichever tier serves it. L1 is not changed when the L2 write fails.
+func (c *Cache) Set(ctx context.Context, key string, value interface{}) error {
+	data, err := c.codec.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("tiered: encode %q: %w", key, err)
+	}
+	if err := c.l2.Set(ctx, key, data, c.l2TTL); err != nil {
+		return err
+	}
+	k := ttlcache.StringKey(key)
+	decoded, err := c.codec.Unmarshal(data)
+	if err != nil {
+		c.l1.Delete(k)
+		return fmt.Errorf("tiered: decode %q: %w", key, err)
+	}
+	c.l1.Set(k, decoded, c.l1TTL)
+	return nil
+}
+
+// Delete removes the key from both tiers.
+// L1 is cleared even when the L2 delete fails, so the process doesn't keep serving the value.
+func (c *Cache) Delete(ctx context.Context, key string) error {
+	c.l1.Delete(ttlcache.StringKey(key))
+	return c.l2.Delete(ctx, key)
+}
+
+// L1 returns the local tier.
+func (c *Cache) L1() *ttlcache.Cache {
+	return c.l1
+}
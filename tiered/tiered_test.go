@@ -0,0 +1,115 @@
+package tiered
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	ttlcache "github.com/loicalleyne/ttlswisscache"
+)
+
+type memBackend struct {
+	mu   sync.Mutex
+	data map[string][]byte
+	ttls map[string]time.Duration
+	err  error
+}
+
+func newMemBackend() *memBackend {
+	return &memBackend{data: make(map[string][]byte), ttls: make(map[string]time.Duration)}
+}
+
+func (b *memBackend) Get(_ context.Context, key string) ([]byte, bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	v, ok := b.data[key]
+	return v, ok, b.err
+}
+
+func (b *memBackend) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.err != nil {
+		return b.err
+	}
+	b.data[key] = value
+	b.ttls[key] = ttl
+	return nil
+}
+
+func (b *memBackend) Delete(_ context.Context, key string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.data, key)
+	return b.err
+}
+
+func TestCache(t *testing.T) {
+	ctx := context.Background()
+	l1 := ttlcache.New(time.Hour)
+	defer l1.Close()
+	l2 := newMemBackend()
+	c := New(l1, l2, ttlcache.GobCodec{}, time.Minute, time.Hour)
+
+	if err := c.Set(ctx, "key", "value"); err != nil {
+		t.Fatal(err)
+	}
+	if l2.ttls["key"] != time.Hour {
+		t.Errorf("incorrect L2 ttl: got: %v expected: %v", l2.ttls["key"], time.Hour)
+	}
+	if ttl, _ := l1.TTL(ttlcache.StringKey("key")); ttl > time.Minute {
+		t.Errorf("incorrect L1 ttl: got: %v expected: %v", ttl, time.Minute)
+	}
+
+	// Another process would only have the value in L2.
+	l1.Clear()
+	v, ok, err := c.Get(ctx, "key")
+	if err != nil || !ok || v != "value" {
+		t.Errorf("incorrect value: got: %v %v %v expected: %v", v, ok, err, "value")
+	}
+	if v, _ := l1.Get(ttlcache.StringKey("key")); v != "value" {
+		t.Errorf("L1 was not filled on L2 hit: got: %v", v)
+	}
+
+	if err := c.Delete(ctx, "key"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, _ := c.Get(ctx, "key"); ok {
+		t.Error("record was not deleted")
+	}
+}
+
+func TestCache_DecodedTypes(t *testing.T) {
+	ctx := context.Background()
+	l1 := ttlcache.New(time.Hour)
+	defer l1.Close()
+	c := New(l1, newMemBackend(), ttlcache.JSONCodec{}, time.Minute, time.Hour)
+
+	if err := c.Set(ctx, "key", 1); err != nil {
+		t.Fatal(err)
+	}
+	fromL1, _, _ := c.Get(ctx, "key")
+	l1.Clear()
+	fromL2, _, _ := c.Get(ctx, "key")
+	if fromL1 != fromL2 {
+		t.Errorf("tiers returned different values: got: %T(%v) and %T(%v)", fromL1, fromL1, fromL2, fromL2)
+	}
+}
+
+func TestCache_BackendError(t *testing.T) {
+	ctx := context.Background()
+	l1 := ttlcache.New(time.Hour)
+	defer l1.Close()
+	l2 := newMemBackend()
+	l2.err = errors.New("unavailable")
+	c := New(l1, l2, ttlcache.GobCodec{}, time.Minute, time.Hour)
+
+	if err := c.Set(ctx, "key", "value"); err == nil {
+		t.Error("expected error")
+	}
+	if _, ok := l1.Get(ttlcache.StringKey("key")); ok {
+		t.Error("L1 was written on L2 failure")
+	}
+}
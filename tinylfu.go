@@ -0,0 +1,232 @@
+package ttlswisscache
+
+import (
+	"math/bits"
+	"sync"
+)
+
+const (
+	sketchDepth      = 4  // Counters per key.
+	sketchMax        = 15 // Saturation of a counter.
+	sketchSamples    = 10 // Additions per record between two halvings.
+	windowPercent    = 1  // Share of the window in the records.
+	protectedPercent = 80 // Share of the protected segment in the main one.
+)
+
+// Segments of W-TinyLFU.
+const (
+	windowSegment = iota
+	probationSegment
+	protectedSegment
+)
+
+// tinyLFU implements W-TinyLFU over the slots of a shard. Each segment is an
+// LRU list linked through the slots, most recent first.
+type tinyLFU struct {
+	mu        sync.Mutex // Guards access, the other methods run under the shard write lock.
+	nodes     []lfuNode  // By slot.
+	segments  [3]lfuList
+	windowCap uint32
+	protCap   uint32
+	sketch    countMinSketch
+}
+
+type lfuNode struct {
+	prev, next uint32
+	segment    uint8
+}
+
+type lfuList struct {
+	head, tail uint32
+	len        uint32
+}
+
+func newTinyLFU(maxCount uint32) *tinyLFU {
+	e := &tinyLFU{
+		windowCap: max(maxCount*windowPercent/100, 1),
+		sketch:    newCountMinSketch(maxCount),
+	}
+	e.protCap = (maxCount - min(e.windowCap, maxCount)) * protectedPercent / 100
+	e.Reset(0)
+	return e
+}
+
+func (e *tinyLFU) OnAdd(key uint64, i uint32) {
+	e.sketch.increment(key)
+	for uint32(len(e.nodes)) <= i {
+		e.nodes = append(e.nodes, lfuNode{})
+	}
+	e.pushFront(windowSegment, i)
+	e.overflow()
+}
+
+// overflow moves the oldest records of the window beyond its capacity to probation.
+func (e *tinyLFU) overflow() {
+	for w := &e.segments[windowSegment]; w.len > e.windowCap; {
+		i := w.tail
+		e.unlink(i)
+		e.pushFront(probationSegment, i)
+	}
+}
+
+func (e *tinyLFU) OnAccess(key uint64, i uint32) {
+	e.mu.Lock()
+	e.sketch.increment(key)
+	if i == NoSlot {
+		e.mu.Unlock()
+		return
+	}
+	switch e.nodes[i].segment {
+	case windowSegment:
+		e.unlink(i)
+		e.pushFront(windowSegment, i)
+	case probationSegment:
+		e.unlink(i)
+		e.pushFront(protectedSegment, i)
+		if p := &e.segments[protectedSegment]; p.len > e.protCap {
+			demoted := p.tail
+			e.unlink(demoted)
+			e.pushFront(probationSegment, demoted)
+		}
+	case protectedSegment:
+		e.unlink(i)
+		e.pushFront(protectedSegment, i)
+	}
+	e.mu.Unlock()
+}
+
+func (e *tinyLFU) OnRemove(_ uint64, i, last uint32) {
+	e.unlink(i)
+	if i == last {
+		return
+	}
+	// Relink the node of the last slot at i.
+	n := e.nodes[last]
+	e.nodes[i] = n
+	l := &e.segments[n.segment]
+	if n.prev == NoSlot {
+		l.head = i
+	} else {
+		e.nodes[n.prev].next = i
+	}
+	if n.next == NoSlot {
+		l.tail = i
+	} else {
+		e.nodes[n.next].prev = i
+	}
+}
+
+// victim makes the oldest record of the window, which the new record pushes
+// to probation, compete with the oldest record of the main segments and
+// returns the less frequent one.
+func (e *tinyLFU) Victim(keys []uint64, _ []int64) uint32 {
+	candidate := e.segments[windowSegment].tail
+	victim := e.segments[probationSegment].tail
+	if victim == NoSlot {
+		victim = e.segments[protectedSegment].tail
+	}
+	switch {
+	case victim == NoSlot:
+		return candidate
+	case candidate == NoSlot:
+		return victim
+	case e.sketch.estimate(keys[candidate]) > e.sketch.estimate(keys[victim]):
+		return victim
+	}
+	return candidate
+}
+
+func (e *tinyLFU) Reset(n uint32) {
+	for s := range e.segments {
+		e.segments[s] = lfuList{head: NoSlot, tail: NoSlot}
+	}
+	e.nodes = e.nodes[:0]
+	for i := uint32(0); i < n; i++ {
+		e.nodes = append(e.nodes, lfuNode{})
+		e.pushFront(windowSegment, i)
+	}
+	e.overflow()
+	e.sketch.clear()
+}
+
+func (e *tinyLFU) pushFront(segment uint8, i uint32) {
+	l := &e.segments[segment]
+	e.nodes[i] = lfuNode{prev: NoSlot, next: l.head, segment: segment}
+	if l.head == NoSlot {
+		l.tail = i
+	} else {
+		e.nodes[l.head].prev = i
+	}
+	l.head = i
+	l.len++
+}
+
+func (e *tinyLFU) unlink(i uint32) {
+	n := e.nodes[i]
+	l := &e.segments[n.segment]
+	if n.prev == NoSlot {
+		l.head = n.next
+	} else {
+		e.nodes[n.prev].next = n.next
+	}
+	if n.next == NoSlot {
+		l.tail = n.prev
+	} else {
+		e.nodes[n.next].prev = n.prev
+	}
+	l.len--
+}
+
+// countMinSketch estimates the frequency of keys with sketchDepth rows of
+// saturating counters. Every counter is halved after sketchSamples additions
+// per record, so the estimates follow recent reads.
+type countMinSketch struct {
+	counters  []uint8 // sketchDepth rows.
+	mask      uint64  // Row width - 1.
+	additions uint32
+	resetAt   uint32
+}
+
+func newCountMinSketch(maxCount uint32) countMinSketch {
+	width := uint64(1) << bits.Len64(uint64(max(maxCount, 16))-1)
+	return countMinSketch{
+		counters: make([]uint8, sketchDepth*width),
+		mask:     width - 1,
+		resetAt:  max(maxCount, 16) * sketchSamples,
+	}
+}
+
+func (s *countMinSketch) increment(key uint64) {
+	h1, h2 := sketchHash(key)
+	for i := uint64(0); i < sketchDepth; i++ {
+		if c := &s.counters[i*(s.mask+1)+(h1+i*h2)&s.mask]; *c < sketchMax {
+			*c++
+		}
+	}
+	if s.additions++; s.additions >= s.resetAt {
+		for i := range s.counters {
+			s.counters[i] >>= 1
+		}
+		s.additions /= 2
+	}
+}
+
+func (s *countMinSketch) estimate(key uint64) uint8 {
+	h1, h2 := sketchHash(key)
+	n := uint8(sketchMax)
+	for i := uint64(0); i < sketchDepth; i++ {
+		n = min(n, s.counters[i*(s.mask+1)+(h1+i*h2)&s.mask])
+	}
+	return n
+}
+
+func (s *countMinSketch) clear() {
+	clear(s.counters)
+	s.additions = 0
+}
+
+// sketchHash returns the two hashes the counters of key are derived from.
+func sketchHash(key uint64) (uint64, uint64) {
+	h := hashKey(key ^ 0xc2b2ae3d27d4eb4f)
+	return h, h>>32 | 1
+}
@@ -0,0 +1,50 @@
+package ttlswisscache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWithEviction_TinyLFU(t *testing.T) {
+	tiny, clock := hitRatio(TinyLFU), hitRatio(CLOCK)
+	if tiny < clock*1.05 {
+		t.Errorf("TinyLFU doesn't beat CLOCK: got: %.3f expected more than: %.3f", tiny, clock*1.05)
+	}
+}
+
+func TestWithEviction_TinyLFU_Segments(t *testing.T) {
+	c := New(0, WithShardCount(1), WithMaxEntries(200), WithEviction(TinyLFU))
+	defer c.Close()
+	s := c.shards.list[0]
+	e := s.evictor.(*tinyLFU)
+
+	for i := 0; i < 1000; i++ {
+		c.Set(IntKey(i%300), i, time.Hour)
+		c.Get(IntKey(i % 7))
+		if i%5 == 0 {
+			c.Delete(IntKey(100 + i%11))
+		}
+		n := uint32(0)
+		for seg := range e.segments {
+			n += e.segments[seg].len
+		}
+		if n != uint32(s.count()) {
+			t.Fatalf("incorrect number of tracked records: got: %d expected: %d", n, s.count())
+		}
+	}
+	if n := s.count(); n != 200 {
+		t.Errorf("incorrect number of records: got: %d expected: %d", n, 200)
+	}
+	// Hot keys are protected.
+	for i := 0; i < 7; i++ {
+		if _, ok := c.Get(IntKey(i)); !ok {
+			t.Errorf("frequently read record was evicted: %d", i)
+		}
+	}
+
+	c.Clone().Close()
+	c.Clear()
+	if e.segments[windowSegment].len != 0 {
+		t.Errorf("records tracked after Clear: got: %d expected: %d", e.segments[windowSegment].len, 0)
+	}
+}
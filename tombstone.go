@@ -0,0 +1,32 @@
+package ttlswisscache
+
+import "time"
+
+// tombstoneValue marks a recently deleted key, see WithTombstones.
+type tombstoneValue struct{}
+
+// WithTombstones makes Delete leave a tombstone of the key for ttl, a record
+// reads report as missing but refusing writes: until it expires Set returns
+// 0, SetAsync, SetIfAbsent, Merge and refreshes skip the key and Txn fails
+// with ErrImmutable, so late writes of a value read before the Delete, e.g. by
+// a loader or a replication stream, can't bring it back. Delete of a missing
+// key leaves a tombstone too, and so do Remove and Keyed.Delete. Tombstones count as
+// entries and are kept until outdated: Expire and GetAndDelete report them as
+// missing, and their removal has no events, callbacks or statistics. Other
+// removals, e.g. GetAndDelete or InvalidateTag, don't leave one.
+func WithTombstones(ttl time.Duration) Option {
+	return func(o *options) {
+		o.tombstoneTTL = ttl
+	}
+}
+
+// buried reports whether the record is a tombstone, see WithTombstones.
+func (it item) buried() bool {
+	_, ok := it.value.(tombstoneValue)
+	return ok
+}
+
+// bury leaves a tombstone of key in the locked shard s.
+func (c *Cache) bury(s *shard, key uint64) {
+	s.put(key, item{deadline: c.clock.unixNano() + int64(c.opts.tombstoneTTL), value: tombstoneValue{}})
+}
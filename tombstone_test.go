@@ -0,0 +1,101 @@
+package ttlswisscache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWithTombstones(t *testing.T) {
+	clock := &fixedClock{now: time.Unix(1000, 0)}
+	c := New(0, WithClock(clock), WithTombstones(time.Second))
+	defer c.Close()
+	events, cancel := c.Subscribe(16)
+	defer cancel()
+	c.Set(IntKey(1), "old", time.Hour)
+	c.Delete(IntKey(1))
+	c.Delete(IntKey(2))
+
+	for _, key := range []uint64{IntKey(1), IntKey(2)} {
+		if v := c.Set(key, "late", time.Hour); v != 0 {
+			t.Errorf("late set of a deleted key was stored: got version: %d", v)
+		}
+		if c.SetIfAbsent(key, "late", time.Hour) {
+			t.Error("late SetIfAbsent of a deleted key was stored")
+		}
+		if v, ok := c.Get(key); ok {
+			t.Errorf("tombstone was read: got: %v", v)
+		}
+	}
+	err := c.Txn(func(tx *Txn) error {
+		tx.Set(IntKey(1), "late", time.Hour)
+		return nil
+	})
+	if err != ErrImmutable {
+		t.Errorf("incorrect error of a late transaction: got: %v expected: %v", err, ErrImmutable)
+	}
+	c.Delete(IntKey(1))
+	if st := c.Stats(); st.Deletes != 1 || st.Entries != 2 {
+		t.Errorf("incorrect stats: got: %d deletes, %d entries expected: 1 deletes, 2 entries", st.Deletes, st.Entries)
+	}
+	var ops []Op
+	for len(events) > 0 {
+		ops = append(ops, (<-events).Op)
+	}
+	if len(ops) != 2 || ops[0] != OpSet || ops[1] != OpDelete {
+		t.Errorf("incorrect events: got: %v expected: %v", ops, []Op{OpSet, OpDelete})
+	}
+
+	clock.now = clock.now.Add(time.Second + time.Nanosecond)
+	if v := c.Set(IntKey(1), "new", time.Hour); v == 0 {
+		t.Error("set after the tombstone expired was dropped")
+	}
+	if v, _ := c.Get(IntKey(1)); v != "new" {
+		t.Errorf("incorrect value: got: %v expected: %v", v, "new")
+	}
+}
+
+func TestWithTombstones_Silent(t *testing.T) {
+	clock := &fixedClock{now: time.Unix(1000, 0)}
+	var evicted []Op
+	c := New(0, WithClock(clock), WithTombstones(time.Second), WithCallbacks(Callbacks{
+		OnEvicted: func(_ uint64, _ interface{}, reason Op) { evicted = append(evicted, reason) },
+	}))
+	defer c.Close()
+	keyed := NewKeyed(c, StringKey)
+	keyed.Set("a", "old", time.Hour)
+	keyed.Delete("a")
+	c.Delete(IntKey(1))
+	events, cancel := c.Subscribe(16)
+	defer cancel()
+
+	if c.Expire(IntKey(1), time.Hour) {
+		t.Error("tombstone was expired like a record")
+	}
+	if n := c.ExpireMany([]uint64{IntKey(1), StringKey("a")}, time.Hour); n != 0 {
+		t.Errorf("incorrect number of expired records: got: %d expected: %d", n, 0)
+	}
+	if v, ok := c.GetAndDelete(IntKey(1)); ok {
+		t.Errorf("tombstone was read: got: %v", v)
+	}
+	keyed.Set("a", "late", time.Hour)
+	if v, ok := keyed.Get("a"); ok {
+		t.Errorf("late set of a deleted Keyed key was stored: got: %v", v)
+	}
+	if v := c.Set(IntKey(1), "late", time.Hour); v != 0 {
+		t.Errorf("tombstone was removed by GetAndDelete: got version: %d", v)
+	}
+
+	clock.now = clock.now.Add(time.Second + time.Nanosecond)
+	if n := c.DeleteExpired(); n != 0 {
+		t.Errorf("incorrect number of expired records: got: %d expected: %d", n, 0)
+	}
+	if st := c.Stats(); st.Entries != 0 || st.Expired != 0 {
+		t.Errorf("incorrect stats: got: %d entries, %d expired expected: 0 entries, 0 expired", st.Entries, st.Expired)
+	}
+	if len(events) != 0 {
+		t.Errorf("incorrect events of tombstones: got: %v", (<-events).Op)
+	}
+	if len(evicted) != 1 || evicted[0] != OpDelete {
+		t.Errorf("incorrect callbacks: got: %v expected: %v", evicted, []Op{OpDelete})
+	}
+}
@@ -0,0 +1,139 @@
+package ttlswisscache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// maxTryPause bounds the pauses between the attempts of TryGet and TrySet to
+// take a busy shard lock.
+const maxTryPause = time.Millisecond
+
+// ErrBusy is returned by TryGet and TrySet when the lock of the shard of the
+// key can't be taken in time.
+var ErrBusy = errors.New("ttlswisscache: shard busy")
+
+// TryGet is Get without waiting for the lock of the shard of key, held e.g. by
+// a writer removing a large batch of outdated records or by a Verify, so
+// real-time paths can degrade to a miss instead of stalling. If the lock is
+// busy and ctx can't be done, e.g. context.Background, it returns ErrBusy
+// right away; otherwise it retries, pausing up to a millisecond between
+// attempts, until ctx is done and returns ErrBusy wrapping ctx.Err(). Busy
+// reads count as misses and in Stats.Busy.
+//
+// Reads consuming a SetOnce record or extending an idle one take the write
+// lock as Get does.
+func (c *Cache) TryGet(ctx context.Context, key uint64) (interface{}, bool, error) {
+	s := c.shards.get(key)
+	if c.filter != nil && !c.filter.contains(key) {
+		s.stats.misses.Add(1)
+		return nil, false, nil
+	}
+	if !s.tryRLock(ctx) {
+		s.stats.misses.Add(1)
+		s.stats.busy.Add(1)
+		return nil, false, busy(ctx)
+	}
+	cacheItem, ok := s.get(key)
+	s.RUnlock()
+	value, ok := c.found(s, key, cacheItem, ok)
+	return value, ok, nil
+}
+
+// TrySet is Set without waiting for the lock of the shard of key beyond ctx,
+// see TryGet. It returns ErrClosed once the cache is closing and
+// ErrValueTooLarge, leaving the stored record as is, if the value is rejected
+// by WithMaxValueSize. It isn't coalesced by WithSetCoalescing.
+func (c *Cache) TrySet(ctx context.Context, key uint64, value interface{}, ttl time.Duration) (uint64, error) {
+	value, ok := c.limit(key, value)
+	if !ok {
+		return 0, ErrValueTooLarge
+	}
+	it := item{
+		deadline: c.clock.unixNano() + int64(ttl),
+		value:    c.clone(value),
+	}
+	s := c.shards.get(key)
+	if !s.tryLock(ctx) {
+		s.stats.busy.Add(1)
+		return 0, busy(ctx)
+	}
+	if c.closing.Load() {
+		s.Unlock()
+		return 0, ErrClosed
+	}
+	if c.protected(s, key) {
+		s.Unlock()
+		return 0, nil
+	}
+	s.put(key, it)
+	version := s.version
+	c.subs.publish(OpSet, key, it)
+	s.Unlock()
+	s.stats.sets.Add(1)
+	return version, nil
+}
+
+// busy returns the error of a lock not taken before ctx was done.
+func busy(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("%w: %w", ErrBusy, err)
+	}
+	return ErrBusy
+}
+
+// tryRLock read locks the shard unless it stays locked until ctx is done, and
+// reports whether it did.
+func (s *shard) tryRLock(ctx context.Context) bool {
+	var l *lockCounters
+	if s.locks != nil {
+		l = &s.locks.read
+	}
+	return tryAcquire(ctx, s.RWMutex.TryRLock, l)
+}
+
+// tryLock write locks the shard unless it stays locked until ctx is done, and
+// reports whether it did.
+func (s *shard) tryLock(ctx context.Context) bool {
+	var l *lockCounters
+	if s.locks != nil {
+		l = &s.locks.write
+	}
+	return tryAcquire(ctx, s.RWMutex.TryLock, l)
+}
+
+// tryAcquire calls try until it succeeds or ctx is done, with pauses doubling
+// up to maxTryPause, and records the acquisition in l, if not nil.
+func tryAcquire(ctx context.Context, try func() bool, l *lockCounters) bool {
+	if try() {
+		if l != nil {
+			l.acquired.Add(1)
+		}
+		return true
+	}
+	done := ctx.Done()
+	if done == nil {
+		return false
+	}
+	start := time.Now()
+	timer := time.NewTimer(time.Microsecond)
+	defer timer.Stop()
+	for pause := time.Microsecond; ; {
+		select {
+		case <-done:
+			return false
+		case <-timer.C:
+		}
+		if try() {
+			if l != nil {
+				l.acquired.Add(1)
+				l.record(time.Since(start))
+			}
+			return true
+		}
+		pause = min(2*pause, maxTryPause)
+		timer.Reset(pause)
+	}
+}
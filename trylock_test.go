@@ -0,0 +1,74 @@
+package ttlswisscache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCache_TryGet(t *testing.T) {
+	c := New(0, WithLockStats())
+	defer c.Close()
+	c.Set(1, "one", time.Hour)
+	if value, ok, err := c.TryGet(context.Background(), 1); value != "one" || !ok || err != nil {
+		t.Errorf("incorrect result: got: %v, %v, %v expected: %v, %v, %v", value, ok, err, "one", true, nil)
+	}
+	if _, ok, err := c.TryGet(context.Background(), 2); ok || err != nil {
+		t.Errorf("incorrect result of a missing key: got: %v, %v expected: %v, %v", ok, err, false, nil)
+	}
+
+	s := c.shards.get(1)
+	s.Lock()
+	if _, ok, err := c.TryGet(context.Background(), 1); ok || !errors.Is(err, ErrBusy) {
+		t.Errorf("incorrect result of a busy shard: got: %v, %v expected: %v, %v", ok, err, false, ErrBusy)
+	}
+	ctx, cancel := context.WithTimeout(t.Context(), 5*time.Millisecond)
+	defer cancel()
+	if _, _, err := c.TryGet(ctx, 1); !errors.Is(err, ErrBusy) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("incorrect error past the deadline: got: %v expected: %v", err, context.DeadlineExceeded)
+	}
+	time.AfterFunc(5*time.Millisecond, s.Unlock)
+	if value, ok, err := c.TryGet(t.Context(), 1); value != "one" || !ok || err != nil {
+		t.Errorf("incorrect result once unlocked: got: %v, %v, %v expected: %v, %v, %v", value, ok, err, "one", true, nil)
+	}
+
+	st := c.Stats()
+	if st.Busy != 2 || st.Hits != 2 || st.Misses != 3 {
+		t.Errorf("incorrect stats: got: %d busy, %d hits, %d misses expected: %d, %d, %d", st.Busy, st.Hits, st.Misses, 2, 2, 3)
+	}
+	if st.ReadLocks.Contended != 1 {
+		t.Errorf("incorrect contended read locks: got: %d expected: %d", st.ReadLocks.Contended, 1)
+	}
+}
+
+func TestCache_TrySet(t *testing.T) {
+	c := New(0, WithMaxValueSize(4, RejectOversized))
+	if version, err := c.TrySet(context.Background(), 1, "one", time.Hour); version == 0 || err != nil {
+		t.Errorf("incorrect result: got: %d, %v expected: a version, %v", version, err, nil)
+	}
+	if _, err := c.TrySet(context.Background(), 1, "eleven", time.Hour); !errors.Is(err, ErrValueTooLarge) {
+		t.Errorf("incorrect error of an oversized value: got: %v expected: %v", err, ErrValueTooLarge)
+	}
+	if value, _ := c.Get(1); value != "one" {
+		t.Errorf("incorrect value: got: %v expected: %v", value, "one")
+	}
+
+	s := c.shards.get(1)
+	s.RLock()
+	if _, err := c.TrySet(context.Background(), 1, "uno", time.Hour); !errors.Is(err, ErrBusy) {
+		t.Errorf("incorrect error of a busy shard: got: %v expected: %v", err, ErrBusy)
+	}
+	time.AfterFunc(5*time.Millisecond, s.RUnlock)
+	if _, err := c.TrySet(t.Context(), 1, "uno", time.Hour); err != nil {
+		t.Errorf("incorrect error once unlocked: got: %v expected: %v", err, nil)
+	}
+	if value, _ := c.Get(1); value != "uno" {
+		t.Errorf("incorrect value: got: %v expected: %v", value, "uno")
+	}
+
+	c.Close()
+	if _, err := c.TrySet(context.Background(), 1, "one", time.Hour); !errors.Is(err, ErrClosed) {
+		t.Errorf("incorrect error once closed: got: %v expected: %v", err, ErrClosed)
+	}
+}
@@ -1,17 +1,48 @@
 package ttlswisscache
 
 import (
+	"context"
+	"errors"
+	"runtime/pprof"
+	"sync/atomic"
 	"time"
-
-	csmap "github.com/mhmtszr/concurrent-swiss-map"
 )
 
 const defaultCapacity = 64 // Just to avoid extra allocations in most of the cases.
 
+// ErrClosed is returned by operations on a closed cache.
+var ErrClosed = errors.New("ttlswisscache: cache closed")
+
 // Cache represents key-value storage.
+//
+// After Close the cache is empty and stays so: reads miss, writes are dropped,
+// and methods returning an error return ErrClosed.
 type Cache struct {
-	done  chan struct{}
-	items *csmap.CsMap[uint64, item]
+	done    chan struct{}
+	base    context.Context // Cancelled by Close, parent of loader and refresh calls.
+	cancel  context.CancelFunc
+	closing atomic.Bool // Set by Shutdown and Close, checked under the shard lock by writes.
+	closed  atomic.Bool
+	shards  shards
+	subs    subscribers
+	bufs    buffers
+	writes  *writeBuffer // nil without WithWriteBuffer
+	clock   *coarseClock // nil without WithCoarseClock and WithClock
+	cleanup cleanupSwitch
+
+	namespaces namespaces
+	deps       dependencies
+	filter     *bloomFilter                     // nil without WithBloomFilter
+	coalescer  *coalescer                       // nil without WithGetManyCoalescing
+	refresher  *refresher                       // nil without WithRefresh
+	sets       *setCoalescer                    // nil without WithSetCoalescing
+	loads      flightGroup[uint64, interface{}] // Loader calls of GetCtx.
+	immutables atomic.Bool                      // Set by the first SetImmutable.
+
+	rebuilds      atomic.Uint64
+	rebuildCursor atomic.Uint32 // Next shard rebuilt by the cleanup manager.
+
+	opts options
 }
 
 type item struct {
@@ -20,19 +51,60 @@ type item struct {
 }
 
 // New creates key-value storage.
-// resolution – configures cleanup manager.
-// Cleanup operation locks storage so think twice before setting it to small value.
-func New(resolution time.Duration) *Cache {
-	items := csmap.Create[uint64, item](
-		csmap.WithShardCount[uint64, item](32),
-		csmap.WithSize[uint64, item](defaultCapacity),
-	)
+// resolution – configures cleanup manager, see WithResolution.
+// It is NewCache with WithResolution(resolution) before opts.
+func New(resolution time.Duration, opts ...Option) *Cache {
+	return NewCache(append([]Option{WithResolution(resolution)}, opts...)...)
+}
+
+// NewCache creates key-value storage configured by opts.
+// The cleanup manager runs every second unless WithResolution says otherwise.
+func NewCache(opts ...Option) *Cache {
+	return newCache(newOptions(opts))
+}
+
+func newCache(o options) *Cache {
 	c := &Cache{
-		done:  make(chan struct{}),
-		items: items,
+		opts:   o,
+		done:   make(chan struct{}),
+		shards: newShards(o.shardCount, o.capacity, o.hasher),
+	}
+	c.base, c.cancel = context.WithCancel(context.Background())
+	c.subs.callbacks = o.callbacks
+	c.subs.evictions = newEvictionPool(o.callbacks.OnEvicted, o.evictionWorkers, o.evictionQueue, c.done)
+	if o.bloomFilter > 0 {
+		c.filter = newBloomFilter(o.bloomFilter)
+	}
+	if o.maxEntries > 0 && o.eviction == NoEviction {
+		o.eviction = CLOCK
+	}
+	if o.evictionPolicy == nil {
+		o.evictionPolicy = o.eviction.NewPolicy
 	}
+	for _, s := range c.shards.list {
+		s.destructor = o.destructor
+		s.retainer = o.retainer
+		if o.lockStats {
+			s.locks = &lockStats{}
+		}
+		s.filter = c.filter
+		if o.maxEntries > 0 {
+			s.maxCount = maxShardEntries(o.maxEntries, len(c.shards.list))
+			s.evictor = o.evictionPolicy(int(s.maxCount))
+			s.evicted = func(key uint64, it item) { c.subs.publish(OpEvict, key, it) }
+		}
+	}
+	c.subs.deps = &c.deps
+	c.coalescer = newCoalescer(c, o.coalesceDelay)
+	c.refresher = newRefresher(c, o.refresh, o.refreshOpts)
+	c.sets = newSetCoalescer(c, o.setCoalescing)
 
-	go cleaner(c, resolution)
+	c.clock = newClock(o)
+	c.cleanup.start(o, c.done, c.sweep, c.clock.coarse())
+	if o.writeBuffer > 0 {
+		c.writes = newWriteBuffer(o.writeBuffer, len(c.shards.list), o.writePolicy)
+		go c.applyWrites()
+	}
 
 	return c
 }
@@ -40,68 +112,540 @@ func New(resolution time.Duration) *Cache {
 // Get returns stored record.
 // The first returned variable is a stored value.
 // The second one is an existence flag like in the map.
+// Weak values collected by the garbage collector are reported as missing.
 func (c *Cache) Get(key uint64) (interface{}, bool) {
-	cacheItem, ok := c.items.Load(key)
-	if !ok {
+	s := c.shards.get(key)
+	if c.filter != nil && !c.filter.contains(key) {
+		s.stats.misses.Add(1)
 		return nil, false
 	}
-	return cacheItem.value, true
+	s.RLock()
+	cacheItem, ok := s.get(key)
+	s.RUnlock()
+	return c.found(s, key, cacheItem, ok)
+}
+
+// found returns the value of the record cacheItem of key read from s, if ok,
+// and counts the read, see Get.
+func (c *Cache) found(s *shard, key uint64, cacheItem item, ok bool) (interface{}, bool) {
+	value, ok, stored := c.hit(s, key, cacheItem, ok)
+	if stored {
+		value = c.clone(value)
+	}
+	return value, ok
+}
+
+// hit is found without the copy of the value. stored reports whether the value
+// is still in the cache, unlike consumed SetOnce values handed over to the
+// caller, and must be cloned.
+func (c *Cache) hit(s *shard, key uint64, cacheItem item, ok bool) (value interface{}, found, stored bool) {
+	if ok && !c.quarantined(cacheItem) {
+		if c.early(cacheItem) {
+			s.stats.misses.Add(1)
+			s.stats.early.Add(1)
+			return nil, false, false
+		}
+		if _, once := cacheItem.value.(*onceValue); once {
+			return c.consume(s, key)
+		}
+		if iv, idle := cacheItem.value.(*idleValue); idle && !c.touch(key, cacheItem.deadline, iv) {
+			s.stats.misses.Add(1)
+			return nil, false, false
+		}
+		value, ok = cacheItem.load()
+	} else {
+		ok = false
+	}
+	if !ok {
+		s.stats.misses.Add(1)
+		return nil, false, false
+	}
+	s.stats.hits.Add(1)
+	if c.refresher != nil {
+		c.refresher.ahead(key, cacheItem.deadline)
+	}
+	return value, true, true
 }
 
-// Set adds value to the cache with given ttl.
+// Set adds value to the cache with given ttl and returns the version of the
+// record, see GetIfChanged. It returns 0 once the cache is closing, if the
+// value is rejected by WithMaxValueSize or deferred by WithSetCoalescing.
 // ttl value should be a multiple of the resolution time value.
-func (c *Cache) Set(key uint64, value interface{}, ttl time.Duration) {
+func (c *Cache) Set(key uint64, value interface{}, ttl time.Duration) uint64 {
+	version, _ := c.SetChecked(key, value, ttl)
+	return version
+}
+
+// SetChecked is Set also reporting whether the write was accepted, for callers
+// telling a rejected value from a deferred one. ok is false when nothing is
+// stored: once the cache is closing, for values rejected by WithMaxValueSize
+// and for keys refusing writes, see SetImmutable and WithTombstones. A write
+// deferred by WithSetCoalescing reports version 0 and true.
+func (c *Cache) SetChecked(key uint64, value interface{}, ttl time.Duration) (version uint64, ok bool) {
+	value, ok = c.limit(key, value)
+	if !ok {
+		c.reject(key)
+		return 0, false
+	}
 	cacheItem := item{
-		deadline: time.Now().UnixNano() + int64(ttl),
-		value:    value,
+		deadline: c.clock.unixNano() + int64(ttl),
+		value:    c.clone(value),
+	}
+	if c.sets != nil {
+		version, deferred := c.sets.set(key, cacheItem)
+		return version, deferred || version != 0
+	}
+	version = c.store(key, cacheItem)
+	return version, version != 0
+}
+
+// SetDefault adds value to the cache with the ttl of the key, see TTLFor.
+func (c *Cache) SetDefault(key uint64, value interface{}) {
+	c.Set(key, value, c.TTLFor(key))
+}
+
+// TTLFor returns the ttl of the first WithTTLPolicy matching key,
+// or the WithDefaultTTL ttl if none does.
+func (c *Cache) TTLFor(key uint64) time.Duration {
+	for _, p := range c.opts.ttlPolicies {
+		if p.match(key) {
+			return p.ttl
+		}
+	}
+	return c.opts.defaultTTL
+}
+
+// clone returns a copy of the value with the WithCloner function, if any.
+// Weak values and SetNotFound markers are returned as is, Keyed values keep their original key
+// and SetWithRefresher values their function.
+func (c *Cache) clone(value interface{}) interface{} {
+	if c.opts.cloner == nil || value == nil {
+		return value
+	}
+	switch v := value.(type) {
+	case *weakValue, notFoundValue, tombstoneValue:
+		return value
+	case *keyedValue:
+		return &keyedValue{key: v.key, value: c.clone(v.value)}
+	case *refreshedValue:
+		return &refreshedValue{value: c.clone(v.value), fn: v.fn}
+	case *onceValue:
+		return &onceValue{value: c.clone(v.value)}
+	case *idleValue:
+		return &idleValue{value: c.clone(v.value), idle: v.idle, expiry: v.expiry}
+	case *immutableValue:
+		return &immutableValue{value: c.clone(v.value)}
+	case *costedValue:
+		return &costedValue{value: c.clone(v.value), cost: v.cost}
+	case *namespacedValue:
+		return &namespacedValue{value: c.clone(v.value), state: v.state, gen: v.gen, cost: v.cost, seq: v.seq}
+	}
+	return c.opts.cloner(value)
+}
+
+// store puts the item under the key as is and returns the version of the record.
+func (c *Cache) store(key uint64, it item) uint64 {
+	s := c.shards.get(key)
+	s.Lock()
+	if c.closing.Load() || c.protected(s, key) {
+		s.Unlock()
+		return 0
+	}
+	s.put(key, it)
+	version := s.version
+	c.subs.publish(OpSet, key, it)
+	s.Unlock()
+	s.stats.sets.Add(1)
+	return version
+}
+
+// TTL returns the remaining time to live of the stored record.
+// Outdated records waiting for the cleanup manager report zero.
+func (c *Cache) TTL(key uint64) (time.Duration, bool) {
+	s := c.shards.get(key)
+	s.RLock()
+	cacheItem, ok := s.get(key)
+	s.RUnlock()
+	if ok {
+		_, ok = cacheItem.load()
+	}
+	if !ok {
+		return 0, false
+	}
+	ttl := time.Duration(cacheItem.deadline - c.clock.unixNano())
+	if ttl < 0 {
+		ttl = 0
+	}
+	return ttl, true
+}
+
+// Peek returns the value of key and its remaining ttl like Get and TTL, without
+// reading the record: it doesn't consume SetOnce values, extend idle records,
+// count as an access of the eviction policy or change the statistics, e.g. for
+// debugging tools.
+func (c *Cache) Peek(key uint64) (interface{}, time.Duration, bool) {
+	s := c.shards.get(key)
+	s.RLock()
+	i, ok := s.index.Get(key)
+	var cacheItem item
+	if ok {
+		cacheItem = item{deadline: s.deadlines[i], value: s.values[i]}
+	}
+	s.RUnlock()
+	if !ok || c.quarantined(cacheItem) {
+		return nil, 0, false
+	}
+	now := c.clock.unixNano()
+	if _, idle := cacheItem.value.(*idleValue); idle && cacheItem.deadline < now {
+		return nil, 0, false
+	}
+	value, ok := cacheItem.load()
+	if !ok {
+		return nil, 0, false
+	}
+	return c.clone(value), max(time.Duration(cacheItem.deadline-now), 0), true
+}
+
+// NextExpiry returns the earliest deadline of the live records, false if
+// there are none, so callers can sleep until the next record expires instead
+// of polling. Outdated records waiting for the cleanup manager are left out;
+// with WithGracePeriod they are removed grace after their deadline. It scans
+// the deadlines of every shard under its read lock.
+func (c *Cache) NextExpiry() (time.Time, bool) {
+	now := c.clock.unixNano()
+	next, found := int64(0), false
+	for _, s := range c.shards.list {
+		s.RLock()
+		for _, deadline := range s.deadlines {
+			if deadline >= now && (!found || deadline < next) {
+				next, found = deadline, true
+			}
+		}
+		s.RUnlock()
 	}
-	c.items.Store(key, cacheItem)
+	if !found {
+		return time.Time{}, false
+	}
+	return time.Unix(0, next), true
+}
+
+// Expire sets a new ttl for the stored record.
+// It reports whether the record exists.
+func (c *Cache) Expire(key uint64, ttl time.Duration) bool {
+	s := c.shards.get(key)
+	s.Lock()
+	defer s.Unlock()
+	cacheItem, ok := s.expire(key, c.clock.unixNano()+int64(ttl))
+	if !ok {
+		return false
+	}
+	c.subs.publish(OpSet, key, cacheItem)
+	return true
+}
+
+// ExpireMany sets a new ttl for the stored records of keys like Expire and
+// returns the number of records found. Keys are grouped by shard, so every
+// shard is locked once, e.g. to renew thousands of leases per heartbeat.
+func (c *Cache) ExpireMany(keys []uint64, ttl time.Duration) int {
+	deadline := c.clock.unixNano() + int64(ttl)
+	sorted, offsets := c.groupByShard(keys)
+	n := 0
+	for i, s := range c.shards.list {
+		lo, hi := offsets[i], offsets[i+1]
+		if lo == hi {
+			continue
+		}
+		s.Lock()
+		for _, key := range sorted[lo:hi] {
+			if it, ok := s.expire(key, deadline); ok {
+				c.subs.publish(OpSet, key, it)
+				n++
+			}
+		}
+		s.Unlock()
+	}
+	return n
+}
+
+// GetAndDelete removes record from storage and returns its value.
+// The value is handed over to the caller, the WithDestructor function is not called.
+// The second returned variable reports whether the record existed
+// and its value wasn't collected, see SetWeak.
+func (c *Cache) GetAndDelete(key uint64) (interface{}, bool) {
+	s := c.shards.get(key)
+	s.Lock()
+	cacheItem, ok := s.get(key)
+	if !ok || c.quarantined(cacheItem) || cacheItem.buried() {
+		s.Unlock()
+		return nil, false
+	}
+	c.remove(s, key, cacheItem)
+	s.Unlock()
+	c.cascade()
+	return cacheItem.load()
 }
 
 // Delete removes record from storage.
+// With WithTombstones it leaves a tombstone of the key.
 func (c *Cache) Delete(key uint64) {
-	c.items.Delete(key)
+	c.delete(key, nil, true)
+}
+
+// Remove is Delete reporting whether key had a record GetAndDelete would have
+// returned. Unlike GetAndDelete it passes the value to WithDestructor, for
+// callers discarding it, e.g. servers deleting keys for clients.
+func (c *Cache) Remove(key uint64) bool {
+	return c.delete(key, nil, true)
+}
+
+// delete is Delete leaving the record of key as is if keep, unless nil,
+// reports true for its value, see Keyed.Delete. It leaves a tombstone only if
+// bury and reports whether key had a record, see Remove.
+func (c *Cache) delete(key uint64, keep func(value interface{}) bool, bury bool) bool {
+	if c.sets != nil {
+		c.sets.discard(key)
+	}
+	s := c.shards.get(key)
+	s.Lock()
+	if i, ok := s.index.Get(key); ok && keep != nil && keep(s.values[i]) {
+		s.Unlock()
+		return false
+	}
+	it, ok := s.delete(key)
+	if ok && it.buried() {
+		ok = false // The key was deleted already.
+	}
+	found := false
+	if ok && !c.quarantined(it) {
+		_, found = it.load()
+	}
+	if ok {
+		s.stats.deletes.Add(1)
+		c.subs.publish(OpDelete, key, it)
+	}
+	if bury && c.opts.tombstoneTTL > 0 && !c.closing.Load() {
+		c.bury(s, key)
+	}
+	s.Unlock()
+	if ok {
+		c.cascade()
+	}
+	return found
+}
+
+// reject removes the record of key after a write of it was rejected by
+// WithMaxValueSize, so its previous value isn't served. Unlike Delete it
+// leaves no tombstone and keeps an existing one.
+func (c *Cache) reject(key uint64) {
+	c.delete(key, func(value interface{}) bool {
+		_, buried := value.(tombstoneValue)
+		return buried
+	}, false)
+}
+
+// remove takes the record from the locked shard, see shard.take.
+func (c *Cache) remove(s *shard, key uint64, it item) {
+	s.take(key)
+	s.stats.deletes.Add(1)
+	c.subs.publish(OpDelete, key, it)
+}
+
+// Reserve grows the shards so the cache holds n records without rehashing.
+// Shards already large enough are left as is. Each shard is locked while it is resized.
+func (c *Cache) Reserve(n int) {
+	perShard := perShardCapacity(n, len(c.shards.list))
+	for _, s := range c.shards.list {
+		s.Lock()
+		if c.closing.Load() {
+			s.Unlock()
+			return
+		}
+		if perShard > s.reserved {
+			s.reserved = perShard
+		}
+		if perShard > s.capacity && int(perShard) > s.count() {
+			s.resize(perShard)
+		}
+		s.Unlock()
+	}
+}
+
+// Compact rebuilds the shards whose maps are much larger than their records,
+// e.g. after Clear or a mass expiration, and returns their number. Maps never
+// shrink otherwise. The memory of the old maps is released by the garbage collector.
+// Shards are not shrunk below the initial capacity or a Reserve call.
+// Each shard is locked while it is rebuilt.
+func (c *Cache) Compact() int {
+	n := 0
+	for _, s := range c.shards.list {
+		s.Lock()
+		if s.compact() {
+			n++
+		}
+		s.Unlock()
+	}
+	return n
 }
 
 // Clear removes all items from storage and leaves the cleanup manager running.
 func (c *Cache) Clear() {
-	c.items.Clear()
+	if c.sets != nil {
+		c.sets.discardAll()
+	}
+	for _, s := range c.shards.list {
+		s.Lock()
+		s.clear()
+		s.Unlock()
+	}
+	c.deps.reset()
+	c.subs.publish(OpClear, 0, item{})
+}
+
+// ClearWithCallbacks removes all items from storage like Clear, but calls OnEvicted
+// and publishes an OpDelete event with the value of every record instead of a single
+// OpClear, so resources held by the values can be released. It returns the number
+// of removed records. Events are dropped for subscribers whose buffer fills up.
+// Tombstones and SetNotFound markers are removed silently.
+func (c *Cache) ClearWithCallbacks() int {
+	if c.sets != nil {
+		c.sets.discardAll()
+	}
+	n := 0
+	for _, s := range c.shards.list {
+		s.Lock()
+		removed := 0
+		for i, key := range s.keys {
+			it := item{deadline: s.deadlines[i], value: s.values[i]}
+			if _, missing := it.value.(notFoundValue); missing || it.buried() {
+				continue
+			}
+			c.subs.publish(OpDelete, key, it)
+			removed++
+		}
+		s.clear()
+		s.Unlock()
+		s.stats.deletes.Add(uint64(removed))
+		n += removed
+	}
+	c.deps.reset()
+	return n
 }
 
 // Close stops cleanup manager and removes records from storage.
+// Event subscriptions are cancelled. Writes still queued by SetAsync are dropped.
+// Closing a closed cache returns ErrClosed.
 func (c *Cache) Close() error {
+	if !c.closed.CompareAndSwap(false, true) {
+		return ErrClosed
+	}
+	c.closing.Store(true)
 	close(c.done)
-	c.items.Clear()
+	c.cancel()
+	c.Clear()
+	c.subs.closeAll()
 	return nil
 }
 
-// cleanup removes outdated items from the storage.
-// It triggers stop the world for the cache.
-func (c *Cache) cleanup() {
-	now := time.Now().UnixNano()
-	k := make([]uint64, c.items.Count())
-	i := 0
-	c.items.Range(func(key uint64, value item) (stop bool) {
-		if value.deadline < now {
-			k[i] = key
-			i++
+// DeleteExpired removes outdated records from storage and returns their number.
+// The cleanup manager calls it every resolution tick.
+// With WithGracePeriod records are removed once the grace period has passed.
+// Shards are scanned under their read lock, so readers are not blocked by the scan.
+// The write lock is taken only to remove the records found, one shard at a time.
+func (c *Cache) DeleteExpired() int {
+	return c.DeleteExpiredBefore(time.Unix(0, c.clock.unixNano()-int64(c.opts.grace)))
+}
+
+// DeleteExpiredBefore removes records with a deadline before t and returns their number.
+// It lets nodes sharing a logical clock, e.g. replicas, expire the same records.
+func (c *Cache) DeleteExpiredBefore(t time.Time) int {
+	n, _ := c.deleteExpired(t.UnixNano())
+	return n
+}
+
+// deleteExpired removes records with a deadline before now and returns their
+// number and the number of scanned records.
+func (c *Cache) deleteExpired(now int64) (n, scanned int) {
+	buf := c.bufs.getEntries()
+	defer c.bufs.putEntries(buf)
+	expired := *buf
+	for _, s := range c.shards.list {
+		s.RLock()
+		scanned += len(s.keys)
+		expired = s.appendExpired(expired[:0], now)
+		s.RUnlock()
+		if len(expired) == 0 {
+			continue
 		}
-		return false
-	})
-	for _, d := range k {
-		c.items.Delete(d)
+
+		removed := 0
+		s.Lock()
+		for _, e := range expired {
+			// The record may have been set again since the scan.
+			it, ok := s.get(e.key)
+			if !ok || it.deadline >= now {
+				continue
+			}
+			s.delete(e.key)
+			if it.buried() {
+				continue
+			}
+			c.subs.publish(OpExpire, e.key, it)
+			removed++
+		}
+		s.Unlock()
+		s.stats.expired.Add(uint64(removed))
+		n += removed
+	}
+	*buf = expired
+	c.cascade()
+	return n, scanned
+}
+
+// ExpiringBefore returns the keys of the records that are not outdated yet but
+// will be by t, in no particular order, e.g. to refresh or archive them before
+// they are removed. Every shard is read locked in turn.
+func (c *Cache) ExpiringBefore(t time.Time) []uint64 {
+	now, before := c.clock.unixNano(), t.UnixNano()
+	var keys []uint64
+	for _, s := range c.shards.list {
+		s.RLock()
+		for i, deadline := range s.deadlines {
+			if deadline >= now && deadline < before {
+				keys = append(keys, s.keys[i])
+			}
+		}
+		s.RUnlock()
 	}
+	return keys
 }
 
-func cleaner(c *Cache, resolution time.Duration) {
-	ticker := time.NewTicker(resolution)
+// cleaner calls deleteExpired every resolution and updates clock, if any,
+// every clockResolution until done is closed or ctx is done. A resolution <= 0
+// only updates the clock.
+// It runs with the profiler labels of ctx, see WithCleanupHooks.
+func cleaner(ctx context.Context, done <-chan struct{}, resolution time.Duration, deleteExpired func(ctx context.Context) int, clock *coarseClock) {
+	pprof.SetGoroutineLabels(ctx)
+	var cleanup, tick <-chan time.Time
+	if resolution > 0 {
+		ticker := time.NewTicker(resolution)
+		defer ticker.Stop()
+		cleanup = ticker.C
+	}
+	if clock != nil {
+		ticker := time.NewTicker(clockResolution)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
 
 	for {
 		select {
-		case <-ticker.C:
-			c.cleanup()
-		case <-c.done:
-			ticker.Stop()
+		case <-cleanup:
+			withPhase(ctx, "sweep", func(ctx context.Context) { deleteExpired(ctx) })
+		case <-tick:
+			clock.update()
+		case <-done:
+			return
+		case <-ctx.Done():
 			return
 		}
 	}
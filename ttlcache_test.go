@@ -1,6 +1,9 @@
 package ttlswisscache
 
 import (
+	"io"
+	"math/rand"
+	"strings"
 	"testing"
 	"time"
 )
@@ -95,6 +98,64 @@ func TestCache_Clear(t *testing.T) {
 	}
 }
 
+func TestCache_ClearWithCallbacks(t *testing.T) {
+	c := New(0)
+	defer c.Close()
+	for i := 1; i < 5; i++ {
+		c.Set(IntKey(i), i, time.Hour)
+	}
+
+	events, cancel := c.Subscribe(16)
+	if n := c.ClearWithCallbacks(); n != 4 {
+		t.Errorf("incorrect number of removed records: got: %d expected: %d", n, 4)
+	}
+	cancel()
+	released := map[uint64]interface{}{}
+	for ev := range events {
+		if ev.Op != OpDelete {
+			t.Errorf("incorrect op: got: %v expected: %v", ev.Op, OpDelete)
+		}
+		released[ev.Key] = ev.Value
+	}
+	for i := 1; i < 5; i++ {
+		if v := released[IntKey(i)]; v != i {
+			t.Errorf("incorrect released value: got: %v expected: %v", v, i)
+		}
+	}
+	if st := c.Stats(); st.Entries != 0 || st.Deletes != 4 {
+		t.Errorf("incorrect stats: got: %+v expected: Entries 0, Deletes 4", st)
+	}
+}
+
+func TestCache_ClearWithCallbacks_Silent(t *testing.T) {
+	var evicted int
+	c := New(0, WithTombstones(time.Hour), WithSetCoalescing(time.Hour), WithCallbacks(Callbacks{
+		OnEvicted: func(uint64, interface{}, Op) { evicted++ },
+	}))
+	defer c.Close()
+	c.Set(IntKey(1), 1, time.Hour)
+	c.Delete(IntKey(1))
+	c.SetNotFound(IntKey(2), time.Hour)
+	c.Set(IntKey(3), "a", time.Hour)
+	c.Set(IntKey(3), "b", time.Hour) // Coalesced.
+	before := c.Stats().Deletes
+	evicted = 0
+
+	if n := c.ClearWithCallbacks(); n != 1 {
+		t.Errorf("incorrect number of removed records: got: %d expected: %d", n, 1)
+	}
+	if evicted != 1 {
+		t.Errorf("incorrect number of evictions: got: %d expected: %d", evicted, 1)
+	}
+	if d := c.Stats().Deletes - before; d != 1 {
+		t.Errorf("incorrect number of deletes: got: %d expected: %d", d, 1)
+	}
+	c.Flush()
+	if v, ok := c.Get(IntKey(3)); ok {
+		t.Errorf("pending set was stored after the clear: got: %v", v)
+	}
+}
+
 func TestClose(t *testing.T) {
 	c := New(time.Second)
 	c.Set(IntKey(1), 1, 0)
@@ -112,3 +173,342 @@ func TestClose(t *testing.T) {
 		t.Error("Storage was not cleaned up")
 	}
 }
+
+func TestCache_TTLExpire(t *testing.T) {
+	key := StringKey("key")
+	c := New(time.Hour)
+	defer c.Close()
+
+	if _, ok := c.TTL(key); ok {
+		t.Error("unexpected ttl for missing record")
+	}
+	if c.Expire(key, time.Minute) {
+		t.Error("missing record was expired")
+	}
+
+	c.Set(key, "value", time.Minute)
+	ttl, ok := c.TTL(key)
+	if !ok || ttl <= 0 || ttl > time.Minute {
+		t.Errorf("incorrect ttl: got: %v expected: (0, %v]", ttl, time.Minute)
+	}
+
+	if !c.Expire(key, time.Hour) {
+		t.Error("existing record was not expired")
+	}
+	ttl, _ = c.TTL(key)
+	if ttl <= time.Minute {
+		t.Errorf("ttl was not extended: got: %v", ttl)
+	}
+}
+
+func TestCache_SetChecked(t *testing.T) {
+	c := New(0, WithMaxValueSize(4, RejectOversized), WithTombstones(time.Hour), WithSetCoalescing(time.Hour))
+	defer c.Close()
+
+	if v, ok := c.SetChecked(IntKey(1), "a", time.Hour); !ok || v == 0 {
+		t.Errorf("incorrect result of a stored value: got: %d, %v", v, ok)
+	}
+	if v, ok := c.SetChecked(IntKey(1), "b", time.Hour); !ok || v != 0 {
+		t.Errorf("incorrect result of a deferred value: got: %d, %v expected: 0, true", v, ok)
+	}
+	if _, ok := c.SetChecked(IntKey(2), "abcde", time.Hour); ok {
+		t.Error("oversized value was accepted")
+	}
+	c.Delete(IntKey(3))
+	if _, ok := c.SetChecked(IntKey(3), "a", time.Hour); ok {
+		t.Error("value of a buried key was accepted")
+	}
+}
+
+func TestCache_Peek(t *testing.T) {
+	c := New(time.Hour)
+	defer c.Close()
+	c.SetOnce(IntKey(1), "token", time.Hour)
+	c.SetWithIdle(IntKey(2), "idle", time.Hour, time.Minute)
+	ttl, _ := c.TTL(IntKey(2))
+
+	for i := 0; i < 2; i++ {
+		if v, ttl, ok := c.Peek(IntKey(1)); !ok || v != "token" || ttl <= 0 {
+			t.Errorf("incorrect peek: got: %v, %v, %v expected: %v", v, ttl, ok, "token")
+		}
+	}
+	if _, peeked, ok := c.Peek(IntKey(2)); !ok || peeked > ttl {
+		t.Errorf("incorrect ttl: got: %v, %v expected at most: %v", peeked, ok, ttl)
+	}
+	if _, _, ok := c.Peek(IntKey(3)); ok {
+		t.Error("missing key was found")
+	}
+	if st := c.Stats(); st.Hits != 0 || st.Misses != 0 {
+		t.Errorf("peek counted as a read: got: %+v", st)
+	}
+	if v, ok := c.Get(IntKey(1)); !ok || v != "token" {
+		t.Errorf("peek consumed the value: got: %v, %v", v, ok)
+	}
+}
+
+func TestCache_NextExpiry(t *testing.T) {
+	clock := &fixedClock{now: time.Unix(1000, 0)}
+	c := New(0, WithShardCount(4), WithClock(clock))
+	defer c.Close()
+	if next, ok := c.NextExpiry(); ok {
+		t.Errorf("empty cache has a next expiry: %v", next)
+	}
+
+	c.Set(1, 1, time.Hour)
+	c.Set(2, 2, time.Minute)
+	c.Set(3, 3, -time.Second)
+	if next, ok := c.NextExpiry(); !ok || !next.Equal(clock.now.Add(time.Minute)) {
+		t.Errorf("incorrect next expiry: got: %v, %v expected: %v", next, ok, clock.now.Add(time.Minute))
+	}
+	c.Delete(2)
+	if next, ok := c.NextExpiry(); !ok || !next.Equal(clock.now.Add(time.Hour)) {
+		t.Errorf("incorrect next expiry after Delete: got: %v, %v expected: %v", next, ok, clock.now.Add(time.Hour))
+	}
+}
+
+func TestCache_ExpireMany(t *testing.T) {
+	c := New(time.Hour, WithShardCount(4))
+	defer c.Close()
+	var keys []uint64
+	for i := 0; i < 100; i++ {
+		keys = append(keys, IntKey(i))
+		if i%2 == 0 {
+			c.Set(IntKey(i), i, time.Minute)
+		}
+	}
+	events, cancel := c.Subscribe(100)
+	defer cancel()
+
+	if n := c.ExpireMany(keys, time.Hour); n != 50 {
+		t.Errorf("incorrect number of expired records: got: %d expected: %d", n, 50)
+	}
+	for i, key := range keys {
+		ttl, ok := c.TTL(key)
+		if ok != (i%2 == 0) {
+			t.Errorf("incorrect existence of record %d: got: %v expected: %v", i, ok, i%2 == 0)
+		}
+		if ok && ttl <= time.Minute {
+			t.Errorf("ttl of record %d was not extended: got: %v", i, ttl)
+		}
+	}
+	if n := len(events); n != 50 {
+		t.Errorf("incorrect number of events: got: %d expected: %d", n, 50)
+	}
+}
+
+func TestCache_GetAndDelete(t *testing.T) {
+	key := StringKey("key")
+	c := New(time.Hour)
+	defer c.Close()
+	c.Set(key, "value", time.Minute)
+
+	val, ok := c.GetAndDelete(key)
+	if !ok || val != "value" {
+		t.Errorf("incorrect value: got: %v expected: %v", val, "value")
+	}
+	if _, ok := c.Get(key); ok {
+		t.Error("record was not removed")
+	}
+	if _, ok := c.GetAndDelete(key); ok {
+		t.Error("record was removed twice")
+	}
+}
+
+func TestCache_Remove(t *testing.T) {
+	c := New(time.Hour, WithTombstones(time.Hour))
+	defer c.Close()
+	c.Set(IntKey(1), "value", time.Minute)
+	c.SetNotFound(IntKey(2), time.Minute)
+
+	if !c.Remove(IntKey(1)) {
+		t.Error("Remove missed an existing key")
+	}
+	if _, ok := c.Get(IntKey(1)); ok {
+		t.Error("record was not removed")
+	}
+	for _, key := range []uint64{IntKey(1), IntKey(2), IntKey(3)} {
+		if c.Remove(key) {
+			t.Errorf("Remove reported key %d without a value", key)
+		}
+	}
+}
+
+func TestCache_GetMany(t *testing.T) {
+	c := New(time.Hour)
+	defer c.Close()
+	c.Set(IntKey(1), 1, time.Hour)
+	c.Set(IntKey(2), 2, time.Hour)
+
+	values := c.GetMany([]uint64{IntKey(1), IntKey(2), IntKey(3)})
+	if len(values) != 2 {
+		t.Errorf("incorrect number of values: got: %d expected: %d", len(values), 2)
+	}
+	if values[IntKey(2)] != 2 {
+		t.Errorf("incorrect value: got: %v expected: %v", values[IntKey(2)], 2)
+	}
+}
+
+func TestCache_DeleteExpired(t *testing.T) {
+	c := New(time.Hour)
+	defer c.Close()
+	c.Set(IntKey(1), 1, time.Hour)
+	c.Set(IntKey(2), 2, -time.Second)
+	c.Set(IntKey(3), 3, -time.Second)
+
+	if n := c.DeleteExpired(); n != 2 {
+		t.Errorf("incorrect number of removed records: got: %d expected: %d", n, 2)
+	}
+	if _, ok := c.Get(IntKey(1)); !ok {
+		t.Error("live record was removed")
+	}
+}
+
+func TestCache_DeleteExpiredBefore(t *testing.T) {
+	c := New(0)
+	defer c.Close()
+	c.Set(IntKey(1), 1, time.Hour)
+	c.Set(IntKey(2), 2, time.Minute)
+
+	if n := c.DeleteExpiredBefore(time.Now()); n != 0 {
+		t.Errorf("incorrect number of removed records: got: %d expected: %d", n, 0)
+	}
+	if n := c.DeleteExpiredBefore(time.Now().Add(30 * time.Minute)); n != 1 {
+		t.Errorf("incorrect number of removed records: got: %d expected: %d", n, 1)
+	}
+	if _, ok := c.Get(IntKey(1)); !ok {
+		t.Error("live record was removed")
+	}
+}
+
+func TestCache_ExpiringBefore(t *testing.T) {
+	clock := &fixedClock{now: time.Unix(1000, 0)}
+	c := New(0, WithClock(clock))
+	defer c.Close()
+	c.Set(IntKey(1), 1, time.Hour)
+	c.Set(IntKey(2), 2, time.Minute)
+	c.Set(IntKey(3), 3, time.Second)
+	c.Set(IntKey(4), 4, 48*time.Hour)
+	clock.now = clock.now.Add(2 * time.Second)
+
+	keys := c.ExpiringBefore(clock.now.Add(10 * time.Minute))
+	if len(keys) != 1 || keys[0] != IntKey(2) {
+		t.Errorf("incorrect expiring keys: got: %v expected: %v", keys, []uint64{IntKey(2)})
+	}
+	if keys := c.ExpiringBefore(clock.now.Add(24 * time.Hour)); len(keys) != 2 {
+		t.Errorf("incorrect number of expiring keys: got: %d expected: %d", len(keys), 2)
+	}
+}
+
+func TestCache_Compact(t *testing.T) {
+	c := New(0)
+	defer c.Close()
+	for i := 0; i < 100000; i++ {
+		c.Set(IntKey(i), i, time.Hour)
+	}
+	if n := c.Compact(); n != 0 {
+		t.Errorf("full shards were compacted: got: %d", n)
+	}
+
+	for i := 10; i < 100000; i++ {
+		c.Delete(IntKey(i))
+	}
+	if n := c.Compact(); n != len(c.shards.list) {
+		t.Errorf("incorrect number of compacted shards: got: %d expected: %d", n, len(c.shards.list))
+	}
+	for i := 0; i < 10; i++ {
+		if v, ok := c.Get(IntKey(i)); !ok || v != i {
+			t.Errorf("record lost by Compact: got: %v expected: %v", v, i)
+		}
+	}
+	if n := c.Compact(); n != 0 {
+		t.Errorf("compacted shards were compacted again: got: %d", n)
+	}
+
+	c.Reserve(100000)
+	for i := 0; i < 100000; i++ {
+		c.Set(IntKey(i), i, time.Hour)
+	}
+	c.Clear()
+	if n := c.Compact(); n != 0 {
+		t.Errorf("reserved shards were compacted: got: %d", n)
+	}
+}
+
+// Random writes, deletes and cleanups checked against a map.
+func TestCache_Model(t *testing.T) {
+	type record struct {
+		value   int
+		expired bool
+	}
+	c := New(0, WithShardCount(2))
+	defer c.Close()
+	model := make(map[uint64]record)
+	rnd := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 100000; i++ {
+		key := uint64(rnd.Intn(1000))
+		switch rnd.Intn(10) {
+		case 0, 1:
+			c.Delete(key)
+			delete(model, key)
+		case 2:
+			c.Set(key, i, -time.Second)
+			model[key] = record{value: i, expired: true}
+		case 3:
+			c.DeleteExpired()
+			for k, r := range model {
+				if r.expired {
+					delete(model, k)
+				}
+			}
+		default:
+			c.Set(key, i, time.Hour)
+			model[key] = record{value: i}
+		}
+	}
+	if n := c.Stats().Entries; n != len(model) {
+		t.Errorf("incorrect number of records: got: %d expected: %d", n, len(model))
+	}
+	for key, r := range model {
+		if v, ok := c.Get(key); !ok || v != r.value {
+			t.Errorf("incorrect value for key %d: got: %v expected: %v", key, v, r.value)
+		}
+	}
+}
+
+func TestCache_Closed(t *testing.T) {
+	c := New(time.Hour, WithWriteBuffer(16))
+	c.Set(IntKey(1), 1, time.Hour)
+	if err := c.Close(); err != nil {
+		t.Errorf("incorrect close error: got: %v expected: %v", err, nil)
+	}
+	if err := c.Close(); err != ErrClosed {
+		t.Errorf("incorrect second close error: got: %v expected: %v", err, ErrClosed)
+	}
+
+	c.Set(IntKey(2), 2, time.Hour)
+	c.SetAsync(IntKey(3), 3, time.Hour)
+	c.Flush()
+	c.Merge(New(0), Overwrite)
+	for _, key := range []uint64{IntKey(1), IntKey(2), IntKey(3)} {
+		if v, ok := c.Get(key); ok {
+			t.Errorf("incorrect value after close: got: %v expected: none", v)
+		}
+	}
+	if c.Expire(IntKey(1), time.Hour) {
+		t.Error("record expired after close")
+	}
+	if st := c.Stats(); st.Entries != 0 {
+		t.Errorf("incorrect entries after close: got: %d expected: %d", st.Entries, 0)
+	}
+	if err := c.Snapshot(io.Discard, GobCodec{}); err != ErrClosed {
+		t.Errorf("incorrect snapshot error: got: %v expected: %v", err, ErrClosed)
+	}
+	if _, err := c.Restore(strings.NewReader(""), GobCodec{}); err != ErrClosed {
+		t.Errorf("incorrect restore error: got: %v expected: %v", err, ErrClosed)
+	}
+	events, _ := c.Subscribe(1)
+	if _, ok := <-events; ok {
+		t.Error("subscription to a closed cache is open")
+	}
+}
@@ -0,0 +1,117 @@
+// Package ttltest helps testing code using a cache without real sleeps.
+//
+// A Harness runs a cache on a fake Clock with the cleanup manager disabled:
+// time only moves with AdvanceTime, which removes the outdated records before
+// returning, so ttl behavior is deterministic.
+//
+//	h := ttltest.New(t)
+//	h.Cache.Set(key, "value", time.Minute)
+//	h.AdvanceTime(time.Minute + time.Second)
+//	h.AssertMissing(key)
+package ttltest
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	ttlcache "github.com/loicalleyne/ttlswisscache"
+)
+
+// Start is the time of a new Clock.
+var Start = time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// Clock is a ttlcache.Clock moving only when told to.
+type Clock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewClock returns a clock set to Start.
+func NewClock() *Clock {
+	return &Clock{now: Start}
+}
+
+// Now returns the time of the clock.
+func (k *Clock) Now() time.Time {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return k.now
+}
+
+// Advance moves the clock forward by d.
+func (k *Clock) Advance(d time.Duration) {
+	k.mu.Lock()
+	k.now = k.now.Add(d)
+	k.mu.Unlock()
+}
+
+// Harness is a cache on a fake clock with assertions over its contents.
+type Harness struct {
+	Cache *ttlcache.Cache
+	Clock *Clock
+	tb    testing.TB
+}
+
+// New returns a harness whose cache is closed when the test ends.
+// opts are applied after the clock and a disabled cleanup manager,
+// the cleanup manager must stay disabled for AdvanceTime to be deterministic.
+func New(tb testing.TB, opts ...ttlcache.Option) *Harness {
+	clock := NewClock()
+	opts = append([]ttlcache.Option{ttlcache.WithResolution(0), ttlcache.WithClock(clock)}, opts...)
+	h := &Harness{Cache: ttlcache.NewCache(opts...), Clock: clock, tb: tb}
+	tb.Cleanup(func() { h.Cache.Close() })
+	return h
+}
+
+// AdvanceTime moves the clock forward by d and removes the records whose
+// deadline is now in the past, like the cleanup manager would. It returns
+// their number.
+func (h *Harness) AdvanceTime(d time.Duration) int {
+	h.Clock.Advance(d)
+	return h.Cache.DeleteExpired()
+}
+
+// AssertValue reports an error unless the cache holds a value deeply equal to want under key.
+func (h *Harness) AssertValue(key uint64, want interface{}) {
+	h.tb.Helper()
+	got, ok := h.Cache.Get(key)
+	if !ok {
+		h.tb.Errorf("missing value for key %d: expected: %v", key, want)
+		return
+	}
+	if !reflect.DeepEqual(got, want) {
+		h.tb.Errorf("incorrect value for key %d: got: %v expected: %v", key, got, want)
+	}
+}
+
+// AssertMissing reports an error if the cache holds a value under key.
+func (h *Harness) AssertMissing(key uint64) {
+	h.tb.Helper()
+	if got, ok := h.Cache.Get(key); ok {
+		h.tb.Errorf("incorrect value for key %d: got: %v expected: none", key, got)
+	}
+}
+
+// AssertTTL reports an error unless the record of key has want left to live.
+// The clock only moves with AdvanceTime, so the ttl is exact.
+func (h *Harness) AssertTTL(key uint64, want time.Duration) {
+	h.tb.Helper()
+	got, ok := h.Cache.TTL(key)
+	if !ok {
+		h.tb.Errorf("missing record for key %d: expected ttl: %v", key, want)
+		return
+	}
+	if got != want {
+		h.tb.Errorf("incorrect ttl for key %d: got: %v expected: %v", key, got, want)
+	}
+}
+
+// AssertLen reports an error unless the cache holds n records.
+func (h *Harness) AssertLen(n int) {
+	h.tb.Helper()
+	if got := h.Cache.Stats().Entries; got != n {
+		h.tb.Errorf("incorrect number of records: got: %d expected: %d", got, n)
+	}
+}
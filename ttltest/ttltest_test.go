@@ -0,0 +1,57 @@
+package ttltest
+
+import (
+	"testing"
+	"time"
+
+	ttlcache "github.com/loicalleyne/ttlswisscache"
+)
+
+func TestHarness(t *testing.T) {
+	h := New(t)
+	key := ttlcache.StringKey("key")
+	h.Cache.Set(key, "value", time.Minute)
+	h.Cache.Set(ttlcache.IntKey(1), 1, time.Hour)
+
+	h.AssertValue(key, "value")
+	h.AssertTTL(key, time.Minute)
+	h.AssertLen(2)
+
+	if n := h.AdvanceTime(time.Minute); n != 0 {
+		t.Errorf("incorrect number of expired records: got: %d expected: %d", n, 0)
+	}
+	h.AssertTTL(key, 0)
+	if n := h.AdvanceTime(time.Nanosecond); n != 1 {
+		t.Errorf("incorrect number of expired records: got: %d expected: %d", n, 1)
+	}
+	h.AssertMissing(key)
+	h.AssertTTL(ttlcache.IntKey(1), time.Hour-time.Minute-time.Nanosecond)
+	h.AssertLen(1)
+}
+
+type recorder struct {
+	testing.TB
+	errors int
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(string, ...interface{}) {
+	r.errors++
+}
+
+func TestHarness_Failures(t *testing.T) {
+	r := &recorder{TB: t}
+	h := New(r)
+	h.Cache.Set(ttlcache.IntKey(1), 1, time.Minute)
+
+	h.AssertValue(ttlcache.IntKey(1), 2)
+	h.AssertValue(ttlcache.IntKey(2), 2)
+	h.AssertMissing(ttlcache.IntKey(1))
+	h.AssertTTL(ttlcache.IntKey(1), time.Hour)
+	h.AssertTTL(ttlcache.IntKey(2), time.Hour)
+	h.AssertLen(2)
+	if r.errors != 6 {
+		t.Errorf("incorrect number of errors: got: %d expected: %d", r.errors, 6)
+	}
+}
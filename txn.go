@@ -0,0 +1,186 @@
+package ttlswisscache
+
+import (
+	"errors"
+	"slices"
+	"time"
+)
+
+// maxTxnAttempts bounds the number of times Txn runs a conflicting transaction.
+const maxTxnAttempts = 10
+
+// ErrTxnConflict is returned by Txn when the shards it read kept changing.
+var ErrTxnConflict = errors.New("ttlswisscache: transaction conflict")
+
+// ErrImmutable is returned by Txn and TypedView.Set when they set a key
+// holding a live immutable record or tombstone, see SetImmutable and
+// WithTombstones.
+var ErrImmutable = errors.New("ttlswisscache: key is immutable")
+
+// Txn is a transaction of Cache.Txn.
+// It must not be used after the function it was passed to returns.
+type Txn struct {
+	cache  *Cache
+	reads  map[int]uint64 // Versions of the shards read by the transaction.
+	writes map[uint64]txnWrite
+	order  []uint64 // Written keys in the order of the writes.
+}
+
+type txnWrite struct {
+	it      item
+	deleted bool
+	taken   bool // The SetOnce value read by the transaction is handed over.
+}
+
+// Txn runs fn and applies the sets and deletes it made atomically: other
+// goroutines see either none or all of them. Nothing is applied if fn returns
+// an error, which Txn returns.
+//
+// Transactions are optimistic. Writes are buffered until fn returns, reads see
+// the writes of the transaction and the records of the cache. Txn then locks the
+// shards involved and checks that no shard read by fn has changed in the
+// meantime, or runs fn again, so fn must be safe to retry. Changes are tracked
+// per shard, not per key, so writes to other keys of those shards count as
+// conflicts too. After maxTxnAttempts attempts Txn returns ErrTxnConflict.
+// Nothing is applied either if fn sets a key refusing writes, Txn returns
+// ErrImmutable.
+func (c *Cache) Txn(fn func(tx *Txn) error) error {
+	for attempt := 0; attempt < maxTxnAttempts; attempt++ {
+		if c.closing.Load() {
+			return ErrClosed
+		}
+		tx := &Txn{cache: c, reads: make(map[int]uint64), writes: make(map[uint64]txnWrite)}
+		if err := fn(tx); err != nil {
+			return err
+		}
+		if err := tx.commit(); err != ErrTxnConflict {
+			c.cascade()
+			return err
+		}
+	}
+	return ErrTxnConflict
+}
+
+func (tx *Txn) shard(key uint64) (int, *shard) {
+	i := int(tx.cache.shards.hash(key) >> tx.cache.shards.shift)
+	return i, tx.cache.shards.list[i]
+}
+
+// Get returns stored record like Cache.Get, including writes of the transaction.
+func (tx *Txn) Get(key uint64) (interface{}, bool) {
+	if w, ok := tx.writes[key]; ok {
+		if w.deleted {
+			return nil, false
+		}
+		return tx.cache.clone(w.it.value), true
+	}
+	i, s := tx.shard(key)
+	s.RLock()
+	it, ok := s.get(key)
+	version := s.version
+	s.RUnlock()
+	if seen, read := tx.reads[i]; !read || seen > version {
+		tx.reads[i] = version
+	}
+	var value interface{}
+	if ok && !tx.cache.quarantined(it) {
+		value, ok = it.load()
+	} else {
+		ok = false
+	}
+	if !ok {
+		return nil, false
+	}
+	if _, once := it.value.(*onceValue); once {
+		// The record is consumed on commit, the read shard can't change meanwhile.
+		tx.write(key, txnWrite{deleted: true, taken: true})
+		return value, true
+	}
+	return tx.cache.clone(value), true
+}
+
+// Set adds value to the cache with given ttl when the transaction is applied.
+func (tx *Txn) Set(key uint64, value interface{}, ttl time.Duration) {
+	value, ok := tx.cache.limit(key, value)
+	if !ok {
+		tx.Delete(key)
+		return
+	}
+	it := item{deadline: tx.cache.clock.unixNano() + int64(ttl), value: tx.cache.clone(value)}
+	tx.write(key, txnWrite{it: it})
+}
+
+// Delete removes record from storage when the transaction is applied.
+func (tx *Txn) Delete(key uint64) {
+	tx.write(key, txnWrite{deleted: true})
+}
+
+func (tx *Txn) write(key uint64, w txnWrite) {
+	if _, ok := tx.writes[key]; !ok {
+		tx.order = append(tx.order, key)
+	}
+	tx.writes[key] = w
+}
+
+// commit applies the writes unless a shard read by the transaction has changed,
+// in which case it returns ErrTxnConflict, or a written key refuses writes, in
+// which case it returns ErrImmutable.
+func (tx *Txn) commit() error {
+	c := tx.cache
+	locked := make([]int, 0, len(tx.reads)+len(tx.order))
+	for i := range tx.reads {
+		locked = append(locked, i)
+	}
+	for _, key := range tx.order {
+		i, _ := tx.shard(key)
+		locked = append(locked, i)
+	}
+	// Shards are locked in index order, so transactions can't deadlock.
+	slices.Sort(locked)
+	locked = slices.Compact(locked)
+	for _, i := range locked {
+		c.shards.list[i].Lock()
+	}
+	defer func() {
+		for _, i := range locked {
+			c.shards.list[i].Unlock()
+		}
+	}()
+
+	for i, version := range tx.reads {
+		if c.shards.list[i].version != version {
+			return ErrTxnConflict
+		}
+	}
+	if c.closing.Load() {
+		return ErrClosed
+	}
+	// Check all the writes first, the transaction is applied whole or not at all.
+	for _, key := range tx.order {
+		_, s := tx.shard(key)
+		if !tx.writes[key].deleted && c.protected(s, key) {
+			return ErrImmutable
+		}
+	}
+	for _, key := range tx.order {
+		w := tx.writes[key]
+		_, s := tx.shard(key)
+		if !w.deleted {
+			s.put(key, w.it)
+			s.stats.sets.Add(1)
+			c.subs.publish(OpSet, key, w.it)
+			continue
+		}
+		if w.taken {
+			if i, ok := s.index.Get(key); ok {
+				c.remove(s, key, item{deadline: s.deadlines[i], value: s.values[i]})
+			}
+			continue
+		}
+		if it, ok := s.delete(key); ok {
+			s.stats.deletes.Add(1)
+			c.subs.publish(OpDelete, key, it)
+		}
+	}
+	return nil
+}
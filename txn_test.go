@@ -0,0 +1,102 @@
+package ttlswisscache
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCache_Txn(t *testing.T) {
+	c := New(0)
+	defer c.Close()
+	c.Set(IntKey(1), "old session", time.Hour)
+
+	err := c.Txn(func(tx *Txn) error {
+		if v, ok := tx.Get(IntKey(1)); !ok || v != "old session" {
+			t.Errorf("incorrect value: got: %v, %v expected: old session, true", v, ok)
+		}
+		tx.Set(IntKey(1), "session", time.Hour)
+		tx.Set(IntKey(2), "refresh token", time.Hour)
+		tx.Delete(IntKey(3))
+		if v, ok := tx.Get(IntKey(1)); !ok || v != "session" {
+			t.Errorf("transaction doesn't read its writes: got: %v, %v expected: session, true", v, ok)
+		}
+		if _, ok := c.Get(IntKey(2)); ok {
+			t.Error("write is visible before commit")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("incorrect error: got: %v expected: %v", err, nil)
+	}
+	for key, expected := range map[uint64]string{IntKey(1): "session", IntKey(2): "refresh token"} {
+		if v, _ := c.Get(key); v != expected {
+			t.Errorf("incorrect value: got: %v expected: %v", v, expected)
+		}
+	}
+}
+
+func TestCache_Txn_Error(t *testing.T) {
+	c := New(0)
+	defer c.Close()
+	failed := errors.New("failed")
+
+	err := c.Txn(func(tx *Txn) error {
+		tx.Set(IntKey(1), 1, time.Hour)
+		return failed
+	})
+	if err != failed {
+		t.Errorf("incorrect error: got: %v expected: %v", err, failed)
+	}
+	if _, ok := c.Get(IntKey(1)); ok {
+		t.Error("write of a failed transaction was applied")
+	}
+}
+
+func TestCache_Txn_Conflict(t *testing.T) {
+	c := New(0)
+	defer c.Close()
+	c.Set(IntKey(1), 0, time.Hour)
+	c.Set(IntKey(2), 0, time.Hour)
+
+	// Move units between two keys, the sum must stay zero.
+	const n = 50
+	var wg sync.WaitGroup
+	wg.Add(n)
+	for i := 0; i < n; i++ {
+		go func() {
+			defer wg.Done()
+			for {
+				err := c.Txn(func(tx *Txn) error {
+					a, _ := tx.Get(IntKey(1))
+					b, _ := tx.Get(IntKey(2))
+					tx.Set(IntKey(1), a.(int)+1, time.Hour)
+					tx.Set(IntKey(2), b.(int)-1, time.Hour)
+					return nil
+				})
+				if err != ErrTxnConflict {
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	a, _ := c.Get(IntKey(1))
+	b, _ := c.Get(IntKey(2))
+	if a != n || b != -n {
+		t.Errorf("incorrect values: got: %v, %v expected: %v, %v", a, b, n, -n)
+	}
+
+	attempts := 0
+	err := c.Txn(func(tx *Txn) error {
+		attempts++
+		tx.Get(IntKey(1))
+		c.Set(IntKey(1), 0, time.Hour) // Changes the shard on every attempt.
+		return nil
+	})
+	if err != ErrTxnConflict || attempts != maxTxnAttempts {
+		t.Errorf("incorrect conflict: got: %v after %d attempts expected: %v after %d", err, attempts, ErrTxnConflict, maxTxnAttempts)
+	}
+}
@@ -0,0 +1,215 @@
+package ttlswisscache
+
+import (
+	"reflect"
+	"time"
+	"unsafe"
+)
+
+// Sizer estimates the size of a value in bytes. A negative size means unknown.
+type Sizer func(key uint64, value interface{}) int64
+
+// SizePolicy tells what happens to values over WithMaxValueSize.
+type SizePolicy uint8
+
+const (
+	// RejectOversized doesn't store oversized values.
+	RejectOversized SizePolicy = iota
+	// TruncateOversized cuts oversized strings and byte slices to the maximum
+	// size and rejects other oversized values. Strings and byte slices no
+	// longer than the maximum size, oversized by the estimate of the Sizer
+	// only, are stored whole.
+	TruncateOversized
+)
+
+// WithSizer sets the estimator of value sizes used by WithMaxValueSize and
+// ShardReport, see Sizer. The default one knows the size of strings and byte
+// slices only, EstimateSize and ReflectSizer are built-in alternatives.
+func WithSizer(sizer Sizer) Option {
+	return func(o *options) {
+		o.sizer = sizer
+	}
+}
+
+// WithMaxValueSize limits values to size bytes, as estimated by the Sizer,
+// so a single runaway value can't take up the memory. Values of unknown size
+// are stored. Oversized values are handled by policy and counted in
+// Stats.Oversized. When a value is rejected Set returns version 0 and removes
+// the key, so its previous value isn't served, without leaving a tombstone of
+// WithTombstones; SetAsync only drops the write.
+func WithMaxValueSize(size int64, policy SizePolicy) Option {
+	return func(o *options) {
+		o.maxValueSize = size
+		o.sizePolicy = policy
+	}
+}
+
+// limit applies WithMaxValueSize to the value of key. It reports false if the
+// value must not be stored.
+func (c *Cache) limit(key uint64, value interface{}) (interface{}, bool) {
+	if c.opts.maxValueSize <= 0 {
+		return value, true
+	}
+	if size := c.size(key, value); size <= c.opts.maxValueSize {
+		return value, true
+	}
+	c.shards.get(key).stats.oversized.Add(1)
+	if c.opts.sizePolicy == TruncateOversized {
+		// The Sizer estimate may exceed the length, truncate by the length.
+		switch v := value.(type) {
+		case string:
+			n := min(int64(len(v)), c.opts.maxValueSize)
+			return v[:n], true
+		case []byte:
+			n := min(int64(len(v)), c.opts.maxValueSize)
+			return v[:n:n], true
+		}
+	}
+	return nil, false
+}
+
+// size returns the size of value estimated by the Sizer.
+func (c *Cache) size(key uint64, value interface{}) int64 {
+	if c.opts.sizer == nil {
+		return sizeOf(key, value)
+	}
+	return c.opts.sizer(key, value)
+}
+
+// sizeOf is the default Sizer.
+func sizeOf(_ uint64, value interface{}) int64 {
+	switch v := value.(type) {
+	case string:
+		return int64(len(v))
+	case []byte:
+		return int64(len(v))
+	}
+	return -1
+}
+
+// Sizeable is implemented by values knowing their size in bytes, see EstimateSize.
+type Sizeable interface {
+	Size() int64
+}
+
+// EstimateSize is a Sizer knowing the size of strings, byte slices, numbers,
+// booleans, times, durations, string and byte slice slices, and of Sizeable
+// values. Other values are of unknown size. Sizes count the data of the
+// values, not the headers of strings and slices.
+func EstimateSize(_ uint64, value interface{}) int64 {
+	switch v := value.(type) {
+	case Sizeable:
+		return v.Size()
+	case string:
+		return int64(len(v))
+	case []byte:
+		return int64(len(v))
+	case bool, int8, uint8:
+		return 1
+	case int16, uint16:
+		return 2
+	case int32, uint32, float32:
+		return 4
+	case int, int64, uint, uint64, uintptr, float64, complex64, time.Duration:
+		return 8
+	case complex128:
+		return 16
+	case time.Time:
+		return int64(unsafe.Sizeof(v))
+	case []string:
+		n := int64(0)
+		for _, s := range v {
+			n += int64(len(s))
+		}
+		return n
+	case [][]byte:
+		n := int64(0)
+		for _, b := range v {
+			n += int64(len(b))
+		}
+		return n
+	}
+	return -1
+}
+
+// ReflectSizer is a Sizer walking values with reflection, for structs, maps
+// and nested values EstimateSize doesn't know. It counts the memory a value
+// references: string and slice data up to their capacity, map entries and
+// pointed values, each pointer followed once. A Sizeable value reports its own
+// size, nested ones are walked too. Map overhead, channels and functions aren't counted. It is
+// proportional to the size of the value, prefer a Sizer of your own for large
+// values on hot paths.
+func ReflectSizer(_ uint64, value interface{}) int64 {
+	if value == nil {
+		return 0
+	}
+	if v, ok := value.(Sizeable); ok {
+		return v.Size()
+	}
+	v := reflect.ValueOf(value)
+	return int64(v.Type().Size()) + indirectSize(v, make(map[uintptr]struct{}))
+}
+
+// indirectSize returns the size of the memory referenced by v, not counting v itself.
+func indirectSize(v reflect.Value, seen map[uintptr]struct{}) int64 {
+	switch v.Kind() {
+	case reflect.String:
+		return int64(v.Len())
+	case reflect.Slice:
+		if v.IsNil() || !visit(v.Pointer(), seen) {
+			return 0
+		}
+		n := int64(v.Cap()) * int64(v.Type().Elem().Size())
+		for i := 0; i < v.Len(); i++ {
+			n += indirectSize(v.Index(i), seen)
+		}
+		return n
+	case reflect.Array:
+		n := int64(0)
+		for i := 0; i < v.Len(); i++ {
+			n += indirectSize(v.Index(i), seen)
+		}
+		return n
+	case reflect.Struct:
+		n := int64(0)
+		for i := 0; i < v.NumField(); i++ {
+			n += indirectSize(v.Field(i), seen)
+		}
+		return n
+	case reflect.Map:
+		if v.IsNil() || !visit(v.Pointer(), seen) {
+			return 0
+		}
+		t := v.Type()
+		n := int64(v.Len()) * int64(t.Key().Size()+t.Elem().Size())
+		for it := v.MapRange(); it.Next(); {
+			n += indirectSize(it.Key(), seen) + indirectSize(it.Value(), seen)
+		}
+		return n
+	case reflect.Pointer:
+		if v.IsNil() || !visit(v.Pointer(), seen) {
+			return 0
+		}
+		return int64(v.Type().Elem().Size()) + indirectSize(v.Elem(), seen)
+	case reflect.Interface:
+		if v.IsNil() {
+			return 0
+		}
+		e := v.Elem()
+		n := indirectSize(e, seen)
+		if e.Kind() != reflect.Pointer {
+			n += int64(e.Type().Size()) // Boxed out of line.
+		}
+		return n
+	}
+	return 0
+}
+
+// visit reports whether the memory at p is seen for the first time.
+func visit(p uintptr, seen map[uintptr]struct{}) bool {
+	if _, ok := seen[p]; ok {
+		return false
+	}
+	seen[p] = struct{}{}
+	return true
+}
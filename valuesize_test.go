@@ -0,0 +1,161 @@
+package ttlswisscache
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWithMaxValueSize(t *testing.T) {
+	c := New(0, WithMaxValueSize(4, RejectOversized))
+	defer c.Close()
+
+	if v := c.Set(IntKey(1), "abcd", time.Hour); v == 0 {
+		t.Error("value of the maximum size was rejected")
+	}
+	if v := c.Set(IntKey(1), "abcde", time.Hour); v != 0 {
+		t.Errorf("incorrect version of an oversized value: got: %d expected: %d", v, 0)
+	}
+	if v, ok := c.Get(IntKey(1)); ok {
+		t.Errorf("previous value was kept: got: %v", v)
+	}
+	c.SetWithTags(IntKey(2), []byte("abcde"), time.Hour, "t")
+	if _, ok := c.Get(IntKey(2)); ok {
+		t.Error("oversized tagged value was stored")
+	}
+	c.Set(IntKey(3), 12345, time.Hour)
+	if _, ok := c.Get(IntKey(3)); !ok {
+		t.Error("value of unknown size was rejected")
+	}
+	if n := c.Stats().Oversized; n != 2 {
+		t.Errorf("incorrect number of oversized values: got: %d expected: %d", n, 2)
+	}
+}
+
+func TestWithMaxValueSize_Tombstones(t *testing.T) {
+	c := New(0, WithMaxValueSize(4, RejectOversized), WithTombstones(time.Hour))
+	defer c.Close()
+	c.Set(IntKey(1), "abcd", time.Hour)
+
+	if v := c.Set(IntKey(1), "abcde", time.Hour); v != 0 {
+		t.Errorf("incorrect version of an oversized value: got: %d expected: %d", v, 0)
+	}
+	if v, ok := c.Get(IntKey(1)); ok {
+		t.Errorf("previous value was kept: got: %v", v)
+	}
+	if v := c.Set(IntKey(1), "ok", time.Hour); v == 0 {
+		t.Error("rejected value left a tombstone")
+	}
+	if v, ok := c.Get(IntKey(1)); !ok || v != "ok" {
+		t.Errorf("incorrect value: got: %v, %v expected: %v", v, ok, "ok")
+	}
+
+	c.Delete(IntKey(2))
+	c.Set(IntKey(2), "abcde", time.Hour)
+	if v := c.Set(IntKey(2), "ok", time.Hour); v != 0 {
+		t.Error("rejected value removed a tombstone")
+	}
+}
+
+func TestWithMaxValueSize_Truncate(t *testing.T) {
+	sizer := func(_ uint64, value interface{}) int64 {
+		if _, ok := value.([]int); ok {
+			return 100
+		}
+		return sizeOf(0, value)
+	}
+	c := New(0, WithMaxValueSize(4, TruncateOversized), WithSizer(sizer))
+	defer c.Close()
+
+	c.Set(IntKey(1), "abcdef", time.Hour)
+	if v, _ := c.Get(IntKey(1)); v != "abcd" {
+		t.Errorf("incorrect truncated string: got: %v expected: %v", v, "abcd")
+	}
+	c.Set(IntKey(2), []byte("abcdef"), time.Hour)
+	if v, _ := c.Get(IntKey(2)); string(v.([]byte)) != "abcd" {
+		t.Errorf("incorrect truncated bytes: got: %s expected: %s", v, "abcd")
+	}
+	c.Set(IntKey(3), []int{1}, time.Hour)
+	if _, ok := c.Get(IntKey(3)); ok {
+		t.Error("oversized value that can't be truncated was stored")
+	}
+}
+
+type sized struct{ n int64 }
+
+func (s sized) Size() int64 { return s.n }
+
+func TestWithMaxValueSize_TruncateEstimate(t *testing.T) {
+	// The header of the value counts, so short values are oversized too.
+	sizer := func(_ uint64, value interface{}) int64 { return ReflectSizer(0, value) + 16 }
+	c := New(0, WithMaxValueSize(20, TruncateOversized), WithSizer(sizer))
+	defer c.Close()
+
+	c.Set(IntKey(1), "0123456789", time.Hour)
+	if v, _ := c.Get(IntKey(1)); v != "0123456789" {
+		t.Errorf("incorrect string shorter than the limit: got: %v expected: %v", v, "0123456789")
+	}
+	c.Set(IntKey(2), []byte("0123456789"), time.Hour)
+	if v, _ := c.Get(IntKey(2)); string(v.([]byte)) != "0123456789" {
+		t.Errorf("incorrect bytes shorter than the limit: got: %s expected: %s", v, "0123456789")
+	}
+	c.Set(IntKey(3), strings.Repeat("x", 30), time.Hour)
+	if v, _ := c.Get(IntKey(3)); v != strings.Repeat("x", 20) {
+		t.Errorf("incorrect truncated string: got: %v expected: %v", v, strings.Repeat("x", 20))
+	}
+}
+
+func TestEstimateSize(t *testing.T) {
+	for _, tc := range []struct {
+		value interface{}
+		size  int64
+	}{
+		{"abc", 3},
+		{[]byte("ab"), 2},
+		{int64(1), 8},
+		{int32(1), 4},
+		{true, 1},
+		{time.Second, 8},
+		{time.Time{}, 24},
+		{[]string{"ab", "cde"}, 5},
+		{sized{42}, 42},
+		{struct{}{}, -1},
+	} {
+		if n := EstimateSize(0, tc.value); n != tc.size {
+			t.Errorf("incorrect size of %#v: got: %d expected: %d", tc.value, n, tc.size)
+		}
+	}
+}
+
+func TestReflectSizer(t *testing.T) {
+	type node struct {
+		Name string
+		Tags []string
+		Next *node
+	}
+	n := &node{Name: "abcd", Tags: make([]string, 1, 2)}
+	n.Tags[0] = "xy"
+	n.Next = n // Cycles are followed once.
+	nodeSize := int64(16 + 24 + 8)
+	want := 8 + nodeSize + 4 + 2*16 + 2
+	if size := ReflectSizer(0, n); size != want {
+		t.Errorf("incorrect size of a struct: got: %d expected: %d", size, want)
+	}
+
+	m := map[string]int64{"a": 1, "bc": 2}
+	if size, want := ReflectSizer(0, m), int64(8+2*(16+8)+3); size != want {
+		t.Errorf("incorrect size of a map: got: %d expected: %d", size, want)
+	}
+	if size := ReflectSizer(0, sized{7}); size != 7 {
+		t.Errorf("incorrect size of a Sizeable value: got: %d expected: %d", size, 7)
+	}
+
+	c := New(0, WithSizer(ReflectSizer), WithMaxValueSize(64, RejectOversized))
+	defer c.Close()
+	if c.Set(IntKey(1), m, time.Hour) == 0 {
+		t.Error("small map was rejected")
+	}
+	if c.Set(IntKey(2), make([]int64, 16), time.Hour) != 0 {
+		t.Error("large slice was stored")
+	}
+}
@@ -0,0 +1,193 @@
+package ttlswisscache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrCorrupted is wrapped by the errors of Verify.
+var ErrCorrupted = errors.New("ttlswisscache: corrupted")
+
+// WithVerify runs Verify after every sweep of the cleanup manager and calls
+// onError with its error, if any, e.g. to log it with a stack dump or panic in
+// tests. Verification read locks every shard at once and visits every record,
+// so it is meant for tracking down corruption, not for production traffic.
+// It runs with the pprof label "phase": "verify".
+func WithVerify(onError func(err error)) Option {
+	return func(o *options) {
+		o.verify = onError
+	}
+}
+
+// verifier is implemented by the built-in eviction policies to check their
+// bookkeeping of n records, see Verify.
+type verifier interface {
+	verify(n uint32) error
+}
+
+// Verify checks the internal invariants of the cache and returns an error
+// wrapping ErrCorrupted describing the first violation found:
+//
+//   - the index of every shard maps each key to its slot and nothing else;
+//   - no record is outdated for longer than the grace period and two
+//     resolutions, which the running cleanup manager would have removed;
+//   - bounded shards hold at most their share of WithMaxEntries records and
+//     the built-in eviction policies track exactly the records of their shard;
+//   - the bloom filter, tags and namespace accounting match the records.
+//
+// Every shard is read locked for the whole check, so the records of all shards
+// are checked at a single point in time and writers are blocked meanwhile.
+func (c *Cache) Verify() error {
+	for _, s := range c.shards.list {
+		s.RLock()
+	}
+	defer func() {
+		for _, s := range c.shards.list {
+			s.RUnlock()
+		}
+	}()
+
+	now := c.clock.unixNano()
+	late := int64(-1)
+	if c.cleanup.running.Load() && !c.cleanup.paused.Load() {
+		late = int64(c.opts.grace + 2*c.opts.resolution)
+	}
+	namespaced := make(map[*namespaceState]int64)
+	for i, s := range c.shards.list {
+		if err := c.verifyShard(s, now, late, namespaced); err != nil {
+			return fmt.Errorf("%w: shard %d: %w", ErrCorrupted, i, err)
+		}
+	}
+	c.namespaces.mu.Lock()
+	defer c.namespaces.mu.Unlock()
+	for st := range namespaced {
+		if c.namespaces.states[st.name] != st {
+			return fmt.Errorf("%w: records accounted to namespace %q of another cache", ErrCorrupted, st.name)
+		}
+	}
+	for name, st := range c.namespaces.states {
+		if entries := st.entries.Load(); entries != namespaced[st] {
+			return fmt.Errorf("%w: namespace %q accounts %d records, holds %d", ErrCorrupted, name, entries, namespaced[st])
+		}
+	}
+	return nil
+}
+
+// verifyShard checks the read locked shard s, see Verify. Records outdated for
+// more than late are reported unless late is negative. The records of every
+// namespace are counted in namespaced.
+func (c *Cache) verifyShard(s *shard, now, late int64, namespaced map[*namespaceState]int64) error {
+	n := len(s.keys)
+	if len(s.deadlines) != n || len(s.values) != n || len(s.versions) != n {
+		return fmt.Errorf("%d keys, %d deadlines, %d values and %d versions", n, len(s.deadlines), len(s.values), len(s.versions))
+	}
+	if indexed := s.index.Count(); indexed != n {
+		return fmt.Errorf("%d keys indexed, %d stored", indexed, n)
+	}
+	for i, key := range s.keys {
+		if j, ok := s.index.Get(key); !ok || j != uint32(i) {
+			return fmt.Errorf("key %d in slot %d indexed in slot %d (%v)", key, i, j, ok)
+		}
+		if s.versions[i] > s.version {
+			return fmt.Errorf("key %d has version %d past the shard's %d", key, s.versions[i], s.version)
+		}
+		if late >= 0 && now-s.deadlines[i] > late {
+			return fmt.Errorf("key %d outdated for %v", key, time.Duration(now-s.deadlines[i]))
+		}
+		if s.filter != nil && !s.filter.contains(key) {
+			return fmt.Errorf("key %d missing from the bloom filter", key)
+		}
+		if nv, ok := s.values[i].(*namespacedValue); ok {
+			namespaced[nv.state]++
+		}
+	}
+	if s.evictor != nil {
+		if uint32(n) > s.maxCount {
+			return fmt.Errorf("%d records past the bound of %d", n, s.maxCount)
+		}
+		if v, ok := s.evictor.(verifier); ok {
+			if err := v.verify(uint32(n)); err != nil {
+				return fmt.Errorf("eviction policy: %w", err)
+			}
+		}
+	}
+	for key, tags := range s.tags {
+		if _, ok := s.index.Get(key); !ok {
+			return fmt.Errorf("tags of missing key %d", key)
+		}
+		for _, tag := range tags {
+			if _, ok := s.tagged[tag][key]; !ok {
+				return fmt.Errorf("key %d missing from tag %q", key, tag)
+			}
+		}
+	}
+	for tag, keys := range s.tagged {
+		for key := range keys {
+			if _, ok := s.tags[key]; !ok {
+				return fmt.Errorf("tag %q of untagged key %d", tag, key)
+			}
+		}
+	}
+	return nil
+}
+
+// verifyPhase runs Verify as a phase of a sweep, see WithVerify.
+func (c *Cache) verifyPhase(ctx context.Context) {
+	withPhase(ctx, "verify", func(context.Context) {
+		if err := c.Verify(); err != nil {
+			c.opts.verify(err)
+		}
+	})
+}
+
+func (e *clockEvictor) verify(n uint32) error {
+	if n > 0 && uint32(len(e.refs))*32 < n {
+		return fmt.Errorf("%d reference bits for %d records", len(e.refs)*32, n)
+	}
+	return nil
+}
+
+func (e *sampledLRU) verify(n uint32) error {
+	if uint32(len(e.used)) < n {
+		return fmt.Errorf("%d ticks for %d records", len(e.used), n)
+	}
+	return nil
+}
+
+// verify walks the segments checking their links, lengths and capacities.
+func (e *tinyLFU) verify(n uint32) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if uint32(len(e.nodes)) < n {
+		return fmt.Errorf("%d nodes for %d records", len(e.nodes), n)
+	}
+	total := uint32(0)
+	for segment := range e.segments {
+		l := &e.segments[segment]
+		prev, count := NoSlot, uint32(0)
+		for i := l.head; i != NoSlot; i = e.nodes[i].next {
+			if i >= n || count == n {
+				return fmt.Errorf("segment %d links slot %d of %d records", segment, i, n)
+			}
+			node := e.nodes[i]
+			if node.prev != prev || node.segment != uint8(segment) {
+				return fmt.Errorf("slot %d of segment %d has prev %d and segment %d", i, segment, node.prev, node.segment)
+			}
+			prev = i
+			count++
+		}
+		if prev != l.tail || count != l.len {
+			return fmt.Errorf("segment %d has %d slots ending at %d, expected %d ending at %d", segment, count, prev, l.len, l.tail)
+		}
+		total += count
+	}
+	if total != n {
+		return fmt.Errorf("segments hold %d slots, the shard %d records", total, n)
+	}
+	if e.segments[windowSegment].len > e.windowCap {
+		return fmt.Errorf("window of %d slots past its capacity of %d", e.segments[windowSegment].len, e.windowCap)
+	}
+	return nil
+}
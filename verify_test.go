@@ -0,0 +1,83 @@
+package ttlswisscache
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCache_Verify(t *testing.T) {
+	policies := []Eviction{CLOCK, TinyLFU, SampledLRU, LRUTTL}
+	for _, policy := range policies {
+		t.Run(policy.String(), func(t *testing.T) {
+			c := New(0, WithShardCount(4), WithMaxEntries(200), WithEviction(policy), WithBloomFilter(1000))
+			defer c.Close()
+			ns := c.Namespace("ns")
+			ns.SetQuota(NamespaceQuota{MaxEntries: 50})
+			for i := 0; i < 1000; i++ {
+				c.SetWithTags(IntKey(i), i, time.Hour, "even", "all")
+				ns.Set(IntKey(i), i, time.Hour)
+				c.Get(IntKey(i / 2))
+				if i%3 == 0 {
+					c.Delete(IntKey(i / 3))
+				}
+			}
+			if err := c.Verify(); err != nil {
+				t.Errorf("incorrect error of a healthy cache: got: %v expected: %v", err, nil)
+			}
+			c.Clear()
+			if err := c.Verify(); err != nil {
+				t.Errorf("incorrect error of a cleared cache: got: %v expected: %v", err, nil)
+			}
+		})
+	}
+}
+
+func TestCache_Verify_Corrupted(t *testing.T) {
+	tt := []struct {
+		name    string
+		corrupt func(c *Cache, s *shard)
+	}{
+		{name: "index", corrupt: func(_ *Cache, s *shard) { s.index.Put(s.keys[0], 1) }},
+		{name: "lengths", corrupt: func(_ *Cache, s *shard) { s.versions = s.versions[:1] }},
+		{name: "eviction", corrupt: func(_ *Cache, s *shard) { s.evictor.(*tinyLFU).segments[windowSegment].len++ }},
+		{name: "namespace", corrupt: func(c *Cache, _ *shard) { c.Namespace("ns").state.entries.Add(1) }},
+		{name: "tags", corrupt: func(_ *Cache, s *shard) { delete(s.tagged["tag"], s.keys[0]) }},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			c := New(0, WithShardCount(1), WithMaxEntries(100), WithEviction(TinyLFU))
+			defer c.Close()
+			for i := 0; i < 10; i++ {
+				c.SetWithTags(IntKey(i), i, time.Hour, "tag")
+				c.Namespace("ns").Set(IntKey(i), i, time.Hour)
+			}
+			tc.corrupt(c, c.shards.list[0])
+			if err := c.Verify(); !errors.Is(err, ErrCorrupted) {
+				t.Errorf("incorrect error: got: %v expected: %v", err, ErrCorrupted)
+			}
+		})
+	}
+}
+
+func TestWithVerify(t *testing.T) {
+	var reported atomic.Value
+	c := New(time.Millisecond, WithVerify(func(err error) { reported.Store(err) }))
+	defer c.Close()
+	c.Set(1, 1, time.Hour)
+	time.Sleep(5 * time.Millisecond)
+	if err := reported.Load(); err != nil {
+		t.Fatalf("healthy cache reported: %v", err)
+	}
+
+	s := c.shards.get(1)
+	s.Lock()
+	s.deadlines[0] = c.clock.unixNano() - int64(time.Hour)
+	s.index.Delete(1) // Hides the record from the sweep.
+	s.Unlock()
+	waitUntil(t, "the corruption report", func() bool { return reported.Load() != nil })
+	if err := reported.Load().(error); !errors.Is(err, ErrCorrupted) {
+		t.Errorf("incorrect error: got: %v expected: %v", err, ErrCorrupted)
+	}
+}
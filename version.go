@@ -0,0 +1,57 @@
+package ttlswisscache
+
+// Version returns the version of the record of key, see GetIfChanged.
+func (c *Cache) Version(key uint64) (uint64, bool) {
+	s := c.shards.get(key)
+	s.RLock()
+	defer s.RUnlock()
+	i, ok := s.index.Get(key)
+	if !ok || s.deadlines[i] < c.clock.unixNano() {
+		return 0, false
+	}
+	return s.versions[i], true
+}
+
+// GetIfChanged returns the value of key and its version unless the record is
+// still at the version since, in which case it reports NotModified without
+// copying the value, like an HTTP ETag. since is a version returned by Set,
+// Version or a previous GetIfChanged, 0 always gets the value.
+//
+// Versions grow every time a value is stored, including again after the key
+// was removed; Expire keeps them. They are only comparable for the same key.
+func (c *Cache) GetIfChanged(key uint64, since uint64) (value interface{}, version uint64, r Result) {
+	s := c.shards.get(key)
+	s.RLock()
+	i, ok := s.index.Get(key)
+	var it item
+	if ok {
+		it = item{deadline: s.deadlines[i], value: s.values[i]}
+		version = s.versions[i]
+	}
+	s.RUnlock()
+	if ok && it.deadline >= c.clock.unixNano() {
+		if version == since {
+			s.stats.hits.Add(1)
+			return nil, version, NotModified
+		}
+		if _, once := it.value.(*onceValue); once {
+			value, found, stored := c.consume(s, key)
+			if !found {
+				return nil, 0, Miss
+			}
+			if stored {
+				value = c.clone(value)
+			}
+			return value, version, Hit
+		}
+		value, ok = it.load()
+	} else {
+		ok = false
+	}
+	if !ok {
+		s.stats.misses.Add(1)
+		return nil, 0, Miss
+	}
+	s.stats.hits.Add(1)
+	return c.clone(value), version, Hit
+}
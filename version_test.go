@@ -0,0 +1,43 @@
+package ttlswisscache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCache_GetIfChanged(t *testing.T) {
+	c := New(0)
+	defer c.Close()
+
+	v1 := c.Set(IntKey(1), "a", time.Hour)
+	if v, ok := c.Version(IntKey(1)); !ok || v != v1 {
+		t.Errorf("incorrect version: got: %d, %v expected: %d, %v", v, ok, v1, true)
+	}
+	if v, version, r := c.GetIfChanged(IntKey(1), 0); r != Hit || v != "a" || version != v1 {
+		t.Errorf("incorrect first read: got: %v, %d, %v expected: %v, %d, %v", v, version, r, "a", v1, Hit)
+	}
+	if v, version, r := c.GetIfChanged(IntKey(1), v1); r != NotModified || v != nil || version != v1 {
+		t.Errorf("incorrect unchanged read: got: %v, %d, %v expected: %v, %d, %v", v, version, r, nil, v1, NotModified)
+	}
+	c.Expire(IntKey(1), 2*time.Hour)
+	if _, _, r := c.GetIfChanged(IntKey(1), v1); r != NotModified {
+		t.Errorf("version changed with the ttl: got: %v expected: %v", r, NotModified)
+	}
+
+	c.Set(IntKey(2), "other", time.Hour)
+	v2 := c.Set(IntKey(1), "b", time.Hour)
+	if v2 <= v1 {
+		t.Errorf("version didn't grow: got: %d expected more than: %d", v2, v1)
+	}
+	if v, version, r := c.GetIfChanged(IntKey(1), v1); r != Hit || v != "b" || version != v2 {
+		t.Errorf("incorrect changed read: got: %v, %d, %v expected: %v, %d, %v", v, version, r, "b", v2, Hit)
+	}
+
+	c.Delete(IntKey(1))
+	if _, _, r := c.GetIfChanged(IntKey(1), v2); r != Miss {
+		t.Errorf("incorrect read of a removed key: got: %v expected: %v", r, Miss)
+	}
+	if v3 := c.Set(IntKey(1), "c", time.Hour); v3 <= v2 {
+		t.Errorf("version didn't grow after a removal: got: %d expected more than: %d", v3, v2)
+	}
+}
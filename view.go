@@ -0,0 +1,108 @@
+package ttlswisscache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrTypeMismatch is returned by TypedView when a record holds a value of
+// another type than the view's.
+var ErrTypeMismatch = errors.New("ttlswisscache: type mismatch")
+
+// TypedView is a view of the records of a Cache holding values of type V,
+// see View. It is safe for concurrent use.
+type TypedView[V any] struct {
+	c *Cache
+}
+
+// View returns a view of c reading and writing values of type V, so code can
+// migrate to typed values one call site at a time while sharing c with
+// untyped code and other views. Views of different types may share c as long
+// as their keys don't collide, e.g. with a Namespace each: the view reports a
+// record of another type as missing on Get and refuses to overwrite it on Set.
+func View[V any](c *Cache) TypedView[V] {
+	return TypedView[V]{c: c}
+}
+
+// Cache returns the cache of the view.
+func (v TypedView[V]) Cache() *Cache {
+	return v.c
+}
+
+// Get returns the value of key like Cache.Get if the record holds a V.
+// A record holding another type is reported as missing.
+func (v TypedView[V]) Get(key uint64) (V, bool) {
+	value, ok := v.c.Get(key)
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	typed, ok := value.(V)
+	return typed, ok
+}
+
+// Set adds value to the cache with given ttl like Cache.Set, unless the cache
+// holds a live record of key with a value of another type: the record is then
+// kept and Set returns an error wrapping ErrTypeMismatch. A value rejected by
+// WithMaxValueSize returns ErrValueTooLarge, a live immutable record or
+// tombstone of key ErrImmutable, and after Close Set returns ErrClosed. Sets
+// of the view aren't coalesced, see WithSetCoalescing.
+func (v TypedView[V]) Set(key uint64, value V, ttl time.Duration) error {
+	c := v.c
+	stored, ok := c.limit(key, value)
+	if !ok {
+		c.reject(key)
+		return ErrValueTooLarge
+	}
+	now := c.clock.unixNano()
+	s := c.shards.get(key)
+	s.Lock()
+	if c.closing.Load() {
+		s.Unlock()
+		return ErrClosed
+	}
+	if it, ok := s.get(key); ok && it.deadline >= now {
+		if old, ok := it.load(); ok {
+			if _, ok := old.(V); !ok {
+				s.Unlock()
+				return fmt.Errorf("%w: key %d holds a %T, not a %T", ErrTypeMismatch, key, old, value)
+			}
+		}
+	}
+	if c.protected(s, key) {
+		s.Unlock()
+		return ErrImmutable
+	}
+	it := item{deadline: now + int64(ttl), value: c.clone(stored)}
+	s.put(key, it)
+	c.subs.publish(OpSet, key, it)
+	s.Unlock()
+	s.stats.sets.Add(1)
+	return nil
+}
+
+// Delete removes the record of key whatever the type of its value.
+func (v TypedView[V]) Delete(key uint64) {
+	v.c.Delete(key)
+}
+
+// GetCtx returns the value of key like Cache.GetCtx, loading it with loader
+// on a miss. A record holding another type, cached or loaded by a concurrent
+// untyped caller, returns an error wrapping ErrTypeMismatch.
+func (v TypedView[V]) GetCtx(ctx context.Context, key uint64, loader func(ctx context.Context) (V, time.Duration, error)) (V, error) {
+	value, err := v.c.GetCtx(ctx, key, func(ctx context.Context) (interface{}, time.Duration, error) {
+		value, ttl, err := loader(ctx)
+		return value, ttl, err
+	})
+	var zero V
+	if err != nil {
+		return zero, err
+	}
+	typed, ok := value.(V)
+	if !ok {
+		return zero, fmt.Errorf("%w: key %d holds a %T, not a %T", ErrTypeMismatch, key, value, zero)
+	}
+	return typed, nil
+}
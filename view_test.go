@@ -0,0 +1,101 @@
+package ttlswisscache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type user struct {
+	name string
+}
+
+func TestView(t *testing.T) {
+	c := New(0)
+	defer c.Close()
+	users := View[*user](c)
+
+	if err := users.Set(1, &user{name: "ada"}, time.Minute); err != nil {
+		t.Errorf("incorrect error: got: %v expected: %v", err, nil)
+	}
+	if u, ok := users.Get(1); !ok || u.name != "ada" {
+		t.Errorf("incorrect value: got: %v, %v expected: %v", u, ok, "ada")
+	}
+	if v, ok := c.Get(1); !ok || v.(*user).name != "ada" {
+		t.Errorf("incorrect untyped value: got: %v, %v expected: %v", v, ok, "ada")
+	}
+
+	c.Set(2, "not a user", time.Minute)
+	if u, ok := users.Get(2); ok {
+		t.Errorf("record of another type was returned: %v", u)
+	}
+	if err := users.Set(2, &user{name: "bob"}, time.Minute); !errors.Is(err, ErrTypeMismatch) {
+		t.Errorf("incorrect error: got: %v expected: %v", err, ErrTypeMismatch)
+	}
+	if v, _ := c.Get(2); v != "not a user" {
+		t.Errorf("record of another type was overwritten: got: %v expected: %v", v, "not a user")
+	}
+
+	// Outdated records of another type are overwritten.
+	c.Set(3, 3, -time.Second)
+	if err := users.Set(3, &user{name: "eve"}, time.Minute); err != nil {
+		t.Errorf("incorrect error: got: %v expected: %v", err, nil)
+	}
+
+	users.Delete(2)
+	if err := users.Set(2, &user{name: "bob"}, time.Minute); err != nil {
+		t.Errorf("incorrect error: got: %v expected: %v", err, nil)
+	}
+
+	c.Close()
+	if err := users.Set(4, &user{}, time.Minute); !errors.Is(err, ErrClosed) {
+		t.Errorf("incorrect error after Close: got: %v expected: %v", err, ErrClosed)
+	}
+}
+
+func TestView_Rejected(t *testing.T) {
+	c := New(0, WithMaxValueSize(4, RejectOversized))
+	defer c.Close()
+	names := View[string](c)
+
+	if err := names.Set(1, "too long", time.Minute); !errors.Is(err, ErrValueTooLarge) {
+		t.Errorf("incorrect error of an oversized value: got: %v expected: %v", err, ErrValueTooLarge)
+	}
+	if _, ok := names.Get(1); ok {
+		t.Errorf("oversized value was stored")
+	}
+
+	c.SetImmutable(2, "ada", time.Minute)
+	if err := names.Set(2, "bob", time.Minute); !errors.Is(err, ErrImmutable) {
+		t.Errorf("incorrect error of an immutable key: got: %v expected: %v", err, ErrImmutable)
+	}
+	if name, _ := names.Get(2); name != "ada" {
+		t.Errorf("immutable record was overwritten: got: %v expected: %v", name, "ada")
+	}
+}
+
+func TestView_GetCtx(t *testing.T) {
+	c := New(0)
+	defer c.Close()
+	counts := View[int](c)
+	loads := 0
+	load := func(context.Context) (int, time.Duration, error) {
+		loads++
+		return 42, time.Minute, nil
+	}
+
+	for i := 0; i < 2; i++ {
+		if n, err := counts.GetCtx(t.Context(), 1, load); err != nil || n != 42 {
+			t.Errorf("incorrect value: got: %v, %v expected: %v", n, err, 42)
+		}
+	}
+	if loads != 1 {
+		t.Errorf("incorrect number of loads: got: %d expected: %d", loads, 1)
+	}
+
+	c.Set(2, "two", time.Minute)
+	if _, err := counts.GetCtx(t.Context(), 2, load); !errors.Is(err, ErrTypeMismatch) {
+		t.Errorf("incorrect error: got: %v expected: %v", err, ErrTypeMismatch)
+	}
+}
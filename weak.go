@@ -0,0 +1,79 @@
+package ttlswisscache
+
+import (
+	"runtime"
+	"time"
+	"weak"
+)
+
+// weakValue is a value the cache holds through a weak pointer, see SetWeak.
+type weakValue struct {
+	load func() (interface{}, bool)
+}
+
+// SetWeak adds value to the cache with given ttl holding only a weak pointer to it.
+// The garbage collector may reclaim the value once nothing else references it,
+// e.g. under memory pressure for large values kept elsewhere by the application.
+// Get then reports a miss, and the record is removed and counted as collected.
+// Get returns the *T while the value is alive.
+//
+// Snapshot skips collected values, Merge copies the weak pointer as is.
+func SetWeak[T any](c *Cache, key uint64, value *T, ttl time.Duration) {
+	p := weak.Make(value)
+	wv := &weakValue{load: func() (interface{}, bool) {
+		v := p.Value()
+		if v == nil {
+			return nil, false
+		}
+		return v, true
+	}}
+	c.store(key, item{deadline: c.clock.unixNano() + int64(ttl), value: wv})
+	runtime.AddCleanup(value, c.dropCollected, weakRecord{key: key, value: wv})
+}
+
+// weakRecord identifies a weak record waiting for its value to be collected.
+type weakRecord struct {
+	key   uint64
+	value *weakValue
+}
+
+// dropCollected removes the record of a collected value unless it has been set again.
+func (c *Cache) dropCollected(r weakRecord) {
+	s := c.shards.get(r.key)
+	s.Lock()
+	if it, ok := s.get(r.key); ok && it.value == interface{}(r.value) {
+		s.delete(r.key)
+		s.stats.collected.Add(1)
+		c.subs.publish(OpExpire, r.key, it)
+	}
+	s.Unlock()
+	c.cascade()
+}
+
+// load returns the value of the record, resolving weak pointers and
+// dropping the original keys of Keyed records.
+// It reports false if the value has been collected or the record caches
+// a missing key, see SetNotFound.
+func (it item) load() (interface{}, bool) {
+	switch v := it.value.(type) {
+	case *weakValue:
+		return v.load()
+	case *keyedValue:
+		return v.value, true
+	case *refreshedValue:
+		return v.value, true
+	case *onceValue:
+		return v.value, true
+	case *idleValue:
+		return v.value, true
+	case *immutableValue:
+		return v.value, true
+	case *costedValue:
+		return v.value, true
+	case *namespacedValue:
+		return v.value, true
+	case notFoundValue, tombstoneValue:
+		return nil, false
+	}
+	return it.value, true
+}
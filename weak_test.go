@@ -0,0 +1,68 @@
+package ttlswisscache
+
+import (
+	"runtime"
+	"testing"
+	"time"
+)
+
+type largeValue struct {
+	data [1 << 16]byte
+}
+
+func TestSetWeak(t *testing.T) {
+	c := New(0)
+	defer c.Close()
+
+	value := &largeValue{}
+	value.data[0] = 1
+	SetWeak(c, IntKey(1), value, time.Hour)
+
+	got, ok := c.Get(IntKey(1))
+	if !ok || got.(*largeValue) != value {
+		t.Errorf("incorrect value: got: %v, %v expected: %p, true", got, ok, value)
+	}
+	if _, ok := c.TTL(IntKey(1)); !ok {
+		t.Errorf("incorrect ttl existence: got: %v expected: %v", ok, true)
+	}
+	runtime.KeepAlive(value)
+}
+
+func TestSetWeak_Collected(t *testing.T) {
+	c := New(0)
+	defer c.Close()
+
+	SetWeak(c, IntKey(1), &largeValue{}, time.Hour)
+	deadline := time.Now().Add(5 * time.Second)
+	for c.Stats().Collected == 0 && time.Now().Before(deadline) {
+		runtime.GC()
+		time.Sleep(time.Millisecond)
+	}
+
+	if value, ok := c.Get(IntKey(1)); ok {
+		t.Errorf("incorrect value: got: %v, %v expected: <nil>, false", value, ok)
+	}
+	st := c.Stats()
+	if st.Collected != 1 || st.Entries != 0 {
+		t.Errorf("incorrect stats: got: %+v expected: Collected 1, Entries 0", st)
+	}
+}
+
+func TestSetWeak_SetAgain(t *testing.T) {
+	c := New(0)
+	defer c.Close()
+
+	SetWeak(c, IntKey(1), &largeValue{}, time.Hour)
+	c.Set(IntKey(1), 2, time.Hour)
+	for i := 0; i < 3; i++ {
+		runtime.GC()
+		time.Sleep(time.Millisecond)
+	}
+
+	if value, ok := c.Get(IntKey(1)); !ok || value != 2 {
+		t.Errorf("incorrect value: got: %v, %v expected: 2, true", value, ok)
+	}
+	if st := c.Stats(); st.Collected != 0 {
+		t.Errorf("incorrect collected: got: %d expected: %d", st.Collected, 0)
+	}
+}
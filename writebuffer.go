@@ -0,0 +1,191 @@
+package ttlswisscache
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// maxWriteBatch bounds the number of writes applied under a single drain of the buffer.
+const maxWriteBatch = 1024
+
+// pendingWrite is a write waiting in the buffer.
+// A write with a non-nil flushed channel is a Flush marker.
+type pendingWrite struct {
+	shard   int
+	key     uint64
+	it      item
+	flushed chan struct{}
+}
+
+// WritePolicy tells SetAsync what to do when the write buffer is full.
+type WritePolicy uint8
+
+const (
+	// BlockWhenFull waits for room in the buffer.
+	BlockWhenFull WritePolicy = iota
+	// DropWhenFull discards the write and counts it in Stats.AsyncDropped.
+	DropWhenFull
+	// SyncWhenFull stores the value right away like Set. Older writes of the
+	// same key still queued may then overwrite it.
+	SyncWhenFull
+)
+
+// writeBuffer queues writes for a background applier.
+type writeBuffer struct {
+	ch      chan pendingWrite
+	policy  WritePolicy
+	dropped atomic.Uint64
+	batch   []pendingWrite
+	sorted  []pendingWrite
+	counts  []int // Writes per shard in the batch, then offsets in sorted.
+}
+
+func newWriteBuffer(size, shards int, policy WritePolicy) *writeBuffer {
+	return &writeBuffer{
+		ch:     make(chan pendingWrite, size),
+		policy: policy,
+		batch:  make([]pendingWrite, 0, maxWriteBatch),
+		sorted: make([]pendingWrite, maxWriteBatch),
+		counts: make([]int, shards),
+	}
+}
+
+// SetAsync queues the value to be stored with given ttl by a background applier.
+// Writes are grouped by shard, so every shard lock is taken once per batch
+// instead of once per write. The record is not visible until it is applied, call
+// Flush to wait. Async writes are applied in call order among themselves, but
+// not with respect to Set and other synchronous writes.
+// When the buffer is full SetAsync blocks, unless WithWritePolicy says otherwise.
+// Without WithWriteBuffer it is Set.
+// It is dropped once Shutdown has been called.
+// The deadline is computed when SetAsync is called.
+func (c *Cache) SetAsync(key uint64, value interface{}, ttl time.Duration) {
+	value, ok := c.limit(key, value)
+	if !ok {
+		return
+	}
+	it := item{deadline: c.clock.unixNano() + int64(ttl), value: c.clone(value)}
+	if c.writes == nil || c.closing.Load() {
+		c.store(key, it)
+		return
+	}
+	w := pendingWrite{shard: int(c.shards.hash(key) >> c.shards.shift), key: key, it: it}
+	if c.writes.policy != BlockWhenFull {
+		select {
+		case c.writes.ch <- w:
+		default:
+			if c.writes.policy == SyncWhenFull {
+				c.store(key, it)
+			} else {
+				c.writes.dropped.Add(1)
+			}
+		}
+		return
+	}
+	select {
+	case c.writes.ch <- w:
+	case <-c.done:
+	}
+}
+
+// Flush waits until the writes queued by SetAsync before the call are applied,
+// and stores the Sets deferred by WithSetCoalescing.
+func (c *Cache) Flush() {
+	c.flush(context.Background())
+}
+
+// flush is Flush giving up when ctx is done.
+func (c *Cache) flush(ctx context.Context) error {
+	if c.sets != nil {
+		c.sets.flush()
+	}
+	if c.writes == nil {
+		return nil
+	}
+	flushed := make(chan struct{})
+	select {
+	case c.writes.ch <- pendingWrite{flushed: flushed}:
+	case <-c.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case <-flushed:
+		return nil
+	case <-c.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// applyWrites drains the buffer until the cache is closed.
+func (c *Cache) applyWrites() {
+	wb := c.writes
+	for {
+		select {
+		case w := <-wb.ch:
+			wb.batch = append(wb.batch[:0], w)
+		case <-c.done:
+			return
+		}
+	drain:
+		for len(wb.batch) < maxWriteBatch {
+			select {
+			case w := <-wb.ch:
+				wb.batch = append(wb.batch, w)
+			default:
+				break drain
+			}
+		}
+		c.applyBatch(wb)
+	}
+}
+
+// applyBatch stores the batch shard by shard and then releases the Flush markers.
+// The batch is grouped with a counting sort, which keeps the order of writes
+// to the same key.
+func (c *Cache) applyBatch(wb *writeBuffer) {
+	clear(wb.counts)
+	for i := range wb.batch {
+		wb.counts[wb.batch[i].shard]++
+	}
+	offset := 0
+	for i, n := range wb.counts {
+		wb.counts[i] = offset
+		offset += n
+	}
+	sorted := wb.sorted[:len(wb.batch)]
+	for i := range wb.batch {
+		w := &wb.batch[i]
+		sorted[wb.counts[w.shard]] = *w
+		wb.counts[w.shard]++
+	}
+
+	for i := 0; i < len(sorted); {
+		s := c.shards.list[sorted[i].shard]
+		j, sets := i, 0
+		s.Lock()
+		closed := c.closed.Load()
+		for ; j < len(sorted) && sorted[j].shard == sorted[i].shard; j++ {
+			if sorted[j].flushed != nil || closed || c.protected(s, sorted[j].key) {
+				continue
+			}
+			s.put(sorted[j].key, sorted[j].it)
+			c.subs.publish(OpSet, sorted[j].key, sorted[j].it)
+			sets++
+		}
+		s.Unlock()
+		s.stats.sets.Add(uint64(sets))
+		i = j
+	}
+	for i := range sorted {
+		if sorted[i].flushed != nil {
+			close(sorted[i].flushed)
+		}
+	}
+	clear(sorted)
+	clear(wb.batch)
+}
@@ -0,0 +1,135 @@
+package ttlswisscache
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCache_SetAsync(t *testing.T) {
+	c := New(time.Hour, WithWriteBuffer(128))
+	defer c.Close()
+
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				c.SetAsync(IntKey(g*1000+i), i, time.Hour)
+			}
+		}(g)
+	}
+	wg.Wait()
+	c.Flush()
+
+	if n := c.Stats().Entries; n != 4000 {
+		t.Errorf("incorrect number of records: got: %d expected: %d", n, 4000)
+	}
+	if v, ok := c.Get(IntKey(2999)); !ok || v != 999 {
+		t.Errorf("incorrect value: got: %v expected: %v", v, 999)
+	}
+}
+
+func TestCache_SetAsync_Order(t *testing.T) {
+	c := New(time.Hour, WithWriteBuffer(16))
+	defer c.Close()
+
+	for i := 0; i < 1000; i++ {
+		c.SetAsync(IntKey(i%3), i, time.Hour)
+	}
+	c.Flush()
+	for k, expected := range []int{999, 997, 998} {
+		if v, _ := c.Get(IntKey(k)); v != expected {
+			t.Errorf("incorrect last write for key %d: got: %v expected: %v", k, v, expected)
+		}
+	}
+}
+
+func TestCache_SetAsync_Unbuffered(t *testing.T) {
+	c := New(time.Hour)
+	defer c.Close()
+
+	c.SetAsync(IntKey(1), 1, time.Hour)
+	c.Flush()
+	if v, ok := c.Get(IntKey(1)); !ok || v != 1 {
+		t.Errorf("incorrect value: got: %v expected: %v", v, 1)
+	}
+}
+
+func TestCache_SetAsync_Close(t *testing.T) {
+	c := New(time.Hour, WithWriteBuffer(1))
+	c.Close()
+
+	done := make(chan struct{})
+	go func() {
+		c.SetAsync(IntKey(1), 1, time.Hour)
+		c.SetAsync(IntKey(2), 2, time.Hour)
+		c.Flush()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Error("SetAsync blocked after Close")
+	}
+}
+
+// stallApplier blocks the applier of c on shard 0 until the returned function is called.
+func stallApplier(t *testing.T, c *Cache, key uint64) func() {
+	s := c.shards.list[0]
+	s.Lock()
+	c.SetAsync(key, 0, time.Hour)
+	for deadline := time.Now().Add(5 * time.Second); len(c.writes.ch) > 0; {
+		if time.Now().After(deadline) {
+			t.Fatal("applier did not take the write")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return s.Unlock
+}
+
+// keyOfShard returns a key of shard i from start on.
+func keyOfShard(c *Cache, i int, start uint64) uint64 {
+	for key := start; ; key++ {
+		if int(c.shards.hash(key)>>c.shards.shift) == i {
+			return key
+		}
+	}
+}
+
+func TestWithWritePolicy_Drop(t *testing.T) {
+	c := New(0, WithShardCount(1), WithWriteBuffer(4), WithWritePolicy(DropWhenFull))
+	defer c.Close()
+	resume := stallApplier(t, c, IntKey(100))
+	for i := 0; i < 10; i++ {
+		c.SetAsync(IntKey(i), i, time.Hour)
+	}
+	if n := len(c.writes.ch); n != 4 {
+		t.Errorf("incorrect queue depth: got: %d expected: %d", n, 4)
+	}
+	resume()
+	c.Flush()
+
+	st := c.Stats()
+	if st.AsyncDropped != 6 || st.AsyncQueued != 0 || st.Entries != 5 {
+		t.Errorf("incorrect stats: got: %+v expected: AsyncDropped 6, AsyncQueued 0, Entries 5", st)
+	}
+}
+
+func TestWithWritePolicy_Sync(t *testing.T) {
+	c := New(0, WithShardCount(2), WithWriteBuffer(1), WithWritePolicy(SyncWhenFull))
+	defer c.Close()
+	resume := stallApplier(t, c, keyOfShard(c, 0, 0))
+	c.SetAsync(keyOfShard(c, 0, 1000), 1, time.Hour)
+	key := keyOfShard(c, 1, 2000)
+	c.SetAsync(key, 2, time.Hour)
+	if v, ok := c.Get(key); !ok || v != 2 {
+		t.Errorf("write was not applied synchronously: got: %v, %v expected: 2, true", v, ok)
+	}
+	resume()
+	c.Flush()
+	if st := c.Stats(); st.AsyncDropped != 0 || st.Entries != 3 {
+		t.Errorf("incorrect stats: got: %+v expected: AsyncDropped 0, Entries 3", st)
+	}
+}
@@ -0,0 +1,53 @@
+package ttlswisscache
+
+import (
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// costedValue is a value stored with the time it took to compute, see WithEarlyExpiration.
+type costedValue struct {
+	value interface{}
+	cost  int64
+}
+
+// WithEarlyExpiration makes Get and Lookup report records stored with a
+// recompute cost as missing a little before their deadline, following the
+// XFetch algorithm: a read at now misses if now - cost*beta*ln(rand) reaches
+// the deadline, rand being uniform in (0, 1]. The costlier the value the
+// earlier the first misses, so a single caller usually recomputes it while
+// the others still hit, instead of all of them missing at the deadline.
+// beta is usually 1, larger values expire earlier. GetCtx stores loaded values
+// with the duration of the loader call, SetWithRecomputeCost with a given one.
+// Outdated records are reported as missing. Early misses are counted in
+// Stats.EarlyExpirations.
+func WithEarlyExpiration(beta float64) Option {
+	return func(o *options) {
+		o.earlyBeta = beta
+	}
+}
+
+// SetWithRecomputeCost adds value with given ttl like Set, along with the time
+// it took to compute, see WithEarlyExpiration.
+func (c *Cache) SetWithRecomputeCost(key uint64, value interface{}, ttl, cost time.Duration) uint64 {
+	value, ok := c.limit(key, value)
+	if !ok {
+		c.reject(key)
+		return 0
+	}
+	return c.store(key, item{
+		deadline: c.clock.unixNano() + int64(ttl),
+		value:    &costedValue{value: c.clone(value), cost: int64(cost)},
+	})
+}
+
+// early reports whether a read of it expires it early, see WithEarlyExpiration.
+func (c *Cache) early(it item) bool {
+	v, ok := it.value.(*costedValue)
+	if !ok || c.opts.earlyBeta <= 0 {
+		return false
+	}
+	gap := -float64(v.cost) * c.opts.earlyBeta * math.Log(1-rand.Float64())
+	return float64(c.clock.unixNano())+gap >= float64(it.deadline)
+}
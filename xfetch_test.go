@@ -0,0 +1,51 @@
+package ttlswisscache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestWithEarlyExpiration(t *testing.T) {
+	clock := &fixedClock{now: time.Unix(1000, 0)}
+	c := New(0, WithClock(clock), WithEarlyExpiration(1))
+	defer c.Close()
+	c.SetWithRecomputeCost(IntKey(1), "costly", time.Hour, time.Minute)
+	c.Set(IntKey(2), "plain", time.Hour)
+
+	misses := func(key uint64) int {
+		n := 0
+		for i := 0; i < 1000; i++ {
+			if _, ok := c.Get(key); !ok {
+				n++
+			}
+		}
+		return n
+	}
+	clock.now = clock.now.Add(50 * time.Minute)
+	if n := misses(IntKey(1)); n > 5 {
+		t.Errorf("incorrect misses 10 costs before the deadline: got: %d expected: at most %d", n, 5)
+	}
+	clock.now = clock.now.Add(9*time.Minute + 30*time.Second)
+	// A read misses with probability e^-0.5, about 0.61.
+	if n := misses(IntKey(1)); n < 500 || n > 700 {
+		t.Errorf("incorrect misses half a cost before the deadline: got: %d expected: in [%d, %d]", n, 500, 700)
+	}
+	if n := misses(IntKey(2)); n != 0 {
+		t.Errorf("record without a recompute cost expired early: got: %d misses", n)
+	}
+	if st := c.Stats(); st.EarlyExpirations < 500 || st.EarlyExpirations != st.Misses {
+		t.Errorf("incorrect early expirations: got: %d of %d misses", st.EarlyExpirations, st.Misses)
+	}
+
+	loader := func(context.Context) (interface{}, time.Duration, error) {
+		clock.now = clock.now.Add(time.Minute)
+		return "loaded", time.Hour, nil
+	}
+	c.GetCtx(context.Background(), IntKey(3), loader)
+	clock.now = clock.now.Add(time.Hour - time.Second)
+	// A read misses with probability e^-1/60, about 0.98.
+	if n := misses(IntKey(3)); n < 900 {
+		t.Errorf("loaded value wasn't stored with the duration of the loader call: got: %d misses", n)
+	}
+}